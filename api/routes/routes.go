@@ -1,25 +1,49 @@
 package routes
 
 import (
+	"bms-backend/internal/amenities"
+	"bms-backend/internal/billing"
+	"bms-backend/internal/blacklist"
 	"bms-backend/internal/config"
 	"bms-backend/internal/handlers"
 	"bms-backend/internal/middleware"
+	"bms-backend/internal/parcels"
+	"bms-backend/internal/parking"
+	"bms-backend/internal/passes"
+	"bms-backend/internal/payments"
+	"bms-backend/internal/receipts"
+	"bms-backend/internal/staff"
+	"bms-backend/internal/store"
+	"bms-backend/internal/uploads"
+	"bms-backend/internal/visitors"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func InitializeRoutes(router *gin.Engine, db *mongo.Database) {
+func InitializeRoutes(router *gin.Engine, s *store.Store, billingEngine *billing.Engine, documents *receipts.Service, amenityService *amenities.Service, paymentService *payments.Service, visitorService *visitors.Service, parcelService *parcels.Service, blacklistService *blacklist.Service, parkingService *parking.Service, uploadService *uploads.Service) {
 	cfg := config.Load()
 
 	// Initialize ALL handlers
-	authHandler := handlers.NewAuthHandler(db, cfg.JWTSecret)
-	userHandler := handlers.NewUserHandler(db)
-	visitorHandler := handlers.NewVisitorHandler(db)
-	maintenanceHandler := handlers.NewMaintenanceHandler(db)
-	amenityHandler := handlers.NewAmenityHandler(db)
-	noticeHandler := handlers.NewNoticeHandler(db)
-	analyticsHandler := handlers.NewAnalyticsHandler(db)
+	authHandler := handlers.NewAuthHandler(s, cfg.JWTSecret)
+	userHandler := handlers.NewUserHandler(s)
+	signer := passes.NewSigner(cfg.VisitorPassSecret, cfg.VisitorPassEarly, cfg.VisitorPassLate)
+	visitorHandler := handlers.NewVisitorHandler(s, signer, visitorService, blacklistService, parkingService)
+	blacklistHandler := handlers.NewBlacklistHandler(s, blacklistService)
+	parcelHandler := handlers.NewParcelHandler(s, parcelService)
+	parkingHandler := handlers.NewParkingHandler(s, parkingService)
+	uploadHandler := handlers.NewUploadHandler(s, uploadService)
+	staffHandler := handlers.NewStaffHandler(s, signer, staff.NewService(s, signer, cfg.StaffPassLifetime))
+	maintenanceHandler := handlers.NewMaintenanceHandler(s, paymentService, documents)
+	amenityHandler := handlers.NewAmenityHandler(s, amenityService, paymentService)
+	noticeHandler := handlers.NewNoticeHandler(s)
+	analyticsHandler := handlers.NewAnalyticsHandler(s)
+	societyHandler := handlers.NewSocietyHandler(s)
+	unitHandler := handlers.NewUnitHandler(s)
+	billingHandler := handlers.NewBillingHandler(s, billingEngine)
+	paymentHandler := handlers.NewPaymentHandler(s, paymentService)
+	receiptHandler := handlers.NewReceiptHandler(s, documents)
+	notificationHandler := handlers.NewNotificationHandler(s)
+	calendarHandler := handlers.NewCalendarHandler(s)
 
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
@@ -42,15 +66,38 @@ func InitializeRoutes(router *gin.Engine, db *mongo.Database) {
 		{
 			auth.POST("/login", authHandler.Login)
 			auth.POST("/register", authHandler.Register)
+			auth.POST("/admin/login", authHandler.AdminLogin)
 		}
 
-		// QR code lookup (public for security guards)
+		// Payment provider callbacks (public, verified by signature)
+		api.POST("/payments/webhook/:provider", paymentHandler.Webhook)
+
+		// iCalendar feeds (public, authorised by the revocable token in the URL)
+		api.GET("/calendar/:token", calendarHandler.ServeFeed)
+
+		// Visitor pass lookup (public for security guards, verified by signature)
 		api.GET("/visitors/qr/:qrcode", visitorHandler.GetVisitorByQR)
 	}
 
+	// Platform administration (no society context)
+	admin := api.Group("/admin")
+	admin.Use(middleware.AuthMiddleware(), middleware.RequireRole("platform_admin"))
+	{
+		societies := admin.Group("/societies")
+		{
+			societies.GET("", societyHandler.GetSocieties)
+			societies.POST("", societyHandler.CreateSociety)
+			societies.GET("/:id", societyHandler.GetSocietyByID)
+			societies.PUT("/:id", societyHandler.UpdateSociety)
+			societies.PUT("/:id/status", societyHandler.SetSocietyStatus)
+			societies.POST("/:id/buildings", societyHandler.AddBuilding)
+			societies.DELETE("/:id/buildings/:buildingId", societyHandler.RemoveBuilding)
+		}
+	}
+
 	// Protected routes (all require society context)
 	protected := api.Group("")
-	protected.Use(middleware.AuthMiddleware())
+	protected.Use(middleware.AuthMiddleware(), middleware.RequireSociety())
 	{
 		analytics := protected.Group("/analytics")
 		{
@@ -61,39 +108,202 @@ func InitializeRoutes(router *gin.Engine, db *mongo.Database) {
 		users := protected.Group("/users")
 		{
 			users.GET("/profile", authHandler.GetProfile)
+			users.POST("/profile/avatar", uploadHandler.UploadAvatar)
 			users.GET("/residents", middleware.RequireRole("secretary"), userHandler.GetResidents)
 			users.GET("/stats", middleware.RequireRole("secretary", "security"), userHandler.GetStats)
 			users.GET("/:id", userHandler.GetUserByID)
 		}
 
+		// Unit routes (all society-aware)
+		units := protected.Group("/units")
+		{
+			units.GET("", unitHandler.GetUnits)
+			units.GET("/:id", unitHandler.GetUnitByID)
+			units.GET("/:id/balance", unitHandler.GetBalance)
+			units.GET("/:id/statement", unitHandler.GetStatement)
+			units.POST("", middleware.RequireRole("secretary"), unitHandler.CreateUnit)
+			units.POST("/generate", middleware.RequireRole("secretary"), unitHandler.GenerateUnits)
+			units.PUT("/:id/occupant", middleware.RequireRole("secretary"), unitHandler.AssignOccupant)
+			units.PUT("/:id/move-out", middleware.RequireRole("secretary"), unitHandler.MoveOut)
+		}
+
 		// Visitor routes (all society-aware)
 		visitors := protected.Group("/visitors")
 		{
 			visitors.GET("", visitorHandler.GetVisitors)
 			visitors.POST("", middleware.RequireRole("resident"), visitorHandler.CreateVisitor)
 			visitors.GET("/pending", middleware.RequireRole("secretary", "security"), userHandler.GetPendingVisitors)
+			visitors.POST("/walk-in", middleware.RequireRole("security"), visitorHandler.RegisterWalkIn)
+			visitors.GET("/approvals", middleware.RequireRole("resident"), visitorHandler.GetHostApprovals)
+			visitors.GET("/overstaying", middleware.RequireRole("secretary", "security"), visitorHandler.GetOverstayingVisitors)
+			visitors.GET("/export", middleware.RequireRole("secretary"), visitorHandler.ExportVisitors)
+			visitors.PUT("/:id/respond", middleware.RequireRole("resident"), visitorHandler.RespondToWalkIn)
+			visitors.GET("/:id/host-contacts", middleware.RequireRole("secretary", "security"), visitorHandler.GetHostContacts)
 			visitors.GET("/:id", middleware.RequireRole("resident", "secretary", "security"), visitorHandler.GetVisitorByID)
 			visitors.PUT("/:id/approve", middleware.RequireRole("secretary", "security"), visitorHandler.ApproveVisitor)
 			visitors.PUT("/:id/checkin", middleware.RequireRole("security"), visitorHandler.CheckInVisitor)
 			visitors.PUT("/:id/checkout", middleware.RequireRole("security"), visitorHandler.CheckOutVisitor)
+			visitors.PUT("/:id/cancel", middleware.RequireRole("resident", "secretary"), visitorHandler.CancelVisitor)
+			visitors.PUT("/:id/pass", middleware.RequireRole("resident", "secretary"), visitorHandler.ReissuePass)
+			visitors.POST("/:id/photo", middleware.RequireRole("resident", "secretary", "security"), uploadHandler.UploadVisitorPhoto)
+			visitors.POST("/:id/id-proof", middleware.RequireRole("secretary", "security"), uploadHandler.UploadVisitorIDProof)
+			visitors.GET("/:id/pass.png", middleware.RequireRole("resident", "secretary", "security"), visitorHandler.GetPassPNG)
+			visitors.GET("/:id/pass.svg", middleware.RequireRole("resident", "secretary", "security"), visitorHandler.GetPassSVG)
+			visitors.GET("/:id/pass-card.png", middleware.RequireRole("resident", "secretary", "security"), visitorHandler.GetPassCard)
+			visitors.PUT("/pass/:token/checkin", middleware.RequireRole("security"), visitorHandler.CheckInByPass)
+		}
+
+		// Society blacklist and watchlist screened at registration and check-in
+		blacklistRoutes := protected.Group("/blacklist")
+		{
+			blacklistRoutes.GET("", middleware.RequireRole("secretary", "security"), blacklistHandler.GetBlacklist)
+			blacklistRoutes.POST("", middleware.RequireRole("secretary", "security"), blacklistHandler.AddToBlacklist)
+			blacklistRoutes.POST("/check", middleware.RequireRole("security"), blacklistHandler.CheckBlacklist)
+			blacklistRoutes.GET("/:id", middleware.RequireRole("secretary", "security"), blacklistHandler.GetBlacklistEntry)
+			blacklistRoutes.PUT("/:id", middleware.RequireRole("secretary", "security"), blacklistHandler.UpdateBlacklistEntry)
+			blacklistRoutes.PUT("/:id/remove", middleware.RequireRole("secretary"), blacklistHandler.RemoveFromBlacklist)
+		}
+
+		// Parcels logged at the security desk (all society-aware)
+		parcelRoutes := protected.Group("/parcels")
+		{
+			parcelRoutes.GET("", parcelHandler.GetParcels)
+			parcelRoutes.POST("", middleware.RequireRole("security"), parcelHandler.ReceiveParcel)
+			parcelRoutes.GET("/:id", parcelHandler.GetParcelByID)
+			parcelRoutes.PUT("/:id/otp", middleware.RequireRole("resident"), parcelHandler.ResendOTP)
+			parcelRoutes.POST("/:id/signature", middleware.RequireRole("security"), uploadHandler.UploadParcelSignature)
+			parcelRoutes.PUT("/:id/collect", middleware.RequireRole("security"), parcelHandler.CollectParcel)
+			parcelRoutes.PUT("/:id/return", middleware.RequireRole("secretary", "security"), parcelHandler.ReturnParcel)
+		}
+
+		// Resident vehicles (all society-aware)
+		vehicles := protected.Group("/vehicles")
+		{
+			vehicles.GET("", middleware.RequireRole("resident", "secretary", "security"), parkingHandler.GetVehicles)
+			vehicles.POST("", middleware.RequireRole("resident", "secretary"), parkingHandler.RegisterVehicle)
+			vehicles.GET("/:id", middleware.RequireRole("resident", "secretary", "security"), parkingHandler.GetVehicleByID)
+			vehicles.PUT("/:id", middleware.RequireRole("resident", "secretary"), parkingHandler.UpdateVehicle)
+			vehicles.PUT("/:id/remove", middleware.RequireRole("resident", "secretary"), parkingHandler.RemoveVehicle)
+		}
+
+		// Parking slots, visitor overstays and the gate vehicle log (all society-aware)
+		parkingRoutes := protected.Group("/parking")
+		{
+			parkingRoutes.GET("/slots", middleware.RequireRole("resident", "secretary", "security"), parkingHandler.GetParkingSlots)
+			parkingRoutes.POST("/slots", middleware.RequireRole("secretary"), parkingHandler.CreateParkingSlot)
+			parkingRoutes.PUT("/slots/:id", middleware.RequireRole("secretary"), parkingHandler.UpdateParkingSlot)
+			parkingRoutes.GET("/overstays", middleware.RequireRole("secretary", "security"), parkingHandler.GetOverstays)
+			parkingRoutes.POST("/gate/entry", middleware.RequireRole("security"), parkingHandler.VehicleEntry)
+			parkingRoutes.POST("/gate/exit", middleware.RequireRole("security"), parkingHandler.VehicleExit)
+			parkingRoutes.GET("/gate/log", middleware.RequireRole("secretary", "security"), parkingHandler.GetVehicleLog)
+		}
+
+		// Domestic staff and regular delivery registry (all society-aware)
+		staffRoutes := protected.Group("/staff")
+		{
+			staffRoutes.GET("", middleware.RequireRole("resident", "secretary", "security"), staffHandler.GetStaff)
+			staffRoutes.POST("", middleware.RequireRole("resident", "secretary"), staffHandler.CreateStaff)
+			staffRoutes.GET("/inside", middleware.RequireRole("secretary", "security"), staffHandler.GetInside)
+			staffRoutes.GET("/attendance", middleware.RequireRole("resident", "secretary"), staffHandler.GetAttendanceReport)
+			staffRoutes.GET("/pass/:token", middleware.RequireRole("security"), staffHandler.GetByPass)
+			staffRoutes.PUT("/pass/:token/checkin", middleware.RequireRole("security"), staffHandler.CheckInByPass)
+			staffRoutes.PUT("/pass/:token/checkout", middleware.RequireRole("security"), staffHandler.CheckOutByPass)
+			staffRoutes.GET("/:id", middleware.RequireRole("resident", "secretary", "security"), staffHandler.GetStaffByID)
+			staffRoutes.PUT("/:id", middleware.RequireRole("resident", "secretary"), staffHandler.UpdateStaff)
+			staffRoutes.POST("/:id/units", middleware.RequireRole("resident", "secretary"), staffHandler.AddUnit)
+			staffRoutes.DELETE("/:id/units/:unitId", middleware.RequireRole("resident", "secretary"), staffHandler.RemoveUnit)
+			staffRoutes.PUT("/:id/pass", middleware.RequireRole("resident", "secretary"), staffHandler.ReissuePass)
+			staffRoutes.GET("/:id/pass.png", middleware.RequireRole("resident", "secretary", "security"), staffHandler.GetPassPNG)
+			staffRoutes.PUT("/:id/checkin", middleware.RequireRole("security"), staffHandler.CheckIn)
+			staffRoutes.PUT("/:id/checkout", middleware.RequireRole("security"), staffHandler.CheckOut)
+			staffRoutes.GET("/:id/attendance", middleware.RequireRole("resident", "secretary", "security"), staffHandler.GetAttendance)
 		}
 
 		// Maintenance routes (all society-aware)
 		maintenance := protected.Group("/maintenance")
 		{
 			maintenance.GET("", maintenanceHandler.GetMaintenanceRecords)
+			maintenance.GET("/penalties", maintenanceHandler.GetPenalties)
+			maintenance.PUT("/penalties/:id/waive", middleware.RequireRole("secretary"), maintenanceHandler.WaivePenalty)
 			maintenance.GET("/:id", maintenanceHandler.GetMaintenanceByID)
 			maintenance.POST("", middleware.RequireRole("secretary"), maintenanceHandler.CreateMaintenanceRecord)
 			maintenance.POST("/pay", middleware.RequireRole("resident"), maintenanceHandler.PayMaintenance)
 		}
 
+		// Payment routes (all society-aware)
+		paymentRoutes := protected.Group("/payments")
+		{
+			paymentRoutes.GET("", paymentHandler.GetPayments)
+			paymentRoutes.GET("/:id", paymentHandler.GetPaymentByID)
+			// The mock gateway settles whatever it is told, so only
+			// secretaries can drive it, and never in production
+			if cfg.Environment != "production" {
+				paymentRoutes.POST("/:id/simulate", middleware.RequireRole("secretary"), paymentHandler.SimulatePayment)
+			}
+		}
+
+		// Invoice and receipt PDFs (residents see their own unit's)
+		receiptRoutes := protected.Group("/receipts")
+		{
+			receiptRoutes.GET("", receiptHandler.GetReceipts)
+			receiptRoutes.GET("/:id", receiptHandler.DownloadReceipt)
+		}
+
+		// Uploaded photos, ID proofs and images; visitor files are limited
+		// to their host, secretaries and security
+		uploadRoutes := protected.Group("/uploads")
+		{
+			uploadRoutes.GET("/:id", uploadHandler.DownloadUpload)
+			uploadRoutes.GET("/:id/thumbnail", uploadHandler.DownloadThumbnail)
+		}
+
+		// Billing routes (secretary only)
+		billingRoutes := protected.Group("/billing")
+		billingRoutes.Use(middleware.RequireRole("secretary"))
+		{
+			billingRoutes.GET("/plan", billingHandler.GetPlan)
+			billingRoutes.PUT("/plan", billingHandler.SavePlan)
+			billingRoutes.GET("/preview", billingHandler.PreviewCycle)
+			billingRoutes.POST("/run", billingHandler.RunCycle)
+			billingRoutes.GET("/late-fee", billingHandler.GetLateFeeRule)
+			billingRoutes.PUT("/late-fee", billingHandler.SaveLateFeeRule)
+		}
+
 		// Amenity routes (all society-aware)
 		amenities := protected.Group("/amenities")
 		{
 			amenities.GET("", amenityHandler.GetAmenities)
+			amenities.GET("/:id/availability", amenityHandler.GetAvailability)
+			amenities.PUT("/:id/schedule", middleware.RequireRole("secretary"), amenityHandler.SetSchedule)
+			amenities.PUT("/:id/policy", middleware.RequireRole("secretary"), amenityHandler.SetPolicy)
+			amenities.POST("/:id/images", middleware.RequireRole("secretary"), uploadHandler.UploadAmenityImage)
+			amenities.DELETE("/:id/images/:uploadId", middleware.RequireRole("secretary"), uploadHandler.RemoveAmenityImage)
 			amenities.POST("/book", middleware.RequireRole("resident"), amenityHandler.BookAmenity)
 			amenities.GET("/bookings", amenityHandler.GetBookings)
-			amenities.PUT("/bookings/:id/cancel", amenityHandler.CancelBooking)
+			amenities.PUT("/bookings/:id/cancel", middleware.RequireRole("resident", "secretary"), amenityHandler.CancelBooking)
+			amenities.POST("/series", middleware.RequireRole("resident"), amenityHandler.CreateSeries)
+			amenities.GET("/series", amenityHandler.GetSeries)
+			amenities.GET("/series/:id", amenityHandler.GetSeriesByID)
+			amenities.PUT("/series/:id/cancel", middleware.RequireRole("resident", "secretary"), amenityHandler.CancelSeries)
+			amenities.GET("/waitlist", amenityHandler.GetWaitlist)
+			amenities.POST("/waitlist", middleware.RequireRole("resident"), amenityHandler.JoinWaitlist)
+			amenities.POST("/waitlist/:id/confirm", middleware.RequireRole("resident"), amenityHandler.ConfirmWaitlist)
+			amenities.PUT("/waitlist/:id/leave", middleware.RequireRole("resident"), amenityHandler.LeaveWaitlist)
+		}
+
+		// Calendar feed management (tokens are issued and revoked here)
+		calendarRoutes := protected.Group("/calendar/feeds")
+		{
+			calendarRoutes.GET("", calendarHandler.GetFeeds)
+			calendarRoutes.POST("", calendarHandler.CreateFeed)
+			calendarRoutes.PUT("/:id/revoke", calendarHandler.RevokeFeed)
+		}
+
+		// Notification routes (current user's inbox)
+		notificationRoutes := protected.Group("/notifications")
+		{
+			notificationRoutes.GET("", notificationHandler.GetNotifications)
+			notificationRoutes.PUT("/:id/read", notificationHandler.MarkRead)
 		}
 
 		// Notice routes (all society-aware)
@@ -10,8 +10,20 @@ import (
 	"time"
 
 	"bms-backend/api/routes"
+	"bms-backend/internal/amenities"
+	"bms-backend/internal/billing"
+	"bms-backend/internal/blacklist"
 	"bms-backend/internal/config"
 	"bms-backend/internal/database"
+	"bms-backend/internal/notifications"
+	"bms-backend/internal/parcels"
+	"bms-backend/internal/parking"
+	"bms-backend/internal/payments"
+	"bms-backend/internal/receipts"
+	"bms-backend/internal/storage"
+	"bms-backend/internal/store"
+	"bms-backend/internal/uploads"
+	"bms-backend/internal/visitors"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -51,8 +63,51 @@ func main() {
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 
+	s := store.NewMongoStore(db)
+
+	files, err := storage.New(cfg.StorageBackend, cfg.StorageDir, storage.S3Config{
+		Endpoint:        cfg.S3Endpoint,
+		Region:          cfg.S3Region,
+		Bucket:          cfg.S3Bucket,
+		AccessKeyID:     cfg.S3AccessKeyID,
+		SecretAccessKey: cfg.S3SecretAccessKey,
+	})
+	if err != nil {
+		log.Fatal("Failed to prepare file storage:", err)
+	}
+	documents := receipts.NewService(s, files)
+	billingEngine := billing.NewEngine(s, documents)
+	notifier := notifications.NewService(s)
+	amenityService := amenities.NewService(s, notifier, cfg.WaitlistOfferWindow)
+	paymentProvider, err := payments.NewProvider(cfg.PaymentProvider, cfg.PaymentWebhookSecret)
+	if err != nil {
+		log.Fatal("Failed to configure payments:", err)
+	}
+	paymentService := payments.NewService(s, paymentProvider, documents, amenityService, cfg.PaymentIntentLifetime)
+	visitorService := visitors.NewService(s, notifier, cfg.WalkInApprovalTimeout, cfg.VisitorNoShowAfter, cfg.VisitorStayLimit)
+	parcelService := parcels.NewService(s, notifier, cfg.ParcelReminderInterval)
+	blacklistService := blacklist.NewService(s, notifier)
+	parkingService := parking.NewService(s, notifier, cfg.VisitorParkingLimit)
+	uploadService := uploads.NewService(s, files, cfg.MaxUploadSize)
+
+	// Generate monthly maintenance dues in the background
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	go billing.NewScheduler(billingEngine, time.Hour).Start(schedulerCtx)
+	// Pass unconfirmed waitlist offers on to the next resident
+	go amenities.NewScheduler(amenityService, time.Minute).Start(schedulerCtx)
+	// Give up payments whose webhook never arrived
+	go payments.NewScheduler(paymentService, time.Minute).Start(schedulerCtx)
+	// Escalate walk-ins the host unit has not answered, expire no-shows and
+	// flag visitors staying too long
+	go visitors.NewScheduler(visitorService, 30*time.Second).Start(schedulerCtx)
+	// Remind residents about parcels left at the security desk
+	go parcels.NewScheduler(parcelService, 10*time.Minute).Start(schedulerCtx)
+	// Report visitor vehicles parked past their limit
+	go parking.NewScheduler(parkingService, 5*time.Minute).Start(schedulerCtx)
+
 	// Initialize routes
-	routes.InitializeRoutes(router, db)
+	routes.InitializeRoutes(router, s, billingEngine, documents, amenityService, paymentService, visitorService, parcelService, blacklistService, parkingService, uploadService)
 
 	// Create server
 	server := &http.Server{
@@ -72,6 +127,9 @@ func main() {
 		log.Printf("   • Society validation API")
 		log.Printf("   • Society-aware authentication")
 		log.Printf("   • Society-scoped data operations")
+		log.Printf("   • Recurring maintenance billing")
+		log.Printf("   • PDF invoices and receipts")
+		log.Printf("   • Amenity waitlists with notifications")
 
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatal("Failed to start server:", err)
@@ -84,6 +142,7 @@ func main() {
 	<-quit
 
 	log.Println("📴 Shutting down server...")
+	stopScheduler()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -0,0 +1,272 @@
+package amenities
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/notifications"
+	"bms-backend/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxAvailabilityDays bounds how many days one availability query covers
+const MaxAvailabilityDays = 31
+
+var ErrInvalidRange = errors.New("amenities: invalid date range")
+
+// inactiveStatuses are the booking statuses that no longer hold a slot
+var inactiveStatuses = []string{"cancelled"}
+
+// ActiveStatuses are the booking statuses that can still be cancelled
+var ActiveStatuses = []string{"held", "pending_payment", "confirmed"}
+
+// Service checks bookings against amenity schedules, holds their seats,
+// reports free slots and runs the waitlist of full slots
+type Service struct {
+	store    *store.Store
+	notifier *notifications.Service
+	// offerWindow is how long a promoted waitlist entry has to confirm
+	offerWindow time.Duration
+}
+
+func NewService(s *store.Store, notifier *notifications.Service, offerWindow time.Duration) *Service {
+	return &Service{store: s, notifier: notifier, offerWindow: offerWindow}
+}
+
+// SlotAvailability is one slot of an availability calendar
+type SlotAvailability struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Label     string    `json:"label"`
+	Status    string    `json:"status"` // free, full
+	Booked    int       `json:"booked"`
+	Remaining int       `json:"remaining"`
+}
+
+// DayAvailability lists a day's slots; blackout days are closed
+type DayAvailability struct {
+	Date   string             `json:"date"`
+	Closed bool               `json:"closed"`
+	Slots  []SlotAvailability `json:"slots"`
+}
+
+// Availability is an amenity's calendar over a range of days
+type Availability struct {
+	AmenityID     primitive.ObjectID `json:"amenity_id"`
+	AmenityName   string             `json:"amenity_name"`
+	Capacity      int                `json:"capacity"`
+	Timezone      string             `json:"timezone"`
+	SlotMinutes   int                `json:"slot_minutes"`
+	BufferMinutes int                `json:"buffer_minutes"`
+	From          string             `json:"from"`
+	To            string             `json:"to"`
+	Days          []DayAvailability  `json:"days"`
+}
+
+// Availability returns the free and booked slots of every day from from to
+// to inclusive, both given as YYYY-MM-DD in the amenity's timezone
+func (s *Service) Availability(ctx context.Context, amenity *models.Amenity, from, to string) (*Availability, error) {
+	schedule := ScheduleFor(amenity)
+	loc, err := Location(schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	first, err := time.ParseInLocation(DateLayout, from, loc)
+	if err != nil {
+		return nil, ErrInvalidRange
+	}
+	last, err := time.ParseInLocation(DateLayout, to, loc)
+	if err != nil || last.Before(first) || last.After(first.AddDate(0, 0, MaxAvailabilityDays-1)) {
+		return nil, ErrInvalidRange
+	}
+	end := last.AddDate(0, 0, 1)
+
+	bookings, err := s.store.Bookings.List(ctx, store.BookingFilter{
+		SocietyCode:     amenity.SocietyCode,
+		AmenityID:       amenity.ID,
+		ExcludeStatuses: inactiveStatuses,
+		OverlapsStart:   first,
+		OverlapsEnd:     end,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	availability := &Availability{
+		AmenityID:     amenity.ID,
+		AmenityName:   amenity.Name,
+		Capacity:      Capacity(amenity),
+		Timezone:      loc.String(),
+		SlotMinutes:   schedule.SlotMinutes,
+		BufferMinutes: schedule.BufferMinutes,
+		From:          from,
+		To:            to,
+		Days:          []DayAvailability{},
+	}
+
+	for day := first; day.Before(end); day = day.AddDate(0, 0, 1) {
+		entry := DayAvailability{
+			Date:   day.Format(DateLayout),
+			Closed: IsBlackout(schedule, day),
+			Slots:  []SlotAvailability{},
+		}
+		for _, slot := range SlotsOn(schedule, day) {
+			booked := overlapping(bookings, slot.Start, slot.End)
+			remaining := availability.Capacity - booked
+			status := "free"
+			if remaining <= 0 {
+				status, remaining = "full", 0
+			}
+			entry.Slots = append(entry.Slots, SlotAvailability{
+				Start:     slot.Start,
+				End:       slot.End,
+				Label:     slot.Label(),
+				Status:    status,
+				Booked:    booked,
+				Remaining: remaining,
+			})
+		}
+		availability.Days = append(availability.Days, entry)
+	}
+	return availability, nil
+}
+
+// Reserve holds a seat in every slot the booking covers and then stores
+// the booking. Seats are claimed through the reservation store's unique
+// (amenity, slot, seat) key, so concurrent requests can never hold more
+// seats than the amenity's capacity; when a slot is full every seat taken
+// so far is released again and ErrSlotTaken is returned. The booking's ID,
+// StartTime and EndTime must be set.
+func (s *Service) Reserve(ctx context.Context, amenity *models.Amenity, booking *models.AmenityBooking) error {
+	slots, err := coveredSlots(ScheduleFor(amenity), booking.StartTime, booking.EndTime)
+	if err != nil {
+		return err
+	}
+
+	capacity := Capacity(amenity)
+	for _, slot := range slots {
+		if err := s.claimSeat(ctx, amenity, booking, slot.Start, capacity); err != nil {
+			s.release(ctx, booking.ID)
+			return err
+		}
+	}
+
+	if err := s.store.Bookings.Create(ctx, booking); err != nil {
+		s.release(ctx, booking.ID)
+		return err
+	}
+	return nil
+}
+
+// Cancel cancels an active booking, frees its seats and offers them to
+// the waitlist. It returns store.ErrConflict when the booking is no longer
+// active.
+func (s *Service) Cancel(ctx context.Context, booking *models.AmenityBooking) error {
+	if err := s.discard(ctx, booking); err != nil {
+		return err
+	}
+	s.promote(ctx, booking.SocietyCode, booking.AmenityID, booking.StartTime, booking.EndTime)
+	return nil
+}
+
+// discard cancels an active booking and frees its seats without touching
+// the waitlist. The booking's RefundPercent, when set, is recorded with
+// the cancellation.
+func (s *Service) discard(ctx context.Context, booking *models.AmenityBooking) error {
+	status := "cancelled"
+	err := s.store.Bookings.Transition(ctx, booking.SocietyCode, booking.ID, ActiveStatuses, store.BookingUpdate{
+		Status:        &status,
+		RefundPercent: booking.RefundPercent,
+	})
+	if err != nil {
+		return err
+	}
+	booking.Status = status
+	return s.store.Reservations.DeleteByBooking(ctx, booking.ID)
+}
+
+// Capacity is how many bookings one slot of the amenity takes; amenities
+// without a capacity take one
+func Capacity(amenity *models.Amenity) int {
+	if amenity.Capacity < 1 {
+		return 1
+	}
+	return amenity.Capacity
+}
+
+// claimSeat takes the first free seat of a slot. A seat that looked free
+// but was taken concurrently fails with ErrDuplicate and the next one is
+// tried.
+func (s *Service) claimSeat(ctx context.Context, amenity *models.Amenity, booking *models.AmenityBooking, slotStart time.Time, capacity int) error {
+	taken, err := s.store.Reservations.TakenSeats(ctx, amenity.ID, slotStart)
+	if err != nil {
+		return err
+	}
+	held := make(map[int]bool, len(taken))
+	for _, seat := range taken {
+		held[seat] = true
+	}
+
+	for seat := 0; seat < capacity; seat++ {
+		if held[seat] {
+			continue
+		}
+		err := s.store.Reservations.Create(ctx, &models.AmenityReservation{
+			AmenityID:   amenity.ID,
+			BookingID:   booking.ID,
+			SlotStart:   slotStart,
+			Seat:        seat,
+			SocietyCode: amenity.SocietyCode,
+			CreatedAt:   time.Now(),
+		})
+		if errors.Is(err, store.ErrDuplicate) {
+			continue
+		}
+		return err
+	}
+	return ErrSlotTaken
+}
+
+// release frees the seats of a booking that could not be completed
+func (s *Service) release(ctx context.Context, bookingID primitive.ObjectID) {
+	if err := s.store.Reservations.DeleteByBooking(ctx, bookingID); err != nil {
+		log.Printf("⚠️ Failed to release seats of booking %s: %v", bookingID.Hex(), err)
+	}
+}
+
+// coveredSlots returns the schedule's slots inside [start, end)
+func coveredSlots(schedule *models.AmenitySchedule, start, end time.Time) ([]Slot, error) {
+	loc, err := Location(schedule)
+	if err != nil {
+		return nil, err
+	}
+	local := start.In(loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+
+	var slots []Slot
+	for _, slot := range SlotsOn(schedule, day) {
+		if !slot.Start.Before(start) && !slot.End.After(end) {
+			slots = append(slots, slot)
+		}
+	}
+	if len(slots) == 0 {
+		return nil, ErrOutsideSchedule
+	}
+	return slots, nil
+}
+
+// overlapping counts the bookings that overlap [start, end)
+func overlapping(bookings []models.AmenityBooking, start, end time.Time) int {
+	n := 0
+	for _, booking := range bookings {
+		if booking.StartTime.Before(end) && booking.EndTime.After(start) {
+			n++
+		}
+	}
+	return n
+}
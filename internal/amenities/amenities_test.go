@@ -0,0 +1,111 @@
+package amenities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/notifications"
+	"bms-backend/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Parallel reservations must never confirm more bookings in a slot than
+// the amenity's capacity. The memory store's reservation table enforces
+// the same unique (amenity, slot, seat) key as the MongoDB index.
+func TestReserveNeverOverbooks(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		// ranges are the HH:MM-HH:MM ranges requested, each by requests residents
+		ranges []string
+	}{
+		{name: "single seat", capacity: 1, ranges: []string{"06:00-07:00"}},
+		{name: "five seats", capacity: 5, ranges: []string{"06:00-07:00"}},
+		{name: "overlapping ranges", capacity: 2, ranges: []string{"06:00-08:00", "07:00-08:00", "07:00-09:00"}},
+	}
+	const requests = 50
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := store.NewMemoryStore()
+			service := NewService(s, notifications.NewService(s), time.Minute)
+			amenity := &models.Amenity{Name: "Court", Capacity: tt.capacity, SocietyCode: "RACE001", IsActive: true}
+			if err := s.Amenities.Create(ctx, amenity); err != nil {
+				t.Fatal(err)
+			}
+
+			loc, err := time.LoadLocation(DefaultTimezone)
+			if err != nil {
+				t.Fatal(err)
+			}
+			day := time.Now().In(loc).AddDate(0, 0, 1)
+			date := day.Format(DateLayout)
+
+			var wg sync.WaitGroup
+			errs := make(chan error, requests*len(tt.ranges))
+			start := make(chan struct{})
+			for _, timeSlot := range tt.ranges {
+				from, err := time.ParseInLocation(DateLayout+" 15:04", date+" "+timeSlot[:5], loc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				to, err := time.ParseInLocation(DateLayout+" 15:04", date+" "+timeSlot[6:], loc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i := 0; i < requests; i++ {
+					booking := &models.AmenityBooking{
+						ID:          primitive.NewObjectID(),
+						AmenityID:   amenity.ID,
+						UserID:      primitive.NewObjectID(),
+						TimeSlot:    timeSlot,
+						StartTime:   from,
+						EndTime:     to,
+						Status:      "confirmed",
+						SocietyCode: amenity.SocietyCode,
+						CreatedAt:   time.Now(),
+					}
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						<-start
+						if err := service.Reserve(ctx, amenity, booking); err != nil && !errors.Is(err, ErrSlotTaken) {
+							errs <- fmt.Errorf("%s: %w", booking.TimeSlot, err)
+						}
+					}()
+				}
+			}
+			close(start)
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				t.Error(err)
+			}
+
+			availability, err := service.Availability(ctx, amenity, date, date)
+			if err != nil {
+				t.Fatal(err)
+			}
+			busiest := 0
+			for _, slot := range availability.Days[0].Slots {
+				if slot.Booked > tt.capacity {
+					t.Errorf("slot %s holds %d bookings, capacity %d", slot.Label, slot.Booked, tt.capacity)
+				}
+				if slot.Booked > busiest {
+					busiest = slot.Booked
+				}
+			}
+			// With this many requests the most contended slot must be full
+			if busiest != tt.capacity {
+				t.Errorf("busiest slot holds %d bookings, want %d", busiest, tt.capacity)
+			}
+		})
+	}
+}
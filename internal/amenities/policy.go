@@ -0,0 +1,205 @@
+package amenities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrInvalidPolicy = errors.New("amenities: invalid booking policy")
+
+// Policy error codes, stable for clients to switch on
+const (
+	PolicyQuotaExceeded      = "quota_exceeded"
+	PolicyTooSoon            = "too_soon"
+	PolicyTooFarAhead        = "too_far_ahead"
+	PolicyCancellationClosed = "cancellation_closed"
+	PolicyAlreadyStarted     = "already_started"
+)
+
+// PolicyError is a booking or cancellation refused by the amenity's
+// policy. Limit is the configured limit that was hit, when there is one.
+type PolicyError struct {
+	Code    string
+	Message string
+	Limit   int
+}
+
+func (e *PolicyError) Error() string {
+	return "amenities: " + e.Message
+}
+
+// PolicyFor returns the amenity's policy, or an empty one without limits
+func PolicyFor(amenity *models.Amenity) *models.BookingPolicy {
+	if amenity.Policy != nil {
+		return amenity.Policy
+	}
+	return &models.BookingPolicy{}
+}
+
+// ValidatePolicy checks the limits are consistent and sorts the refund
+// tiers from the highest HoursBefore down
+func ValidatePolicy(policy *models.BookingPolicy) error {
+	if policy.MaxPerUnitPerWeek < 0 || policy.MinAdvanceDays < 0 || policy.MaxAdvanceDays < 0 || policy.CancelCutoffHours < 0 {
+		return fmt.Errorf("%w: limits cannot be negative", ErrInvalidPolicy)
+	}
+	if policy.MaxAdvanceDays > 0 && policy.MinAdvanceDays > policy.MaxAdvanceDays {
+		return fmt.Errorf("%w: min_advance_days is above max_advance_days", ErrInvalidPolicy)
+	}
+
+	seen := make(map[int]bool)
+	for _, tier := range policy.RefundTiers {
+		if tier.HoursBefore < 0 {
+			return fmt.Errorf("%w: refund tier hours_before cannot be negative", ErrInvalidPolicy)
+		}
+		if tier.Percent < 0 || tier.Percent > 100 {
+			return fmt.Errorf("%w: refund tier percent must be between 0 and 100", ErrInvalidPolicy)
+		}
+		if seen[tier.HoursBefore] {
+			return fmt.Errorf("%w: two refund tiers for %d hours", ErrInvalidPolicy, tier.HoursBefore)
+		}
+		seen[tier.HoursBefore] = true
+	}
+	sort.Slice(policy.RefundTiers, func(i, j int) bool {
+		return policy.RefundTiers[i].HoursBefore > policy.RefundTiers[j].HoursBefore
+	})
+	return nil
+}
+
+// CheckBooking applies the amenity's advance window and weekly quota to a
+// new booking by user starting at start
+func (s *Service) CheckBooking(ctx context.Context, amenity *models.Amenity, user *models.User, start, now time.Time) error {
+	policy := PolicyFor(amenity)
+	loc, err := Location(ScheduleFor(amenity))
+	if err != nil {
+		return err
+	}
+
+	days := daysBetween(now.In(loc), start.In(loc))
+	if days < policy.MinAdvanceDays {
+		return &PolicyError{
+			Code:    PolicyTooSoon,
+			Message: fmt.Sprintf("%s must be booked at least %d days ahead", amenity.Name, policy.MinAdvanceDays),
+			Limit:   policy.MinAdvanceDays,
+		}
+	}
+	if policy.MaxAdvanceDays > 0 && days > policy.MaxAdvanceDays {
+		return &PolicyError{
+			Code:    PolicyTooFarAhead,
+			Message: fmt.Sprintf("%s can be booked at most %d days ahead", amenity.Name, policy.MaxAdvanceDays),
+			Limit:   policy.MaxAdvanceDays,
+		}
+	}
+
+	return s.checkQuota(ctx, amenity, user.ID, user.UnitID, start)
+}
+
+// checkQuota refuses a booking that would take the unit over its weekly
+// limit. Weeks run Monday to Sunday in the amenity's timezone; residents
+// without a unit are counted on their own. Two bookings racing for the
+// last place may both pass, which the quota tolerates.
+func (s *Service) checkQuota(ctx context.Context, amenity *models.Amenity, userID primitive.ObjectID, unitID *primitive.ObjectID, start time.Time) error {
+	policy := PolicyFor(amenity)
+	if policy.MaxPerUnitPerWeek == 0 {
+		return nil
+	}
+	loc, err := Location(ScheduleFor(amenity))
+	if err != nil {
+		return err
+	}
+
+	weekStart := startOfWeek(start.In(loc))
+	filter := store.BookingFilter{
+		SocietyCode:   amenity.SocietyCode,
+		AmenityID:     amenity.ID,
+		Statuses:      ActiveStatuses,
+		OverlapsStart: weekStart,
+		OverlapsEnd:   weekStart.AddDate(0, 0, 7),
+	}
+	if unitID != nil {
+		filter.UnitID = *unitID
+	} else {
+		filter.UserID = userID
+	}
+	booked, err := s.store.Bookings.Count(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if booked >= int64(policy.MaxPerUnitPerWeek) {
+		return &PolicyError{
+			Code:    PolicyQuotaExceeded,
+			Message: fmt.Sprintf("Your unit already has %d bookings of %s that week", booked, amenity.Name),
+			Limit:   policy.MaxPerUnitPerWeek,
+		}
+	}
+	return nil
+}
+
+// CancellationRefund is the percentage of the fee refunded when a resident
+// cancels the booking at now. Cancellations after the cutoff, or once the
+// booking has started, are refused with a PolicyError.
+func CancellationRefund(amenity *models.Amenity, booking *models.AmenityBooking, now time.Time) (float64, error) {
+	if booking.StartTime.IsZero() {
+		// Bookings made before schedules existed carry no start time
+		return 100, nil
+	}
+
+	policy := PolicyFor(amenity)
+	ahead := booking.StartTime.Sub(now)
+	if ahead <= 0 {
+		return 0, &PolicyError{
+			Code:    PolicyAlreadyStarted,
+			Message: "Bookings cannot be cancelled once they have started",
+		}
+	}
+	if ahead < time.Duration(policy.CancelCutoffHours)*time.Hour {
+		return 0, &PolicyError{
+			Code:    PolicyCancellationClosed,
+			Message: fmt.Sprintf("%s bookings cannot be cancelled less than %d hours before the start", amenity.Name, policy.CancelCutoffHours),
+			Limit:   policy.CancelCutoffHours,
+		}
+	}
+	return RefundPercent(policy, ahead), nil
+}
+
+// RefundPercent picks the refund tier for a cancellation made ahead of the
+// start. Without tiers the fee is refunded in full.
+func RefundPercent(policy *models.BookingPolicy, ahead time.Duration) float64 {
+	if len(policy.RefundTiers) == 0 {
+		return 100
+	}
+	tiers := append([]models.RefundTier(nil), policy.RefundTiers...)
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].HoursBefore > tiers[j].HoursBefore })
+	for _, tier := range tiers {
+		if ahead >= time.Duration(tier.HoursBefore)*time.Hour {
+			return tier.Percent
+		}
+	}
+	return 0
+}
+
+// RefundAmount applies a refund percentage to a fee, rounded to paise
+func RefundAmount(fee, percent float64) float64 {
+	return math.Round(fee*percent) / 100
+}
+
+// daysBetween counts calendar days from the day of from to the day of to
+func daysBetween(from, to time.Time) int {
+	fromDay := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDay := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(toDay.Sub(fromDay).Hours() / 24)
+}
+
+// startOfWeek is midnight of the Monday on or before t, in t's location
+func startOfWeek(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,273 @@
+package amenities
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxOccurrences caps how many bookings one recurring series can expand to
+const MaxOccurrences = 104
+
+// maxPeriods bounds expansion of rules that rarely or never match, such as
+// the 30th of every February
+const maxPeriods = 1000
+
+var ErrInvalidRule = errors.New("amenities: invalid recurrence rule")
+
+var ruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Recurrence is the subset of an RFC 5545 RRULE that recurring bookings
+// support: FREQ=WEEKLY or MONTHLY, INTERVAL, BYDAY, BYMONTHDAY, and an end
+// given by UNTIL or COUNT. Monthly BYDAY entries carry an ordinal, such as
+// 1TU for the first Tuesday or -1FR for the last Friday.
+type Recurrence struct {
+	Frequency string // weekly, monthly
+	Interval  int
+	Weekdays  []time.Weekday
+	// Ordinals pairs with Weekdays for monthly rules
+	Ordinals  []int
+	MonthDays []int
+	Until     time.Time // Last date that can hold an occurrence, inclusive
+	Count     int
+}
+
+// ParseRule parses an RRULE such as "FREQ=WEEKLY;BYDAY=TU;UNTIL=20261231".
+// The UNTIL date is read in loc.
+func ParseRule(rule string, loc *time.Location) (*Recurrence, error) {
+	rule = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(rule)), "RRULE:")
+	if rule == "" {
+		return nil, fmt.Errorf("%w: empty rule", ErrInvalidRule)
+	}
+
+	r := &Recurrence{Interval: 1}
+	for _, part := range strings.Split(rule, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("%w: %q is not NAME=VALUE", ErrInvalidRule, part)
+		}
+		switch name {
+		case "FREQ":
+			switch value {
+			case "WEEKLY":
+				r.Frequency = "weekly"
+			case "MONTHLY":
+				r.Frequency = "monthly"
+			default:
+				return nil, fmt.Errorf("%w: only WEEKLY and MONTHLY are supported", ErrInvalidRule)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: INTERVAL must be a positive number", ErrInvalidRule)
+			}
+			r.Interval = n
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				if len(day) < 2 {
+					return nil, fmt.Errorf("%w: unknown day %q", ErrInvalidRule, day)
+				}
+				weekday, ok := ruleWeekdays[day[len(day)-2:]]
+				if !ok {
+					return nil, fmt.Errorf("%w: unknown day %q", ErrInvalidRule, day)
+				}
+				ordinal := 0
+				if prefix := day[:len(day)-2]; prefix != "" {
+					n, err := strconv.Atoi(prefix)
+					if err != nil || n == 0 || n < -5 || n > 5 {
+						return nil, fmt.Errorf("%w: unknown day %q", ErrInvalidRule, day)
+					}
+					ordinal = n
+				}
+				r.Weekdays = append(r.Weekdays, weekday)
+				r.Ordinals = append(r.Ordinals, ordinal)
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(day)
+				if err != nil || n < 1 || n > 31 {
+					return nil, fmt.Errorf("%w: BYMONTHDAY must be between 1 and 31", ErrInvalidRule)
+				}
+				r.MonthDays = append(r.MonthDays, n)
+			}
+		case "UNTIL":
+			// A date, or a date-time of which only the date counts
+			until, err := time.ParseInLocation("20060102", value[:min(len(value), 8)], loc)
+			if err != nil {
+				return nil, fmt.Errorf("%w: UNTIL must be YYYYMMDD", ErrInvalidRule)
+			}
+			r.Until = until
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: COUNT must be a positive number", ErrInvalidRule)
+			}
+			r.Count = n
+		default:
+			return nil, fmt.Errorf("%w: %s is not supported", ErrInvalidRule, name)
+		}
+	}
+
+	if r.Frequency == "" {
+		return nil, fmt.Errorf("%w: FREQ is required", ErrInvalidRule)
+	}
+	if r.Frequency == "weekly" && len(r.MonthDays) > 0 {
+		return nil, fmt.Errorf("%w: BYMONTHDAY needs FREQ=MONTHLY", ErrInvalidRule)
+	}
+	for _, ordinal := range r.Ordinals {
+		if r.Frequency == "weekly" && ordinal != 0 {
+			return nil, fmt.Errorf("%w: weekly BYDAY cannot have an ordinal", ErrInvalidRule)
+		}
+		if r.Frequency == "monthly" && ordinal == 0 {
+			return nil, fmt.Errorf("%w: monthly BYDAY needs an ordinal, e.g. 1TU", ErrInvalidRule)
+		}
+	}
+	if r.Count > MaxOccurrences {
+		return nil, fmt.Errorf("%w: at most %d occurrences", ErrInvalidRule, MaxOccurrences)
+	}
+	return r, nil
+}
+
+// String formats the recurrence back into an RRULE
+func (r *Recurrence) String() string {
+	parts := []string{"FREQ=" + strings.ToUpper(r.Frequency)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.Weekdays) > 0 {
+		days := make([]string, len(r.Weekdays))
+		for i, weekday := range r.Weekdays {
+			days[i] = strings.ToUpper(weekday.String()[:2])
+			if r.Ordinals[i] != 0 {
+				days[i] = strconv.Itoa(r.Ordinals[i]) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.MonthDays) > 0 {
+		days := make([]string, len(r.MonthDays))
+		for i, day := range r.MonthDays {
+			days[i] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Dates expands the recurrence from start, the first date of the series,
+// into occurrence dates at midnight in start's location. Without BYDAY or
+// BYMONTHDAY it repeats on start's weekday or day of the month. Either
+// UNTIL or COUNT must bound the series.
+func (r *Recurrence) Dates(start time.Time) ([]time.Time, error) {
+	if r.Until.IsZero() && r.Count == 0 {
+		return nil, fmt.Errorf("%w: an end date (UNTIL) or COUNT is required", ErrInvalidRule)
+	}
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	if !r.Until.IsZero() && r.Until.Before(start) {
+		return nil, fmt.Errorf("%w: UNTIL is before the first date", ErrInvalidRule)
+	}
+
+	var dates []time.Time
+	// add collects one period's dates; it reports false once the series
+	// has ended
+	add := func(candidates []time.Time) bool {
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+		for _, date := range candidates {
+			if date.Before(start) || (len(dates) > 0 && date.Equal(dates[len(dates)-1])) {
+				continue
+			}
+			if !r.Until.IsZero() && date.After(r.Until) {
+				return false
+			}
+			if r.Count > 0 && len(dates) == r.Count {
+				return false
+			}
+			dates = append(dates, date)
+		}
+		return true
+	}
+
+	switch r.Frequency {
+	case "weekly":
+		weekdays := r.Weekdays
+		if len(weekdays) == 0 {
+			weekdays = []time.Weekday{start.Weekday()}
+		}
+		for period := 0; period < maxPeriods; period++ {
+			week := startOfWeek(start).AddDate(0, 0, 7*r.Interval*period)
+			var candidates []time.Time
+			for _, weekday := range weekdays {
+				candidates = append(candidates, week.AddDate(0, 0, (int(weekday)+6)%7))
+			}
+			if !add(candidates) {
+				break
+			}
+			if len(dates) > MaxOccurrences {
+				return nil, fmt.Errorf("%w: more than %d occurrences", ErrInvalidRule, MaxOccurrences)
+			}
+		}
+
+	case "monthly":
+		monthDays := r.MonthDays
+		if len(monthDays) == 0 && len(r.Weekdays) == 0 {
+			monthDays = []int{start.Day()}
+		}
+		first := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, start.Location())
+		for period := 0; period < maxPeriods; period++ {
+			month := first.AddDate(0, r.Interval*period, 0)
+			if !r.Until.IsZero() && month.After(r.Until) {
+				break
+			}
+			var candidates []time.Time
+			for _, day := range monthDays {
+				// Months without the day are skipped, as RFC 5545 does
+				if date := month.AddDate(0, 0, day-1); date.Month() == month.Month() {
+					candidates = append(candidates, date)
+				}
+			}
+			for i, weekday := range r.Weekdays {
+				if date, ok := nthWeekday(month, weekday, r.Ordinals[i]); ok {
+					candidates = append(candidates, date)
+				}
+			}
+			if !add(candidates) {
+				break
+			}
+			if len(dates) > MaxOccurrences {
+				return nil, fmt.Errorf("%w: more than %d occurrences", ErrInvalidRule, MaxOccurrences)
+			}
+		}
+	}
+	return dates, nil
+}
+
+// nthWeekday finds the nth weekday of month, counting from the end for a
+// negative n
+func nthWeekday(month time.Time, weekday time.Weekday, n int) (time.Time, bool) {
+	if n > 0 {
+		first := (int(weekday) - int(month.Weekday()) + 7) % 7
+		date := month.AddDate(0, 0, first+7*(n-1))
+		return date, date.Month() == month.Month()
+	}
+	last := month.AddDate(0, 1, -1)
+	back := (int(last.Weekday()) - int(weekday) + 7) % 7
+	date := last.AddDate(0, 0, -back+7*(n+1))
+	return date, date.Month() == month.Month()
+}
@@ -0,0 +1,265 @@
+package amenities
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	_ "time/tzdata" // Schedules name their timezone; don't depend on the host's zoneinfo
+
+	"bms-backend/internal/models"
+)
+
+// DateLayout is the format of dates in requests and blackout lists
+const DateLayout = "2006-01-02"
+
+// DefaultTimezone applies to schedules that don't name one
+const DefaultTimezone = "Asia/Kolkata"
+
+const clockLayout = "15:04"
+
+var (
+	ErrInvalidSchedule = errors.New("amenities: invalid schedule")
+	ErrOutsideSchedule = errors.New("amenities: requested time is outside the amenity's schedule")
+	ErrSlotTaken       = errors.New("amenities: requested time is fully booked")
+)
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// Slot is one bookable interval of a schedule
+type Slot struct {
+	Start time.Time
+	End   time.Time
+	// window numbers the opening window the slot belongs to within its day;
+	// a booking may span consecutive slots of one window only
+	window int
+}
+
+// Label formats the slot as "HH:MM-HH:MM"
+func (s Slot) Label() string {
+	return Label(s.Start, s.End)
+}
+
+// Label formats a time range as "HH:MM-HH:MM"
+func Label(start, end time.Time) string {
+	return start.Format(clockLayout) + "-" + end.Format(clockLayout)
+}
+
+// DefaultSchedule is used for amenities without a schedule of their own:
+// hourly slots from 06:00 to 22:00 every day
+func DefaultSchedule() *models.AmenitySchedule {
+	schedule := &models.AmenitySchedule{Timezone: DefaultTimezone, SlotMinutes: 60}
+	for name := range weekdays {
+		schedule.Hours = append(schedule.Hours, models.OpeningHours{Weekday: name, Open: "06:00", Close: "22:00"})
+	}
+	return schedule
+}
+
+// ScheduleFor returns the amenity's schedule or the default one
+func ScheduleFor(amenity *models.Amenity) *models.AmenitySchedule {
+	if amenity.Schedule != nil {
+		return amenity.Schedule
+	}
+	return DefaultSchedule()
+}
+
+// ValidateSchedule checks that a schedule can be turned into slots
+func ValidateSchedule(schedule *models.AmenitySchedule) error {
+	if _, err := Location(schedule); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", ErrInvalidSchedule, schedule.Timezone)
+	}
+	if schedule.SlotMinutes <= 0 {
+		return fmt.Errorf("%w: slot_minutes must be positive", ErrInvalidSchedule)
+	}
+	if schedule.BufferMinutes < 0 {
+		return fmt.Errorf("%w: buffer_minutes cannot be negative", ErrInvalidSchedule)
+	}
+	if len(schedule.Hours) == 0 {
+		return fmt.Errorf("%w: at least one opening window is required", ErrInvalidSchedule)
+	}
+
+	type window struct{ open, closes int }
+	windows := make(map[time.Weekday][]window)
+	for _, hours := range schedule.Hours {
+		weekday, ok := weekdays[strings.ToLower(hours.Weekday)]
+		if !ok {
+			return fmt.Errorf("%w: unknown weekday %q", ErrInvalidSchedule, hours.Weekday)
+		}
+		open, err := parseClock(hours.Open)
+		if err != nil {
+			return fmt.Errorf("%w: open must be HH:MM", ErrInvalidSchedule)
+		}
+		closes, err := parseClock(hours.Close)
+		if err != nil {
+			return fmt.Errorf("%w: close must be HH:MM", ErrInvalidSchedule)
+		}
+		if closes-open < schedule.SlotMinutes {
+			return fmt.Errorf("%w: %s %s-%s is shorter than one slot", ErrInvalidSchedule, hours.Weekday, hours.Open, hours.Close)
+		}
+		for _, other := range windows[weekday] {
+			if open < other.closes && other.open < closes {
+				return fmt.Errorf("%w: opening windows on %s overlap", ErrInvalidSchedule, hours.Weekday)
+			}
+		}
+		windows[weekday] = append(windows[weekday], window{open, closes})
+	}
+
+	for _, date := range schedule.BlackoutDates {
+		if _, err := time.Parse(DateLayout, date); err != nil {
+			return fmt.Errorf("%w: blackout date %q must be YYYY-MM-DD", ErrInvalidSchedule, date)
+		}
+	}
+	return nil
+}
+
+// Location loads the schedule's timezone
+func Location(schedule *models.AmenitySchedule) (*time.Location, error) {
+	if schedule.Timezone == "" {
+		return time.LoadLocation(DefaultTimezone)
+	}
+	return time.LoadLocation(schedule.Timezone)
+}
+
+// IsBlackout reports whether the day is closed for bookings
+func IsBlackout(schedule *models.AmenitySchedule, day time.Time) bool {
+	date := day.Format(DateLayout)
+	for _, blackout := range schedule.BlackoutDates {
+		if blackout == date {
+			return true
+		}
+	}
+	return false
+}
+
+// SlotsOn returns the slots of the given day in start order. The day must
+// be midnight in the schedule's location. Blackout days have no slots.
+func SlotsOn(schedule *models.AmenitySchedule, day time.Time) []Slot {
+	if IsBlackout(schedule, day) {
+		return nil
+	}
+
+	slotLength := time.Duration(schedule.SlotMinutes) * time.Minute
+	step := slotLength + time.Duration(schedule.BufferMinutes)*time.Minute
+
+	var slots []Slot
+	for window, hours := range schedule.Hours {
+		if weekdays[strings.ToLower(hours.Weekday)] != day.Weekday() {
+			continue
+		}
+		open, err := parseClock(hours.Open)
+		if err != nil {
+			continue
+		}
+		closes, err := parseClock(hours.Close)
+		if err != nil {
+			continue
+		}
+
+		closing := atMinute(day, closes)
+		for start := atMinute(day, open); !start.Add(slotLength).After(closing); start = start.Add(step) {
+			slots = append(slots, Slot{Start: start, End: start.Add(slotLength), window: window})
+		}
+	}
+
+	// Windows may be listed in any order
+	sort.Slice(slots, func(i, j int) bool { return slots[i].Start.Before(slots[j].Start) })
+	return slots
+}
+
+// ParseDate reads a booking date as YYYY-MM-DD, or as a full timestamp for
+// older clients, and returns midnight of that day in loc
+func ParseDate(value string, loc *time.Location) (time.Time, error) {
+	day, err := time.ParseInLocation(DateLayout, value, loc)
+	if err == nil {
+		return day, nil
+	}
+	stamp, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("amenities: date must be YYYY-MM-DD")
+	}
+	stamp = stamp.In(loc)
+	return time.Date(stamp.Year(), stamp.Month(), stamp.Day(), 0, 0, 0, 0, loc), nil
+}
+
+// Resolve turns a requested date and clock range into a booking interval.
+// The range must start at a slot's start and end at a slot's end within
+// the same opening window; an empty end means the end of the first slot.
+func Resolve(schedule *models.AmenitySchedule, date, start, end string) (time.Time, time.Time, error) {
+	loc, err := Location(schedule)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	day, err := ParseDate(date, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	startMinute, err := parseClock(start)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: start time must be HH:MM", ErrOutsideSchedule)
+	}
+	from := atMinute(day, startMinute)
+
+	slots := SlotsOn(schedule, day)
+	first := -1
+	for i, slot := range slots {
+		if slot.Start.Equal(from) {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return time.Time{}, time.Time{}, ErrOutsideSchedule
+	}
+	if end == "" {
+		return from, slots[first].End, nil
+	}
+
+	endMinute, err := parseClock(end)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: end time must be HH:MM", ErrOutsideSchedule)
+	}
+	to := atMinute(day, endMinute)
+	for _, slot := range slots[first:] {
+		if slot.window != slots[first].window {
+			continue
+		}
+		if slot.End.Equal(to) {
+			return from, to, nil
+		}
+	}
+	return time.Time{}, time.Time{}, ErrOutsideSchedule
+}
+
+// SplitLabel splits a "HH:MM-HH:MM" time slot label into its two clocks
+func SplitLabel(label string) (string, string, bool) {
+	start, end, ok := strings.Cut(strings.ReplaceAll(label, " ", ""), "-")
+	return start, end, ok && start != "" && end != ""
+}
+
+// parseClock returns the minutes since midnight of an HH:MM time. "24:00"
+// is accepted as the end of the day.
+func parseClock(value string) (int, error) {
+	if value == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse(clockLayout, value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// atMinute is the wall clock time minutes after midnight of day
+func atMinute(day time.Time, minutes int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, minutes, 0, 0, day.Location())
+}
@@ -0,0 +1,45 @@
+package amenities
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Scheduler expires waitlist offers that were not confirmed in time, so
+// the seat moves on to the next resident even when nobody touches the API
+type Scheduler struct {
+	service  *Service
+	interval time.Duration
+}
+
+func NewScheduler(service *Service, interval time.Duration) *Scheduler {
+	return &Scheduler{service: service, interval: interval}
+}
+
+// Start runs a pass immediately and then on every tick until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.RunOnce(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce performs one expiry pass
+func (s *Scheduler) RunOnce(ctx context.Context, now time.Time) {
+	expired, err := s.service.ExpireOffers(ctx, now)
+	if err != nil {
+		log.Printf("⚠️ Waitlist: failed to expire offers: %v", err)
+	}
+	if expired > 0 {
+		log.Printf("⏳ Waitlist: %d offers expired and moved on", expired)
+	}
+}
@@ -0,0 +1,117 @@
+package amenities
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Occurrence conflict codes, besides the policy codes
+const (
+	ConflictSlotTaken       = "slot_taken"
+	ConflictOutsideSchedule = "outside_schedule"
+	ConflictPast            = "in_past"
+)
+
+// Occurrence reports what became of one date of a recurring series
+type Occurrence struct {
+	Date      string              `json:"date"`
+	Status    string              `json:"status"` // booked, conflict
+	BookingID *primitive.ObjectID `json:"booking_id,omitempty"`
+	Code      string              `json:"code,omitempty"`
+	Reason    string              `json:"reason,omitempty"`
+}
+
+// BookSeries books startClock-endClock on each of dates for the series,
+// which must have its ID, amenity, user and society set. Every date is
+// held to the schedule, the policy and capacity on its own; dates that
+// fail are reported as conflicts rather than failing the series. Paid
+// amenities leave their bookings pending_payment under a fresh PaymentID
+// for the caller to start.
+func (s *Service) BookSeries(ctx context.Context, amenity *models.Amenity, user *models.User, series *models.BookingSeries, dates []time.Time, startClock, endClock string, now time.Time) ([]Occurrence, []models.AmenityBooking, error) {
+	schedule := ScheduleFor(amenity)
+
+	occurrences := make([]Occurrence, 0, len(dates))
+	var bookings []models.AmenityBooking
+	// fail gives back the seats already taken when the series cannot finish
+	fail := func(err error) ([]Occurrence, []models.AmenityBooking, error) {
+		for i := range bookings {
+			s.discard(ctx, &bookings[i])
+		}
+		return nil, nil, err
+	}
+	for _, date := range dates {
+		occurrence := Occurrence{Date: date.Format(DateLayout), Status: "conflict"}
+
+		start, end, err := Resolve(schedule, occurrence.Date, startClock, endClock)
+		if err != nil {
+			occurrence.Code, occurrence.Reason = ConflictOutsideSchedule, "The amenity is closed at that time"
+			occurrences = append(occurrences, occurrence)
+			continue
+		}
+		if start.Before(now) {
+			occurrence.Code, occurrence.Reason = ConflictPast, "The slot has already started"
+			occurrences = append(occurrences, occurrence)
+			continue
+		}
+
+		err = s.CheckBooking(ctx, amenity, user, start, now)
+		var policyErr *PolicyError
+		if errors.As(err, &policyErr) {
+			occurrence.Code, occurrence.Reason = policyErr.Code, policyErr.Message
+			occurrences = append(occurrences, occurrence)
+			continue
+		}
+		if err != nil {
+			return fail(err)
+		}
+
+		booking := models.AmenityBooking{
+			ID:          primitive.NewObjectID(),
+			AmenityID:   amenity.ID,
+			AmenityName: amenity.Name,
+			UserID:      user.ID,
+			UserName:    user.Name,
+			UnitID:      user.UnitID,
+			Date:        date,
+			TimeSlot:    Label(start, end),
+			StartTime:   start,
+			EndTime:     end,
+			Status:      "confirmed",
+			TotalAmount: amenity.BookingFee,
+			SeriesID:    &series.ID,
+			SocietyID:   series.SocietyID,
+			SocietyCode: series.SocietyCode,
+			CreatedAt:   now,
+		}
+		if amenity.BookingFee > 0 {
+			booking.Status = "pending_payment"
+			booking.PaymentID = primitive.NewObjectID().Hex()
+		}
+
+		err = s.Reserve(ctx, amenity, &booking)
+		if errors.Is(err, ErrSlotTaken) {
+			occurrence.Code, occurrence.Reason = ConflictSlotTaken, "The slot is fully booked"
+			occurrences = append(occurrences, occurrence)
+			continue
+		}
+		if errors.Is(err, ErrOutsideSchedule) {
+			occurrence.Code, occurrence.Reason = ConflictOutsideSchedule, err.Error()
+			occurrences = append(occurrences, occurrence)
+			continue
+		}
+		if err != nil {
+			return fail(err)
+		}
+
+		occurrence.Status = "booked"
+		occurrence.BookingID = &booking.ID
+		occurrences = append(occurrences, occurrence)
+		bookings = append(bookings, booking)
+	}
+	return occurrences, bookings, nil
+}
@@ -0,0 +1,320 @@
+package amenities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/notifications"
+	"bms-backend/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrSlotAvailable  = errors.New("amenities: slot has free seats, book it instead")
+	ErrAlreadyWaiting = errors.New("amenities: already on the waitlist for this slot")
+	ErrNoOffer        = errors.New("amenities: waitlist entry has no open offer")
+)
+
+// openStatuses are the waitlist statuses still in the queue
+var openStatuses = []string{"waiting", "offered"}
+
+// Join puts a resident on the waitlist for a full range. The entry's
+// amenity, user, times and society must be set; StartTime and EndTime
+// must have been resolved against the amenity's schedule.
+func (s *Service) Join(ctx context.Context, amenity *models.Amenity, entry *models.WaitlistEntry) error {
+	full, err := s.isFull(ctx, amenity, entry.StartTime, entry.EndTime)
+	if err != nil {
+		return err
+	}
+	if !full {
+		return ErrSlotAvailable
+	}
+
+	queued, err := s.store.Waitlist.Count(ctx, store.WaitlistFilter{
+		SocietyCode:   entry.SocietyCode,
+		AmenityID:     amenity.ID,
+		UserID:        entry.UserID,
+		Statuses:      openStatuses,
+		OverlapsStart: entry.StartTime,
+		OverlapsEnd:   entry.EndTime,
+	})
+	if err != nil {
+		return err
+	}
+	if queued > 0 {
+		return ErrAlreadyWaiting
+	}
+
+	entry.Status = "waiting"
+	entry.CreatedAt = time.Now()
+	return s.store.Waitlist.Create(ctx, entry)
+}
+
+// Position is the entry's place in the queue for its range, from 1. Only
+// waiting entries have a position.
+func (s *Service) Position(ctx context.Context, entry *models.WaitlistEntry) (int, error) {
+	if entry.Status != "waiting" {
+		return 0, nil
+	}
+	ahead, err := s.store.Waitlist.List(ctx, store.WaitlistFilter{
+		SocietyCode:   entry.SocietyCode,
+		AmenityID:     entry.AmenityID,
+		Statuses:      []string{"waiting"},
+		OverlapsStart: entry.StartTime,
+		OverlapsEnd:   entry.EndTime,
+	})
+	if err != nil {
+		return 0, err
+	}
+	for i, other := range ahead {
+		if other.ID == entry.ID {
+			return i + 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// Leave takes an entry off the waitlist. Leaving with an open offer
+// releases the held booking to the next in line.
+func (s *Service) Leave(ctx context.Context, entry *models.WaitlistEntry) error {
+	status := "cancelled"
+	switch entry.Status {
+	case "waiting":
+		if err := s.store.Waitlist.Transition(ctx, entry.ID, "waiting", store.WaitlistUpdate{Status: &status}); err != nil {
+			return err
+		}
+	case "offered":
+		if err := s.store.Waitlist.Transition(ctx, entry.ID, "offered", store.WaitlistUpdate{Status: &status}); err != nil {
+			return err
+		}
+		s.dropOffer(ctx, entry)
+	default:
+		return store.ErrConflict
+	}
+	entry.Status = status
+	return nil
+}
+
+// Accept takes up an open offer, turning the held booking into status:
+// confirmed, or pending_payment with paymentID for paid amenities.
+func (s *Service) Accept(ctx context.Context, entry *models.WaitlistEntry, status, paymentID string) (*models.AmenityBooking, error) {
+	if entry.Status != "offered" || entry.BookingID == nil {
+		return nil, ErrNoOffer
+	}
+	if entry.OfferExpiresAt != nil && time.Now().After(*entry.OfferExpiresAt) {
+		return nil, ErrNoOffer
+	}
+
+	accepted := "accepted"
+	err := s.store.Waitlist.Transition(ctx, entry.ID, "offered", store.WaitlistUpdate{Status: &accepted})
+	if errors.Is(err, store.ErrConflict) {
+		return nil, ErrNoOffer
+	}
+	if err != nil {
+		return nil, err
+	}
+	entry.Status = accepted
+
+	update := store.BookingUpdate{Status: &status}
+	if paymentID != "" {
+		update.PaymentID = &paymentID
+	}
+	err = s.store.Bookings.Transition(ctx, entry.SocietyCode, *entry.BookingID, []string{"held"}, update)
+	if errors.Is(err, store.ErrConflict) {
+		// The held booking was cancelled under the offer
+		cancelled := "cancelled"
+		s.store.Waitlist.Transition(ctx, entry.ID, accepted, store.WaitlistUpdate{Status: &cancelled})
+		entry.Status = cancelled
+		return nil, ErrNoOffer
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.store.Bookings.GetByID(ctx, entry.SocietyCode, *entry.BookingID)
+}
+
+// ExpireOffers passes every offer that ran out before now on to the next
+// resident in line and returns how many expired
+func (s *Service) ExpireOffers(ctx context.Context, now time.Time) (int, error) {
+	entries, err := s.store.Waitlist.List(ctx, store.WaitlistFilter{
+		Statuses:           []string{"offered"},
+		OfferExpiredBefore: now,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	status := "expired"
+	for i := range entries {
+		entry := &entries[i]
+		err := s.store.Waitlist.Transition(ctx, entry.ID, "offered", store.WaitlistUpdate{Status: &status})
+		if errors.Is(err, store.ErrConflict) {
+			// Accepted or left in the meantime
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		expired++
+
+		s.notify(ctx, entry, notifications.Message{
+			Kind:  "waitlist_expired",
+			Title: "Waitlist offer expired",
+			Body: fmt.Sprintf("Your offer for %s on %s, %s was not confirmed in time and has passed to the next resident.",
+				entry.AmenityName, entry.Date.Format("02 Jan 2006"), entry.TimeSlot),
+		})
+		s.dropOffer(ctx, entry)
+	}
+	return expired, nil
+}
+
+// promote offers seats freed in [start, end) to waiting residents, first
+// come first served. Each offer holds a booking for the resident's whole
+// range; residents whose range is still full elsewhere keep their place.
+func (s *Service) promote(ctx context.Context, societyCode string, amenityID primitive.ObjectID, start, end time.Time) {
+	if start.IsZero() || end.IsZero() {
+		// Bookings made before schedules existed have no range to match
+		return
+	}
+
+	entries, err := s.store.Waitlist.List(ctx, store.WaitlistFilter{
+		SocietyCode:   societyCode,
+		AmenityID:     amenityID,
+		Statuses:      []string{"waiting"},
+		OverlapsStart: start,
+		OverlapsEnd:   end,
+	})
+	if err != nil || len(entries) == 0 {
+		if err != nil {
+			log.Printf("⚠️ Failed to load the waitlist of amenity %s: %v", amenityID.Hex(), err)
+		}
+		return
+	}
+
+	amenity, err := s.store.Amenities.GetByID(ctx, societyCode, amenityID)
+	if err != nil {
+		log.Printf("⚠️ Failed to load amenity %s for its waitlist: %v", amenityID.Hex(), err)
+		return
+	}
+
+	now := time.Now()
+	for i := range entries {
+		entry := &entries[i]
+		if !entry.StartTime.After(now) {
+			expired := "expired"
+			s.store.Waitlist.Transition(ctx, entry.ID, "waiting", store.WaitlistUpdate{Status: &expired})
+			continue
+		}
+
+		// The advance window was checked when the resident joined; the
+		// quota may have filled up since
+		err := s.checkQuota(ctx, amenity, entry.UserID, entry.UnitID, entry.StartTime)
+		var policyErr *PolicyError
+		if errors.As(err, &policyErr) {
+			continue
+		}
+		if err != nil {
+			log.Printf("⚠️ Failed to check the quota of waitlist entry %s: %v", entry.ID.Hex(), err)
+			return
+		}
+
+		booking := &models.AmenityBooking{
+			ID:          primitive.NewObjectID(),
+			AmenityID:   amenity.ID,
+			AmenityName: amenity.Name,
+			UserID:      entry.UserID,
+			UserName:    entry.UserName,
+			UnitID:      entry.UnitID,
+			Date:        entry.Date,
+			TimeSlot:    entry.TimeSlot,
+			StartTime:   entry.StartTime,
+			EndTime:     entry.EndTime,
+			Status:      "held",
+			TotalAmount: amenity.BookingFee,
+			SocietyID:   entry.SocietyID,
+			SocietyCode: entry.SocietyCode,
+			CreatedAt:   now,
+		}
+		err = s.Reserve(ctx, amenity, booking)
+		if errors.Is(err, ErrSlotTaken) || errors.Is(err, ErrOutsideSchedule) {
+			continue
+		}
+		if err != nil {
+			log.Printf("⚠️ Failed to hold a seat for waitlist entry %s: %v", entry.ID.Hex(), err)
+			return
+		}
+
+		// The offer never runs past the start of the booking
+		expiresAt := now.Add(s.offerWindow)
+		if expiresAt.After(entry.StartTime) {
+			expiresAt = entry.StartTime
+		}
+		offered := "offered"
+		err = s.store.Waitlist.Transition(ctx, entry.ID, "waiting", store.WaitlistUpdate{
+			Status:         &offered,
+			BookingID:      &booking.ID,
+			OfferedAt:      &now,
+			OfferExpiresAt: &expiresAt,
+		})
+		if err != nil {
+			// Left the queue while we were holding the seat
+			s.discard(ctx, booking)
+			continue
+		}
+
+		s.notify(ctx, entry, notifications.Message{
+			Kind:  "waitlist_offer",
+			Title: "A slot opened up for you",
+			Body: fmt.Sprintf("%s on %s, %s is now available. Confirm by %s to keep it.",
+				entry.AmenityName, entry.Date.Format("02 Jan 2006"), entry.TimeSlot,
+				expiresAt.In(entry.StartTime.Location()).Format("02 Jan 15:04")),
+			ReferenceID: entry.ID,
+		})
+	}
+}
+
+// dropOffer cancels the booking held for an offer and passes its seats on
+func (s *Service) dropOffer(ctx context.Context, entry *models.WaitlistEntry) {
+	if entry.BookingID == nil {
+		return
+	}
+	booking, err := s.store.Bookings.GetByID(ctx, entry.SocietyCode, *entry.BookingID)
+	if err == nil {
+		err = s.Cancel(ctx, booking)
+	}
+	if err != nil && !errors.Is(err, store.ErrConflict) {
+		log.Printf("⚠️ Failed to release the booking held for waitlist entry %s: %v", entry.ID.Hex(), err)
+	}
+}
+
+// isFull reports whether any slot of [start, end) has no free seat
+func (s *Service) isFull(ctx context.Context, amenity *models.Amenity, start, end time.Time) (bool, error) {
+	slots, err := coveredSlots(ScheduleFor(amenity), start, end)
+	if err != nil {
+		return false, err
+	}
+	capacity := Capacity(amenity)
+	for _, slot := range slots {
+		taken, err := s.store.Reservations.TakenSeats(ctx, amenity.ID, slot.Start)
+		if err != nil {
+			return false, err
+		}
+		if len(taken) >= capacity {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Service) notify(ctx context.Context, entry *models.WaitlistEntry, msg notifications.Message) {
+	if err := s.notifier.Notify(ctx, entry.SocietyCode, entry.UserID, msg); err != nil {
+		log.Printf("⚠️ Failed to notify user %s: %v", entry.UserID.Hex(), err)
+	}
+}
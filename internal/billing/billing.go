@@ -0,0 +1,283 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"bms-backend/internal/ledger"
+	"bms-backend/internal/models"
+	"bms-backend/internal/receipts"
+	"bms-backend/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CycleLayout is the format of a billing cycle identifier, e.g. "2025-10"
+const CycleLayout = "2006-01"
+
+const defaultDueDay = 10
+
+var (
+	ErrInvalidCycle       = errors.New("billing: cycle must be formatted as YYYY-MM")
+	ErrInvalidPlan        = errors.New("billing: invalid plan")
+	ErrInvalidLateFeeRule = errors.New("billing: invalid late fee rule")
+)
+
+// Line is the due a plan produces for one unit in one cycle
+type Line struct {
+	UnitID        primitive.ObjectID `json:"unit_id"`
+	UnitNumber    string             `json:"unit_number"`
+	Amount        float64            `json:"amount"`
+	AlreadyBilled bool               `json:"already_billed"`
+}
+
+// Result summarises a preview or a run of one billing cycle
+type Result struct {
+	Cycle   string    `json:"cycle"`
+	Month   string    `json:"month"`
+	DueDate time.Time `json:"due_date"`
+	Lines   []Line    `json:"lines"`
+	Created int       `json:"created"`
+	Skipped int       `json:"skipped"` // Units already billed or without a rate
+	Total   float64   `json:"total"`
+}
+
+// Engine turns a society's billing plan into maintenance records and
+// issues an invoice for each
+type Engine struct {
+	store     *store.Store
+	ledger    *ledger.Ledger
+	documents *receipts.Service
+}
+
+func NewEngine(s *store.Store, documents *receipts.Service) *Engine {
+	return &Engine{store: s, ledger: ledger.New(s), documents: documents}
+}
+
+// CurrentCycle returns the cycle identifier for the month containing t
+func CurrentCycle(t time.Time) string {
+	return t.Format(CycleLayout)
+}
+
+// ValidatePlan checks that the plan carries the rate its type needs
+func ValidatePlan(plan *models.BillingPlan) error {
+	switch plan.Type {
+	case "flat":
+		if plan.FlatAmount <= 0 {
+			return fmt.Errorf("%w: flat_amount must be positive", ErrInvalidPlan)
+		}
+	case "per_sq_ft":
+		if plan.RatePerSqFt <= 0 {
+			return fmt.Errorf("%w: rate_per_sq_ft must be positive", ErrInvalidPlan)
+		}
+	case "per_building":
+		if len(plan.BuildingRates) == 0 {
+			return fmt.Errorf("%w: building_rates is required", ErrInvalidPlan)
+		}
+		for _, rate := range plan.BuildingRates {
+			if rate.Amount <= 0 {
+				return fmt.Errorf("%w: building rate amounts must be positive", ErrInvalidPlan)
+			}
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidPlan, plan.Type)
+	}
+	if plan.DueDay < 0 || plan.DueDay > 31 {
+		return fmt.Errorf("%w: due_day must be between 1 and 31", ErrInvalidPlan)
+	}
+	return nil
+}
+
+// Amount computes a unit's monthly due. The second result is false when the
+// plan has no rate for the unit, e.g. its building is missing from a
+// per-building plan.
+func Amount(plan *models.BillingPlan, unit *models.Unit) (float64, bool) {
+	switch plan.Type {
+	case "flat":
+		return plan.FlatAmount, true
+	case "per_sq_ft":
+		if unit.AreaSqFt <= 0 {
+			return 0, false
+		}
+		return math.Round(plan.RatePerSqFt*unit.AreaSqFt*100) / 100, true
+	case "per_building":
+		for _, rate := range plan.BuildingRates {
+			if rate.BuildingID == unit.BuildingID {
+				return rate.Amount, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// IdempotencyKey identifies the single record a unit may receive per cycle
+func IdempotencyKey(societyCode string, unitID primitive.ObjectID, cycle string) string {
+	return societyCode + ":" + unitID.Hex() + ":" + cycle
+}
+
+// Preview computes the dues of a cycle without writing anything
+func (e *Engine) Preview(ctx context.Context, plan *models.BillingPlan, cycle string) (*Result, error) {
+	return e.process(ctx, plan, cycle, false)
+}
+
+// RunCycle creates the maintenance records of a cycle and posts them to
+// the ledger. Units that were already billed for the cycle are skipped, so
+// running it twice is safe; a record whose ledger posting failed last time
+// is posted again.
+func (e *Engine) RunCycle(ctx context.Context, plan *models.BillingPlan, cycle string) (*Result, error) {
+	return e.process(ctx, plan, cycle, true)
+}
+
+func (e *Engine) process(ctx context.Context, plan *models.BillingPlan, cycle string, commit bool) (*Result, error) {
+	start, err := time.ParseInLocation(CycleLayout, cycle, time.Local)
+	if err != nil {
+		return nil, ErrInvalidCycle
+	}
+
+	units, err := e.store.Units.List(ctx, store.UnitFilter{SocietyCode: plan.SocietyCode})
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := e.store.Maintenance.List(ctx, store.MaintenanceFilter{
+		SocietyCode:  plan.SocietyCode,
+		BillingCycle: cycle,
+	})
+	if err != nil {
+		return nil, err
+	}
+	billed := make(map[primitive.ObjectID]bool, len(existing))
+	for _, record := range existing {
+		billed[record.UnitID] = true
+	}
+	if commit {
+		if err := e.repostDues(ctx, plan.SocietyCode, existing); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &Result{
+		Cycle:   cycle,
+		Month:   start.Format("January 2006"),
+		DueDate: dueDate(start, plan.DueDay),
+		Lines:   []Line{},
+	}
+
+	for i := range units {
+		unit := &units[i]
+		amount, ok := Amount(plan, unit)
+		if !ok {
+			result.Skipped++
+			continue
+		}
+
+		line := Line{
+			UnitID:        unit.ID,
+			UnitNumber:    unit.Number,
+			Amount:        amount,
+			AlreadyBilled: billed[unit.ID],
+		}
+
+		if !line.AlreadyBilled && commit {
+			record := models.MaintenanceRecord{
+				UnitID:         unit.ID,
+				UnitNumber:     unit.Number,
+				Amount:         amount,
+				Month:          result.Month,
+				DueDate:        result.DueDate,
+				Status:         "pending",
+				Description:    plan.Description,
+				BillingCycle:   cycle,
+				IdempotencyKey: IdempotencyKey(plan.SocietyCode, unit.ID, cycle),
+				SocietyID:      plan.SocietyID,
+				SocietyCode:    plan.SocietyCode,
+				CreatedAt:      time.Now(),
+			}
+			err := e.store.Maintenance.Create(ctx, &record)
+			if errors.Is(err, store.ErrDuplicate) {
+				// Another run billed the unit after we listed the cycle
+				line.AlreadyBilled = true
+			} else if err != nil {
+				return nil, err
+			} else if err := e.post(ctx, &record); err != nil {
+				return nil, err
+			} else {
+				result.Created++
+			}
+		}
+
+		if line.AlreadyBilled {
+			result.Skipped++
+		} else {
+			result.Total += amount
+		}
+		result.Lines = append(result.Lines, line)
+	}
+
+	result.Total = math.Round(result.Total*100) / 100
+	return result, nil
+}
+
+// post debits a new record on the unit's ledger and issues its invoice.
+// Both are idempotent, keyed on the record.
+func (e *Engine) post(ctx context.Context, record *models.MaintenanceRecord) error {
+	if err := e.ledger.PostDue(ctx, record); err != nil {
+		return err
+	}
+	if _, err := e.documents.IssueInvoice(ctx, record); err != nil {
+		log.Printf("⚠️ Failed to issue invoice for unit %s (%s): %v", record.UnitNumber, record.BillingCycle, err)
+	}
+	return nil
+}
+
+// repostDues posts the records of a cycle that have no ledger debit yet,
+// left behind when a run created the record but failed to post it
+func (e *Engine) repostDues(ctx context.Context, societyCode string, records []models.MaintenanceRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	ids := make([]primitive.ObjectID, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, record.ID)
+	}
+	entries, err := e.store.Ledger.List(ctx, store.LedgerFilter{
+		SocietyCode:  societyCode,
+		Kinds:        []string{"maintenance_due"},
+		ReferenceIDs: ids,
+	})
+	if err != nil {
+		return err
+	}
+	posted := make(map[primitive.ObjectID]bool, len(entries))
+	for _, entry := range entries {
+		posted[entry.ReferenceID] = true
+	}
+
+	for i := range records {
+		if posted[records[i].ID] {
+			continue
+		}
+		log.Printf("⚠️ Billing: posting unit %s (%s) to the ledger again", records[i].UnitNumber, records[i].BillingCycle)
+		if err := e.post(ctx, &records[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dueDate places the due day inside the cycle's month, clamping it to the
+// last day for short months
+func dueDate(start time.Time, day int) time.Time {
+	if day <= 0 {
+		day = defaultDueDay
+	}
+	lastDay := start.AddDate(0, 1, -1).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(start.Year(), start.Month(), day, 0, 0, 0, 0, start.Location())
+}
@@ -0,0 +1,168 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ValidateLateFeeRule checks the rule's amounts make sense for its type
+func ValidateLateFeeRule(rule *models.LateFeeRule) error {
+	switch rule.Type {
+	case "fixed":
+	case "percentage", "daily_interest":
+		if rule.Amount > 100 {
+			return fmt.Errorf("%w: percentage amount cannot exceed 100", ErrInvalidLateFeeRule)
+		}
+	default:
+		return fmt.Errorf("%w: unknown late fee type %q", ErrInvalidLateFeeRule, rule.Type)
+	}
+	if rule.Amount <= 0 {
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidLateFeeRule)
+	}
+	if rule.GraceDays < 0 || rule.MaxPenalty < 0 {
+		return fmt.Errorf("%w: grace_days and max_penalty cannot be negative", ErrInvalidLateFeeRule)
+	}
+	return nil
+}
+
+// MarkOverdue moves every pending record past its due date to overdue
+func (e *Engine) MarkOverdue(ctx context.Context, now time.Time) (int64, error) {
+	return e.store.Maintenance.MarkOverdue(ctx, now)
+}
+
+// ApplyLateFees charges the rule's penalties on every overdue record of the
+// rule's society. Each penalty has an idempotency key, so days missed while
+// the server was down are caught up and nothing is ever charged twice.
+func (e *Engine) ApplyLateFees(ctx context.Context, rule *models.LateFeeRule, now time.Time) (int, error) {
+	records, err := e.store.Maintenance.List(ctx, store.MaintenanceFilter{
+		SocietyCode: rule.SocietyCode,
+		Statuses:    []string{"overdue"},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for i := range records {
+		n, err := e.applyRecordLateFees(ctx, rule, &records[i], now)
+		applied += n
+		if err != nil {
+			return applied, err
+		}
+	}
+	return applied, nil
+}
+
+func (e *Engine) applyRecordLateFees(ctx context.Context, rule *models.LateFeeRule, record *models.MaintenanceRecord, now time.Time) (int, error) {
+	dueDay := startOfDay(record.DueDate.In(now.Location()))
+	daysLate := int(startOfDay(now).Sub(dueDay).Hours() / 24)
+	if daysLate <= rule.GraceDays {
+		return 0, nil
+	}
+
+	existing, err := e.store.Penalties.List(ctx, store.PenaltyFilter{
+		SocietyCode:   rule.SocietyCode,
+		MaintenanceID: record.ID,
+	})
+	if err != nil {
+		return 0, err
+	}
+	charged := make(map[string]bool, len(existing))
+	var total float64
+	for _, penalty := range existing {
+		charged[penalty.IdempotencyKey] = true
+		// Waived penalties still count towards the cap so waiving one does
+		// not make room for new charges
+		total += penalty.Amount
+	}
+
+	// Fixed and percentage fees are charged once, on the first day after the
+	// grace period; daily interest is charged for every day since then
+	firstDay := rule.GraceDays + 1
+	lastDay := firstDay
+	if rule.Type == "daily_interest" {
+		lastDay = daysLate
+	}
+
+	applied := 0
+	for day := firstDay; day <= lastDay; day++ {
+		accruedOn := dueDay.AddDate(0, 0, day)
+		key := penaltyKey(record.ID, rule.Type, accruedOn)
+		if charged[key] {
+			continue
+		}
+
+		// Interest and percentages apply to what is still outstanding
+		amount := penaltyAmount(rule, record.Amount-record.AmountPaid)
+		if rule.MaxPenalty > 0 {
+			amount = math.Min(amount, rule.MaxPenalty-total)
+		}
+		if amount <= 0 {
+			break
+		}
+
+		penalty := models.MaintenancePenalty{
+			MaintenanceID:  record.ID,
+			UnitID:         record.UnitID,
+			UnitNumber:     record.UnitNumber,
+			RuleType:       rule.Type,
+			Amount:         amount,
+			Description:    penaltyDescription(rule, record, accruedOn),
+			AccruedOn:      accruedOn,
+			Status:         "applied",
+			IdempotencyKey: key,
+			SocietyID:      record.SocietyID,
+			SocietyCode:    record.SocietyCode,
+			CreatedAt:      now,
+		}
+		if err := e.store.Penalties.Create(ctx, &penalty); err != nil {
+			if errors.Is(err, store.ErrDuplicate) {
+				continue
+			}
+			return applied, err
+		}
+		if err := e.ledger.PostPenalty(ctx, &penalty); err != nil {
+			return applied, err
+		}
+		total += amount
+		applied++
+	}
+	return applied, nil
+}
+
+func penaltyAmount(rule *models.LateFeeRule, due float64) float64 {
+	if rule.Type == "fixed" {
+		return rule.Amount
+	}
+	return math.Round(due*rule.Amount) / 100
+}
+
+func penaltyKey(maintenanceID primitive.ObjectID, ruleType string, accruedOn time.Time) string {
+	if ruleType == "daily_interest" {
+		return maintenanceID.Hex() + ":" + ruleType + ":" + accruedOn.Format("2006-01-02")
+	}
+	return maintenanceID.Hex() + ":" + ruleType
+}
+
+func penaltyDescription(rule *models.LateFeeRule, record *models.MaintenanceRecord, accruedOn time.Time) string {
+	switch rule.Type {
+	case "percentage":
+		return fmt.Sprintf("Late fee (%g%%) on %s maintenance", rule.Amount, record.Month)
+	case "daily_interest":
+		return fmt.Sprintf("Interest (%g%%/day) on %s maintenance for %s", rule.Amount, record.Month, accruedOn.Format("02 Jan 2006"))
+	default:
+		return fmt.Sprintf("Late fee on %s maintenance", record.Month)
+	}
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
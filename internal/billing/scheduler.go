@@ -0,0 +1,91 @@
+package billing
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Scheduler runs the current billing cycle for every active plan, then
+// moves unpaid dues to overdue and charges late fees. It ticks more often
+// than once a month so a server that was down on the 1st still bills when
+// it comes back; idempotency keys keep repeated runs harmless.
+type Scheduler struct {
+	engine   *Engine
+	interval time.Duration
+}
+
+func NewScheduler(engine *Engine, interval time.Duration) *Scheduler {
+	return &Scheduler{engine: engine, interval: interval}
+}
+
+// Start runs a pass immediately and then on every tick until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.RunOnce(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce performs one billing and late-fee pass
+func (s *Scheduler) RunOnce(ctx context.Context, now time.Time) {
+	s.runBilling(ctx, now)
+	s.runLateFees(ctx, now)
+}
+
+// runBilling bills the cycle containing now for every active plan
+func (s *Scheduler) runBilling(ctx context.Context, now time.Time) {
+	plans, err := s.engine.store.BillingPlans.ListActive(ctx)
+	if err != nil {
+		log.Printf("⚠️ Billing: failed to load plans: %v", err)
+		return
+	}
+
+	cycle := CurrentCycle(now)
+	for i := range plans {
+		result, err := s.engine.RunCycle(ctx, &plans[i], cycle)
+		if err != nil {
+			log.Printf("⚠️ Billing: cycle %s failed for society %s: %v", cycle, plans[i].SocietyCode, err)
+			continue
+		}
+		if result.Created > 0 {
+			log.Printf("🧾 Billing: created %d dues for society %s (%s)", result.Created, plans[i].SocietyCode, cycle)
+		}
+	}
+}
+
+// runLateFees marks overdue dues and charges every active late-fee rule
+func (s *Scheduler) runLateFees(ctx context.Context, now time.Time) {
+	overdue, err := s.engine.MarkOverdue(ctx, now)
+	if err != nil {
+		log.Printf("⚠️ Billing: failed to mark overdue dues: %v", err)
+		return
+	}
+	if overdue > 0 {
+		log.Printf("⏰ Billing: %d dues became overdue", overdue)
+	}
+
+	rules, err := s.engine.store.LateFeeRules.ListActive(ctx)
+	if err != nil {
+		log.Printf("⚠️ Billing: failed to load late fee rules: %v", err)
+		return
+	}
+
+	for i := range rules {
+		applied, err := s.engine.ApplyLateFees(ctx, &rules[i], now)
+		if err != nil {
+			log.Printf("⚠️ Billing: late fees failed for society %s: %v", rules[i].SocietyCode, err)
+		}
+		if applied > 0 {
+			log.Printf("💸 Billing: applied %d late fees for society %s", applied, rules[i].SocietyCode)
+		}
+	}
+}
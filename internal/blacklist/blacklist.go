@@ -0,0 +1,257 @@
+package blacklist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/notifications"
+	"bms-backend/internal/parking"
+	"bms-backend/internal/store"
+)
+
+// Entry actions
+const (
+	ActionBlock = "block"
+	ActionWarn  = "warn"
+)
+
+// KindAlert is the notification secretaries get when a flagged person
+// turns up
+const KindAlert = "blacklist_alert"
+
+var (
+	ErrNoKeys   = errors.New("blacklist: a phone, vehicle number or name is required")
+	ErrInactive = errors.New("blacklist: entry has already been removed")
+)
+
+// Person is someone being screened at registration or at the gate
+type Person struct {
+	Name          string
+	Phone         string
+	VehicleNumber string
+}
+
+// Result is the outcome of screening a person. Action is empty when
+// nothing matched, otherwise the strictest action of the matches.
+type Result struct {
+	Action  string
+	Matches []models.BlacklistMatch
+}
+
+// Blocked reports whether the person must not be admitted
+func (r *Result) Blocked() bool {
+	return r.Action == ActionBlock
+}
+
+// Flagged reports whether the person matched anything at all
+func (r *Result) Flagged() bool {
+	return len(r.Matches) > 0
+}
+
+// Service keeps the society blacklist and screens people against it
+type Service struct {
+	store    *store.Store
+	notifier *notifications.Service
+}
+
+func NewService(s *store.Store, notifier *notifications.Service) *Service {
+	return &Service{store: s, notifier: notifier}
+}
+
+// NormalizePhone keeps the last ten digits, so +91 98765 43210 and
+// 098765-43210 are the same number
+func NormalizePhone(phone string) string {
+	var digits strings.Builder
+	for _, r := range phone {
+		if unicode.IsDigit(r) {
+			digits.WriteRune(r)
+		}
+	}
+	key := digits.String()
+	if len(key) > 10 {
+		key = key[len(key)-10:]
+	}
+	return key
+}
+
+// NormalizeVehicle keys valid plates the way the gate does, so MH 1 AB 1234
+// and mh01ab1234 are the same vehicle. Anything else keeps its letters and
+// digits, upper-cased.
+func NormalizeVehicle(number string) string {
+	if plate, err := parking.NormalizePlate(number); err == nil {
+		return plate
+	}
+	var key strings.Builder
+	for _, r := range number {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			key.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return key.String()
+}
+
+// NormalizeName lower-cases the name and collapses its spaces
+func NormalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// Keys are the normalised keys a person is screened on
+func Keys(person Person) store.BlacklistKeys {
+	return store.BlacklistKeys{
+		Phone:   NormalizePhone(person.Phone),
+		Vehicle: NormalizeVehicle(person.VehicleNumber),
+		Name:    NormalizeName(person.Name),
+	}
+}
+
+// Screen checks a person against the society's active entries. A match on
+// the name alone only warns, whatever the entry says: names are shared
+// too often to turn someone away on them.
+func (s *Service) Screen(ctx context.Context, societyCode string, person Person) (*Result, error) {
+	keys := Keys(person)
+	entries, err := s.store.Blacklist.List(ctx, store.BlacklistFilter{
+		SocietyCode: societyCode,
+		ActiveOnly:  true,
+		Matching:    &keys,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	for _, entry := range entries {
+		match := models.BlacklistMatch{EntryID: entry.ID, Action: entry.Action, Reason: entry.Reason}
+		if keys.Phone != "" && entry.PhoneKey == keys.Phone {
+			match.Fields = append(match.Fields, "phone")
+		}
+		if keys.Vehicle != "" && entry.VehicleKey == keys.Vehicle {
+			match.Fields = append(match.Fields, "vehicle_number")
+		}
+		if keys.Name != "" && entry.NameKey == keys.Name {
+			match.Fields = append(match.Fields, "name")
+		}
+		if len(match.Fields) == 1 && match.Fields[0] == "name" {
+			match.Action = ActionWarn
+		}
+		result.Matches = append(result.Matches, match)
+
+		if match.Action == ActionBlock || result.Action == "" {
+			result.Action = match.Action
+		}
+	}
+	return result, nil
+}
+
+// Alert tells the society's secretaries that a flagged person turned up.
+// where says what they were doing, such as "registered as a walk-in for
+// A-101".
+func (s *Service) Alert(ctx context.Context, societyCode string, person Person, result *Result, where string) {
+	secretaries, err := s.store.Users.List(ctx, store.UserFilter{
+		SocietyCode: societyCode,
+		Roles:       []string{"secretary"},
+		ActiveOnly:  true,
+	})
+	if err != nil {
+		log.Printf("⚠️ Blacklist: failed to load secretaries of %s: %v", societyCode, err)
+		return
+	}
+
+	verb := "flagged on the watchlist"
+	if result.Blocked() {
+		verb = "blocked by the blacklist"
+	}
+	var reasons []string
+	for _, match := range result.Matches {
+		reasons = append(reasons, fmt.Sprintf("%s (matched on %s)", match.Reason, strings.Join(match.Fields, ", ")))
+	}
+	msg := notifications.Message{
+		Kind:        KindAlert,
+		Title:       person.Name + " was " + verb,
+		Body:        fmt.Sprintf("%s (%s) %s and was %s: %s.", person.Name, person.Phone, where, verb, strings.Join(reasons, "; ")),
+		ReferenceID: result.Matches[0].EntryID,
+	}
+	for _, secretary := range secretaries {
+		if err := s.notifier.Notify(ctx, societyCode, secretary.ID, msg); err != nil {
+			log.Printf("⚠️ Blacklist: failed to notify user %s: %v", secretary.ID.Hex(), err)
+		}
+	}
+}
+
+// Add stores a new entry on behalf of by, recording why in its history
+func (s *Service) Add(ctx context.Context, entry *models.BlacklistEntry, by *models.User, now time.Time) error {
+	keys := Keys(Person{Name: entry.Name, Phone: entry.Phone, VehicleNumber: entry.VehicleNumber})
+	if keys.Phone == "" && keys.Vehicle == "" && keys.Name == "" {
+		return ErrNoKeys
+	}
+
+	entry.PhoneKey, entry.VehicleKey, entry.NameKey = keys.Phone, keys.Vehicle, keys.Name
+	entry.IsActive = true
+	entry.AddedBy = by.ID
+	entry.AddedByName = by.Name
+	entry.History = []models.BlacklistChange{change("added", by, entry.Reason, now)}
+	entry.SocietyCode = by.SocietyCode
+	entry.CreatedAt = now
+	entry.UpdatedAt = now
+	return s.store.Blacklist.Create(ctx, entry)
+}
+
+// Update changes an active entry, recording who did it and why
+func (s *Service) Update(ctx context.Context, entry *models.BlacklistEntry, req models.UpdateBlacklistRequest, by *models.User, now time.Time) error {
+	if !entry.IsActive {
+		return ErrInactive
+	}
+
+	person := Person{Name: entry.Name, Phone: entry.Phone, VehicleNumber: entry.VehicleNumber}
+	if req.Name != nil {
+		person.Name = *req.Name
+	}
+	if req.Phone != nil {
+		person.Phone = *req.Phone
+	}
+	if req.VehicleNumber != nil {
+		person.VehicleNumber = *req.VehicleNumber
+	}
+	keys := Keys(person)
+	if keys.Phone == "" && keys.Vehicle == "" && keys.Name == "" {
+		return ErrNoKeys
+	}
+
+	event := change("updated", by, req.Reason, now)
+	return s.store.Blacklist.Update(ctx, entry.SocietyCode, entry.ID, store.BlacklistUpdate{
+		Name:          &person.Name,
+		Phone:         &person.Phone,
+		VehicleNumber: &person.VehicleNumber,
+		NameKey:       &keys.Name,
+		PhoneKey:      &keys.Phone,
+		VehicleKey:    &keys.Vehicle,
+		Action:        req.Action,
+		Event:         &event,
+		UpdatedAt:     now,
+	})
+}
+
+// Remove lifts an entry. Entries are never deleted, so the audit trail
+// survives.
+func (s *Service) Remove(ctx context.Context, entry *models.BlacklistEntry, reason string, by *models.User, now time.Time) error {
+	if !entry.IsActive {
+		return ErrInactive
+	}
+
+	inactive := false
+	event := change("removed", by, reason, now)
+	return s.store.Blacklist.Update(ctx, entry.SocietyCode, entry.ID, store.BlacklistUpdate{
+		IsActive:  &inactive,
+		Event:     &event,
+		UpdatedAt: now,
+	})
+}
+
+func change(what string, by *models.User, reason string, now time.Time) models.BlacklistChange {
+	return models.BlacklistChange{Change: what, ByID: by.ID, ByName: by.Name, ByRole: by.Role, Reason: reason, At: now}
+}
@@ -0,0 +1,196 @@
+package calendar
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"time"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/store"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+// Feeds show what happened recently and everything planned well ahead
+const (
+	lookBack  = 30 * 24 * time.Hour
+	lookAhead = 365 * 24 * time.Hour
+	// visitorDuration is how long an expected visit is shown for
+	visitorDuration = time.Hour
+)
+
+var ErrRevoked = errors.New("calendar: feed has been revoked")
+
+// Service publishes bookings and expected visitors as iCalendar feeds
+type Service struct {
+	store *store.Store
+}
+
+func NewService(s *store.Store) *Service {
+	return &Service{store: s}
+}
+
+// NewToken returns a fresh feed token and the hash to store for it
+func NewToken() (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, HashToken(token), nil
+}
+
+// HashToken is how tokens are stored and looked up; the token itself is
+// only shown once, when the feed is created
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// URL is where calendar apps subscribe to a feed
+func URL(token string) string {
+	return "/api/v1/calendar/" + token + ".ics"
+}
+
+// Open finds the live feed for a token and records the access. It returns
+// store.ErrNotFound for unknown tokens and ErrRevoked for revoked feeds.
+func (s *Service) Open(ctx context.Context, token string, now time.Time) (*models.CalendarFeed, error) {
+	feed, err := s.store.CalendarFeeds.GetByTokenHash(ctx, HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if feed.RevokedAt != nil {
+		return nil, ErrRevoked
+	}
+	s.store.CalendarFeeds.Update(ctx, feed.ID, store.FeedUpdate{LastAccessedAt: &now})
+	return feed, nil
+}
+
+// Write renders the feed's events around now. Feeds die with their owner:
+// if the owner has left the society the feed reads as not found, and so
+// does a society feed whose owner is no longer a secretary.
+func (s *Service) Write(ctx context.Context, w io.Writer, feed *models.CalendarFeed, now time.Time) error {
+	owner, err := s.store.Users.GetByID(ctx, feed.SocietyCode, feed.UserID)
+	if err != nil {
+		return err
+	}
+	if feed.Kind == "society" && owner.Role != "secretary" {
+		return store.ErrNotFound
+	}
+
+	cal := ics.NewCalendarFor("BMS")
+	cal.SetMethod(ics.MethodPublish)
+	cal.SetXWRCalName(feed.Name)
+	cal.SetRefreshInterval("PT1H")
+	cal.SetXPublishedTTL("PT1H")
+
+	from, to := now.Add(-lookBack), now.Add(lookAhead)
+	switch feed.Kind {
+	case "user":
+		err = s.addUserEvents(ctx, cal, owner, from, to)
+	case "amenity":
+		// Residents see when an amenity is taken, not by whom
+		err = s.addBookings(ctx, cal, store.BookingFilter{
+			SocietyCode: feed.SocietyCode,
+			AmenityID:   *feed.AmenityID,
+		}, owner, owner.Role == "secretary", from, to)
+	case "society":
+		err = s.addBookings(ctx, cal, store.BookingFilter{SocietyCode: feed.SocietyCode}, owner, true, from, to)
+	}
+	if err != nil {
+		return err
+	}
+	return cal.SerializeTo(w)
+}
+
+// addUserEvents adds the owner's confirmed bookings and the visitors they
+// are expecting
+func (s *Service) addUserEvents(ctx context.Context, cal *ics.Calendar, owner *models.User, from, to time.Time) error {
+	bookings, err := s.store.Bookings.List(ctx, store.BookingFilter{
+		SocietyCode:   owner.SocietyCode,
+		UserID:        owner.ID,
+		Statuses:      []string{"confirmed"},
+		OverlapsStart: from,
+		OverlapsEnd:   to,
+	})
+	if err != nil {
+		return err
+	}
+	for i := range bookings {
+		addBooking(cal, &bookings[i], bookings[i].AmenityName, "")
+	}
+
+	visitors, err := s.store.Visitors.List(ctx, store.VisitorFilter{
+		SocietyCode: owner.SocietyCode,
+		HostID:      owner.ID,
+		Statuses:    []string{"pending", "approved"},
+	})
+	if err != nil {
+		return err
+	}
+	for i := range visitors {
+		visitor := &visitors[i]
+		if visitor.ExpectedTime.Before(from) || visitor.ExpectedTime.After(to) {
+			continue
+		}
+		event := cal.AddEvent(visitor.ID.Hex() + "@visitors.bms")
+		event.SetDtStampTime(visitor.UpdatedAt)
+		event.SetStartAt(visitor.ExpectedTime)
+		event.SetEndAt(visitor.ExpectedTime.Add(visitorDuration))
+		event.SetSummary("Visitor: " + visitor.Name)
+		event.SetDescription(visitor.Purpose)
+		if visitor.Status == "approved" {
+			event.SetStatus(ics.ObjectStatusConfirmed)
+		} else {
+			event.SetStatus(ics.ObjectStatusTentative)
+		}
+	}
+	return nil
+}
+
+// addBookings adds an occupancy view of the matching bookings. Bookings
+// awaiting payment or confirmation are tentative. Without detailed, other
+// residents' bookings show only that the amenity is taken.
+func (s *Service) addBookings(ctx context.Context, cal *ics.Calendar, filter store.BookingFilter, owner *models.User, detailed bool, from, to time.Time) error {
+	filter.Statuses = []string{"held", "pending_payment", "confirmed"}
+	filter.OverlapsStart, filter.OverlapsEnd = from, to
+	bookings, err := s.store.Bookings.List(ctx, filter)
+	if err != nil {
+		return err
+	}
+	for i := range bookings {
+		booking := &bookings[i]
+		summary := booking.AmenityName + " booked"
+		if detailed || booking.UserID == owner.ID {
+			summary = booking.AmenityName + ": " + booking.UserName
+		}
+		addBooking(cal, booking, summary, booking.TimeSlot)
+	}
+	return nil
+}
+
+func addBooking(cal *ics.Calendar, booking *models.AmenityBooking, summary, description string) {
+	if booking.StartTime.IsZero() {
+		// Bookings made before schedules existed have no times to show
+		return
+	}
+	event := cal.AddEvent(booking.ID.Hex() + "@bookings.bms")
+	event.SetDtStampTime(booking.CreatedAt)
+	event.SetStartAt(booking.StartTime)
+	event.SetEndAt(booking.EndTime)
+	event.SetSummary(summary)
+	event.SetLocation(booking.AmenityName)
+	if description != "" {
+		event.SetDescription(description)
+	}
+	if booking.Status == "confirmed" {
+		event.SetStatus(ics.ObjectStatusConfirmed)
+	} else {
+		event.SetStatus(ics.ObjectStatusTentative)
+	}
+}
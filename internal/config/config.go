@@ -3,15 +3,66 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// DefaultPaymentWebhookSecret is the development fallback for
+// PAYMENT_WEBHOOK_SECRET. It is public, so anyone could sign payment
+// webhooks with it; production refuses to start on it.
+const DefaultPaymentWebhookSecret = "payment-webhook-secret"
+
 type Config struct {
 	Port        string
 	DatabaseURL string
 	JWTSecret   string
 	Environment string
+	// PaymentProvider selects the payments gateway; only "mock" ships today
+	PaymentProvider      string
+	PaymentWebhookSecret string
+	// PaymentIntentLifetime is how long a payment may stay pending before
+	// it is given up and a new attempt may start
+	PaymentIntentLifetime time.Duration
+	// StorageBackend is "local", keeping files below StorageDir, or "s3",
+	// keeping them in an S3-compatible bucket
+	StorageBackend string
+	// StorageDir is where generated and uploaded files are kept locally
+	StorageDir        string
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	// MaxUploadSize caps uploaded photos, ID proofs and images, in bytes
+	MaxUploadSize int64
+	// WaitlistOfferWindow is how long a resident promoted from an amenity
+	// waitlist has to confirm before the slot passes on
+	WaitlistOfferWindow time.Duration
+	// VisitorPassSecret signs visitor passes; passes are valid from
+	// VisitorPassEarly before the expected time until VisitorPassLate after
+	VisitorPassSecret string
+	VisitorPassEarly  time.Duration
+	VisitorPassLate   time.Duration
+	// WalkInApprovalTimeout is how long a host unit has to answer for a
+	// walk-in before the secretaries are asked instead
+	WalkInApprovalTimeout time.Duration
+	// VisitorNoShowAfter is how long after their expected time an approved
+	// visitor who never arrived is expired
+	VisitorNoShowAfter time.Duration
+	// VisitorStayLimit is how long a checked-in visitor may stay before the
+	// host and security are told
+	VisitorStayLimit time.Duration
+	// StaffPassLifetime is how long a domestic staff pass stays valid
+	StaffPassLifetime time.Duration
+	// ParcelReminderInterval is how long a parcel waits at the desk before,
+	// and between, reminders to the unit
+	ParcelReminderInterval time.Duration
+	// VisitorParkingLimit is how long a visitor vehicle may stay in its
+	// slot before the overstay is reported
+	VisitorParkingLimit time.Duration
 }
 
 func Load() *Config {
@@ -21,16 +72,60 @@ func Load() *Config {
 	}
 
 	cfg := &Config{
-		Port:        getEnv("PORT", "8080"),
-		DatabaseURL: getEnv("DATABASE_URL", "mongodb://localhost:27017/building_management_society"),
-		JWTSecret:   getEnv("JWT_SECRET", "jwt-secret"),
-		Environment: getEnv("ENVIRONMENT", "development"),
+		Port:                   getEnv("PORT", "8080"),
+		DatabaseURL:            getEnv("DATABASE_URL", "mongodb://localhost:27017/building_management_society"),
+		JWTSecret:              getEnv("JWT_SECRET", "jwt-secret"),
+		Environment:            getEnv("ENVIRONMENT", "development"),
+		PaymentProvider:        getEnv("PAYMENT_PROVIDER", "mock"),
+		PaymentWebhookSecret:   getEnv("PAYMENT_WEBHOOK_SECRET", DefaultPaymentWebhookSecret),
+		PaymentIntentLifetime:  time.Duration(getEnvInt("PAYMENT_INTENT_MINUTES", 30)) * time.Minute,
+		StorageBackend:         getEnv("STORAGE_BACKEND", "local"),
+		StorageDir:             getEnv("STORAGE_DIR", "./data"),
+		S3Endpoint:             getEnv("S3_ENDPOINT", ""),
+		S3Region:               getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:               getEnv("S3_BUCKET", ""),
+		S3AccessKeyID:          getEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey:      getEnv("S3_SECRET_ACCESS_KEY", ""),
+		MaxUploadSize:          int64(getEnvInt("UPLOAD_MAX_MB", 5)) << 20,
+		WaitlistOfferWindow:    time.Duration(getEnvInt("WAITLIST_OFFER_MINUTES", 30)) * time.Minute,
+		VisitorPassSecret:      getEnv("VISITOR_PASS_SECRET", "visitor-pass-secret"),
+		VisitorPassEarly:       time.Duration(getEnvInt("VISITOR_PASS_EARLY_MINUTES", 120)) * time.Minute,
+		VisitorPassLate:        time.Duration(getEnvInt("VISITOR_PASS_LATE_MINUTES", 360)) * time.Minute,
+		WalkInApprovalTimeout:  time.Duration(getEnvInt("WALKIN_APPROVAL_MINUTES", 5)) * time.Minute,
+		VisitorNoShowAfter:     time.Duration(getEnvInt("VISITOR_NO_SHOW_HOURS", 6)) * time.Hour,
+		VisitorStayLimit:       time.Duration(getEnvInt("VISITOR_STAY_HOURS", 4)) * time.Hour,
+		StaffPassLifetime:      time.Duration(getEnvInt("STAFF_PASS_DAYS", 365)) * 24 * time.Hour,
+		ParcelReminderInterval: time.Duration(getEnvInt("PARCEL_REMINDER_HOURS", 24)) * time.Hour,
+		VisitorParkingLimit:    time.Duration(getEnvInt("VISITOR_PARKING_HOURS", 4)) * time.Hour,
 	}
 
 	log.Printf("🔧 Configuration loaded:")
 	log.Printf("   Port: %s", cfg.Port)
 	log.Printf("   Database: %s", cfg.DatabaseURL)
 	log.Printf("   Environment: %s", cfg.Environment)
+	log.Printf("   Payments: %s, intents expire after %s", cfg.PaymentProvider, cfg.PaymentIntentLifetime)
+	if cfg.StorageBackend == "s3" {
+		log.Printf("   Storage: s3 bucket %s at %s", cfg.S3Bucket, cfg.S3Endpoint)
+	} else {
+		log.Printf("   Storage: %s", cfg.StorageDir)
+	}
+	log.Printf("   Uploads: up to %d MB", cfg.MaxUploadSize>>20)
+	log.Printf("   Waitlist offers: %s", cfg.WaitlistOfferWindow)
+	log.Printf("   Visitor passes: %s before to %s after the expected time", cfg.VisitorPassEarly, cfg.VisitorPassLate)
+	log.Printf("   Walk-in approvals: %s", cfg.WalkInApprovalTimeout)
+	log.Printf("   Visitor no-shows: %s after the expected time, overstays: after %s", cfg.VisitorNoShowAfter, cfg.VisitorStayLimit)
+	log.Printf("   Staff passes: %d days", int(cfg.StaffPassLifetime.Hours()/24))
+	log.Printf("   Parcel reminders: every %s", cfg.ParcelReminderInterval)
+	log.Printf("   Visitor parking: %s", cfg.VisitorParkingLimit)
+
+	// The payment webhook needs no login; its signature is all that stops
+	// anyone from marking their own payments succeeded
+	if strings.TrimSpace(cfg.PaymentWebhookSecret) == "" || cfg.PaymentWebhookSecret == DefaultPaymentWebhookSecret {
+		if cfg.Environment == "production" {
+			log.Fatal("PAYMENT_WEBHOOK_SECRET must be set in production; with the default anyone can forge payment webhooks")
+		}
+		log.Printf("⚠️ WARNING: PAYMENT_WEBHOOK_SECRET is not set; payment webhooks are signed with the public default secret and can be forged. Never run like this in production")
+	}
 
 	return cfg
 }
@@ -41,3 +136,13 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+		log.Printf("⚠️ Ignoring invalid %s=%q", key, value)
+	}
+	return defaultValue
+}
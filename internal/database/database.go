@@ -5,6 +5,7 @@ import (
 	"log"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -72,6 +73,16 @@ func createIndexes(db *mongo.Database) {
 		Options: options.Index().SetUnique(true),
 	})
 
+	// Units collection compound unique index (number + society_code)
+	unitsCollection := db.Collection("units")
+	unitsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: map[string]interface{}{
+			"number":       1,
+			"society_code": 1,
+		},
+		Options: options.Index().SetUnique(true),
+	})
+
 	// Visitors collection QR code index
 	visitorsCollection := db.Collection("visitors")
 	visitorsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
@@ -79,8 +90,283 @@ func createIndexes(db *mongo.Database) {
 		Options: options.Index().SetUnique(true),
 	})
 
+	// Residents list walk-ins waiting for their unit; the escalation sweep
+	// looks for host approvals that ran out across societies
+	visitorsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "society_code", Value: 1},
+			{Key: "host_unit_id", Value: 1},
+			{Key: "status", Value: 1},
+		},
+	})
+	visitorsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "status", Value: 1},
+			{Key: "host_approval.expires_at", Value: 1},
+		},
+	})
+
+	// The no-show and overstay sweeps look for approved visitors past their
+	// expected time and checked-in visitors past their stay
+	visitorsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "status", Value: 1},
+			{Key: "expected_time", Value: 1},
+		},
+	})
+	visitorsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "status", Value: 1},
+			{Key: "actual_arrival", Value: 1},
+		},
+	})
+
+	// Maintenance idempotency keys stop the billing engine from billing a unit twice per cycle
+	maintenanceCollection := db.Collection("maintenance")
+	maintenanceCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: map[string]interface{}{"idempotency_key": 1},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(map[string]interface{}{
+			"idempotency_key": map[string]interface{}{"$type": "string"},
+		}),
+	})
+
+	// One billing plan per society
+	billingPlansCollection := db.Collection("billing_plans")
+	billingPlansCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    map[string]interface{}{"society_code": 1},
+		Options: options.Index().SetUnique(true),
+	})
+
+	// Penalty idempotency keys stop a late fee from being charged twice
+	penaltiesCollection := db.Collection("maintenance_penalties")
+	penaltiesCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    map[string]interface{}{"idempotency_key": 1},
+		Options: options.Index().SetUnique(true),
+	})
+
+	// One late fee rule per society
+	lateFeeRulesCollection := db.Collection("late_fee_rules")
+	lateFeeRulesCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    map[string]interface{}{"society_code": 1},
+		Options: options.Index().SetUnique(true),
+	})
+
+	// Payments are looked up by the provider's reference when webhooks arrive
+	paymentsCollection := db.Collection("payments")
+	paymentsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: map[string]interface{}{
+			"provider":     1,
+			"provider_ref": 1,
+		},
+		Options: options.Index().SetUnique(true),
+	})
+	// The payments scheduler looks for pending intents that have expired
+	paymentsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "status", Value: 1},
+			{Key: "expires_at", Value: 1},
+		},
+	})
+
+	// Ledger idempotency keys stop an event from being posted twice
+	ledgerCollection := db.Collection("ledger_entries")
+	ledgerCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    map[string]interface{}{"idempotency_key": 1},
+		Options: options.Index().SetUnique(true),
+	})
+	// Statements read a unit's entries in posting order; key order matters
+	ledgerCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "society_code", Value: 1},
+			{Key: "unit_id", Value: 1},
+			{Key: "posted_at", Value: 1},
+		},
+	})
+	// Billing checks which of a cycle's records have been posted
+	ledgerCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "society_code", Value: 1},
+			{Key: "reference_id", Value: 1},
+		},
+	})
+
+	// One invoice per maintenance record and one receipt per payment
+	db.Collection("billing_documents").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "society_code", Value: 1},
+			{Key: "kind", Value: 1},
+			{Key: "reference_id", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	})
+
+	// Availability and overlap checks scan an amenity's bookings by time
+	db.Collection("amenity_bookings").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "society_code", Value: 1},
+			{Key: "amenity_id", Value: 1},
+			{Key: "start_time", Value: 1},
+		},
+	})
+
+	// Occurrences of a recurring series are cancelled together
+	db.Collection("amenity_bookings").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: map[string]interface{}{"series_id": 1},
+	})
+	db.Collection("amenity_booking_series").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "society_code", Value: 1},
+			{Key: "user_id", Value: 1},
+			{Key: "created_at", Value: -1},
+		},
+	})
+
+	// A seat of a slot can only be held once; this is what enforces capacity
+	reservations := db.Collection("amenity_reservations")
+	reservations.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "amenity_id", Value: 1},
+			{Key: "slot_start", Value: 1},
+			{Key: "seat", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	})
+	reservations.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: map[string]interface{}{"booking_id": 1},
+	})
+
+	// Waitlists are read first come first served per amenity; the expiry
+	// sweep looks for open offers across societies
+	waitlist := db.Collection("amenity_waitlist")
+	waitlist.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "society_code", Value: 1},
+			{Key: "amenity_id", Value: 1},
+			{Key: "status", Value: 1},
+			{Key: "created_at", Value: 1},
+		},
+	})
+	waitlist.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "status", Value: 1},
+			{Key: "offer_expires_at", Value: 1},
+		},
+	})
+
+	// A user's inbox, newest first
+	db.Collection("notifications").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "society_code", Value: 1},
+			{Key: "user_id", Value: 1},
+			{Key: "created_at", Value: -1},
+		},
+	})
+
+	// Feed URLs are looked up by the hash of their token
+	db.Collection("calendar_feeds").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    map[string]interface{}{"token_hash": 1},
+		Options: options.Index().SetUnique(true),
+	})
+
+	// Residents list the staff serving their unit
+	db.Collection("staff").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "society_code", Value: 1},
+			{Key: "unit_ids", Value: 1},
+		},
+	})
+
+	// A staff member can only be inside once; reports read a month of
+	// check-ins per staff member
+	attendance := db.Collection("staff_attendance")
+	attendance.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: map[string]interface{}{"staff_id": 1},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(map[string]interface{}{
+			"inside": true,
+		}),
+	})
+	attendance.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "society_code", Value: 1},
+			{Key: "staff_id", Value: 1},
+			{Key: "check_in", Value: 1},
+		},
+	})
+
+	// The parcel register is read per unit, newest first; the reminder
+	// sweep looks for due reminders across societies
+	parcelsCollection := db.Collection("parcels")
+	parcelsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "society_code", Value: 1},
+			{Key: "unit_id", Value: 1},
+			{Key: "received_at", Value: -1},
+		},
+	})
+	parcelsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "status", Value: 1},
+			{Key: "next_reminder_at", Value: 1},
+		},
+	})
+
+	// Visitors are screened against the blacklist on each of its keys
+	blacklistCollection := db.Collection("blacklist")
+	for _, key := range []string{"phone_key", "vehicle_key", "name_key"} {
+		blacklistCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys: bson.D{
+				{Key: "society_code", Value: 1},
+				{Key: key, Value: 1},
+			},
+		})
+	}
+
+	// A plate is registered to one active vehicle per society
+	db.Collection("vehicles").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "society_code", Value: 1},
+			{Key: "plate", Value: 1},
+		},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(map[string]interface{}{
+			"is_active": true,
+		}),
+	})
+
+	// Slot labels are unique per building; the overstay sweep looks for
+	// visitor vehicles past their limit across societies
+	parkingSlots := db.Collection("parking_slots")
+	parkingSlots.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "society_code", Value: 1},
+			{Key: "building_id", Value: 1},
+			{Key: "label", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	})
+	parkingSlots.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: map[string]interface{}{"occupant.park_until": 1},
+	})
+
+	// A plate can only be inside once; the gate log is read newest first
+	vehicleLogs := db.Collection("vehicle_logs")
+	vehicleLogs.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "society_code", Value: 1},
+			{Key: "plate", Value: 1},
+		},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(map[string]interface{}{
+			"inside": true,
+		}),
+	})
+	vehicleLogs.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "society_code", Value: 1},
+			{Key: "entered_at", Value: -1},
+		},
+	})
+
 	// Society code indexes for all collections
-	collections := []string{"users", "visitors", "maintenance", "amenities", "amenity_bookings", "notices"}
+	collections := []string{"users", "units", "visitors", "maintenance", "maintenance_penalties", "amenities", "amenity_bookings", "notices", "payments", "billing_documents", "calendar_feeds", "staff", "parcels", "blacklist", "vehicles", "parking_slots", "uploads"}
 	for _, collName := range collections {
 		collection := db.Collection(collName)
 		collection.Indexes().CreateOne(ctx, mongo.IndexModel{
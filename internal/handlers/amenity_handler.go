@@ -2,44 +2,39 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
+	"bms-backend/internal/amenities"
 	"bms-backend/internal/models"
-	"bms-backend/internal/middleware"
+	"bms-backend/internal/payments"
+	"bms-backend/internal/store"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type AmenityHandler struct {
-	db *mongo.Database
+	store     *store.Store
+	amenities *amenities.Service
+	payments  *payments.Service
 }
 
-func NewAmenityHandler(db *mongo.Database) *AmenityHandler {
-	return &AmenityHandler{db: db}
+func NewAmenityHandler(s *store.Store, amenityService *amenities.Service, paymentService *payments.Service) *AmenityHandler {
+	return &AmenityHandler{store: s, amenities: amenityService, payments: paymentService}
 }
 
 func (h *AmenityHandler) GetAmenities(c *gin.Context) {
-	societyFilter := middleware.GetSocietyFilter(c)
-	societyFilter["is_active"] = true
-
-	collection := h.db.Collection("amenities")
-	cursor, err := collection.Find(context.Background(), societyFilter)
+	amenities, err := h.store.Amenities.List(context.Background(), store.AmenityFilter{
+		SocietyCode: c.GetString("society_code"),
+		ActiveOnly:  true,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch amenities"})
 		return
 	}
-	defer cursor.Close(context.Background())
-
-	var amenities []models.Amenity
-	if err = cursor.All(context.Background(), &amenities); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode amenities"})
-		return
-	}
 
 	if amenities == nil {
 		amenities = []models.Amenity{}
@@ -48,9 +43,77 @@ func (h *AmenityHandler) GetAmenities(c *gin.Context) {
 	c.JSON(http.StatusOK, amenities)
 }
 
+// GetAvailability returns the amenity's free and booked slots between
+// from and to (YYYY-MM-DD, inclusive), defaulting to the coming week
+func (h *AmenityHandler) GetAvailability(c *gin.Context) {
+	amenity, ok := h.findAmenity(c)
+	if !ok {
+		return
+	}
+
+	loc, err := amenities.Location(amenities.ScheduleFor(amenity))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Amenity schedule has an invalid timezone"})
+		return
+	}
+	today := time.Now().In(loc)
+	from := c.DefaultQuery("from", today.Format(amenities.DateLayout))
+	to := c.DefaultQuery("to", today.AddDate(0, 0, 6).Format(amenities.DateLayout))
+
+	availability, err := h.amenities.Availability(context.Background(), amenity, from, to)
+	if err != nil {
+		if errors.Is(err, amenities.ErrInvalidRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be YYYY-MM-DD, in order, at most 31 days apart"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch availability"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, availability)
+}
+
+// SetSchedule replaces the amenity's opening hours, slot length, buffer
+// and blackout dates. Existing bookings are kept as they are.
+func (h *AmenityHandler) SetSchedule(c *gin.Context) {
+	var req models.AmenityScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	amenity, ok := h.findAmenity(c)
+	if !ok {
+		return
+	}
+
+	schedule := &models.AmenitySchedule{
+		Timezone:      req.Timezone,
+		Hours:         req.Hours,
+		SlotMinutes:   req.SlotMinutes,
+		BufferMinutes: req.BufferMinutes,
+		BlackoutDates: req.BlackoutDates,
+	}
+	if schedule.Timezone == "" {
+		schedule.Timezone = amenities.DefaultTimezone
+	}
+	if err := amenities.ValidateSchedule(schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.store.Amenities.SetSchedule(context.Background(), amenity.SocietyCode, amenity.ID, schedule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save schedule"})
+		return
+	}
+
+	amenity.Schedule = schedule
+	c.JSON(http.StatusOK, amenity)
+}
+
 func (h *AmenityHandler) BookAmenity(c *gin.Context) {
-	var booking models.AmenityBooking
-	if err := c.ShouldBindJSON(&booking); err != nil {
+	var req models.BookAmenityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -59,58 +122,99 @@ func (h *AmenityHandler) BookAmenity(c *gin.Context) {
 	societyCode := c.GetString("society_code")
 
 	// Get society ID
-	societyCollection := h.db.Collection("societies")
-	var society models.Society
-	err := societyCollection.FindOne(context.Background(), bson.M{"code": societyCode}).Decode(&society)
+	society, err := h.store.Societies.GetByCode(context.Background(), societyCode)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Society not found"})
 		return
 	}
 
 	// Get amenity details (with society check)
-	amenityID := booking.AmenityID
-	amenityCollection := h.db.Collection("amenities")
-	var amenity models.Amenity
-	err = amenityCollection.FindOne(context.Background(), bson.M{
-		"_id":          amenityID,
-		"society_code": societyCode,
-	}).Decode(&amenity)
+	amenity, err := h.store.Amenities.GetByID(context.Background(), societyCode, req.AmenityID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Amenity not found in your society"})
 		return
 	}
+	if !amenity.IsActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Amenity is not available for booking"})
+		return
+	}
 
-	// Check if time slot is available in the same society
-	bookingCollection := h.db.Collection("amenity_bookings")
-	existingBooking := bookingCollection.FindOne(context.Background(), bson.M{
-		"amenity_id":   amenityID,
-		"date":         booking.Date,
-		"time_slot":    booking.TimeSlot,
-		"society_code": societyCode,
-		"status":       bson.M{"$ne": "cancelled"},
-	})
+	start, end, ok := resolveRange(c, amenity, req)
+	if !ok {
+		return
+	}
 
-	if existingBooking.Err() == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Time slot already booked"})
+	user, err := h.store.Users.GetByID(context.Background(), societyCode, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err := h.amenities.CheckBooking(context.Background(), amenity, user, start, time.Now()); err != nil {
+		if !writePolicyError(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check booking policy"})
+		}
 		return
 	}
 
-	booking.ID = primitive.NewObjectID()
-	booking.UserID = userID
-	booking.AmenityName = amenity.Name
-	booking.Status = "confirmed"
-	booking.TotalAmount = amenity.BookingFee
-	booking.PaymentID = primitive.NewObjectID().Hex()
-	booking.SocietyID = society.ID
-	booking.SocietyCode = societyCode
-	booking.CreatedAt = time.Now()
+	booking := models.AmenityBooking{
+		ID:          primitive.NewObjectID(),
+		AmenityID:   amenity.ID,
+		AmenityName: amenity.Name,
+		UserID:      userID,
+		UserName:    user.Name,
+		UnitID:      user.UnitID,
+		Date:        time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location()),
+		TimeSlot:    amenities.Label(start, end),
+		StartTime:   start,
+		EndTime:     end,
+		Status:      "confirmed",
+		TotalAmount: amenity.BookingFee,
+		SocietyID:   society.ID,
+		SocietyCode: societyCode,
+		CreatedAt:   time.Now(),
+	}
 
-	_, err = bookingCollection.InsertOne(context.Background(), booking)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
+	// Paid amenities hold the slot until the provider confirms the fee
+	var payment *models.Payment
+	if amenity.BookingFee > 0 {
+		payment = &models.Payment{
+			ID:          primitive.NewObjectID(),
+			Purpose:     "amenity_booking",
+			ReferenceID: booking.ID,
+			UserID:      userID,
+			UnitID:      user.UnitID,
+			Amount:      amenity.BookingFee,
+			SocietyID:   society.ID,
+			SocietyCode: societyCode,
+		}
+		booking.Status = "pending_payment"
+		booking.PaymentID = payment.ID.Hex()
+	}
+
+	if err := h.amenities.Reserve(context.Background(), amenity, &booking); err != nil {
+		switch {
+		case errors.Is(err, amenities.ErrSlotTaken):
+			c.JSON(http.StatusConflict, gin.H{
+				"error":    "Time slot already booked",
+				"waitlist": "POST /api/v1/amenities/waitlist with the same body to join the waitlist",
+			})
+		case errors.Is(err, amenities.ErrOutsideSchedule):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
+		}
 		return
 	}
 
+	if payment != nil {
+		if err := h.payments.Start(context.Background(), payment, "Booking "+amenity.Name+" "+booking.TimeSlot); err != nil {
+			// Release the slot again
+			h.amenities.Cancel(context.Background(), &booking)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to start payment"})
+			return
+		}
+	}
+
 	c.JSON(http.StatusCreated, booking)
 }
 
@@ -119,26 +223,18 @@ func (h *AmenityHandler) GetBookings(c *gin.Context) {
 	userID := c.GetString("user_id")
 
 	// Base filter with society
-	filter := middleware.GetSocietyFilter(c)
+	filter := store.BookingFilter{SocietyCode: c.GetString("society_code")}
 
 	if userRole == "resident" {
 		objID, _ := primitive.ObjectIDFromHex(userID)
-		filter["user_id"] = objID
+		filter.UserID = objID
 	}
 
-	collection := h.db.Collection("amenity_bookings")
-	cursor, err := collection.Find(context.Background(), filter, options.Find().SetSort(bson.M{"date": -1}))
+	bookings, err := h.store.Bookings.List(context.Background(), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
 		return
 	}
-	defer cursor.Close(context.Background())
-
-	var bookings []models.AmenityBooking
-	if err = cursor.All(context.Background(), &bookings); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode bookings"})
-		return
-	}
 
 	if bookings == nil {
 		bookings = []models.AmenityBooking{}
@@ -147,6 +243,10 @@ func (h *AmenityHandler) GetBookings(c *gin.Context) {
 	c.JSON(http.StatusOK, bookings)
 }
 
+// CancelBooking cancels a booking and refunds its fee through the payment
+// record. Residents can cancel their own bookings up to the amenity's
+// cutoff and are refunded by its refund tiers; secretaries can cancel any
+// booking at any time with a full refund.
 func (h *AmenityHandler) CancelBooking(c *gin.Context) {
 	bookingID := c.Param("id")
 	objID, err := primitive.ObjectIDFromHex(bookingID)
@@ -155,26 +255,718 @@ func (h *AmenityHandler) CancelBooking(c *gin.Context) {
 		return
 	}
 
-	societyFilter := middleware.GetSocietyFilter(c)
-	societyFilter["_id"] = objID
+	booking, err := h.store.Bookings.GetByID(context.Background(), c.GetString("society_code"), objID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found in your society"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return
+	}
+	if c.GetString("user_role") != "secretary" && booking.UserID.Hex() != c.GetString("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only cancel your own bookings"})
+		return
+	}
+
+	amenity, err := h.store.Amenities.GetByID(context.Background(), booking.SocietyCode, booking.AmenityID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load amenity"})
+		return
+	}
+
+	result, err := h.cancelBooking(c, amenity, booking)
+	if err != nil {
+		switch {
+		case writePolicyError(c, err):
+		case errors.Is(err, store.ErrConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": "Booking is not active"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel booking"})
+		}
+		return
+	}
+
+	result["message"] = "Booking cancelled successfully"
+	c.JSON(http.StatusOK, result)
+}
+
+// cancelBooking cancels one booking on behalf of the current user and
+// refunds its fee, returning the refund details. Residents are held to
+// the amenity's cutoff and refund tiers; secretaries get a full refund.
+func (h *AmenityHandler) cancelBooking(c *gin.Context, amenity *models.Amenity, booking *models.AmenityBooking) (gin.H, error) {
+	percent := 100.0
+	if c.GetString("user_role") != "secretary" {
+		var err error
+		if percent, err = amenities.CancellationRefund(amenity, booking, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	// Recorded with the cancellation, for a payment still pending to be
+	// refunded by the same tier if it completes later
+	booking.RefundPercent = &percent
+	if err := h.amenities.Cancel(context.Background(), booking); err != nil {
+		return nil, err
+	}
+
+	result := gin.H{"refund_percent": percent}
+	payment, err := h.payments.RefundBooking(context.Background(), booking, percent)
+	if err != nil {
+		// The booking stays cancelled; the secretary can settle the refund
+		log.Printf("⚠️ Failed to refund booking %s: %v", booking.ID.Hex(), err)
+		result["refund_error"] = "Refund could not be processed"
+	} else if payment != nil {
+		result["refund_amount"] = payment.RefundAmount
+		result["payment"] = payment
+	}
+	return result, nil
+}
+
+// CreateSeries books the same range on every date of a recurring rule.
+// Each occurrence is booked on its own; the response lists which dates
+// were booked and why the others conflicted. Paid amenities start one
+// payment per booked occurrence.
+func (h *AmenityHandler) CreateSeries(c *gin.Context) {
+	var req models.RecurringBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	societyCode := c.GetString("society_code")
+
+	amenity, err := h.store.Amenities.GetByID(context.Background(), societyCode, req.AmenityID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Amenity not found in your society"})
+		return
+	}
+	if !amenity.IsActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Amenity is not available for booking"})
+		return
+	}
+
+	startClock, endClock := req.StartTime, req.EndTime
+	if startClock == "" {
+		var ok bool
+		if startClock, endClock, ok = amenities.SplitLabel(req.TimeSlot); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "start_time or a HH:MM-HH:MM time_slot is required"})
+			return
+		}
+	}
+
+	loc, err := amenities.Location(amenities.ScheduleFor(amenity))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Amenity has an invalid timezone"})
+		return
+	}
+	startDate, err := amenities.ParseDate(req.StartDate, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start_date must be YYYY-MM-DD"})
+		return
+	}
+	rule, err := amenities.ParseRule(req.RRule, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Until != "" {
+		if rule.Until, err = amenities.ParseDate(req.Until, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until must be YYYY-MM-DD"})
+			return
+		}
+	}
+	dates, err := rule.Dates(startDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.store.Users.GetByID(context.Background(), societyCode, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	series := models.BookingSeries{
+		ID:          primitive.NewObjectID(),
+		AmenityID:   amenity.ID,
+		AmenityName: amenity.Name,
+		UserID:      userID,
+		UserName:    user.Name,
+		UnitID:      user.UnitID,
+		RRule:       rule.String(),
+		StartDate:   startDate,
+		Status:      "pending",
+		SocietyID:   amenity.SocietyID,
+		SocietyCode: societyCode,
+		CreatedAt:   time.Now(),
+	}
+	// The series is recorded before its bookings so none of them points at
+	// a series that does not exist; it turns active once all are in place
+	if err := h.store.Series.Create(context.Background(), &series); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save series"})
+		return
+	}
+
+	occurrences, bookings, err := h.amenities.BookSeries(context.Background(), amenity, user, &series, dates, startClock, endClock, time.Now())
+	if err != nil {
+		h.failSeries(&series)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to book series"})
+		return
+	}
+	if len(bookings) == 0 {
+		h.failSeries(&series)
+		c.JSON(http.StatusConflict, gin.H{"error": "None of the occurrences could be booked", "occurrences": occurrences})
+		return
+	}
+
+	var paymentList []models.Payment
+	// rollback takes back the whole series rather than leave unpaid holds
+	// or payable intents behind
+	rollback := func() {
+		for i := range paymentList {
+			if err := h.payments.Cancel(context.Background(), &paymentList[i], "Booking series was not created"); err != nil && !errors.Is(err, store.ErrConflict) {
+				log.Printf("⚠️ Failed to cancel payment %s of series %s: %v", paymentList[i].ID.Hex(), series.ID.Hex(), err)
+			}
+		}
+		for i := range bookings {
+			h.amenities.Cancel(context.Background(), &bookings[i])
+		}
+		h.failSeries(&series)
+	}
+
+	for i := range bookings {
+		booking := &bookings[i]
+		if booking.PaymentID == "" {
+			continue
+		}
+		paymentID, _ := primitive.ObjectIDFromHex(booking.PaymentID)
+		payment := &models.Payment{
+			ID:          paymentID,
+			Purpose:     "amenity_booking",
+			ReferenceID: booking.ID,
+			UserID:      userID,
+			UnitID:      user.UnitID,
+			Amount:      amenity.BookingFee,
+			SocietyID:   amenity.SocietyID,
+			SocietyCode: societyCode,
+		}
+		if err := h.payments.Start(context.Background(), payment, "Booking "+amenity.Name+" "+booking.Date.Format("02 Jan")+" "+booking.TimeSlot); err != nil {
+			rollback()
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to start payment"})
+			return
+		}
+		paymentList = append(paymentList, *payment)
+	}
+
+	series.TimeSlot = bookings[0].TimeSlot
+	series.Booked = len(bookings)
+	series.Conflicts = len(occurrences) - len(bookings)
+	series.Status = "active"
+	err = h.store.Series.Update(context.Background(), societyCode, series.ID, store.SeriesUpdate{
+		TimeSlot:  &series.TimeSlot,
+		Booked:    &series.Booked,
+		Conflicts: &series.Conflicts,
+		Status:    &series.Status,
+	})
+	if err != nil {
+		rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save series"})
+		return
+	}
+
+	response := gin.H{"series": series, "occurrences": occurrences}
+	if len(paymentList) > 0 {
+		response["payments"] = paymentList
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// failSeries marks a series whose bookings could not all be made as failed
+func (h *AmenityHandler) failSeries(series *models.BookingSeries) {
+	status := "failed"
+	if err := h.store.Series.Update(context.Background(), series.SocietyCode, series.ID, store.SeriesUpdate{Status: &status}); err != nil {
+		log.Printf("⚠️ Failed to mark series %s as failed: %v", series.ID.Hex(), err)
+	}
+}
+
+// GetSeries lists recurring series, newest first. Residents only see
+// their own.
+func (h *AmenityHandler) GetSeries(c *gin.Context) {
+	filter := store.SeriesFilter{
+		SocietyCode: c.GetString("society_code"),
+		Status:      c.Query("status"),
+	}
+	if c.GetString("user_role") == "resident" {
+		filter.UserID, _ = primitive.ObjectIDFromHex(c.GetString("user_id"))
+	}
+
+	series, err := h.store.Series.List(context.Background(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch series"})
+		return
+	}
+
+	if series == nil {
+		series = []models.BookingSeries{}
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+// GetSeriesByID returns a series with its bookings
+func (h *AmenityHandler) GetSeriesByID(c *gin.Context) {
+	series, ok := h.findSeries(c)
+	if !ok {
+		return
+	}
+
+	bookings, err := h.store.Bookings.List(context.Background(), store.BookingFilter{
+		SocietyCode: series.SocietyCode,
+		SeriesID:    series.ID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
+		return
+	}
+
+	if bookings == nil {
+		bookings = []models.AmenityBooking{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"series": series, "bookings": bookings})
+}
+
+// CancelSeries cancels one occurrence of a series, named by date, or every
+// occurrence still to come. Each is cancelled and refunded as a single
+// booking would be; occurrences the policy keeps are reported.
+func (h *AmenityHandler) CancelSeries(c *gin.Context) {
+	var req models.CancelSeriesRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
-	update := bson.M{
-		"$set": bson.M{
-			"status": "cancelled",
-		},
+	series, ok := h.findSeries(c)
+	if !ok {
+		return
+	}
+	if series.Status == "cancelled" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Series is already cancelled"})
+		return
+	}
+	if series.Status != "active" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Series was never booked"})
+		return
 	}
 
-	collection := h.db.Collection("amenity_bookings")
-	result, err := collection.UpdateOne(context.Background(), societyFilter, update)
+	amenity, err := h.store.Amenities.GetByID(context.Background(), series.SocietyCode, series.AmenityID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel booking"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load amenity"})
+		return
+	}
+
+	filter := store.BookingFilter{
+		SocietyCode:   series.SocietyCode,
+		SeriesID:      series.ID,
+		Statuses:      amenities.ActiveStatuses,
+		OverlapsStart: time.Now(),
+	}
+	if req.Date != "" {
+		loc, _ := amenities.Location(amenities.ScheduleFor(amenity))
+		day, err := amenities.ParseDate(req.Date, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date must be YYYY-MM-DD"})
+			return
+		}
+		filter.OverlapsStart, filter.OverlapsEnd = day, day.AddDate(0, 0, 1)
+	}
+
+	bookings, err := h.store.Bookings.List(context.Background(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
+		return
+	}
+	if req.Date != "" && len(bookings) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No active occurrence on that date"})
+		return
+	}
+
+	var cancelled, kept []gin.H
+	for i := range bookings {
+		booking := &bookings[i]
+		result, err := h.cancelBooking(c, amenity, booking)
+		var policyErr *amenities.PolicyError
+		switch {
+		case errors.As(err, &policyErr):
+			kept = append(kept, gin.H{"booking_id": booking.ID, "date": booking.Date, "code": policyErr.Code, "reason": policyErr.Message})
+			continue
+		case errors.Is(err, store.ErrConflict):
+			// Cancelled in the meantime
+			continue
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel booking"})
+			return
+		}
+		result["booking_id"] = booking.ID
+		result["date"] = booking.Date
+		cancelled = append(cancelled, result)
+	}
+	if req.Date != "" && len(kept) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": kept[0]["reason"], "code": kept[0]["code"]})
+		return
+	}
+
+	if req.Date == "" {
+		status := "cancelled"
+		if err := h.store.Series.Update(context.Background(), series.SocietyCode, series.ID, store.SeriesUpdate{Status: &status}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update series"})
+			return
+		}
+	}
+
+	if cancelled == nil {
+		cancelled = []gin.H{}
+	}
+	response := gin.H{"message": "Series cancelled", "cancelled": cancelled}
+	if req.Date != "" {
+		response["message"] = "Occurrence cancelled"
+	}
+	if len(kept) > 0 {
+		response["kept"] = kept
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// findSeries loads the series named in the URL. Residents can only reach
+// their own. It writes the error response itself on failure.
+func (h *AmenityHandler) findSeries(c *gin.Context) (*models.BookingSeries, bool) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return nil, false
+	}
+
+	series, err := h.store.Series.GetByID(context.Background(), c.GetString("society_code"), objID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Series not found in your society"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return nil, false
+	}
+	if c.GetString("user_role") == "resident" && series.UserID.Hex() != c.GetString("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not your series"})
+		return nil, false
+	}
+	return series, true
+}
+
+// SetPolicy replaces the amenity's booking limits and refund tiers.
+// Existing bookings are kept as they are.
+func (h *AmenityHandler) SetPolicy(c *gin.Context) {
+	var req models.BookingPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	amenity, ok := h.findAmenity(c)
+	if !ok {
+		return
+	}
+
+	policy := &models.BookingPolicy{
+		MaxPerUnitPerWeek: req.MaxPerUnitPerWeek,
+		MinAdvanceDays:    req.MinAdvanceDays,
+		MaxAdvanceDays:    req.MaxAdvanceDays,
+		CancelCutoffHours: req.CancelCutoffHours,
+		RefundTiers:       req.RefundTiers,
+	}
+	if err := amenities.ValidatePolicy(policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.store.Amenities.SetPolicy(context.Background(), amenity.SocietyCode, amenity.ID, policy); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save policy"})
+		return
+	}
+
+	amenity.Policy = policy
+	c.JSON(http.StatusOK, amenity)
+}
+
+// JoinWaitlist queues the resident for a fully booked range. The body is
+// the same as for BookAmenity.
+func (h *AmenityHandler) JoinWaitlist(c *gin.Context) {
+	var req models.BookAmenityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	societyCode := c.GetString("society_code")
+
+	amenity, err := h.store.Amenities.GetByID(context.Background(), societyCode, req.AmenityID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Amenity not found in your society"})
+		return
+	}
+	if !amenity.IsActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Amenity is not available for booking"})
 		return
 	}
 
-	if result.MatchedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found in your society"})
+	start, end, ok := resolveRange(c, amenity, req)
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Booking cancelled successfully"})
-}
\ No newline at end of file
+	user, err := h.store.Users.GetByID(context.Background(), societyCode, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	// Residents can only wait for slots they would be allowed to book
+	if err := h.amenities.CheckBooking(context.Background(), amenity, user, start, time.Now()); err != nil {
+		if !writePolicyError(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check booking policy"})
+		}
+		return
+	}
+
+	entry := models.WaitlistEntry{
+		ID:          primitive.NewObjectID(),
+		AmenityID:   amenity.ID,
+		AmenityName: amenity.Name,
+		UserID:      userID,
+		UserName:    user.Name,
+		UnitID:      user.UnitID,
+		Date:        time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location()),
+		TimeSlot:    amenities.Label(start, end),
+		StartTime:   start,
+		EndTime:     end,
+		SocietyID:   amenity.SocietyID,
+		SocietyCode: societyCode,
+	}
+
+	if err := h.amenities.Join(context.Background(), amenity, &entry); err != nil {
+		switch {
+		case errors.Is(err, amenities.ErrSlotAvailable):
+			c.JSON(http.StatusConflict, gin.H{"error": "Time slot has free seats, book it directly"})
+		case errors.Is(err, amenities.ErrAlreadyWaiting):
+			c.JSON(http.StatusConflict, gin.H{"error": "You are already on the waitlist for this slot"})
+		case errors.Is(err, amenities.ErrOutsideSchedule):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join waitlist"})
+		}
+		return
+	}
+
+	position, _ := h.amenities.Position(context.Background(), &entry)
+	c.JSON(http.StatusCreated, gin.H{"entry": entry, "position": position})
+}
+
+// GetWaitlist lists waitlist entries, oldest first. Residents only see
+// their own; ?amenity_id= narrows to one amenity.
+func (h *AmenityHandler) GetWaitlist(c *gin.Context) {
+	filter := store.WaitlistFilter{SocietyCode: c.GetString("society_code")}
+
+	if amenityID := c.Query("amenity_id"); amenityID != "" {
+		objID, err := primitive.ObjectIDFromHex(amenityID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amenity ID"})
+			return
+		}
+		filter.AmenityID = objID
+	}
+	if status := c.Query("status"); status != "" {
+		filter.Statuses = []string{status}
+	}
+	if c.GetString("user_role") == "resident" {
+		filter.UserID, _ = primitive.ObjectIDFromHex(c.GetString("user_id"))
+	}
+
+	entries, err := h.store.Waitlist.List(context.Background(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist"})
+		return
+	}
+
+	if entries == nil {
+		entries = []models.WaitlistEntry{}
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// ConfirmWaitlist takes up an open offer. Free amenities are confirmed
+// straight away; paid ones move to pending_payment like a normal booking.
+func (h *AmenityHandler) ConfirmWaitlist(c *gin.Context) {
+	entry, ok := h.findOwnWaitlistEntry(c)
+	if !ok {
+		return
+	}
+
+	amenity, err := h.store.Amenities.GetByID(context.Background(), entry.SocietyCode, entry.AmenityID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load amenity"})
+		return
+	}
+
+	status := "confirmed"
+	var payment *models.Payment
+	if amenity.BookingFee > 0 && entry.BookingID != nil {
+		payment = &models.Payment{
+			ID:          primitive.NewObjectID(),
+			Purpose:     "amenity_booking",
+			ReferenceID: *entry.BookingID,
+			UserID:      entry.UserID,
+			UnitID:      entry.UnitID,
+			Amount:      amenity.BookingFee,
+			SocietyID:   entry.SocietyID,
+			SocietyCode: entry.SocietyCode,
+		}
+		status = "pending_payment"
+	}
+
+	paymentID := ""
+	if payment != nil {
+		paymentID = payment.ID.Hex()
+	}
+	booking, err := h.amenities.Accept(context.Background(), entry, status, paymentID)
+	if err != nil {
+		if errors.Is(err, amenities.ErrNoOffer) {
+			c.JSON(http.StatusConflict, gin.H{"error": "No open offer for this waitlist entry"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm booking"})
+		}
+		return
+	}
+
+	if payment != nil {
+		if err := h.payments.Start(context.Background(), payment, "Booking "+amenity.Name+" "+booking.TimeSlot); err != nil {
+			// Release the slot to the next in line
+			h.amenities.Cancel(context.Background(), booking)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to start payment"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, booking)
+}
+
+// LeaveWaitlist drops the resident's entry. An open offer passes to the
+// next in line.
+func (h *AmenityHandler) LeaveWaitlist(c *gin.Context) {
+	entry, ok := h.findOwnWaitlistEntry(c)
+	if !ok {
+		return
+	}
+
+	if err := h.amenities.Leave(context.Background(), entry); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Waitlist entry is no longer active"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave waitlist"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Left the waitlist"})
+}
+
+// findOwnWaitlistEntry loads the waitlist entry named in the URL, which
+// must belong to the current user. It writes the error response itself
+// on failure.
+func (h *AmenityHandler) findOwnWaitlistEntry(c *gin.Context) (*models.WaitlistEntry, bool) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid waitlist entry ID"})
+		return nil, false
+	}
+
+	entry, err := h.store.Waitlist.GetByID(context.Background(), c.GetString("society_code"), objID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Waitlist entry not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return nil, false
+	}
+	if entry.UserID.Hex() != c.GetString("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not your waitlist entry"})
+		return nil, false
+	}
+	return entry, true
+}
+
+// writePolicyError responds with the policy rule a request broke, as
+// {"error", "code", "limit"}. It reports false, writing nothing, for other
+// errors.
+func writePolicyError(c *gin.Context, err error) bool {
+	var policyErr *amenities.PolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+	response := gin.H{"error": policyErr.Message, "code": policyErr.Code}
+	if policyErr.Limit > 0 {
+		response["limit"] = policyErr.Limit
+	}
+	c.JSON(http.StatusUnprocessableEntity, response)
+	return true
+}
+
+// resolveRange turns a booking request into a future, slot-aligned range
+// of the amenity's schedule. It writes the error response itself on
+// failure.
+func resolveRange(c *gin.Context, amenity *models.Amenity, req models.BookAmenityRequest) (time.Time, time.Time, bool) {
+	startClock, endClock := req.StartTime, req.EndTime
+	if startClock == "" {
+		var ok bool
+		if startClock, endClock, ok = amenities.SplitLabel(req.TimeSlot); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "start_time or a HH:MM-HH:MM time_slot is required"})
+			return time.Time{}, time.Time{}, false
+		}
+	}
+	start, end, err := amenities.Resolve(amenities.ScheduleFor(amenity), req.Date, startClock, endClock)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return time.Time{}, time.Time{}, false
+	}
+	if start.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot book a slot in the past"})
+		return time.Time{}, time.Time{}, false
+	}
+	return start, end, true
+}
+
+// findAmenity loads the amenity named in the URL. It writes the error
+// response itself on failure.
+func (h *AmenityHandler) findAmenity(c *gin.Context) (*models.Amenity, bool) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amenity ID"})
+		return nil, false
+	}
+
+	amenity, err := h.store.Amenities.GetByID(context.Background(), c.GetString("society_code"), objID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Amenity not found in your society"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return nil, false
+	}
+	return amenity, true
+}
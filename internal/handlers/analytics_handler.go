@@ -5,28 +5,18 @@ import (
 	"net/http"
 	"time"
 
+	"bms-backend/internal/store"
+
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type AnalyticsHandler struct {
-	userCollection        *mongo.Collection
-	visitorCollection     *mongo.Collection
-	maintenanceCollection *mongo.Collection
-	amenityCollection     *mongo.Collection
-	noticeCollection      *mongo.Collection
+	store *store.Store
 }
 
-func NewAnalyticsHandler(db *mongo.Database) *AnalyticsHandler {
-	return &AnalyticsHandler{
-		userCollection:        db.Collection("users"),
-		visitorCollection:     db.Collection("visitors"),
-		maintenanceCollection: db.Collection("maintenance"),
-		amenityCollection:     db.Collection("amenity_bookings"),
-		noticeCollection:      db.Collection("notices"),
-	}
+func NewAnalyticsHandler(s *store.Store) *AnalyticsHandler {
+	return &AnalyticsHandler{store: s}
 }
 
 type DashboardStats struct {
@@ -54,7 +44,6 @@ func (h *AnalyticsHandler) GetStats(c *gin.Context) {
 	userID := c.GetString("user_id")
 	role := c.GetString("user_role")
 	societyCode := c.GetString("society_code")
-	unit := c.GetString("unit")
 
 	stats := DashboardStats{
 		SocietyCode: societyCode,
@@ -65,7 +54,7 @@ func (h *AnalyticsHandler) GetStats(c *gin.Context) {
 	case "secretary":
 		stats = h.getSecretaryStats(ctx, societyCode)
 	case "resident":
-		stats = h.getResidentStats(ctx, societyCode, userID, unit)
+		stats = h.getResidentStats(ctx, societyCode, userID, currentUnitID(ctx, h.store, c))
 	case "security":
 		stats = h.getSecurityStats(ctx, societyCode)
 	default:
@@ -82,77 +71,58 @@ func (h *AnalyticsHandler) getSecretaryStats(ctx context.Context, societyCode st
 		LastUpdated: time.Now().Format(time.RFC3339),
 	}
 
-	totalUsers, _ := h.userCollection.CountDocuments(ctx, bson.M{"society_code": societyCode})
+	totalUsers, _ := h.store.Users.Count(ctx, store.UserFilter{SocietyCode: societyCode})
 	stats.TotalUsers = int(totalUsers)
 
-	totalResidents, _ := h.userCollection.CountDocuments(ctx, bson.M{
-		"society_code": societyCode,
-		"role":         "resident",
+	totalResidents, _ := h.store.Users.Count(ctx, store.UserFilter{
+		SocietyCode: societyCode,
+		Roles:       []string{"resident"},
 	})
 	stats.TotalResidents = int(totalResidents)
 
-	pendingVisitors, _ := h.visitorCollection.CountDocuments(ctx, bson.M{
-		"society_code": societyCode,
-		"status":       "pending",
+	pendingVisitors, _ := h.store.Visitors.Count(ctx, store.VisitorFilter{
+		SocietyCode: societyCode,
+		Statuses:    []string{"pending"},
 	})
 	stats.PendingVisitors = int(pendingVisitors)
 
 	todayStart := time.Now().Truncate(24 * time.Hour)
-	approvedToday, _ := h.visitorCollection.CountDocuments(ctx, bson.M{
-		"society_code": societyCode,
-		"status":       bson.M{"$in": []string{"approved", "checked_in"}},
-		"created_at":   bson.M{"$gte": todayStart},
+	approvedToday, _ := h.store.Visitors.Count(ctx, store.VisitorFilter{
+		SocietyCode:  societyCode,
+		Statuses:     []string{"approved", "checked_in"},
+		CreatedSince: todayStart,
 	})
 	stats.ApprovedVisitorsToday = int(approvedToday)
 
-	overdueMaintenance, _ := h.maintenanceCollection.CountDocuments(ctx, bson.M{
-		"society_code": societyCode,
-		"status":       "pending",
-		"due_date":     bson.M{"$lt": time.Now()},
+	overdueMaintenance, _ := h.store.Maintenance.Count(ctx, store.MaintenanceFilter{
+		SocietyCode: societyCode,
+		Statuses:    []string{"pending", "partially_paid", "overdue"},
+		DueBefore:   time.Now(),
 	})
 	stats.OverdueMaintenance = int(overdueMaintenance)
 
-	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: bson.M{"society_code": societyCode}}},
-		{{Key: "$group", Value: bson.M{
-			"_id":   "$status",
-			"total": bson.M{"$sum": "$amount"},
-		}}},
-	}
-	cursor, _ := h.maintenanceCollection.Aggregate(ctx, pipeline)
-	var results []bson.M
-	cursor.All(ctx, &results)
-
-	var totalPaid, totalDue float64
-	for _, result := range results {
-		status := result["_id"].(string)
-		amount := result["total"].(float64)
-		if status == "paid" {
-			totalPaid = amount
-		} else if status == "pending" || status == "overdue" {
-			totalDue += amount
-		}
-	}
+	totals, _ := h.store.Maintenance.SumByStatus(ctx, store.MaintenanceFilter{SocietyCode: societyCode})
+	totalPaid, totalDue := collected(totals)
 	if totalPaid+totalDue > 0 {
 		stats.MaintenanceCollectionPercentage = (totalPaid / (totalPaid + totalDue)) * 100
 	}
 
-	activeBookings, _ := h.amenityCollection.CountDocuments(ctx, bson.M{
-		"society_code": societyCode,
-		"status":       "confirmed",
+	activeBookings, _ := h.store.Bookings.Count(ctx, store.BookingFilter{
+		SocietyCode: societyCode,
+		Statuses:    []string{"confirmed"},
 	})
 	stats.ActiveAmenityBookings = int(activeBookings)
 
-	unreadNotices, _ := h.noticeCollection.CountDocuments(ctx, bson.M{
-		"society_code": societyCode,
-		"is_active":    true,
+	unreadNotices, _ := h.store.Notices.Count(ctx, store.NoticeFilter{
+		SocietyCode: societyCode,
+		ActiveOnly:  true,
 	})
 	stats.UnreadNotices = int(unreadNotices)
 
 	return stats
 }
 
-func (h *AnalyticsHandler) getResidentStats(ctx context.Context, societyCode, userID, unit string) DashboardStats {
+func (h *AnalyticsHandler) getResidentStats(ctx context.Context, societyCode, userID string, unitID primitive.ObjectID) DashboardStats {
 	stats := DashboardStats{
 		SocietyCode: societyCode,
 		LastUpdated: time.Now().Format(time.RFC3339),
@@ -160,95 +130,91 @@ func (h *AnalyticsHandler) getResidentStats(ctx context.Context, societyCode, us
 
 	userObjectID, _ := primitive.ObjectIDFromHex(userID)
 
-	myPendingVisitors, _ := h.visitorCollection.CountDocuments(ctx, bson.M{
-		"society_code": societyCode,
-		"host_id":      userObjectID,
-		"status":       "pending",
+	myPendingVisitors, _ := h.store.Visitors.Count(ctx, store.VisitorFilter{
+		SocietyCode: societyCode,
+		HostID:      userObjectID,
+		Statuses:    []string{"pending"},
 	})
 	stats.PendingVisitors = int(myPendingVisitors)
 
 	todayStart := time.Now().Truncate(24 * time.Hour)
-	myVisitorsToday, _ := h.visitorCollection.CountDocuments(ctx, bson.M{
-		"society_code": societyCode,
-		"host_id":      userObjectID,
-		"created_at":   bson.M{"$gte": todayStart},
+	myVisitorsToday, _ := h.store.Visitors.Count(ctx, store.VisitorFilter{
+		SocietyCode:  societyCode,
+		HostID:       userObjectID,
+		CreatedSince: todayStart,
 	})
 	stats.ApprovedVisitorsToday = int(myVisitorsToday)
 
-	myOverdue, _ := h.maintenanceCollection.CountDocuments(ctx, bson.M{
-		"society_code": societyCode,
-		"unit_number":  unit,
-		"status":       "pending",
-		"due_date":     bson.M{"$lt": time.Now()},
-	})
-	stats.OverdueMaintenance = int(myOverdue)
-
-	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: bson.M{
-			"society_code": societyCode,
-			"unit_number":  unit,
-		}}},
-		{{Key: "$group", Value: bson.M{
-			"_id":   "$status",
-			"total": bson.M{"$sum": "$amount"},
-		}}},
-	}
-	cursor, _ := h.maintenanceCollection.Aggregate(ctx, pipeline)
-	var results []bson.M
-	cursor.All(ctx, &results)
-
+	// Maintenance is billed per unit; residents without a unit owe nothing
 	var myPending, myPaid float64
-	for _, result := range results {
-		status := result["_id"].(string)
-		amount := result["total"].(float64)
-		if status == "paid" {
-			myPaid = amount
-		} else if status == "pending" || status == "overdue" {
-			myPending += amount
-		}
+	if !unitID.IsZero() {
+		myOverdue, _ := h.store.Maintenance.Count(ctx, store.MaintenanceFilter{
+			SocietyCode: societyCode,
+			UnitID:      unitID,
+			Statuses:    []string{"pending", "partially_paid", "overdue"},
+			DueBefore:   time.Now(),
+		})
+		stats.OverdueMaintenance = int(myOverdue)
+
+		totals, _ := h.store.Maintenance.SumByStatus(ctx, store.MaintenanceFilter{
+			SocietyCode: societyCode,
+			UnitID:      unitID,
+		})
+		myPaid, myPending = collected(totals)
 	}
 	stats.MyPendingAmount = &myPending
 	stats.MyPaidAmount = &myPaid
 
-	myBookings, _ := h.amenityCollection.CountDocuments(ctx, bson.M{
-		"society_code": societyCode,
-		"user_id":      userObjectID,
-		"status":       "confirmed",
+	myBookings, _ := h.store.Bookings.Count(ctx, store.BookingFilter{
+		SocietyCode: societyCode,
+		UserID:      userObjectID,
+		Statuses:    []string{"confirmed"},
 	})
 	stats.ActiveAmenityBookings = int(myBookings)
 
-	unreadNotices, _ := h.noticeCollection.CountDocuments(ctx, bson.M{
-		"society_code": societyCode,
-		"is_active":    true,
+	unreadNotices, _ := h.store.Notices.Count(ctx, store.NoticeFilter{
+		SocietyCode: societyCode,
+		ActiveOnly:  true,
 	})
 	stats.UnreadNotices = int(unreadNotices)
 
 	return stats
 }
 
+// collected splits maintenance totals into what has been paid, including
+// partial payments on open records, and what is still outstanding
+func collected(totals map[string]store.MaintenanceSum) (paid, outstanding float64) {
+	paid = totals["paid"].Amount
+	for _, status := range []string{"pending", "partially_paid", "overdue"} {
+		paid += totals[status].AmountPaid
+		outstanding += totals[status].Amount - totals[status].AmountPaid
+	}
+	return paid, outstanding
+}
+
 func (h *AnalyticsHandler) getSecurityStats(ctx context.Context, societyCode string) DashboardStats {
 	stats := DashboardStats{
 		SocietyCode: societyCode,
 		LastUpdated: time.Now().Format(time.RFC3339),
 	}
 
-	pendingVisitors, _ := h.visitorCollection.CountDocuments(ctx, bson.M{
-		"society_code": societyCode,
-		"status":       "pending",
+	pendingVisitors, _ := h.store.Visitors.Count(ctx, store.VisitorFilter{
+		SocietyCode: societyCode,
+		Statuses:    []string{"pending"},
 	})
 	stats.PendingVisitors = int(pendingVisitors)
 
 	todayStart := time.Now().Truncate(24 * time.Hour)
-	approvedToday, _ := h.visitorCollection.CountDocuments(ctx, bson.M{
-		"society_code": societyCode,
-		"status":       bson.M{"$in": []string{"approved", "checked_in"}},
-		"created_at":   bson.M{"$gte": todayStart},
+	approvedToday, _ := h.store.Visitors.Count(ctx, store.VisitorFilter{
+		SocietyCode:  societyCode,
+		Statuses:     []string{"approved", "checked_in"},
+		CreatedSince: todayStart,
 	})
 	stats.ApprovedVisitorsToday = int(approvedToday)
 
-	checkedInNow, _ := h.visitorCollection.CountDocuments(ctx, bson.M{
-		"society_code": societyCode,
-		"status":       "checked_in",
+	checkedInNow, _ := h.store.Visitors.Count(ctx, store.VisitorFilter{
+		SocietyCode: societyCode,
+		Statuses:    []string{"checked_in"},
 	})
 	checkedIn := int(checkedInNow)
 	stats.CheckedInNow = &checkedIn
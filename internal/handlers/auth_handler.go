@@ -2,27 +2,27 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
 	"bms-backend/internal/models"
+	"bms-backend/internal/store"
 	"bms-backend/pkg/auth"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthHandler struct {
-	db        *mongo.Database
+	store     *store.Store
 	jwtSecret string
 }
 
-func NewAuthHandler(db *mongo.Database, jwtSecret string) *AuthHandler {
+func NewAuthHandler(s *store.Store, jwtSecret string) *AuthHandler {
 	return &AuthHandler{
-		db:        db,
+		store:     s,
 		jwtSecret: jwtSecret,
 	}
 }
@@ -35,9 +35,7 @@ func (h *AuthHandler) ValidateSociety(c *gin.Context) {
 	}
 
 	// Find society by code
-	collection := h.db.Collection("societies")
-	var society models.Society
-	err := collection.FindOne(context.Background(), bson.M{"code": req.Code, "is_active": true}).Decode(&society)
+	society, err := h.store.Societies.GetActiveByCode(context.Background(), req.Code)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Invalid society code"})
 		return
@@ -66,22 +64,14 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	// First validate society code
-	societyCollection := h.db.Collection("societies")
-	var society models.Society
-	err := societyCollection.FindOne(context.Background(), bson.M{"code": req.SocietyCode, "is_active": true}).Decode(&society)
+	society, err := h.store.Societies.GetActiveByCode(context.Background(), req.SocietyCode)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Invalid society code"})
 		return
 	}
 
 	// Find user by email and society code
-	collection := h.db.Collection("users")
-	var user models.User
-	err = collection.FindOne(context.Background(), bson.M{
-		"email":        req.Email,
-		"society_code": req.SocietyCode,
-		"is_active":    true,
-	}).Decode(&user)
+	user, err := h.store.Users.GetActiveByEmail(context.Background(), req.SocietyCode, req.Email)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
@@ -115,11 +105,47 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	c.JSON(http.StatusOK, models.LoginResponse{
 		Token:   token,
-		User:    user,
+		User:    *user,
 		Society: societyResponse,
 	})
 }
 
+// AdminLogin authenticates platform admins. They do not belong to any
+// society, so their users carry an empty society code.
+func (h *AuthHandler) AdminLogin(c *gin.Context) {
+	var req models.AdminLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.store.Users.GetActiveByEmail(context.Background(), "", req.Email)
+	if err != nil || user.Role != "platform_admin" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+
+	token, err := auth.GenerateToken(user.ID, user.Email, user.Role, "", h.jwtSecret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	// Remove password from response
+	user.Password = ""
+
+	c.JSON(http.StatusOK, gin.H{
+		"token": token,
+		"user":  user,
+	})
+}
+
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req models.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -127,10 +153,14 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	// Platform admins are provisioned out of band, never self-registered
+	if req.Role == "platform_admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Role cannot be self-registered"})
+		return
+	}
+
 	// First validate society code
-	societyCollection := h.db.Collection("societies")
-	var society models.Society
-	err := societyCollection.FindOne(context.Background(), bson.M{"code": req.SocietyCode, "is_active": true}).Decode(&society)
+	society, err := h.store.Societies.GetActiveByCode(context.Background(), req.SocietyCode)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Invalid society code"})
 		return
@@ -159,10 +189,9 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		UpdatedAt:   time.Now(),
 	}
 
-	collection := h.db.Collection("users")
-	_, err = collection.InsertOne(context.Background(), user)
+	err = h.store.Users.Create(context.Background(), &user)
 	if err != nil {
-		if mongo.IsDuplicateKeyError(err) {
+		if errors.Is(err, store.ErrDuplicate) {
 			c.JSON(http.StatusConflict, gin.H{"error": "User already exists in this society"})
 			return
 		}
@@ -184,12 +213,7 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 	societyCode := c.GetString("society_code")
 	objID, _ := primitive.ObjectIDFromHex(userID)
 
-	collection := h.db.Collection("users")
-	var user models.User
-	err := collection.FindOne(context.Background(), bson.M{
-		"_id":          objID,
-		"society_code": societyCode,
-	}).Decode(&user)
+	user, err := h.store.Users.GetByID(context.Background(), societyCode, objID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"bms-backend/internal/billing"
+	"bms-backend/internal/models"
+	"bms-backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BillingHandler struct {
+	store  *store.Store
+	engine *billing.Engine
+}
+
+func NewBillingHandler(s *store.Store, engine *billing.Engine) *BillingHandler {
+	return &BillingHandler{store: s, engine: engine}
+}
+
+func (h *BillingHandler) GetPlan(c *gin.Context) {
+	plan, ok := h.loadPlan(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+func (h *BillingHandler) SavePlan(c *gin.Context) {
+	var req models.BillingPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	societyCode := c.GetString("society_code")
+
+	// Get society ID
+	society, err := h.store.Societies.GetByCode(context.Background(), societyCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Society not found"})
+		return
+	}
+
+	plan := models.BillingPlan{
+		Type:          req.Type,
+		FlatAmount:    req.FlatAmount,
+		RatePerSqFt:   req.RatePerSqFt,
+		BuildingRates: req.BuildingRates,
+		DueDay:        req.DueDay,
+		Description:   req.Description,
+		IsActive:      req.IsActive == nil || *req.IsActive,
+		SocietyID:     society.ID,
+		SocietyCode:   societyCode,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	if plan.Description == "" {
+		plan.Description = "Monthly maintenance"
+	}
+
+	if err := billing.ValidatePlan(&plan); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Every building rate must point at a building of this society
+	for _, rate := range plan.BuildingRates {
+		found := false
+		for _, building := range society.Buildings {
+			if building.ID == rate.BuildingID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Building " + rate.BuildingID.Hex() + " not found in your society"})
+			return
+		}
+	}
+
+	if err := h.store.BillingPlans.Upsert(context.Background(), &plan); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save billing plan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+func (h *BillingHandler) GetLateFeeRule(c *gin.Context) {
+	rule, err := h.store.LateFeeRules.Get(context.Background(), c.GetString("society_code"))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No late fee rule configured"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+func (h *BillingHandler) SaveLateFeeRule(c *gin.Context) {
+	var req models.LateFeeRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	societyCode := c.GetString("society_code")
+
+	// Get society ID
+	society, err := h.store.Societies.GetByCode(context.Background(), societyCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Society not found"})
+		return
+	}
+
+	rule := models.LateFeeRule{
+		Type:        req.Type,
+		Amount:      req.Amount,
+		GraceDays:   req.GraceDays,
+		MaxPenalty:  req.MaxPenalty,
+		IsActive:    req.IsActive == nil || *req.IsActive,
+		SocietyID:   society.ID,
+		SocietyCode: societyCode,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	if err := billing.ValidateLateFeeRule(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.store.LateFeeRules.Upsert(context.Background(), &rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save late fee rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+func (h *BillingHandler) PreviewCycle(c *gin.Context) {
+	plan, ok := h.loadPlan(c)
+	if !ok {
+		return
+	}
+
+	cycle := c.DefaultQuery("cycle", billing.CurrentCycle(time.Now()))
+	result, err := h.engine.Preview(context.Background(), plan, cycle)
+	if err != nil {
+		h.cycleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *BillingHandler) RunCycle(c *gin.Context) {
+	var req models.BillingRunRequest
+	// The body is optional; an empty one runs the current month
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Cycle == "" {
+		req.Cycle = billing.CurrentCycle(time.Now())
+	}
+
+	plan, ok := h.loadPlan(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.engine.RunCycle(context.Background(), plan, req.Cycle)
+	if err != nil {
+		h.cycleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// loadPlan fetches the caller's society plan, writing the error response
+// itself when there is none.
+func (h *BillingHandler) loadPlan(c *gin.Context) (*models.BillingPlan, bool) {
+	plan, err := h.store.BillingPlans.Get(context.Background(), c.GetString("society_code"))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No billing plan configured"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return nil, false
+	}
+	return plan, true
+}
+
+func (h *BillingHandler) cycleError(c *gin.Context, err error) {
+	if errors.Is(err, billing.ErrInvalidCycle) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	} else {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process billing cycle"})
+	}
+}
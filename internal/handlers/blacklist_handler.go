@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"bms-backend/internal/blacklist"
+	"bms-backend/internal/models"
+	"bms-backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type BlacklistHandler struct {
+	store     *store.Store
+	blacklist *blacklist.Service
+}
+
+func NewBlacklistHandler(s *store.Store, blacklistService *blacklist.Service) *BlacklistHandler {
+	return &BlacklistHandler{store: s, blacklist: blacklistService}
+}
+
+// GetBlacklist lists the society's entries, newest first (?active=true,
+// ?action=block|warn)
+func (h *BlacklistHandler) GetBlacklist(c *gin.Context) {
+	entries, err := h.store.Blacklist.List(context.Background(), store.BlacklistFilter{
+		SocietyCode: c.GetString("society_code"),
+		ActiveOnly:  c.Query("active") == "true",
+		Action:      c.Query("action"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch blacklist"})
+		return
+	}
+
+	if entries == nil {
+		entries = []models.BlacklistEntry{}
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// AddToBlacklist flags a person by phone, vehicle number and/or name
+func (h *BlacklistHandler) AddToBlacklist(c *gin.Context) {
+	var req models.BlacklistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	by, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	society, err := h.store.Societies.GetByCode(context.Background(), by.SocietyCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Society not found"})
+		return
+	}
+
+	entry := models.BlacklistEntry{
+		Name:          req.Name,
+		Phone:         req.Phone,
+		VehicleNumber: req.VehicleNumber,
+		Action:        req.Action,
+		Reason:        req.Reason,
+		SocietyID:     society.ID,
+	}
+	if err := h.blacklist.Add(context.Background(), &entry, by, time.Now()); err != nil {
+		writeBlacklistError(c, err, "Failed to add blacklist entry")
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+func (h *BlacklistHandler) GetBlacklistEntry(c *gin.Context) {
+	entry, ok := h.findEntry(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// UpdateBlacklistEntry changes an active entry; the reason is kept in its
+// history
+func (h *BlacklistHandler) UpdateBlacklistEntry(c *gin.Context) {
+	var req models.UpdateBlacklistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, ok := h.findEntry(c)
+	if !ok {
+		return
+	}
+	by, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.blacklist.Update(context.Background(), entry, req, by, time.Now()); err != nil {
+		writeBlacklistError(c, err, "Failed to update blacklist entry")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Blacklist entry updated successfully"})
+}
+
+// RemoveFromBlacklist lifts an entry. It stays on record with its history.
+func (h *BlacklistHandler) RemoveFromBlacklist(c *gin.Context) {
+	var req models.RemoveBlacklistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, ok := h.findEntry(c)
+	if !ok {
+		return
+	}
+	by, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.blacklist.Remove(context.Background(), entry, req.Reason, by, time.Now()); err != nil {
+		writeBlacklistError(c, err, "Failed to remove blacklist entry")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Blacklist entry removed successfully"})
+}
+
+// CheckBlacklist screens a person at the gate without registering them
+func (h *BlacklistHandler) CheckBlacklist(c *gin.Context) {
+	var req models.BlacklistCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	person := blacklist.Person{Name: req.Name, Phone: req.Phone, VehicleNumber: req.VehicleNumber}
+	result, err := h.blacklist.Screen(context.Background(), c.GetString("society_code"), person)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check blacklist"})
+		return
+	}
+
+	matches := result.Matches
+	if matches == nil {
+		matches = []models.BlacklistMatch{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"action": result.Action, "matches": matches})
+}
+
+// currentUser loads the logged-in user, who is recorded in the history of
+// the entries they change. It writes the error response itself.
+func (h *BlacklistHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	userID, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	user, err := h.store.Users.GetByID(context.Background(), c.GetString("society_code"), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	return user, true
+}
+
+// findEntry loads the :id entry of the caller's society, writing the error
+// response itself
+func (h *BlacklistHandler) findEntry(c *gin.Context) (*models.BlacklistEntry, bool) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid blacklist entry ID"})
+		return nil, false
+	}
+
+	entry, err := h.store.Blacklist.GetByID(context.Background(), c.GetString("society_code"), objID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Blacklist entry not found in your society"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return nil, false
+	}
+	return entry, true
+}
+
+// writeBlacklistError answers a failed blacklist change
+func writeBlacklistError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, blacklist.ErrNoKeys):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A phone, vehicle_number or name is required"})
+	case errors.Is(err, blacklist.ErrInactive):
+		c.JSON(http.StatusConflict, gin.H{"error": "Blacklist entry has already been removed"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
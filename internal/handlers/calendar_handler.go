@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"bms-backend/internal/calendar"
+	"bms-backend/internal/models"
+	"bms-backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CalendarHandler struct {
+	store *store.Store
+	feeds *calendar.Service
+}
+
+func NewCalendarHandler(s *store.Store) *CalendarHandler {
+	return &CalendarHandler{store: s, feeds: calendar.NewService(s)}
+}
+
+// CreateFeed issues a calendar feed URL for the current user. The token in
+// the URL is only returned here; a lost URL is revoked and replaced.
+func (h *CalendarHandler) CreateFeed(c *gin.Context) {
+	var req models.CalendarFeedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	societyCode := c.GetString("society_code")
+
+	society, err := h.store.Societies.GetByCode(context.Background(), societyCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Society not found"})
+		return
+	}
+
+	feed := models.CalendarFeed{
+		Kind:        req.Kind,
+		Name:        req.Name,
+		UserID:      userID,
+		SocietyID:   society.ID,
+		SocietyCode: societyCode,
+		CreatedAt:   time.Now(),
+	}
+	switch req.Kind {
+	case "user":
+		if feed.Name == "" {
+			feed.Name = "My bookings and visitors"
+		}
+	case "amenity":
+		if req.AmenityID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "amenity_id is required for amenity feeds"})
+			return
+		}
+		amenity, err := h.store.Amenities.GetByID(context.Background(), societyCode, *req.AmenityID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Amenity not found in your society"})
+			return
+		}
+		feed.AmenityID = &amenity.ID
+		if feed.Name == "" {
+			feed.Name = amenity.Name
+		}
+	case "society":
+		if c.GetString("user_role") != "secretary" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only secretaries can subscribe to society occupancy"})
+			return
+		}
+		if feed.Name == "" {
+			feed.Name = society.Name + " amenities"
+		}
+	}
+
+	token, hash, err := calendar.NewToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create feed token"})
+		return
+	}
+	feed.TokenHash = hash
+
+	if err := h.store.CalendarFeeds.Create(context.Background(), &feed); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create feed"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"feed": feed, "url": calendar.URL(token)})
+}
+
+// GetFeeds lists the current user's feeds; secretaries see every feed in
+// the society
+func (h *CalendarHandler) GetFeeds(c *gin.Context) {
+	filter := store.FeedFilter{
+		SocietyCode: c.GetString("society_code"),
+		ActiveOnly:  c.Query("active") == "true",
+	}
+	if c.GetString("user_role") != "secretary" {
+		filter.UserID, _ = primitive.ObjectIDFromHex(c.GetString("user_id"))
+	}
+
+	feeds, err := h.store.CalendarFeeds.List(context.Background(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch feeds"})
+		return
+	}
+
+	if feeds == nil {
+		feeds = []models.CalendarFeed{}
+	}
+
+	c.JSON(http.StatusOK, feeds)
+}
+
+// RevokeFeed stops a feed URL from working. Owners can revoke their own
+// feeds and secretaries any feed in the society.
+func (h *CalendarHandler) RevokeFeed(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid feed ID"})
+		return
+	}
+
+	feed, err := h.store.CalendarFeeds.GetByID(context.Background(), c.GetString("society_code"), objID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Feed not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return
+	}
+	if c.GetString("user_role") != "secretary" && feed.UserID.Hex() != c.GetString("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not your feed"})
+		return
+	}
+	if feed.RevokedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Feed is already revoked"})
+		return
+	}
+
+	now := time.Now()
+	if err := h.store.CalendarFeeds.Update(context.Background(), feed.ID, store.FeedUpdate{RevokedAt: &now}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke feed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Feed revoked"})
+}
+
+// ServeFeed answers calendar apps polling a feed URL. The token is the
+// only credential, so unknown and revoked tokens look the same.
+func (h *CalendarHandler) ServeFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	feed, err := h.feeds.Open(context.Background(), token, time.Now())
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) || errors.Is(err, calendar.ErrRevoked) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return
+	}
+
+	var body bytes.Buffer
+	if err := h.feeds.Write(context.Background(), &body, feed, time.Now()); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build calendar"})
+		}
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=900")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", body.Bytes())
+}
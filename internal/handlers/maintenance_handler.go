@@ -2,40 +2,43 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
-	"bms-backend/internal/middleware"
+	"bms-backend/internal/ledger"
 	"bms-backend/internal/models"
+	"bms-backend/internal/payments"
+	"bms-backend/internal/receipts"
+	"bms-backend/internal/store"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type MaintenanceHandler struct {
-	db *mongo.Database
+	store     *store.Store
+	ledger    *ledger.Ledger
+	payments  *payments.Service
+	documents *receipts.Service
 }
 
-func NewMaintenanceHandler(db *mongo.Database) *MaintenanceHandler {
-	return &MaintenanceHandler{db: db}
+func NewMaintenanceHandler(s *store.Store, paymentService *payments.Service, documents *receipts.Service) *MaintenanceHandler {
+	return &MaintenanceHandler{store: s, ledger: ledger.New(s), payments: paymentService, documents: documents}
 }
 
 func (h *MaintenanceHandler) GetMaintenanceByID(c *gin.Context) {
-	noticeID := c.Param("id")
-	objID, err := primitive.ObjectIDFromHex(noticeID)
+	maintenanceID := c.Param("id")
+	objID, err := primitive.ObjectIDFromHex(maintenanceID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid maintenance ID"})
 		return
 	}
 
-	collection := h.db.Collection("maintenance")
-	var maintenance models.MaintenanceRecord
-	err = collection.FindOne(context.Background(), bson.M{"_id": objID}).Decode(&maintenance)
+	maintenance, err := h.store.Maintenance.GetByID(context.Background(), c.GetString("society_code"), objID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if errors.Is(err, store.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Maintenance not found"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
@@ -48,31 +51,25 @@ func (h *MaintenanceHandler) GetMaintenanceByID(c *gin.Context) {
 
 func (h *MaintenanceHandler) GetMaintenanceRecords(c *gin.Context) {
 	userRole := c.GetString("user_role")
-	userID := c.GetString("user_id")
-	// societyCode := c.GetString("society_code")
 
 	// Base filter with society
-	filter := middleware.GetSocietyFilter(c)
+	filter := store.MaintenanceFilter{SocietyCode: c.GetString("society_code")}
 
 	if userRole == "resident" {
-		// Residents can only see their own maintenance records
-		objID, _ := primitive.ObjectIDFromHex(userID)
-		filter["unit_id"] = objID
+		// Residents can only see the records of their own unit
+		unitID := currentUnitID(context.Background(), h.store, c)
+		if unitID.IsZero() {
+			c.JSON(http.StatusOK, []models.MaintenanceRecord{})
+			return
+		}
+		filter.UnitID = unitID
 	}
 
-	collection := h.db.Collection("maintenance")
-	cursor, err := collection.Find(context.Background(), filter, options.Find().SetSort(bson.M{"due_date": -1}))
+	records, err := h.store.Maintenance.List(context.Background(), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch maintenance records"})
 		return
 	}
-	defer cursor.Close(context.Background())
-
-	var records []models.MaintenanceRecord
-	if err = cursor.All(context.Background(), &records); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode maintenance records"})
-		return
-	}
 
 	if records == nil {
 		records = []models.MaintenanceRecord{}
@@ -94,38 +91,61 @@ func (h *MaintenanceHandler) PayMaintenance(c *gin.Context) {
 		return
 	}
 
-	// Society filter for security
-	societyFilter := middleware.GetSocietyFilter(c)
-	societyFilter["_id"] = maintenanceID
-
-	// Simulate payment processing
-	paymentID := primitive.NewObjectID().Hex()
-	now := time.Now()
+	societyCode := c.GetString("society_code")
+	record, err := h.store.Maintenance.GetByID(context.Background(), societyCode, maintenanceID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Maintenance record not found in your society"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return
+	}
 
-	update := bson.M{
-		"$set": bson.M{
-			"status":      "paid",
-			"paid_date":   now,
-			"receipt_url": "/receipts/" + paymentID + ".pdf",
-		},
+	if record.Status == "paid" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Maintenance record is already paid"})
+		return
 	}
 
-	collection := h.db.Collection("maintenance")
-	result, err := collection.UpdateOne(context.Background(), societyFilter, update)
+	// Hand back the payment already in progress instead of charging twice;
+	// one left pending past its expiry is given up and a new one started
+	inProgress, err := h.payments.InProgress(context.Background(), societyCode, record.ID, time.Now())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process payment"})
 		return
 	}
+	if inProgress != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Payment already in progress",
+			"payment": inProgress,
+		})
+		return
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	unitID := record.UnitID
+
+	payment := models.Payment{
+		ID:          primitive.NewObjectID(),
+		Purpose:     "maintenance",
+		ReferenceID: record.ID,
+		UserID:      userID,
+		UnitID:      &unitID,
+		Amount:      req.Amount,
+		Method:      req.PaymentMethod,
+		SocietyID:   record.SocietyID,
+		SocietyCode: societyCode,
+	}
 
-	if result.MatchedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Maintenance record not found in your society"})
+	// The record only becomes paid once the provider confirms the payment
+	if err := h.payments.Start(context.Background(), &payment, "Maintenance "+record.Month+" - "+record.UnitNumber); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to start payment"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":     "Payment processed successfully",
-		"payment_id":  paymentID,
-		"receipt_url": "/receipts/" + paymentID + ".pdf",
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Payment initiated",
+		"payment": payment,
 	})
 }
 
@@ -139,31 +159,126 @@ func (h *MaintenanceHandler) CreateMaintenanceRecord(c *gin.Context) {
 	societyCode := c.GetString("society_code")
 
 	// Get society ID
-	societyCollection := h.db.Collection("societies")
-	var society models.Society
-	err := societyCollection.FindOne(context.Background(), bson.M{"code": societyCode}).Decode(&society)
+	society, err := h.store.Societies.GetByCode(context.Background(), societyCode)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Society not found"})
 		return
 	}
 
+	// Every record must belong to a real unit, given by id or by number
+	var unit *models.Unit
+	if !record.UnitID.IsZero() {
+		unit, err = h.store.Units.GetByID(context.Background(), societyCode, record.UnitID)
+	} else if record.UnitNumber != "" {
+		unit, err = h.store.Units.GetByNumber(context.Background(), societyCode, record.UnitNumber)
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unit_id or unit_number is required"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unit not found in your society"})
+		return
+	}
+
 	record.ID = primitive.NewObjectID()
+	record.UnitID = unit.ID
+	record.UnitNumber = unit.Number
 	record.Status = "pending"
+	record.AmountPaid = 0
+	record.PaidDate = nil
+	record.ReceiptURL = ""
+	record.InvoiceURL = ""
 	record.SocietyID = society.ID
 	record.SocietyCode = societyCode
 	record.CreatedAt = time.Now()
 
-	// If no unit_id provided, create a dummy one
-	if record.UnitID.IsZero() {
-		record.UnitID = primitive.NewObjectID()
+	if err := h.store.Maintenance.Create(context.Background(), &record); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create maintenance record"})
+		return
+	}
+
+	// Debit the unit, settling the record from advance credit when there is some
+	if err := h.ledger.PostDue(context.Background(), &record); err != nil {
+		log.Printf("⚠️ Failed to post maintenance record %s to the ledger: %v", record.ID.Hex(), err)
+	}
+	if _, err := h.documents.IssueInvoice(context.Background(), &record); err != nil {
+		log.Printf("⚠️ Failed to issue invoice for maintenance record %s: %v", record.ID.Hex(), err)
+	}
+	if updated, err := h.store.Maintenance.GetByID(context.Background(), societyCode, record.ID); err == nil {
+		record = *updated
+	}
+
+	c.JSON(http.StatusCreated, record)
+}
+
+func (h *MaintenanceHandler) GetPenalties(c *gin.Context) {
+	filter := store.PenaltyFilter{SocietyCode: c.GetString("society_code")}
+
+	if maintenanceID := c.Query("maintenance_id"); maintenanceID != "" {
+		objID, err := primitive.ObjectIDFromHex(maintenanceID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid maintenance ID"})
+			return
+		}
+		filter.MaintenanceID = objID
+	}
+
+	if c.GetString("user_role") == "resident" {
+		// Residents can only see the penalties of their own unit
+		unitID := currentUnitID(context.Background(), h.store, c)
+		if unitID.IsZero() {
+			c.JSON(http.StatusOK, []models.MaintenancePenalty{})
+			return
+		}
+		filter.UnitID = unitID
 	}
 
-	collection := h.db.Collection("maintenance")
-	_, err = collection.InsertOne(context.Background(), record)
+	penalties, err := h.store.Penalties.List(context.Background(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create maintenance record"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch penalties"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, record)
+	if penalties == nil {
+		penalties = []models.MaintenancePenalty{}
+	}
+
+	c.JSON(http.StatusOK, penalties)
+}
+
+func (h *MaintenanceHandler) WaivePenalty(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid penalty ID"})
+		return
+	}
+
+	var req models.WaivePenaltyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	waivedBy, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+
+	err = h.store.Penalties.Waive(context.Background(), c.GetString("society_code"), objID, waivedBy, req.Reason, time.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Penalty not found in your society"})
+		case errors.Is(err, store.ErrConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": "Penalty is already waived"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to waive penalty"})
+		}
+		return
+	}
+
+	if penalty, err := h.store.Penalties.GetByID(context.Background(), c.GetString("society_code"), objID); err == nil {
+		if err := h.ledger.PostWaiver(context.Background(), penalty); err != nil {
+			log.Printf("⚠️ Failed to post waiver of penalty %s to the ledger: %v", objID.Hex(), err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Penalty waived successfully"})
 }
@@ -2,25 +2,23 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
-	"bms-backend/internal/middleware"
 	"bms-backend/internal/models"
+	"bms-backend/internal/store"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type NoticeHandler struct {
-	db *mongo.Database
+	store *store.Store
 }
 
-func NewNoticeHandler(db *mongo.Database) *NoticeHandler {
-	return &NoticeHandler{db: db}
+func NewNoticeHandler(s *store.Store) *NoticeHandler {
+	return &NoticeHandler{store: s}
 }
 
 func (h *NoticeHandler) GetNoticeByID(c *gin.Context) {
@@ -31,11 +29,9 @@ func (h *NoticeHandler) GetNoticeByID(c *gin.Context) {
 		return
 	}
 
-	collection := h.db.Collection("notices")
-	var notice models.Notice
-	err = collection.FindOne(context.Background(), bson.M{"_id": objID}).Decode(&notice)
+	notice, err := h.store.Notices.GetByID(context.Background(), c.GetString("society_code"), objID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if errors.Is(err, store.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Notice not found"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
@@ -47,22 +43,14 @@ func (h *NoticeHandler) GetNoticeByID(c *gin.Context) {
 }
 
 func (h *NoticeHandler) GetNotices(c *gin.Context) {
-	societyFilter := middleware.GetSocietyFilter(c)
-	societyFilter["is_active"] = true
-
-	collection := h.db.Collection("notices")
-	cursor, err := collection.Find(context.Background(), societyFilter, options.Find().SetSort(bson.M{"created_at": -1}))
+	notices, err := h.store.Notices.List(context.Background(), store.NoticeFilter{
+		SocietyCode: c.GetString("society_code"),
+		ActiveOnly:  true,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notices"})
 		return
 	}
-	defer cursor.Close(context.Background())
-
-	var notices []models.Notice
-	if err = cursor.All(context.Background(), &notices); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode notices"})
-		return
-	}
 
 	if notices == nil {
 		notices = []models.Notice{}
@@ -82,9 +70,7 @@ func (h *NoticeHandler) CreateNotice(c *gin.Context) {
 	societyCode := c.GetString("society_code")
 
 	// Get society ID
-	societyCollection := h.db.Collection("societies")
-	var society models.Society
-	err := societyCollection.FindOne(context.Background(), bson.M{"code": societyCode}).Decode(&society)
+	society, err := h.store.Societies.GetByCode(context.Background(), societyCode)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Society not found"})
 		return
@@ -97,9 +83,7 @@ func (h *NoticeHandler) CreateNotice(c *gin.Context) {
 	notice.IsActive = true
 	notice.CreatedAt = time.Now()
 
-	collection := h.db.Collection("notices")
-	_, err = collection.InsertOne(context.Background(), notice)
-	if err != nil {
+	if err := h.store.Notices.Create(context.Background(), &notice); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create notice"})
 		return
 	}
@@ -115,24 +99,25 @@ func (h *NoticeHandler) UpdateNotice(c *gin.Context) {
 		return
 	}
 
-	var updates bson.M
-	if err := c.ShouldBindJSON(&updates); err != nil {
+	var req models.UpdateNoticeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	societyFilter := middleware.GetSocietyFilter(c)
-	societyFilter["_id"] = objID
-
-	collection := h.db.Collection("notices")
-	result, err := collection.UpdateOne(context.Background(), societyFilter, bson.M{"$set": updates})
+	err = h.store.Notices.Update(context.Background(), c.GetString("society_code"), objID, store.NoticeUpdate{
+		Title:     req.Title,
+		Content:   req.Content,
+		Type:      req.Type,
+		IsActive:  req.IsActive,
+		ExpiresAt: req.ExpiresAt,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notice"})
-		return
-	}
-
-	if result.MatchedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Notice not found in your society"})
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Notice not found in your society"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notice"})
+		}
 		return
 	}
 
@@ -147,24 +132,16 @@ func (h *NoticeHandler) DeleteNotice(c *gin.Context) {
 		return
 	}
 
-	societyFilter := middleware.GetSocietyFilter(c)
-	societyFilter["_id"] = objID
-
-	update := bson.M{
-		"$set": bson.M{
-			"is_active": false,
-		},
-	}
-
-	collection := h.db.Collection("notices")
-	result, err := collection.UpdateOne(context.Background(), societyFilter, update)
+	isActive := false
+	err = h.store.Notices.Update(context.Background(), c.GetString("society_code"), objID, store.NoticeUpdate{
+		IsActive: &isActive,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete notice"})
-		return
-	}
-
-	if result.MatchedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Notice not found in your society"})
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Notice not found in your society"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete notice"})
+		}
 		return
 	}
 
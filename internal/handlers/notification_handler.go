@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type NotificationHandler struct {
+	store *store.Store
+}
+
+func NewNotificationHandler(s *store.Store) *NotificationHandler {
+	return &NotificationHandler{store: s}
+}
+
+// GetNotifications lists the current user's notifications, newest first.
+// ?unread=true leaves out the ones already read.
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	userID, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	filter := store.NotificationFilter{
+		SocietyCode: c.GetString("society_code"),
+		UserID:      userID,
+		UnreadOnly:  c.Query("unread") == "true",
+	}
+
+	notifications, err := h.store.Notifications.List(context.Background(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+
+	if notifications == nil {
+		notifications = []models.Notification{}
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
+
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+		return
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	err = h.store.Notifications.MarkRead(context.Background(), c.GetString("society_code"), userID, objID, time.Now())
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"bms-backend/internal/amenities"
+	"bms-backend/internal/models"
+	"bms-backend/internal/parcels"
+	"bms-backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ParcelHandler struct {
+	store   *store.Store
+	parcels *parcels.Service
+}
+
+func NewParcelHandler(s *store.Store, parcelService *parcels.Service) *ParcelHandler {
+	return &ParcelHandler{store: s, parcels: parcelService}
+}
+
+// ReceiveParcel logs a parcel at the security desk; the unit's residents
+// are sent the OTP to collect it with
+func (h *ParcelHandler) ReceiveParcel(c *gin.Context) {
+	var req models.ParcelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	societyCode := c.GetString("society_code")
+	society, err := h.store.Societies.GetByCode(context.Background(), societyCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Society not found"})
+		return
+	}
+	unit, err := h.store.Units.GetByID(context.Background(), societyCode, req.UnitID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unit not found in your society"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return
+	}
+
+	parcel := models.Parcel{
+		Courier:        req.Courier,
+		TrackingNumber: req.TrackingNumber,
+		Description:    req.Description,
+		PhotoURL:       req.PhotoURL,
+		SocietyID:      society.ID,
+		SocietyCode:    societyCode,
+	}
+	guard, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err := h.parcels.Receive(context.Background(), &parcel, unit, guard, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log parcel"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, parcel)
+}
+
+// GetParcels is the society's parcel register (?status=, ?unit_id=,
+// ?from= and ?to= as YYYY-MM-DD, inclusive). Residents see their own
+// unit's parcels.
+func (h *ParcelHandler) GetParcels(c *gin.Context) {
+	filter := store.ParcelFilter{SocietyCode: c.GetString("society_code")}
+	if status := c.Query("status"); status != "" {
+		filter.Statuses = []string{status}
+	}
+
+	if c.GetString("user_role") == "resident" {
+		unitID, ok := residentUnit(c, h.store)
+		if !ok {
+			return
+		}
+		filter.UnitID = unitID
+	} else if raw := c.Query("unit_id"); raw != "" {
+		unitID, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unit ID"})
+			return
+		}
+		filter.UnitID = unitID
+	}
+
+	loc, _ := time.LoadLocation(amenities.DefaultTimezone)
+	if raw := c.Query("from"); raw != "" {
+		from, err := time.ParseInLocation(amenities.DateLayout, raw, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be YYYY-MM-DD"})
+			return
+		}
+		filter.ReceivedFrom = from
+	}
+	if raw := c.Query("to"); raw != "" {
+		to, err := time.ParseInLocation(amenities.DateLayout, raw, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be YYYY-MM-DD"})
+			return
+		}
+		filter.ReceivedTo = to.AddDate(0, 0, 1)
+	}
+
+	list, err := h.store.Parcels.List(context.Background(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch parcels"})
+		return
+	}
+
+	if list == nil {
+		list = []models.Parcel{}
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *ParcelHandler) GetParcelByID(c *gin.Context) {
+	parcel, ok := h.findParcel(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, parcel)
+}
+
+// ResendOTP gives the resident a new OTP for a parcel waiting at the desk.
+// The old OTP stops working and wrong attempts are forgiven.
+func (h *ParcelHandler) ResendOTP(c *gin.Context) {
+	parcel, ok := h.findParcel(c)
+	if !ok {
+		return
+	}
+
+	otp, err := h.parcels.NewOTP(context.Background(), parcel, time.Now())
+	if err != nil {
+		writeParcelError(c, err, "Failed to issue OTP")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"otp": otp})
+}
+
+// CollectParcel hands a parcel over against the unit's OTP or a signature
+func (h *ParcelHandler) CollectParcel(c *gin.Context) {
+	var req models.CollectParcelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	parcel, ok := h.findParcel(c)
+	if !ok {
+		return
+	}
+
+	guard, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err := h.parcels.Collect(context.Background(), parcel, req, guard, time.Now()); err != nil {
+		writeParcelError(c, err, "Failed to collect parcel")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Parcel collected successfully", "parcel": parcel})
+}
+
+// ReturnParcel records that an uncollected parcel went back with the
+// courier
+func (h *ParcelHandler) ReturnParcel(c *gin.Context) {
+	var req models.ReturnParcelRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	parcel, ok := h.findParcel(c)
+	if !ok {
+		return
+	}
+
+	if err := h.parcels.Return(context.Background(), parcel, req.Reason, time.Now()); err != nil {
+		writeParcelError(c, err, "Failed to return parcel")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Parcel returned successfully", "parcel": parcel})
+}
+
+// findParcel loads the :id parcel of the caller's society, writing the
+// error response itself. Residents only get their own unit's parcels.
+func (h *ParcelHandler) findParcel(c *gin.Context) (*models.Parcel, bool) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parcel ID"})
+		return nil, false
+	}
+
+	parcel, err := h.store.Parcels.GetByID(context.Background(), c.GetString("society_code"), objID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Parcel not found in your society"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return nil, false
+	}
+	if c.GetString("user_role") == "resident" {
+		unitID, ok := residentUnit(c, h.store)
+		if !ok {
+			return nil, false
+		}
+		if parcel.UnitID != unitID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not your unit's parcel"})
+			return nil, false
+		}
+	}
+	return parcel, true
+}
+
+// writeParcelError answers a failed parcel change
+func writeParcelError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, parcels.ErrProofRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An otp or signature_upload_id is required"})
+	case errors.Is(err, parcels.ErrBadSignature):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "signature_upload_id must be a signature uploaded for this parcel"})
+	case errors.Is(err, parcels.ErrWrongOTP):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Wrong OTP"})
+	case errors.Is(err, parcels.ErrOTPLocked):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many wrong OTPs; the resident must request a new one before the parcel can be collected"})
+	case errors.Is(err, parcels.ErrNotWaiting):
+		c.JSON(http.StatusConflict, gin.H{"error": "Parcel is no longer at the security desk"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
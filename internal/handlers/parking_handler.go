@@ -0,0 +1,551 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"bms-backend/internal/amenities"
+	"bms-backend/internal/models"
+	"bms-backend/internal/parking"
+	"bms-backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ParkingHandler struct {
+	store   *store.Store
+	parking *parking.Service
+}
+
+func NewParkingHandler(s *store.Store, parkingService *parking.Service) *ParkingHandler {
+	return &ParkingHandler{store: s, parking: parkingService}
+}
+
+// GetVehicles lists registered vehicles (?unit_id=, ?plate=, ?active=true).
+// Residents see their own unit's vehicles.
+func (h *ParkingHandler) GetVehicles(c *gin.Context) {
+	filter := store.VehicleFilter{
+		SocietyCode: c.GetString("society_code"),
+		ActiveOnly:  c.Query("active") == "true",
+	}
+	if c.GetString("user_role") == "resident" {
+		unitID, ok := residentUnit(c, h.store)
+		if !ok {
+			return
+		}
+		filter.UnitID = unitID
+	} else if raw := c.Query("unit_id"); raw != "" {
+		unitID, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unit ID"})
+			return
+		}
+		filter.UnitID = unitID
+	}
+	if raw := c.Query("plate"); raw != "" {
+		plate, err := parking.NormalizePlate(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "plate is not a valid licence plate"})
+			return
+		}
+		filter.Plate = plate
+	}
+
+	vehicles, err := h.store.Vehicles.List(context.Background(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch vehicles"})
+		return
+	}
+
+	if vehicles == nil {
+		vehicles = []models.Vehicle{}
+	}
+
+	c.JSON(http.StatusOK, vehicles)
+}
+
+// RegisterVehicle adds a vehicle to a unit: residents register their own
+// unit's vehicles, secretaries any unit's
+func (h *ParkingHandler) RegisterVehicle(c *gin.Context) {
+	var req models.VehicleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	societyCode := c.GetString("society_code")
+	unitID := req.UnitID
+	if c.GetString("user_role") == "resident" {
+		own, ok := residentUnit(c, h.store)
+		if !ok {
+			return
+		}
+		if !unitID.IsZero() && unitID != own {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Residents can only register vehicles for their own unit"})
+			return
+		}
+		unitID = own
+	} else if unitID.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unit_id is required"})
+		return
+	}
+
+	society, err := h.store.Societies.GetByCode(context.Background(), societyCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Society not found"})
+		return
+	}
+	unit, err := h.store.Units.GetByID(context.Background(), societyCode, unitID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unit not found in your society"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return
+	}
+
+	createdBy, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	vehicle := models.Vehicle{
+		Number:    req.Number,
+		Type:      req.Type,
+		Make:      req.Make,
+		Model:     req.Model,
+		Color:     req.Color,
+		SlotID:    req.SlotID,
+		CreatedBy: createdBy,
+		SocietyID: society.ID,
+	}
+	if err := h.parking.RegisterVehicle(context.Background(), &vehicle, unit, time.Now()); err != nil {
+		writeParkingError(c, err, "Failed to register vehicle")
+		return
+	}
+
+	c.JSON(http.StatusCreated, vehicle)
+}
+
+func (h *ParkingHandler) GetVehicleByID(c *gin.Context) {
+	vehicle, ok := h.findVehicle(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, vehicle)
+}
+
+// UpdateVehicle changes a vehicle's details or the unit slot it parks in
+func (h *ParkingHandler) UpdateVehicle(c *gin.Context) {
+	var req models.UpdateVehicleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	vehicle, ok := h.findVehicle(c)
+	if !ok {
+		return
+	}
+
+	update := store.VehicleUpdate{
+		Make:      req.Make,
+		Model:     req.Model,
+		Color:     req.Color,
+		ClearSlot: req.ClearSlot,
+		UpdatedAt: time.Now(),
+	}
+	if req.SlotID != nil && !req.ClearSlot {
+		slot, err := h.parking.UnitSlot(context.Background(), vehicle.SocietyCode, *req.SlotID, vehicle.UnitID)
+		if err != nil {
+			writeParkingError(c, err, "Failed to update vehicle")
+			return
+		}
+		update.SlotID = &slot.ID
+		update.SlotLabel = &slot.Label
+	}
+
+	if err := h.store.Vehicles.Update(context.Background(), vehicle.SocietyCode, vehicle.ID, update); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update vehicle"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Vehicle updated successfully"})
+}
+
+// RemoveVehicle deregisters a vehicle; its gate history is kept
+func (h *ParkingHandler) RemoveVehicle(c *gin.Context) {
+	vehicle, ok := h.findVehicle(c)
+	if !ok {
+		return
+	}
+
+	inactive := false
+	err := h.store.Vehicles.Update(context.Background(), vehicle.SocietyCode, vehicle.ID, store.VehicleUpdate{
+		IsActive:  &inactive,
+		ClearSlot: true,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove vehicle"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Vehicle removed successfully"})
+}
+
+// GetParkingSlots lists the society's slots (?building_id=, ?kind=,
+// ?vehicle_type=, ?free=true). Residents see their own unit's slots.
+func (h *ParkingHandler) GetParkingSlots(c *gin.Context) {
+	filter := store.ParkingSlotFilter{
+		SocietyCode: c.GetString("society_code"),
+		Kind:        c.Query("kind"),
+		VehicleType: c.Query("vehicle_type"),
+		FreeOnly:    c.Query("free") == "true",
+	}
+	if c.GetString("user_role") == "resident" {
+		unitID, ok := residentUnit(c, h.store)
+		if !ok {
+			return
+		}
+		filter.UnitID = unitID
+	}
+	if raw := c.Query("building_id"); raw != "" {
+		buildingID, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid building ID"})
+			return
+		}
+		filter.BuildingID = buildingID
+	}
+
+	slots, err := h.store.ParkingSlots.List(context.Background(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch parking slots"})
+		return
+	}
+
+	if slots == nil {
+		slots = []models.ParkingSlot{}
+	}
+
+	c.JSON(http.StatusOK, slots)
+}
+
+// CreateParkingSlot adds a slot to one of the society's buildings.
+// Assigned slots may be given to a unit straight away.
+func (h *ParkingHandler) CreateParkingSlot(c *gin.Context) {
+	var req models.ParkingSlotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Kind == parking.SlotVisitor && req.UnitID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Visitor slots cannot be assigned to a unit"})
+		return
+	}
+
+	societyCode := c.GetString("society_code")
+	society, err := h.store.Societies.GetByCode(context.Background(), societyCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Society not found"})
+		return
+	}
+	var building *models.Building
+	for i := range society.Buildings {
+		if society.Buildings[i].ID == req.BuildingID {
+			building = &society.Buildings[i]
+		}
+	}
+	if building == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Building not found in your society"})
+		return
+	}
+
+	now := time.Now()
+	slot := models.ParkingSlot{
+		BuildingID:   building.ID,
+		BuildingName: building.Name,
+		Label:        strings.ToUpper(strings.TrimSpace(req.Label)),
+		Kind:         req.Kind,
+		VehicleType:  req.VehicleType,
+		IsActive:     true,
+		SocietyID:    society.ID,
+		SocietyCode:  societyCode,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if req.UnitID != nil {
+		unit, ok := h.findUnit(c, *req.UnitID)
+		if !ok {
+			return
+		}
+		slot.UnitID = &unit.ID
+		slot.UnitNumber = unit.Number
+	}
+
+	if err := h.store.ParkingSlots.Create(context.Background(), &slot); err != nil {
+		if errors.Is(err, store.ErrDuplicate) {
+			c.JSON(http.StatusConflict, gin.H{"error": "The building already has a slot " + slot.Label})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create parking slot"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, slot)
+}
+
+// UpdateParkingSlot relabels a slot, takes it in or out of use, or moves an
+// assigned slot to another unit. Vehicles parked in a slot its unit loses
+// are unassigned from it.
+func (h *ParkingHandler) UpdateParkingSlot(c *gin.Context) {
+	var req models.UpdateParkingSlotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parking slot ID"})
+		return
+	}
+	societyCode := c.GetString("society_code")
+	slot, err := h.store.ParkingSlots.GetByID(context.Background(), societyCode, objID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Parking slot not found in your society"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return
+	}
+
+	update := store.ParkingSlotUpdate{
+		VehicleType: req.VehicleType,
+		IsActive:    req.IsActive,
+		ClearUnit:   req.ClearUnit,
+		UpdatedAt:   time.Now(),
+	}
+	if req.Label != nil {
+		label := strings.ToUpper(strings.TrimSpace(*req.Label))
+		taken, err := h.store.ParkingSlots.List(context.Background(), store.ParkingSlotFilter{
+			SocietyCode: societyCode,
+			BuildingID:  slot.BuildingID,
+			Label:       label,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		if len(taken) > 0 && taken[0].ID != slot.ID {
+			c.JSON(http.StatusConflict, gin.H{"error": "The building already has a slot " + label})
+			return
+		}
+		update.Label = &label
+	}
+	if req.UnitID != nil && !req.ClearUnit {
+		if slot.Kind != parking.SlotAssigned {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Visitor slots cannot be assigned to a unit"})
+			return
+		}
+		unit, ok := h.findUnit(c, *req.UnitID)
+		if !ok {
+			return
+		}
+		update.UnitID = &unit.ID
+		update.UnitNumber = &unit.Number
+	}
+
+	if err := h.store.ParkingSlots.Update(context.Background(), societyCode, slot.ID, update); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update parking slot"})
+		return
+	}
+
+	// The old unit's vehicles no longer park here
+	if slot.UnitID != nil && (req.ClearUnit || (update.UnitID != nil && *update.UnitID != *slot.UnitID)) {
+		parked, err := h.store.Vehicles.List(context.Background(), store.VehicleFilter{SocietyCode: societyCode, SlotID: slot.ID})
+		if err != nil {
+			log.Printf("⚠️ Failed to load vehicles in slot %s: %v", slot.Label, err)
+		}
+		for _, vehicle := range parked {
+			err := h.store.Vehicles.Update(context.Background(), societyCode, vehicle.ID, store.VehicleUpdate{ClearSlot: true, UpdatedAt: update.UpdatedAt})
+			if err != nil {
+				log.Printf("⚠️ Failed to unassign slot %s from vehicle %s: %v", slot.Label, vehicle.Plate, err)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Parking slot updated successfully"})
+}
+
+// GetOverstays lists visitor vehicles still parked past their limit
+func (h *ParkingHandler) GetOverstays(c *gin.Context) {
+	slots, err := h.store.ParkingSlots.List(context.Background(), store.ParkingSlotFilter{
+		SocietyCode: c.GetString("society_code"),
+		Kind:        parking.SlotVisitor,
+		OverstayBy:  time.Now(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch overstays"})
+		return
+	}
+
+	if slots == nil {
+		slots = []models.ParkingSlot{}
+	}
+
+	c.JSON(http.StatusOK, slots)
+}
+
+// VehicleEntry logs a vehicle in at the gate
+func (h *ParkingHandler) VehicleEntry(c *gin.Context) {
+	var req models.GateVehicleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	guard, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	entry, err := h.parking.Enter(context.Background(), c.GetString("society_code"), req.VehicleNumber, guard, time.Now())
+	if err != nil {
+		writeParkingError(c, err, "Failed to log vehicle entry")
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// VehicleExit logs a vehicle out at the gate
+func (h *ParkingHandler) VehicleExit(c *gin.Context) {
+	var req models.GateVehicleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	guard, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	entry, err := h.parking.Exit(context.Background(), c.GetString("society_code"), req.VehicleNumber, guard, time.Now())
+	if err != nil {
+		writeParkingError(c, err, "Failed to log vehicle exit")
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// GetVehicleLog is the gate's vehicle register (?plate=, ?kind=,
+// ?inside=true, ?from= and ?to= as YYYY-MM-DD, inclusive)
+func (h *ParkingHandler) GetVehicleLog(c *gin.Context) {
+	filter := store.VehicleLogFilter{
+		SocietyCode: c.GetString("society_code"),
+		Kind:        c.Query("kind"),
+		InsideOnly:  c.Query("inside") == "true",
+	}
+	if raw := c.Query("plate"); raw != "" {
+		plate, err := parking.NormalizePlate(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "plate is not a valid licence plate"})
+			return
+		}
+		filter.Plate = plate
+	}
+
+	loc, _ := time.LoadLocation(amenities.DefaultTimezone)
+	if raw := c.Query("from"); raw != "" {
+		from, err := time.ParseInLocation(amenities.DateLayout, raw, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be YYYY-MM-DD"})
+			return
+		}
+		filter.From = from
+	}
+	if raw := c.Query("to"); raw != "" {
+		to, err := time.ParseInLocation(amenities.DateLayout, raw, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be YYYY-MM-DD"})
+			return
+		}
+		filter.To = to.AddDate(0, 0, 1)
+	}
+
+	entries, err := h.store.VehicleLogs.List(context.Background(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch vehicle log"})
+		return
+	}
+
+	if entries == nil {
+		entries = []models.VehicleLog{}
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// findVehicle loads the :id vehicle of the caller's society, writing the
+// error response itself. Residents only get their own unit's vehicles.
+func (h *ParkingHandler) findVehicle(c *gin.Context) (*models.Vehicle, bool) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vehicle ID"})
+		return nil, false
+	}
+
+	vehicle, err := h.store.Vehicles.GetByID(context.Background(), c.GetString("society_code"), objID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle not found in your society"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return nil, false
+	}
+	if c.GetString("user_role") == "resident" {
+		unitID, ok := residentUnit(c, h.store)
+		if !ok {
+			return nil, false
+		}
+		if vehicle.UnitID != unitID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not your unit's vehicle"})
+			return nil, false
+		}
+	}
+	return vehicle, true
+}
+
+// findUnit loads a unit of the caller's society, writing the error
+// response itself
+func (h *ParkingHandler) findUnit(c *gin.Context, unitID primitive.ObjectID) (*models.Unit, bool) {
+	unit, err := h.store.Units.GetByID(context.Background(), c.GetString("society_code"), unitID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unit not found in your society"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return nil, false
+	}
+	return unit, true
+}
+
+// writeParkingError answers a failed vehicle or parking change
+func writeParkingError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, parking.ErrInvalidPlate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Not a valid Indian licence plate"})
+	case errors.Is(err, parking.ErrPlateTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": "A vehicle with this plate is already registered"})
+	case errors.Is(err, parking.ErrNotUnitSlot):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "slot_id must be one of the unit's assigned slots"})
+	case errors.Is(err, parking.ErrInside):
+		c.JSON(http.StatusConflict, gin.H{"error": "Vehicle is already inside"})
+	case errors.Is(err, parking.ErrNotInside):
+		c.JSON(http.StatusConflict, gin.H{"error": "Vehicle is not inside"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
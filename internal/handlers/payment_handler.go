@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/payments"
+	"bms-backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PaymentHandler struct {
+	store    *store.Store
+	payments *payments.Service
+}
+
+func NewPaymentHandler(s *store.Store, paymentService *payments.Service) *PaymentHandler {
+	return &PaymentHandler{store: s, payments: paymentService}
+}
+
+func (h *PaymentHandler) GetPayments(c *gin.Context) {
+	filter := store.PaymentFilter{SocietyCode: c.GetString("society_code")}
+
+	if c.GetString("user_role") == "resident" {
+		// Residents can only see their own payments
+		userID, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+		filter.UserID = userID
+	}
+
+	if referenceID := c.Query("reference_id"); referenceID != "" {
+		objID, err := primitive.ObjectIDFromHex(referenceID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reference ID"})
+			return
+		}
+		filter.ReferenceID = objID
+	}
+
+	list, err := h.store.Payments.List(context.Background(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payments"})
+		return
+	}
+
+	if list == nil {
+		list = []models.Payment{}
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *PaymentHandler) GetPaymentByID(c *gin.Context) {
+	payment, ok := h.findPayment(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, payment)
+}
+
+// Webhook receives provider callbacks. It is public; authenticity comes
+// from the provider's signature.
+func (h *PaymentHandler) Webhook(c *gin.Context) {
+	if c.Param("provider") != h.payments.Provider().Name() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown payment provider"})
+		return
+	}
+
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read webhook body"})
+		return
+	}
+
+	payment, err := h.payments.HandleWebhook(context.Background(), payload, c.Request.Header)
+	if err != nil {
+		h.webhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"payment_id": payment.ID, "status": payment.Status})
+}
+
+// SimulatePayment plays the provider's part for offline providers, sending
+// the outcome through the same signed webhook path as a real gateway.
+func (h *PaymentHandler) SimulatePayment(c *gin.Context) {
+	simulator, ok := h.payments.Provider().(payments.Simulator)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": payments.ErrNotSimulated.Error()})
+		return
+	}
+
+	var req models.SimulatePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	payment, ok := h.findPayment(c)
+	if !ok {
+		return
+	}
+
+	payload, header, err := simulator.Simulate(payment.ProviderRef, req.Outcome)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to simulate payment"})
+		return
+	}
+
+	payment, err = h.payments.HandleWebhook(context.Background(), payload, header)
+	if err != nil {
+		h.webhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, payment)
+}
+
+// findPayment loads the payment named in the URL, keeping residents to
+// their own payments. It writes the error response itself on failure.
+func (h *PaymentHandler) findPayment(c *gin.Context) (*models.Payment, bool) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return nil, false
+	}
+
+	payment, err := h.store.Payments.GetByID(context.Background(), c.GetString("society_code"), objID)
+	if err == nil && c.GetString("user_role") == "resident" && payment.UserID.Hex() != c.GetString("user_id") {
+		err = store.ErrNotFound
+	}
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return nil, false
+	}
+	return payment, true
+}
+
+func (h *PaymentHandler) webhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, payments.ErrInvalidSignature):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, payments.ErrMalformedWebhook):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+	default:
+		log.Printf("⚠️ Payment webhook failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process webhook"})
+	}
+}
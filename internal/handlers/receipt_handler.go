@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/receipts"
+	"bms-backend/internal/storage"
+	"bms-backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ReceiptHandler struct {
+	store     *store.Store
+	documents *receipts.Service
+}
+
+func NewReceiptHandler(s *store.Store, documents *receipts.Service) *ReceiptHandler {
+	return &ReceiptHandler{store: s, documents: documents}
+}
+
+// GetReceipts lists invoices and receipts, optionally for one kind,
+// maintenance record or payment
+func (h *ReceiptHandler) GetReceipts(c *gin.Context) {
+	filter := store.DocumentFilter{
+		SocietyCode: c.GetString("society_code"),
+		Kind:        c.Query("kind"),
+	}
+
+	for param, target := range map[string]*primitive.ObjectID{
+		"maintenance_id": &filter.MaintenanceID,
+		"payment_id":     &filter.PaymentID,
+	} {
+		if value := c.Query(param); value != "" {
+			objID, err := primitive.ObjectIDFromHex(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param})
+				return
+			}
+			*target = objID
+		}
+	}
+
+	var docs []models.BillingDocument
+	var err error
+	if c.GetString("user_role") == "resident" {
+		docs, err = h.residentDocuments(context.Background(), c, filter)
+	} else {
+		docs, err = h.store.Documents.List(context.Background(), filter)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch receipts"})
+		return
+	}
+
+	if docs == nil {
+		docs = []models.BillingDocument{}
+	}
+
+	c.JSON(http.StatusOK, docs)
+}
+
+// DownloadReceipt streams the PDF of an invoice or receipt
+func (h *ReceiptHandler) DownloadReceipt(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid receipt ID"})
+		return
+	}
+
+	doc, err := h.store.Documents.GetByID(context.Background(), c.GetString("society_code"), objID)
+	if err == nil && c.GetString("user_role") == "resident" && !h.residentCanView(c, doc) {
+		err = store.ErrNotFound
+	}
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Receipt not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return
+	}
+
+	file, err := h.documents.Open(context.Background(), doc)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Receipt file not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read receipt"})
+		}
+		return
+	}
+	defer file.Close()
+
+	c.DataFromReader(http.StatusOK, -1, "application/pdf", file, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`inline; filename="%s.pdf"`, doc.Number),
+	})
+}
+
+// residentDocuments returns the documents of the resident's unit together
+// with receipts for payments they made themselves
+func (h *ReceiptHandler) residentDocuments(ctx context.Context, c *gin.Context, filter store.DocumentFilter) ([]models.BillingDocument, error) {
+	userID, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	byUser := filter
+	byUser.UserID = userID
+	docs, err := h.store.Documents.List(ctx, byUser)
+	if err != nil {
+		return nil, err
+	}
+
+	unitID := currentUnitID(ctx, h.store, c)
+	if unitID.IsZero() {
+		return docs, nil
+	}
+
+	byUnit := filter
+	byUnit.UnitID = unitID
+	unitDocs, err := h.store.Documents.List(ctx, byUnit)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[primitive.ObjectID]bool, len(docs))
+	for _, doc := range docs {
+		seen[doc.ID] = true
+	}
+	for _, doc := range unitDocs {
+		if !seen[doc.ID] {
+			docs = append(docs, doc)
+		}
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].IssuedAt.After(docs[j].IssuedAt) })
+	return docs, nil
+}
+
+func (h *ReceiptHandler) residentCanView(c *gin.Context, doc *models.BillingDocument) bool {
+	if doc.UserID != nil && doc.UserID.Hex() == c.GetString("user_id") {
+		return true
+	}
+	unitID := currentUnitID(context.Background(), h.store, c)
+	return doc.UnitID != nil && !unitID.IsZero() && *doc.UnitID == unitID
+}
@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/store"
+	"bms-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxCodeAttempts bounds how often a generated society code is retried
+// after colliding with the unique index on "code".
+const maxCodeAttempts = 10
+
+var (
+	societyCodePattern  = regexp.MustCompile(`^[A-Z0-9]{4,12}$`)
+	buildingCodePattern = regexp.MustCompile(`^[A-Z0-9]{1,6}$`)
+)
+
+type SocietyHandler struct {
+	store *store.Store
+}
+
+func NewSocietyHandler(s *store.Store) *SocietyHandler {
+	return &SocietyHandler{store: s}
+}
+
+func (h *SocietyHandler) GetSocieties(c *gin.Context) {
+	filter := store.SocietyFilter{ActiveOnly: c.Query("active") == "true"}
+
+	societies, err := h.store.Societies.List(context.Background(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch societies"})
+		return
+	}
+
+	if societies == nil {
+		societies = []models.Society{}
+	}
+
+	c.JSON(http.StatusOK, societies)
+}
+
+func (h *SocietyHandler) GetSocietyByID(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid society ID"})
+		return
+	}
+
+	society, err := h.store.Societies.GetByID(context.Background(), objID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Society not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, society)
+}
+
+func (h *SocietyHandler) CreateSociety(c *gin.Context) {
+	var req models.CreateSocietyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	if code != "" && !societyCodePattern.MatchString(code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Society code must be 4-12 letters or digits"})
+		return
+	}
+
+	now := time.Now()
+	society := models.Society{
+		ID:           primitive.NewObjectID(),
+		Name:         req.Name,
+		Address:      req.Address,
+		City:         req.City,
+		State:        req.State,
+		PinCode:      req.PinCode,
+		ContactEmail: req.ContactEmail,
+		ContactPhone: req.ContactPhone,
+		Buildings:    []models.Building{},
+		IsActive:     true,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	for _, b := range req.Buildings {
+		buildingCode, ok := newBuildingCode(c, b.Code, b.Name, society.Buildings)
+		if !ok {
+			return
+		}
+		society.Buildings = append(society.Buildings, models.Building{
+			ID:            primitive.NewObjectID(),
+			Name:          b.Name,
+			Code:          buildingCode,
+			Floors:        b.Floors,
+			UnitsPerFloor: b.UnitsPerFloor,
+			SocietyID:     society.ID,
+		})
+	}
+
+	// A requested code gets exactly one attempt; a generated one is retried
+	// until the unique index accepts it.
+	attempts := 1
+	if code == "" {
+		attempts = maxCodeAttempts
+	}
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		society.Code = code
+		if society.Code == "" {
+			society.Code = utils.GenerateSocietyCode(req.Name)
+		}
+		err = h.store.Societies.Create(context.Background(), &society)
+		if !errors.Is(err, store.ErrDuplicate) {
+			break
+		}
+	}
+
+	if err != nil {
+		if errors.Is(err, store.ErrDuplicate) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Society code already in use"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create society"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, society)
+}
+
+func (h *SocietyHandler) UpdateSociety(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid society ID"})
+		return
+	}
+
+	var req models.UpdateSocietyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Name != nil && strings.TrimSpace(*req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Society name cannot be empty"})
+		return
+	}
+
+	err = h.store.Societies.Update(context.Background(), objID, store.SocietyUpdate{
+		Name:         req.Name,
+		Address:      req.Address,
+		City:         req.City,
+		State:        req.State,
+		PinCode:      req.PinCode,
+		ContactEmail: req.ContactEmail,
+		ContactPhone: req.ContactPhone,
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Society not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update society"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Society updated successfully"})
+}
+
+func (h *SocietyHandler) SetSocietyStatus(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid society ID"})
+		return
+	}
+
+	var req models.SocietyStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = h.store.Societies.Update(context.Background(), objID, store.SocietyUpdate{
+		IsActive: req.IsActive,
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Society not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update society status"})
+		}
+		return
+	}
+
+	status := "deactivated"
+	if *req.IsActive {
+		status = "activated"
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Society " + status + " successfully"})
+}
+
+func (h *SocietyHandler) AddBuilding(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid society ID"})
+		return
+	}
+
+	var req models.BuildingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	society, err := h.store.Societies.GetByID(context.Background(), objID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Society not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return
+	}
+	code, ok := newBuildingCode(c, req.Code, req.Name, society.Buildings)
+	if !ok {
+		return
+	}
+
+	building := models.Building{
+		ID:            primitive.NewObjectID(),
+		Name:          req.Name,
+		Code:          code,
+		Floors:        req.Floors,
+		UnitsPerFloor: req.UnitsPerFloor,
+		SocietyID:     objID,
+	}
+
+	err = h.store.Societies.AddBuilding(context.Background(), objID, building)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Society not found"})
+		} else if errors.Is(err, store.ErrDuplicate) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Another building already uses code " + code})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add building"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, building)
+}
+
+func (h *SocietyHandler) RemoveBuilding(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid society ID"})
+		return
+	}
+
+	buildingID, err := primitive.ObjectIDFromHex(c.Param("buildingId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid building ID"})
+		return
+	}
+
+	err = h.store.Societies.RemoveBuilding(context.Background(), objID, buildingID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Building not found in society"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove building"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Building removed successfully"})
+}
+
+// newBuildingCode returns the code for a new building: the requested one,
+// or one derived from its name. Unit numbers start with the code, so it
+// must differ from the codes of the society's other buildings. It writes
+// the error response itself.
+func newBuildingCode(c *gin.Context, requested, name string, existing []models.Building) (string, bool) {
+	code := strings.ToUpper(strings.TrimSpace(requested))
+	if code == "" {
+		code = utils.BuildingCode(name)
+	}
+	if !buildingCodePattern.MatchString(code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Building code must be 1-6 letters or digits"})
+		return "", false
+	}
+	for _, b := range existing {
+		if buildingCode(b) == code {
+			c.JSON(http.StatusConflict, gin.H{"error": "Building " + b.Name + " already uses code " + code + "; give " + name + " its own code"})
+			return "", false
+		}
+	}
+	return code, true
+}
+
+// buildingCode is the prefix of the building's unit numbers. Buildings
+// added before codes were stored use the code derived from their name.
+func buildingCode(building models.Building) string {
+	if building.Code != "" {
+		return building.Code
+	}
+	return utils.BuildingCode(building.Name)
+}
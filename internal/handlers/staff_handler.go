@@ -0,0 +1,640 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"bms-backend/internal/amenities"
+	"bms-backend/internal/models"
+	"bms-backend/internal/passes"
+	"bms-backend/internal/staff"
+	"bms-backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type StaffHandler struct {
+	store  *store.Store
+	passes *passes.Signer
+	staff  *staff.Service
+}
+
+func NewStaffHandler(s *store.Store, signer *passes.Signer, staffService *staff.Service) *StaffHandler {
+	return &StaffHandler{store: s, passes: signer, staff: staffService}
+}
+
+// GetStaff lists the society's staff (?category=, ?active=true). Residents
+// see the staff serving their unit, or look up a staff member by ?phone= to
+// add their unit to an existing entry.
+func (h *StaffHandler) GetStaff(c *gin.Context) {
+	filter := store.StaffFilter{
+		SocietyCode: c.GetString("society_code"),
+		Category:    c.Query("category"),
+		Phone:       c.Query("phone"),
+		ActiveOnly:  c.Query("active") == "true",
+	}
+	if c.GetString("user_role") == "resident" && filter.Phone == "" {
+		unitID, ok := residentUnit(c, h.store)
+		if !ok {
+			return
+		}
+		filter.UnitID = unitID
+	}
+
+	members, err := h.store.Staff.List(context.Background(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch staff"})
+		return
+	}
+
+	if members == nil {
+		members = []models.Staff{}
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// CreateStaff registers a staff member and issues their pass. Residents
+// register staff for their own unit; secretaries for any units.
+func (h *StaffHandler) CreateStaff(c *gin.Context) {
+	var req models.StaffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	societyCode := c.GetString("society_code")
+	society, err := h.store.Societies.GetByCode(context.Background(), societyCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Society not found"})
+		return
+	}
+
+	if c.GetString("user_role") == "resident" {
+		unitID, ok := residentUnit(c, h.store)
+		if !ok {
+			return
+		}
+		req.UnitIDs = []primitive.ObjectID{unitID}
+	}
+	if len(req.UnitIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unit_ids is required"})
+		return
+	}
+	if req.Timezone == "" {
+		req.Timezone = amenities.DefaultTimezone
+	}
+	if err := staff.ValidateHours(req.Timezone, req.Hours); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member := models.Staff{
+		ID:          primitive.NewObjectID(),
+		Name:        req.Name,
+		Phone:       req.Phone,
+		Category:    req.Category,
+		Hours:       req.Hours,
+		Timezone:    req.Timezone,
+		PhotoURL:    req.PhotoURL,
+		IDDocument:  req.IDDocument,
+		IsActive:    true,
+		SocietyID:   society.ID,
+		SocietyCode: societyCode,
+	}
+	for _, unitID := range req.UnitIDs {
+		if containsUnit(member.UnitIDs, unitID) {
+			continue
+		}
+		unit, err := h.store.Units.GetByID(context.Background(), societyCode, unitID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unit not found in your society", "unit_id": unitID})
+			return
+		}
+		member.UnitIDs = append(member.UnitIDs, unit.ID)
+		member.Units = append(member.Units, unit.Number)
+	}
+	member.CreatedBy, _ = primitive.ObjectIDFromHex(c.GetString("user_id"))
+
+	now := time.Now()
+	member.CreatedAt = now
+	member.UpdatedAt = now
+	if err := h.staff.IssuePass(&member, now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue staff pass"})
+		return
+	}
+
+	if err := h.store.Staff.Create(context.Background(), &member); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register staff"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, member)
+}
+
+func (h *StaffHandler) GetStaffByID(c *gin.Context) {
+	member, ok := h.findStaff(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// UpdateStaff changes a staff member's details, hours or active flag.
+// Residents can update the staff serving their unit.
+func (h *StaffHandler) UpdateStaff(c *gin.Context) {
+	var req models.UpdateStaffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, ok := h.findStaff(c)
+	if !ok {
+		return
+	}
+
+	timezone, hours := member.Timezone, member.Hours
+	if req.Timezone != nil {
+		timezone = *req.Timezone
+	}
+	if req.Hours != nil {
+		hours = *req.Hours
+	}
+	if err := staff.ValidateHours(timezone, hours); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	update := store.StaffUpdate{
+		Name:       req.Name,
+		Phone:      req.Phone,
+		Hours:      req.Hours,
+		Timezone:   req.Timezone,
+		PhotoURL:   req.PhotoURL,
+		IDDocument: req.IDDocument,
+		IsActive:   req.IsActive,
+		UpdatedAt:  time.Now(),
+	}
+	if err := h.store.Staff.Update(context.Background(), member.SocietyCode, member.ID, update); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update staff"})
+		return
+	}
+
+	updated, err := h.store.Staff.GetByID(context.Background(), member.SocietyCode, member.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// AddUnit adds a unit to the ones a staff member serves. Residents add
+// their own unit to a staff member another unit registered.
+func (h *StaffHandler) AddUnit(c *gin.Context) {
+	var req models.StaffUnitRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	member, ok := h.loadStaff(c)
+	if !ok {
+		return
+	}
+
+	if c.GetString("user_role") == "resident" {
+		unitID, ok := residentUnit(c, h.store)
+		if !ok {
+			return
+		}
+		req.UnitID = unitID
+	}
+	if req.UnitID.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unit_id is required"})
+		return
+	}
+	if containsUnit(member.UnitIDs, req.UnitID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Staff member already serves this unit"})
+		return
+	}
+	unit, err := h.store.Units.GetByID(context.Background(), member.SocietyCode, req.UnitID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unit not found in your society"})
+		return
+	}
+
+	unitIDs := append(append([]primitive.ObjectID{}, member.UnitIDs...), unit.ID)
+	units := append(append([]string{}, member.Units...), unit.Number)
+	if !h.setUnits(c, member, unitIDs, units) {
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// RemoveUnit stops a staff member serving a unit. Residents can only
+// remove their own unit. Staff left without units are deactivated.
+func (h *StaffHandler) RemoveUnit(c *gin.Context) {
+	unitID, err := primitive.ObjectIDFromHex(c.Param("unitId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unit ID"})
+		return
+	}
+
+	member, ok := h.findStaff(c)
+	if !ok {
+		return
+	}
+	if c.GetString("user_role") == "resident" {
+		own, ok := residentUnit(c, h.store)
+		if !ok {
+			return
+		}
+		if own != unitID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only remove your own unit"})
+			return
+		}
+	}
+	if !containsUnit(member.UnitIDs, unitID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Staff member does not serve this unit"})
+		return
+	}
+
+	unitIDs := []primitive.ObjectID{}
+	units := []string{}
+	for i, id := range member.UnitIDs {
+		if id == unitID {
+			continue
+		}
+		unitIDs = append(unitIDs, id)
+		if i < len(member.Units) {
+			units = append(units, member.Units[i])
+		}
+	}
+	if !h.setUnits(c, member, unitIDs, units) {
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// setUnits saves the staff member's units, writing the error response
+// itself
+func (h *StaffHandler) setUnits(c *gin.Context, member *models.Staff, unitIDs []primitive.ObjectID, units []string) bool {
+	update := store.StaffUpdate{UnitIDs: &unitIDs, Units: &units, UpdatedAt: time.Now()}
+	if len(unitIDs) == 0 {
+		inactive := false
+		update.IsActive = &inactive
+		member.IsActive = false
+	}
+	if err := h.store.Staff.Update(context.Background(), member.SocietyCode, member.ID, update); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update staff units"})
+		return false
+	}
+	member.UnitIDs, member.Units, member.UpdatedAt = unitIDs, units, update.UpdatedAt
+	return true
+}
+
+// ReissuePass replaces a staff member's pass, for a lost card or when the
+// old one is about to run out
+func (h *StaffHandler) ReissuePass(c *gin.Context) {
+	member, ok := h.findStaff(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	if err := h.staff.IssuePass(member, now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue staff pass"})
+		return
+	}
+	err := h.store.Staff.Update(context.Background(), member.SocietyCode, member.ID, store.StaffUpdate{
+		PassToken:     &member.PassToken,
+		PassExpiresAt: &member.PassExpiresAt,
+		UpdatedAt:     now,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reissue staff pass"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pass_token": member.PassToken, "pass_expires_at": member.PassExpiresAt})
+}
+
+// GetPassPNG renders the staff member's pass as a QR code PNG (?size= in
+// pixels, 128-1024)
+func (h *StaffHandler) GetPassPNG(c *gin.Context) {
+	member, ok := h.findStaff(c)
+	if !ok {
+		return
+	}
+
+	size := passes.DefaultQRSize
+	if raw := c.Query("size"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < passes.MinQRSize || n > passes.MaxQRSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "size must be between 128 and 1024"})
+			return
+		}
+		size = n
+	}
+
+	image, err := passes.PNG(member.PassToken, size)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render staff pass"})
+		return
+	}
+	c.Data(http.StatusOK, "image/png", image)
+}
+
+// GetByPass is the guard's lookup of a scanned staff pass: who it is,
+// whether they may enter now and whether they are already inside
+func (h *StaffHandler) GetByPass(c *gin.Context) {
+	member, ok := h.findByPass(c)
+	if !ok {
+		return
+	}
+
+	inside, err := h.store.Attendance.List(context.Background(), store.AttendanceFilter{
+		SocietyCode: member.SocietyCode,
+		StaffID:     member.ID,
+		InsideOnly:  true,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"staff":      member,
+		"allowed":    member.IsActive && staff.AllowedAt(member, time.Now()),
+		"inside":     len(inside) > 0,
+		"checked_in": checkedInAt(inside),
+	})
+}
+
+func (h *StaffHandler) CheckInByPass(c *gin.Context) {
+	member, ok := h.findByPass(c)
+	if !ok {
+		return
+	}
+	h.checkIn(c, member)
+}
+
+func (h *StaffHandler) CheckOutByPass(c *gin.Context) {
+	member, ok := h.findByPass(c)
+	if !ok {
+		return
+	}
+	h.checkOut(c, member)
+}
+
+// CheckIn lets a staff member in by ID, for when they don't have their pass
+func (h *StaffHandler) CheckIn(c *gin.Context) {
+	member, ok := h.findStaff(c)
+	if !ok {
+		return
+	}
+	h.checkIn(c, member)
+}
+
+func (h *StaffHandler) CheckOut(c *gin.Context) {
+	member, ok := h.findStaff(c)
+	if !ok {
+		return
+	}
+	h.checkOut(c, member)
+}
+
+func (h *StaffHandler) checkIn(c *gin.Context, member *models.Staff) {
+	guard, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	entry, err := h.staff.CheckIn(context.Background(), member, guard, time.Now())
+	switch {
+	case errors.Is(err, staff.ErrInactive):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Staff member is not active"})
+		return
+	case errors.Is(err, staff.ErrOutsideHours):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Outside the staff member's allowed hours", "hours": member.Hours, "timezone": member.Timezone})
+		return
+	case errors.Is(err, staff.ErrInside):
+		c.JSON(http.StatusConflict, gin.H{"error": "Staff member is already inside"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check in staff"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Staff checked in successfully", "attendance": entry})
+}
+
+func (h *StaffHandler) checkOut(c *gin.Context, member *models.Staff) {
+	guard, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	entry, err := h.staff.CheckOut(context.Background(), member, guard, time.Now())
+	if errors.Is(err, staff.ErrNotInside) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Staff member is not inside"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check out staff"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Staff checked out successfully", "attendance": entry})
+}
+
+// GetInside lists the staff currently inside the society
+func (h *StaffHandler) GetInside(c *gin.Context) {
+	entries, err := h.store.Attendance.List(context.Background(), store.AttendanceFilter{
+		SocietyCode: c.GetString("society_code"),
+		InsideOnly:  true,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch attendance"})
+		return
+	}
+
+	if entries == nil {
+		entries = []models.StaffAttendance{}
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// GetAttendance is a staff member's monthly attendance (?month=YYYY-MM,
+// default this month): each day present with its entries and minutes
+func (h *StaffHandler) GetAttendance(c *gin.Context) {
+	member, ok := h.findStaff(c)
+	if !ok {
+		return
+	}
+
+	month := c.DefaultQuery("month", time.Now().In(staff.Location(member)).Format(staff.MonthLayout))
+	report, err := h.staff.Report(context.Background(), member, month)
+	if err != nil {
+		if errors.Is(err, staff.ErrInvalidMonth) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "month must be YYYY-MM"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build attendance report"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetAttendanceReport is the monthly attendance of every staff member
+// serving the resident's unit, or of the whole society for secretaries
+func (h *StaffHandler) GetAttendanceReport(c *gin.Context) {
+	loc, _ := time.LoadLocation(amenities.DefaultTimezone)
+	month := c.DefaultQuery("month", time.Now().In(loc).Format(staff.MonthLayout))
+	if _, err := time.Parse(staff.MonthLayout, month); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "month must be YYYY-MM"})
+		return
+	}
+
+	filter := store.StaffFilter{SocietyCode: c.GetString("society_code")}
+	if c.GetString("user_role") == "resident" {
+		unitID, ok := residentUnit(c, h.store)
+		if !ok {
+			return
+		}
+		filter.UnitID = unitID
+	}
+	members, err := h.store.Staff.List(context.Background(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch staff"})
+		return
+	}
+
+	reports := []staff.Report{}
+	for i := range members {
+		report, err := h.staff.Report(context.Background(), &members[i], month)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build attendance report"})
+			return
+		}
+		reports = append(reports, *report)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"month": month, "staff": reports})
+}
+
+// findStaff loads the :id staff member of the caller's society, writing
+// the error response itself. Residents only get staff serving their unit.
+func (h *StaffHandler) findStaff(c *gin.Context) (*models.Staff, bool) {
+	member, ok := h.loadStaff(c)
+	if !ok {
+		return nil, false
+	}
+	if c.GetString("user_role") == "resident" {
+		unitID, ok := residentUnit(c, h.store)
+		if !ok {
+			return nil, false
+		}
+		if !containsUnit(member.UnitIDs, unitID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Staff member does not serve your unit"})
+			return nil, false
+		}
+	}
+	return member, true
+}
+
+// loadStaff loads the :id staff member of the caller's society without
+// checking who is asking
+func (h *StaffHandler) loadStaff(c *gin.Context) (*models.Staff, bool) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid staff ID"})
+		return nil, false
+	}
+
+	member, err := h.store.Staff.GetByID(context.Background(), c.GetString("society_code"), objID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Staff member not found in your society"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return nil, false
+	}
+	return member, true
+}
+
+// findByPass verifies a scanned staff pass and loads its holder, writing
+// the error response itself. Reissued passes no longer match and read as
+// invalid.
+func (h *StaffHandler) findByPass(c *gin.Context) (*models.Staff, bool) {
+	token := c.Param("token")
+	claims, err := h.passes.Verify(token, time.Now())
+	switch {
+	case errors.Is(err, passes.ErrNotYetValid):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Staff pass is not valid yet", "valid_from": claims.NotBefore})
+		return nil, false
+	case errors.Is(err, passes.ErrExpired):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Staff pass has expired", "expires_at": claims.ExpiresAt})
+		return nil, false
+	case err != nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invalid staff pass"})
+		return nil, false
+	}
+	if claims.Kind != passes.KindStaff || claims.SocietyCode != c.GetString("society_code") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invalid staff pass"})
+		return nil, false
+	}
+
+	member, err := h.store.Staff.GetByID(context.Background(), claims.SocietyCode, claims.SubjectID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid staff pass"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return nil, false
+	}
+	if member.PassToken != token {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invalid staff pass"})
+		return nil, false
+	}
+	return member, true
+}
+
+// residentUnit is the logged-in resident's unit, writing the error
+// response itself when they have none
+func residentUnit(c *gin.Context, s *store.Store) (primitive.ObjectID, bool) {
+	userID, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	user, err := s.Users.GetByID(context.Background(), c.GetString("society_code"), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return primitive.NilObjectID, false
+	}
+	if user.UnitID == nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not linked to a unit"})
+		return primitive.NilObjectID, false
+	}
+	return *user.UnitID, true
+}
+
+func containsUnit(unitIDs []primitive.ObjectID, unitID primitive.ObjectID) bool {
+	for _, id := range unitIDs {
+		if id == unitID {
+			return true
+		}
+	}
+	return false
+}
+
+func checkedInAt(inside []models.StaffAttendance) *time.Time {
+	if len(inside) == 0 {
+		return nil
+	}
+	return &inside[0].CheckIn
+}
@@ -0,0 +1,434 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"bms-backend/internal/ledger"
+	"bms-backend/internal/models"
+	"bms-backend/internal/store"
+	"bms-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type UnitHandler struct {
+	store  *store.Store
+	ledger *ledger.Ledger
+}
+
+func NewUnitHandler(s *store.Store) *UnitHandler {
+	return &UnitHandler{store: s, ledger: ledger.New(s)}
+}
+
+func (h *UnitHandler) GetUnits(c *gin.Context) {
+	filter := store.UnitFilter{SocietyCode: c.GetString("society_code")}
+
+	if buildingID := c.Query("building_id"); buildingID != "" {
+		objID, err := primitive.ObjectIDFromHex(buildingID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid building ID"})
+			return
+		}
+		filter.BuildingID = objID
+	}
+
+	if c.GetString("user_role") == "resident" {
+		// Residents only see the units they own or rent
+		userID, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+		filter.OccupantID = userID
+	}
+
+	units, err := h.store.Units.List(context.Background(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch units"})
+		return
+	}
+
+	if units == nil {
+		units = []models.Unit{}
+	}
+
+	c.JSON(http.StatusOK, units)
+}
+
+func (h *UnitHandler) GetUnitByID(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unit ID"})
+		return
+	}
+
+	unit, err := h.store.Units.GetByID(context.Background(), c.GetString("society_code"), objID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unit not found in your society"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, unit)
+}
+
+func (h *UnitHandler) CreateUnit(c *gin.Context) {
+	var req models.CreateUnitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	society, building, ok := h.findBuilding(c, req.BuildingID)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	unit := models.Unit{
+		ID:           primitive.NewObjectID(),
+		Number:       strings.ToUpper(strings.TrimSpace(req.Number)),
+		BuildingID:   building.ID,
+		BuildingName: building.Name,
+		Floor:        req.Floor,
+		AreaSqFt:     req.AreaSqFt,
+		SocietyID:    society.ID,
+		SocietyCode:  society.Code,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	if err := h.store.Units.Create(context.Background(), &unit); err != nil {
+		if errors.Is(err, store.ErrDuplicate) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Unit number already exists in your society"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create unit"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, unit)
+}
+
+// GenerateUnits creates every unit of a building from its Floors and
+// UnitsPerFloor. Units the building already has are skipped, so it is safe
+// to call again after a building grows; a number taken by another building
+// is an error.
+func (h *UnitHandler) GenerateUnits(c *gin.Context) {
+	var req models.GenerateUnitsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	society, building, ok := h.findBuilding(c, req.BuildingID)
+	if !ok {
+		return
+	}
+
+	code := buildingCode(*building)
+	created := []models.Unit{}
+	skipped := 0
+	now := time.Now()
+	for floor := 1; floor <= building.Floors; floor++ {
+		for position := 1; position <= building.UnitsPerFloor; position++ {
+			unit := models.Unit{
+				ID:           primitive.NewObjectID(),
+				Number:       utils.UnitNumber(code, floor, position),
+				BuildingID:   building.ID,
+				BuildingName: building.Name,
+				Floor:        floor,
+				AreaSqFt:     req.AreaSqFt,
+				SocietyID:    society.ID,
+				SocietyCode:  society.Code,
+				CreatedAt:    now,
+				UpdatedAt:    now,
+			}
+
+			err := h.store.Units.Create(context.Background(), &unit)
+			if errors.Is(err, store.ErrDuplicate) {
+				existing, err := h.store.Units.GetByNumber(context.Background(), society.Code, unit.Number)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate units"})
+					return
+				}
+				if existing.BuildingID != building.ID {
+					c.JSON(http.StatusConflict, gin.H{
+						"error":   "Unit " + unit.Number + " already belongs to " + existing.BuildingName + ", which uses the same building code",
+						"created": len(created),
+						"units":   created,
+					})
+					return
+				}
+				skipped++
+				continue
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate units"})
+				return
+			}
+			created = append(created, unit)
+		}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"created": len(created),
+		"skipped": skipped,
+		"units":   created,
+	})
+}
+
+func (h *UnitHandler) AssignOccupant(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unit ID"})
+		return
+	}
+
+	var req models.UnitOccupantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(req.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	societyCode := c.GetString("society_code")
+	ctx := context.Background()
+
+	user, err := h.store.Users.GetByID(ctx, societyCode, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found in your society"})
+		return
+	}
+
+	unit, err := h.store.Units.GetByID(ctx, societyCode, objID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unit not found in your society"})
+		return
+	}
+
+	movedIn := time.Now()
+	if req.MovedIn != nil {
+		movedIn = *req.MovedIn
+	}
+
+	err = h.store.Units.SetOccupant(ctx, societyCode, unit.ID, models.UnitOccupancy{
+		UserID:   user.ID,
+		UserName: user.Name,
+		Role:     req.Role,
+		MovedIn:  movedIn,
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Unit already has a current " + req.Role + "; move them out first"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign " + req.Role})
+		}
+		return
+	}
+
+	if err := h.store.Users.SetUnit(ctx, societyCode, user.ID, unit); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link user to unit"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": user.Name + " moved in as " + req.Role + " of " + unit.Number})
+}
+
+func (h *UnitHandler) MoveOut(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unit ID"})
+		return
+	}
+
+	var req models.UnitMoveOutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	societyCode := c.GetString("society_code")
+	ctx := context.Background()
+
+	unit, err := h.store.Units.GetByID(ctx, societyCode, objID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unit not found in your society"})
+		return
+	}
+
+	occupantID := unit.TenantID
+	if req.Role == "owner" {
+		occupantID = unit.OwnerID
+	}
+
+	movedOut := time.Now()
+	if req.MovedOut != nil {
+		movedOut = *req.MovedOut
+	}
+
+	err = h.store.Units.ClearOccupant(ctx, societyCode, unit.ID, req.Role, movedOut)
+	if err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Unit has no current " + req.Role})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move out " + req.Role})
+		}
+		return
+	}
+
+	// Only unlink the user if this unit is still the one on their profile
+	if occupantID != nil {
+		user, err := h.store.Users.GetByID(ctx, societyCode, *occupantID)
+		if err == nil && user.UnitID != nil && *user.UnitID == unit.ID {
+			if err := h.store.Users.SetUnit(ctx, societyCode, user.ID, nil); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink user from unit"})
+				return
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Moved out " + req.Role + " of " + unit.Number})
+}
+
+// GetBalance returns what the unit owes right now and the advance credit
+// waiting to be applied to its next dues
+func (h *UnitHandler) GetBalance(c *gin.Context) {
+	unit, ok := h.findAccountUnit(c)
+	if !ok {
+		return
+	}
+
+	balance, err := h.ledger.Balance(context.Background(), unit, time.Time{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute balance"})
+		return
+	}
+	credit, err := h.ledger.AvailableCredit(context.Background(), unit.SocietyCode, unit.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute balance"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"unit_id":     unit.ID,
+		"unit_number": unit.Number,
+		"balance":     balance,
+		"credit":      math.Max(credit, 0),
+	})
+}
+
+// GetStatement returns the unit's ledger between ?from= and ?to= (both
+// YYYY-MM-DD and inclusive), defaulting to the current month so far
+func (h *UnitHandler) GetStatement(c *gin.Context) {
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	var err error
+	if value := c.Query("from"); value != "" {
+		if from, err = time.ParseInLocation("2006-01-02", value, now.Location()); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be formatted as YYYY-MM-DD"})
+			return
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if to, err = time.ParseInLocation("2006-01-02", value, now.Location()); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be formatted as YYYY-MM-DD"})
+			return
+		}
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must not be before from"})
+		return
+	}
+
+	unit, ok := h.findAccountUnit(c)
+	if !ok {
+		return
+	}
+
+	statement, err := h.ledger.Statement(context.Background(), unit, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build statement"})
+		return
+	}
+
+	c.JSON(http.StatusOK, statement)
+}
+
+// findAccountUnit loads the unit named in the URL for account views.
+// Residents may only see the units they own or rent. It writes the error
+// response itself when it fails.
+func (h *UnitHandler) findAccountUnit(c *gin.Context) (*models.Unit, bool) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unit ID"})
+		return nil, false
+	}
+
+	unit, err := h.store.Units.GetByID(context.Background(), c.GetString("society_code"), objID)
+	if err == nil && c.GetString("user_role") == "resident" {
+		userID, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+		isOwner := unit.OwnerID != nil && *unit.OwnerID == userID
+		isTenant := unit.TenantID != nil && *unit.TenantID == userID
+		if !isOwner && !isTenant {
+			err = store.ErrNotFound
+		}
+	}
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unit not found in your society"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return nil, false
+	}
+	return unit, true
+}
+
+// findBuilding resolves a building of the caller's society, writing the
+// error response itself when it cannot.
+func (h *UnitHandler) findBuilding(c *gin.Context, buildingID string) (*models.Society, *models.Building, bool) {
+	objID, err := primitive.ObjectIDFromHex(buildingID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid building ID"})
+		return nil, nil, false
+	}
+
+	society, err := h.store.Societies.GetByCode(context.Background(), c.GetString("society_code"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Society not found"})
+		return nil, nil, false
+	}
+
+	for i := range society.Buildings {
+		if society.Buildings[i].ID == objID {
+			return society, &society.Buildings[i], true
+		}
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "Building not found in your society"})
+	return nil, nil, false
+}
+
+// currentUnitID returns the unit linked to the calling user's profile, or
+// a zero ID when they are not linked to any unit.
+func currentUnitID(ctx context.Context, s *store.Store, c *gin.Context) primitive.ObjectID {
+	userID, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	user, err := s.Users.GetByID(ctx, c.GetString("society_code"), userID)
+	if err != nil || user.UnitID == nil {
+		return primitive.NilObjectID
+	}
+	return *user.UnitID
+}
@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/parcels"
+	"bms-backend/internal/storage"
+	"bms-backend/internal/store"
+	"bms-backend/internal/uploads"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxAmenityImages caps the gallery of one amenity
+const maxAmenityImages = 10
+
+type UploadHandler struct {
+	store   *store.Store
+	uploads *uploads.Service
+}
+
+func NewUploadHandler(s *store.Store, uploadService *uploads.Service) *UploadHandler {
+	return &UploadHandler{store: s, uploads: uploadService}
+}
+
+// UploadVisitorPhoto sets the visitor's photo from the multipart "file"
+// field, replacing any earlier one. Residents can only photograph their
+// own visitors.
+func (h *UploadHandler) UploadVisitorPhoto(c *gin.Context) {
+	visitor, ok := h.findVisitor(c)
+	if !ok {
+		return
+	}
+	upload, ok := h.save(c, uploads.KindVisitorPhoto, visitor.ID)
+	if !ok {
+		return
+	}
+
+	err := h.store.Visitors.Update(context.Background(), visitor.SocietyCode, visitor.ID, store.VisitorUpdate{PhotoURL: &upload.URL})
+	if err != nil {
+		h.discard(upload)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set visitor photo"})
+		return
+	}
+	h.uploads.Replaced(context.Background(), visitor.SocietyCode, visitor.PhotoURL)
+
+	c.JSON(http.StatusCreated, upload)
+}
+
+// UploadVisitorIDProof attaches a scan of the visitor's ID, as an image or
+// PDF, replacing any earlier one
+func (h *UploadHandler) UploadVisitorIDProof(c *gin.Context) {
+	visitor, ok := h.findVisitor(c)
+	if !ok {
+		return
+	}
+	upload, ok := h.save(c, uploads.KindVisitorIDProof, visitor.ID)
+	if !ok {
+		return
+	}
+
+	err := h.store.Visitors.Update(context.Background(), visitor.SocietyCode, visitor.ID, store.VisitorUpdate{IDProofURL: &upload.URL})
+	if err != nil {
+		h.discard(upload)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set visitor ID proof"})
+		return
+	}
+	h.uploads.Replaced(context.Background(), visitor.SocietyCode, visitor.IDProofURL)
+
+	c.JSON(http.StatusCreated, upload)
+}
+
+// UploadAmenityImage adds an image to the amenity's gallery
+func (h *UploadHandler) UploadAmenityImage(c *gin.Context) {
+	amenity, ok := h.findAmenity(c)
+	if !ok {
+		return
+	}
+	if len(amenity.Images) >= maxAmenityImages {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("An amenity can have at most %d images", maxAmenityImages)})
+		return
+	}
+	upload, ok := h.save(c, uploads.KindAmenityImage, amenity.ID)
+	if !ok {
+		return
+	}
+
+	// The cap is checked again by the store, as other uploads may have
+	// filled the gallery meanwhile
+	err := h.store.Amenities.AddImage(context.Background(), amenity.SocietyCode, amenity.ID, upload.URL, maxAmenityImages)
+	if err != nil {
+		h.discard(upload)
+		if errors.Is(err, store.ErrConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("An amenity can have at most %d images", maxAmenityImages)})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add amenity image"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, upload)
+}
+
+// RemoveAmenityImage takes an uploaded image out of the amenity's gallery
+// and deletes it
+func (h *UploadHandler) RemoveAmenityImage(c *gin.Context) {
+	amenity, ok := h.findAmenity(c)
+	if !ok {
+		return
+	}
+	uploadID, err := primitive.ObjectIDFromHex(c.Param("uploadId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image ID"})
+		return
+	}
+
+	url := uploads.URL(uploadID)
+	images, err := h.store.Amenities.RemoveImage(context.Background(), amenity.SocietyCode, amenity.ID, url)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Image not found on this amenity"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove amenity image"})
+		}
+		return
+	}
+	h.uploads.Replaced(context.Background(), amenity.SocietyCode, url)
+
+	if images == nil {
+		images = []string{}
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Image removed", "images": images})
+}
+
+// UploadParcelSignature stores the signature of whoever collects a parcel
+// without the OTP. Its ID is then passed to the collect call, which only
+// accepts signatures uploaded for that parcel.
+func (h *UploadHandler) UploadParcelSignature(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parcel ID"})
+		return
+	}
+	parcel, err := h.store.Parcels.GetByID(context.Background(), c.GetString("society_code"), objID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Parcel not found in your society"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return
+	}
+	if parcel.Status != parcels.StatusReceived {
+		c.JSON(http.StatusConflict, gin.H{"error": "Parcel is no longer at the security desk"})
+		return
+	}
+	upload, ok := h.save(c, uploads.KindParcelSignature, parcel.ID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusCreated, upload)
+}
+
+// UploadAvatar sets the logged-in user's avatar, replacing any earlier one
+func (h *UploadHandler) UploadAvatar(c *gin.Context) {
+	userID, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	societyCode := c.GetString("society_code")
+	user, err := h.store.Users.GetByID(context.Background(), societyCode, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	upload, ok := h.save(c, uploads.KindAvatar, user.ID)
+	if !ok {
+		return
+	}
+
+	if err := h.store.Users.SetAvatar(context.Background(), societyCode, user.ID, upload.URL); err != nil {
+		h.discard(upload)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set avatar"})
+		return
+	}
+	h.uploads.Replaced(context.Background(), societyCode, user.Avatar)
+
+	c.JSON(http.StatusCreated, upload)
+}
+
+// DownloadUpload streams an uploaded file
+func (h *UploadHandler) DownloadUpload(c *gin.Context) {
+	h.download(c, false)
+}
+
+// DownloadThumbnail streams the JPEG thumbnail of an uploaded image
+func (h *UploadHandler) DownloadThumbnail(c *gin.Context) {
+	h.download(c, true)
+}
+
+func (h *UploadHandler) download(c *gin.Context, thumb bool) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid upload ID"})
+		return
+	}
+
+	upload, err := h.store.Uploads.GetByID(context.Background(), c.GetString("society_code"), objID)
+	if err == nil && !h.canView(c, upload) {
+		err = store.ErrNotFound
+	}
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return
+	}
+
+	file, contentType, err := h.uploads.Open(context.Background(), upload, thumb)
+	if err != nil {
+		if errors.Is(err, uploads.ErrNoThumbnail) || errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		}
+		return
+	}
+	defer file.Close()
+
+	size := upload.Size
+	if thumb {
+		size = -1
+	}
+	c.DataFromReader(http.StatusOK, size, contentType, file, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("inline", map[string]string{"filename": upload.FileName}),
+		"Cache-Control":          "private, max-age=86400",
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// canView applies the per-kind rules on top of the society scoping:
+// visitor photos are for the host, secretaries and security, ID proofs for
+// secretaries and security only, parcel signatures for the parcel's unit,
+// secretaries and security
+func (h *UploadHandler) canView(c *gin.Context, upload *models.Upload) bool {
+	role := c.GetString("user_role")
+	switch upload.Kind {
+	case uploads.KindVisitorIDProof:
+		return role == "secretary" || role == "security"
+	case uploads.KindVisitorPhoto:
+		if role != "resident" {
+			return true
+		}
+		visitor, err := h.store.Visitors.GetByID(context.Background(), upload.SocietyCode, upload.OwnerID)
+		return err == nil && visitor.HostID.Hex() == c.GetString("user_id")
+	case uploads.KindParcelSignature:
+		if role != "resident" {
+			return true
+		}
+		userID, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+		user, err := h.store.Users.GetByID(context.Background(), upload.SocietyCode, userID)
+		if err != nil || user.UnitID == nil {
+			return false
+		}
+		parcel, err := h.store.Parcels.GetByID(context.Background(), upload.SocietyCode, upload.OwnerID)
+		return err == nil && parcel.UnitID == *user.UnitID
+	default:
+		return true
+	}
+}
+
+// save stores the multipart "file" field as an upload of kind owned by
+// owner, writing the error response itself on failure
+func (h *UploadHandler) save(c *gin.Context, kind string, owner primitive.ObjectID) (*models.Upload, bool) {
+	// Leave room for the multipart headers around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.uploads.MaxSize()+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.writeTooLarge(c)
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A multipart file field named file is required"})
+		}
+		return nil, false
+	}
+	if header.Size > h.uploads.MaxSize() {
+		h.writeTooLarge(c)
+		return nil, false
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the uploaded file"})
+		return nil, false
+	}
+	defer file.Close()
+
+	uploader, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	upload := &models.Upload{
+		Kind:        kind,
+		OwnerID:     owner,
+		FileName:    filepath.Base(header.Filename),
+		UploadedBy:  uploader,
+		SocietyCode: c.GetString("society_code"),
+	}
+	if err := h.uploads.Save(context.Background(), upload, file); err != nil {
+		switch {
+		case errors.Is(err, uploads.ErrTooLarge):
+			h.writeTooLarge(c)
+		case errors.Is(err, uploads.ErrUnsupported):
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store the uploaded file"})
+		}
+		return nil, false
+	}
+	return upload, true
+}
+
+// discard removes an upload that could not be linked to its owner
+func (h *UploadHandler) discard(upload *models.Upload) {
+	if err := h.uploads.Remove(context.Background(), upload); err != nil {
+		log.Printf("⚠️ Failed to remove unused upload %s: %v", upload.ID.Hex(), err)
+	}
+}
+
+func (h *UploadHandler) writeTooLarge(c *gin.Context) {
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Files can be at most %d MB", h.uploads.MaxSize()>>20)})
+}
+
+// findVisitor loads the :id visitor of the caller's society, writing the
+// error response itself. Residents only get their own visitors.
+func (h *UploadHandler) findVisitor(c *gin.Context) (*models.Visitor, bool) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid visitor ID"})
+		return nil, false
+	}
+
+	visitor, err := h.store.Visitors.GetByID(context.Background(), c.GetString("society_code"), objID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Visitor not found in your society"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return nil, false
+	}
+	if c.GetString("user_role") == "resident" && visitor.HostID.Hex() != c.GetString("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not your visitor"})
+		return nil, false
+	}
+	return visitor, true
+}
+
+// findAmenity loads the :id amenity of the caller's society, writing the
+// error response itself
+func (h *UploadHandler) findAmenity(c *gin.Context) (*models.Amenity, bool) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amenity ID"})
+		return nil, false
+	}
+
+	amenity, err := h.store.Amenities.GetByID(context.Background(), c.GetString("society_code"), objID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Amenity not found in your society"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return nil, false
+	}
+	return amenity, true
+}
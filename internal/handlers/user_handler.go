@@ -5,41 +5,30 @@ import (
 	"net/http"
 
 	"bms-backend/internal/models"
-	"bms-backend/internal/middleware"
+	"bms-backend/internal/store"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type UserHandler struct {
-	db *mongo.Database
+	store *store.Store
 }
 
-func NewUserHandler(db *mongo.Database) *UserHandler {
-	return &UserHandler{db: db}
+func NewUserHandler(s *store.Store) *UserHandler {
+	return &UserHandler{store: s}
 }
 
 func (h *UserHandler) GetResidents(c *gin.Context) {
-	societyFilter := middleware.GetSocietyFilter(c)
-	societyFilter["role"] = bson.M{"$in": []string{"resident", "secretary"}}
-	societyFilter["is_active"] = true
-
-	collection := h.db.Collection("users")
-	cursor, err := collection.Find(context.Background(), societyFilter, options.Find().SetSort(bson.M{"name": 1}))
+	users, err := h.store.Users.List(context.Background(), store.UserFilter{
+		SocietyCode: c.GetString("society_code"),
+		Roles:       []string{"resident", "secretary"},
+		ActiveOnly:  true,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch residents"})
 		return
 	}
-	defer cursor.Close(context.Background())
-
-	var users []models.User
-	if err = cursor.All(context.Background(), &users); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode residents"})
-		return
-	}
 
 	// Remove passwords from response
 	for i := range users {
@@ -54,39 +43,40 @@ func (h *UserHandler) GetResidents(c *gin.Context) {
 }
 
 func (h *UserHandler) GetStats(c *gin.Context) {
-	societyFilter := middleware.GetSocietyFilter(c)
+	societyCode := c.GetString("society_code")
 	ctx := context.Background()
 
 	// Count total users in society
-	usersCollection := h.db.Collection("users")
-	userFilter := societyFilter
-	userFilter["is_active"] = true
-	totalUsers, _ := usersCollection.CountDocuments(ctx, userFilter)
+	totalUsers, _ := h.store.Users.Count(ctx, store.UserFilter{
+		SocietyCode: societyCode,
+		ActiveOnly:  true,
+	})
 
 	// Count residents in society
-	residentFilter := societyFilter
-	residentFilter["role"] = "resident"
-	residentFilter["is_active"] = true
-	totalResidents, _ := usersCollection.CountDocuments(ctx, residentFilter)
+	totalResidents, _ := h.store.Users.Count(ctx, store.UserFilter{
+		SocietyCode: societyCode,
+		Roles:       []string{"resident"},
+		ActiveOnly:  true,
+	})
 
 	// Count pending visitors in society
-	visitorsCollection := h.db.Collection("visitors")
-	visitorFilter := societyFilter
-	visitorFilter["status"] = "pending"
-	pendingVisitors, _ := visitorsCollection.CountDocuments(ctx, visitorFilter)
+	pendingVisitors, _ := h.store.Visitors.Count(ctx, store.VisitorFilter{
+		SocietyCode: societyCode,
+		Statuses:    []string{"pending"},
+	})
 
 	// Count overdue maintenance in society
-	maintenanceCollection := h.db.Collection("maintenance")
-	maintenanceFilter := societyFilter
-	maintenanceFilter["status"] = "overdue"
-	overdueMaintenance, _ := maintenanceCollection.CountDocuments(ctx, maintenanceFilter)
+	overdueMaintenance, _ := h.store.Maintenance.Count(ctx, store.MaintenanceFilter{
+		SocietyCode: societyCode,
+		Statuses:    []string{"overdue"},
+	})
 
 	stats := map[string]interface{}{
-		"total_users":        totalUsers,
-		"total_residents":    totalResidents,
-		"pending_visitors":   pendingVisitors,
+		"total_users":         totalUsers,
+		"total_residents":     totalResidents,
+		"pending_visitors":    pendingVisitors,
 		"overdue_maintenance": overdueMaintenance,
-		"society_code":       c.GetString("society_code"),
+		"society_code":        societyCode,
 	}
 
 	c.JSON(http.StatusOK, stats)
@@ -100,12 +90,7 @@ func (h *UserHandler) GetUserByID(c *gin.Context) {
 		return
 	}
 
-	societyFilter := middleware.GetSocietyFilter(c)
-	societyFilter["_id"] = objID
-
-	collection := h.db.Collection("users")
-	var user models.User
-	err = collection.FindOne(context.Background(), societyFilter).Decode(&user)
+	user, err := h.store.Users.GetByID(context.Background(), c.GetString("society_code"), objID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found in your society"})
 		return
@@ -118,26 +103,14 @@ func (h *UserHandler) GetUserByID(c *gin.Context) {
 }
 
 func (h *UserHandler) GetPendingVisitors(c *gin.Context) {
-	societyFilter := middleware.GetSocietyFilter(c)
-	societyFilter["status"] = "pending"
-
-	collection := h.db.Collection("visitors")
-	cursor, err := collection.Find(context.Background(), societyFilter, options.Find().SetSort(bson.M{"created_at": -1}))
+	visitors, err := h.store.Visitors.List(context.Background(), store.VisitorFilter{
+		SocietyCode: c.GetString("society_code"),
+		Statuses:    []string{"pending"},
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pending visitors"})
 		return
 	}
-	defer cursor.Close(context.Background())
 
-	var visitors []models.Visitor
-	if err = cursor.All(context.Background(), &visitors); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode visitors"})
-		return
-	}
-
-	if visitors == nil {
-		visitors = []models.Visitor{}
-	}
-
-	c.JSON(http.StatusOK, visitors)
-}
\ No newline at end of file
+	c.JSON(http.StatusOK, visitorViews(c, visitors))
+}
@@ -2,61 +2,57 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
-	"bms-backend/internal/middleware"
+	"bms-backend/internal/amenities"
+	"bms-backend/internal/blacklist"
 	"bms-backend/internal/models"
-	"bms-backend/internal/utils"
+	"bms-backend/internal/parking"
+	"bms-backend/internal/passes"
+	"bms-backend/internal/store"
+	"bms-backend/internal/visitors"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type VisitorHandler struct {
-	db *mongo.Database
+	store     *store.Store
+	passes    *passes.Signer
+	visitors  *visitors.Service
+	blacklist *blacklist.Service
+	parking   *parking.Service
 }
 
-func NewVisitorHandler(db *mongo.Database) *VisitorHandler {
-	return &VisitorHandler{db: db}
+func NewVisitorHandler(s *store.Store, signer *passes.Signer, visitorService *visitors.Service, blacklistService *blacklist.Service, parkingService *parking.Service) *VisitorHandler {
+	return &VisitorHandler{store: s, passes: signer, visitors: visitorService, blacklist: blacklistService, parking: parkingService}
 }
 
 func (h *VisitorHandler) GetVisitors(c *gin.Context) {
 	userRole := c.GetString("user_role")
 	userID := c.GetString("user_id")
-	// societyCode := c.GetString("society_code")
 
 	// Base filter with society
-	filter := middleware.GetSocietyFilter(c)
+	filter := store.VisitorFilter{SocietyCode: c.GetString("society_code")}
 
 	if userRole == "resident" {
 		// Residents can only see their own visitors
 		hostID, _ := primitive.ObjectIDFromHex(userID)
-		filter["host_id"] = hostID
+		filter.HostID = hostID
 	}
 
-	collection := h.db.Collection("visitors")
-	cursor, err := collection.Find(context.Background(), filter, options.Find().SetSort(bson.M{"created_at": -1}))
+	visitors, err := h.store.Visitors.List(context.Background(), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch visitors"})
 		return
 	}
-	defer cursor.Close(context.Background())
 
-	var visitors []models.Visitor
-	if err = cursor.All(context.Background(), &visitors); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode visitors"})
-		return
-	}
-
-	if visitors == nil {
-		visitors = []models.Visitor{}
-	}
-
-	c.JSON(http.StatusOK, visitors)
+	c.JSON(http.StatusOK, visitorViews(c, visitors))
 }
 
 func (h *VisitorHandler) CreateVisitor(c *gin.Context) {
@@ -66,23 +62,46 @@ func (h *VisitorHandler) CreateVisitor(c *gin.Context) {
 		return
 	}
 
+	if !normalizeVehicle(c, &visitor.VehicleNumber) {
+		return
+	}
+
 	userID := c.GetString("user_id")
 	societyCode := c.GetString("society_code")
 	hostID, _ := primitive.ObjectIDFromHex(userID)
 
 	// Get society ID
-	societyCollection := h.db.Collection("societies")
-	var society models.Society
-	err := societyCollection.FindOne(context.Background(), bson.M{"code": societyCode}).Decode(&society)
+	society, err := h.store.Societies.GetByCode(context.Background(), societyCode)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Society not found"})
 		return
 	}
 
+	// The host's unit comes from their profile, not from the request
+	host, err := h.store.Users.GetByID(context.Background(), societyCode, hostID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Blacklisted visitors cannot be invited; the resident is not told why
+	screening, ok := h.screen(c, &visitor, "was invited by "+host.Name+" ("+host.Unit+")")
+	if !ok {
+		return
+	}
+	if screening.Blocked() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This visitor cannot be invited; please contact the society office"})
+		return
+	}
+
 	visitor.ID = primitive.NewObjectID()
+	visitor.Watchlist = screening.Matches
 	visitor.HostID = hostID
-	visitor.Status = "pending"
-	visitor.QRCode = utils.GenerateQRCode(visitor.ID.Hex(), societyCode)
+	visitor.HostName = host.Name
+	visitor.HostUnit = host.Unit
+	visitor.HostUnitID = host.UnitID
+	visitor.Status = visitors.StatusPending
+	visitor.History = []models.VisitorStatusChange{visitors.Created(actor(c), time.Now())}
 	visitor.SocietyID = society.ID
 	visitor.SocietyCode = societyCode
 	visitor.CreatedAt = time.Now()
@@ -93,9 +112,18 @@ func (h *VisitorHandler) CreateVisitor(c *gin.Context) {
 		visitor.ExpectedTime = time.Now()
 	}
 
-	collection := h.db.Collection("visitors")
-	_, err = collection.InsertOne(context.Background(), visitor)
+	// The pass is bound to the expected time, so it is issued last
+	token, claims, err := h.passes.Issue(&visitor)
 	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue visitor pass"})
+		return
+	}
+	visitor.QRCode = token
+	visitor.PassValidFrom = claims.NotBefore
+	visitor.PassExpiresAt = claims.ExpiresAt
+	visitor.PassUsedAt = nil
+
+	if err := h.store.Visitors.Create(context.Background(), &visitor); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create visitor"})
 		return
 	}
@@ -103,153 +131,759 @@ func (h *VisitorHandler) CreateVisitor(c *gin.Context) {
 	c.JSON(http.StatusCreated, visitor)
 }
 
-func (h *VisitorHandler) ApproveVisitor(c *gin.Context) {
-	visitorID := c.Param("id")
-	objID, err := primitive.ObjectIDFromHex(visitorID)
+// screen checks a visitor against the society blacklist and alerts the
+// secretaries about any match. It writes the error response itself.
+func (h *VisitorHandler) screen(c *gin.Context, visitor *models.Visitor, where string) (*blacklist.Result, bool) {
+	person := blacklist.Person{Name: visitor.Name, Phone: visitor.Phone, VehicleNumber: visitor.VehicleNumber}
+	result, err := h.blacklist.Screen(context.Background(), c.GetString("society_code"), person)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid visitor ID"})
-		return
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check blacklist"})
+		return nil, false
 	}
+	if result.Flagged() {
+		h.blacklist.Alert(context.Background(), c.GetString("society_code"), person, result, where)
+	}
+	return result, true
+}
 
-	var req models.VisitorApprovalRequest
+// RegisterWalkIn records an unannounced visitor at the gate and asks the
+// host unit's residents to approve. Blacklisted visitors are turned away;
+// watchlisted ones are registered with the matches for the guard.
+func (h *VisitorHandler) RegisterWalkIn(c *gin.Context) {
+	var req models.WalkInVisitorRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !normalizeVehicle(c, &req.VehicleNumber) {
+		return
+	}
+
+	societyCode := c.GetString("society_code")
+	society, err := h.store.Societies.GetByCode(context.Background(), societyCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Society not found"})
+		return
+	}
+	unit, err := h.store.Units.GetByID(context.Background(), societyCode, req.UnitID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unit not found in your society"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return
+	}
 
-	approvedBy, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
-	societyFilter := middleware.GetSocietyFilter(c)
-	societyFilter["_id"] = objID
+	now := time.Now()
+	visitor := models.Visitor{
+		ID:            primitive.NewObjectID(),
+		Name:          req.Name,
+		Phone:         req.Phone,
+		Purpose:       req.Purpose,
+		VehicleNumber: req.VehicleNumber,
+		ExpectedTime:  now,
+		SingleUse:     true,
+		SocietyID:     society.ID,
+		SocietyCode:   societyCode,
+	}
+	screening, ok := h.screen(c, &visitor, "arrived at the gate for "+unit.Number)
+	if !ok {
+		return
+	}
+	if screening.Blocked() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Visitor is blacklisted and must not be admitted", "watchlist": screening.Matches})
+		return
+	}
+	visitor.Watchlist = screening.Matches
+	token, claims, err := h.passes.Issue(&visitor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue visitor pass"})
+		return
+	}
+	visitor.QRCode = token
+	visitor.PassValidFrom = claims.NotBefore
+	visitor.PassExpiresAt = claims.ExpiresAt
 
-	update := bson.M{
-		"$set": bson.M{
-			"status":      req.Status,
-			"approved_by": approvedBy,
-			"updated_at":  time.Now(),
-		},
+	if err := h.visitors.RegisterWalkIn(context.Background(), &visitor, unit, actor(c), now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register visitor"})
+		return
 	}
 
-	collection := h.db.Collection("visitors")
-	result, err := collection.UpdateOne(context.Background(), societyFilter, update)
+	c.JSON(http.StatusCreated, visitorView(c, &visitor))
+}
+
+// GetHostApprovals lists the walk-ins waiting for the resident's unit
+func (h *VisitorHandler) GetHostApprovals(c *gin.Context) {
+	resident, ok := h.currentResident(c)
+	if !ok {
+		return
+	}
+	if resident.UnitID == nil {
+		c.JSON(http.StatusOK, []models.Visitor{})
+		return
+	}
+
+	pending, err := h.store.Visitors.List(context.Background(), store.VisitorFilter{
+		SocietyCode: resident.SocietyCode,
+		HostUnitID:  *resident.UnitID,
+		Statuses:    []string{visitors.StatusPending},
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update visitor"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch visitors"})
 		return
 	}
 
-	if result.MatchedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Visitor not found in your society"})
+	waiting := []models.Visitor{}
+	for _, visitor := range pending {
+		if visitor.WalkIn {
+			waiting = append(waiting, visitor)
+		}
+	}
+
+	c.JSON(http.StatusOK, waiting)
+}
+
+// GetOverstayingVisitors lists checked-in visitors who have stayed past
+// the limit, longest stay first, for the security dashboard
+func (h *VisitorHandler) GetOverstayingVisitors(c *gin.Context) {
+	now := time.Now()
+	overstaying, err := h.visitors.Overstaying(context.Background(), c.GetString("society_code"), now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch visitors"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Visitor " + req.Status + " successfully"})
+	c.JSON(http.StatusOK, visitorViews(c, overstaying))
 }
 
-func (h *VisitorHandler) GetVisitorByQR(c *gin.Context) {
-	qrCode := c.Param("qrcode")
+// ExportVisitors downloads the visitor log for a security audit as
+// ?format=csv (the default) or xlsx. ?from= and ?to= (YYYY-MM-DD, inclusive)
+// bound the expected time and are required; ?status= (comma-separated),
+// ?unit_id= and ?building_id= narrow it down. Times are written in ?tz=.
+func (h *VisitorHandler) ExportVisitors(c *gin.Context) {
+	societyCode := c.GetString("society_code")
+	format := c.DefaultQuery("format", visitors.FormatCSV)
+	contentType := map[string]string{
+		visitors.FormatCSV:  "text/csv; charset=utf-8",
+		visitors.FormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	}[format]
+	if contentType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or xlsx"})
+		return
+	}
 
-	collection := h.db.Collection("visitors")
-	var visitor models.Visitor
-	err := collection.FindOne(context.Background(), bson.M{"qr_code": qrCode}).Decode(&visitor)
+	loc, err := time.LoadLocation(c.DefaultQuery("tz", amenities.DefaultTimezone))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown timezone"})
+		return
+	}
+	from, err := time.ParseInLocation(amenities.DateLayout, c.Query("from"), loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be YYYY-MM-DD"})
+		return
+	}
+	to, err := time.ParseInLocation(amenities.DateLayout, c.Query("to"), loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be YYYY-MM-DD"})
+		return
+	}
+	if to.Before(from) || to.After(from.AddDate(1, 0, 0)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be on or after from and at most a year later"})
+		return
+	}
+
+	filter := store.VisitorFilter{
+		SocietyCode:    societyCode,
+		ExpectedFrom:   from,
+		ExpectedBefore: to.AddDate(0, 0, 1),
+	}
+	if raw := c.Query("status"); raw != "" {
+		filter.Statuses = strings.Split(raw, ",")
+	}
+	if raw := c.Query("unit_id"); raw != "" {
+		unitID, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unit ID"})
+			return
+		}
+		filter.HostUnitID = unitID
+	}
+	if raw := c.Query("building_id"); raw != "" {
+		buildingID, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid building ID"})
+			return
+		}
+		units, err := h.store.Units.List(context.Background(), store.UnitFilter{SocietyCode: societyCode, BuildingID: buildingID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch units"})
+			return
+		}
+		filter.HostUnitIDs = []primitive.ObjectID{}
+		for _, unit := range units {
+			filter.HostUnitIDs = append(filter.HostUnitIDs, unit.ID)
+		}
+	}
+
+	// The body is streamed, so a failure part way through can only be logged
+	filename := "visitors-" + from.Format(amenities.DateLayout) + "-" + to.Format(amenities.DateLayout) + "." + format
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+	if err := h.visitors.Export(context.Background(), c.Writer, format, filter, loc); err != nil {
+		log.Printf("⚠️ Visitors: export of %s failed: %v", societyCode, err)
+	}
+}
+
+// RespondToWalkIn lets a resident of the host unit approve or deny a
+// walk-in from their app
+func (h *VisitorHandler) RespondToWalkIn(c *gin.Context) {
+	var req models.VisitorApprovalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid visitor ID"})
+		return
+	}
+	resident, ok := h.currentResident(c)
+	if !ok {
+		return
+	}
+	visitor, err := h.store.Visitors.GetByID(context.Background(), resident.SocietyCode, objID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Visitor not found"})
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Visitor not found in your society"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 		}
 		return
 	}
 
-	c.JSON(http.StatusOK, visitor)
+	err = h.visitors.Respond(context.Background(), visitor, resident, req.Status, req.Reason, time.Now())
+	switch {
+	case errors.Is(err, visitors.ErrNotHost):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only residents of the host unit can answer"})
+		return
+	case errors.Is(err, visitors.ErrNotAwaiting):
+		c.JSON(http.StatusConflict, gin.H{"error": "Visitor is not waiting for host approval"})
+		return
+	case err != nil:
+		writeTransitionError(c, err, "Failed to update visitor")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Visitor " + req.Status + " successfully", "visitor": visitor})
 }
 
-func (h *VisitorHandler) CheckInVisitor(c *gin.Context) {
-	visitorID := c.Param("id")
-	objID, err := primitive.ObjectIDFromHex(visitorID)
+// GetHostContacts gives the guard the host unit's phone numbers, the
+// fallback when nobody answers a walk-in in the app
+func (h *VisitorHandler) GetHostContacts(c *gin.Context) {
+	visitor, ok := h.findVisitor(c)
+	if !ok {
+		return
+	}
+
+	contacts := []models.HostContact{}
+	if visitor.HostUnitID != nil {
+		residents, err := h.visitors.HostResidents(context.Background(), visitor.SocietyCode, *visitor.HostUnitID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch host contacts"})
+			return
+		}
+		for _, resident := range residents {
+			contacts = append(contacts, models.HostContact{UserID: resident.ID, Name: resident.Name, Phone: resident.Phone})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"host_unit": visitor.HostUnit, "contacts": contacts})
+}
+
+// currentResident loads the logged-in resident, writing the error
+// response itself
+func (h *VisitorHandler) currentResident(c *gin.Context) (*models.User, bool) {
+	userID, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	user, err := h.store.Users.GetByID(context.Background(), c.GetString("society_code"), userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid visitor ID"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	return user, true
+}
+
+// ApproveVisitor approves or rejects a pending visitor
+func (h *VisitorHandler) ApproveVisitor(c *gin.Context) {
+	var req models.VisitorApprovalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	societyFilter := middleware.GetSocietyFilter(c)
-	societyFilter["_id"] = objID
+	visitor, ok := h.findVisitor(c)
+	if !ok {
+		return
+	}
 
-	now := time.Now()
-	update := bson.M{
-		"$set": bson.M{
-			"actual_arrival": now,
-			"updated_at":     now,
-		},
+	by := actor(c)
+	update := store.VisitorUpdate{}
+	if req.Status == visitors.StatusApproved {
+		update.ApprovedBy = &by.ID
+	}
+	if err := h.visitors.Transition(context.Background(), visitor, req.Status, by, req.Reason, update, time.Now()); err != nil {
+		writeTransitionError(c, err, "Failed to update visitor")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Visitor " + req.Status + " successfully", "visitor": visitorView(c, visitor)})
+}
+
+// CancelVisitor calls off an expected visit. Residents can cancel their
+// own visitors.
+func (h *VisitorHandler) CancelVisitor(c *gin.Context) {
+	var req models.VisitorCancelRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 	}
 
-	collection := h.db.Collection("visitors")
-	result, err := collection.UpdateOne(context.Background(), societyFilter, update)
+	visitor, ok := h.findVisitor(c)
+	if !ok {
+		return
+	}
+
+	if err := h.visitors.Transition(context.Background(), visitor, visitors.StatusCancelled, actor(c), req.Reason, store.VisitorUpdate{}, time.Now()); err != nil {
+		writeTransitionError(c, err, "Failed to cancel visitor")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Visitor cancelled successfully", "visitor": visitor})
+}
+
+// GetVisitorByQR is the public pass lookup for guards at the gate. Only a
+// pass that is genuine, current and unused is answered, and only with what
+// the guard needs to know.
+func (h *VisitorHandler) GetVisitorByQR(c *gin.Context) {
+	visitor, ok := h.findByPass(c, c.Param("qrcode"))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, visitorPass(visitor))
+}
+
+// CheckInByPass checks in the approved visitor holding a scanned pass
+func (h *VisitorHandler) CheckInByPass(c *gin.Context) {
+	visitor, ok := h.findByPass(c, c.Param("token"))
+	if !ok {
+		return
+	}
+	if visitor.SocietyCode != c.GetString("society_code") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invalid visitor pass"})
+		return
+	}
+
+	response := gin.H{"message": "Visitor checked in successfully"}
+	if !h.checkIn(c, visitor, response) {
+		return
+	}
+	response["pass"] = visitorPass(visitor)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ReissuePass replaces a visitor's pass, so a pass that was shared too
+// widely stops working. Hosts can reissue their own visitors' passes.
+func (h *VisitorHandler) ReissuePass(c *gin.Context) {
+	visitor, ok := h.findVisitor(c)
+	if !ok {
+		return
+	}
+	if visitor.Status != visitors.StatusPending && visitor.Status != visitors.StatusApproved {
+		c.JSON(http.StatusConflict, gin.H{"error": "Passes can only be reissued for pending or approved visitors", "status": visitor.Status})
+		return
+	}
+
+	token, claims, err := h.passes.Issue(visitor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue visitor pass"})
+		return
+	}
+	err = h.store.Visitors.Update(context.Background(), visitor.SocietyCode, visitor.ID, store.VisitorUpdate{
+		QRCode:        &token,
+		PassValidFrom: &claims.NotBefore,
+		PassExpiresAt: &claims.ExpiresAt,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check in visitor"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reissue visitor pass"})
 		return
 	}
 
-	if result.MatchedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Visitor not found in your society"})
+	c.JSON(http.StatusOK, gin.H{"qr_code": token, "pass_valid_from": claims.NotBefore, "pass_expires_at": claims.ExpiresAt})
+}
+
+// GetPassPNG renders the visitor's pass as a QR code PNG (?size= in
+// pixels, 128-1024)
+func (h *VisitorHandler) GetPassPNG(c *gin.Context) {
+	visitor, ok := h.findPassHolder(c)
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Visitor checked in successfully"})
+	size := passes.DefaultQRSize
+	if raw := c.Query("size"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < passes.MinQRSize || n > passes.MaxQRSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "size must be between 128 and 1024"})
+			return
+		}
+		size = n
+	}
+
+	image, err := passes.PNG(visitor.QRCode, size)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render visitor pass"})
+		return
+	}
+	c.Data(http.StatusOK, "image/png", image)
 }
 
-func (h *VisitorHandler) CheckOutVisitor(c *gin.Context) {
-	visitorID := c.Param("id")
-	objID, err := primitive.ObjectIDFromHex(visitorID)
+// GetPassSVG renders the visitor's pass as a scalable QR code
+func (h *VisitorHandler) GetPassSVG(c *gin.Context) {
+	visitor, ok := h.findPassHolder(c)
+	if !ok {
+		return
+	}
+
+	image, err := passes.SVG(visitor.QRCode)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid visitor ID"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render visitor pass"})
 		return
 	}
+	c.Data(http.StatusOK, "image/svg+xml", image)
+}
 
-	societyFilter := middleware.GetSocietyFilter(c)
-	societyFilter["_id"] = objID
+// GetPassCard renders a pass card for the host to forward to their guest.
+// Validity times are printed in ?tz= (an IANA name, default Asia/Kolkata).
+func (h *VisitorHandler) GetPassCard(c *gin.Context) {
+	visitor, ok := h.findPassHolder(c)
+	if !ok {
+		return
+	}
 
-	now := time.Now()
-	update := bson.M{
-		"$set": bson.M{
-			"actual_departure": now,
-			"status":           "completed",
-			"updated_at":       now,
-		},
+	loc, err := time.LoadLocation(c.DefaultQuery("tz", amenities.DefaultTimezone))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown timezone"})
+		return
+	}
+	society, err := h.store.Societies.GetByCode(context.Background(), visitor.SocietyCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Society not found"})
+		return
 	}
 
-	collection := h.db.Collection("visitors")
-	result, err := collection.UpdateOne(context.Background(), societyFilter, update)
+	image, err := passes.RenderCard(visitor.QRCode, passes.Card{
+		SocietyName: society.Name,
+		VisitorName: visitor.Name,
+		HostName:    visitor.HostName,
+		HostUnit:    visitor.HostUnit,
+		ValidFrom:   visitor.PassValidFrom,
+		ExpiresAt:   visitor.PassExpiresAt,
+		SingleUse:   visitor.SingleUse,
+		Location:    loc,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check out visitor"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render visitor pass"})
 		return
 	}
+	c.Header("Content-Disposition", `inline; filename="visitor-pass-`+visitor.ID.Hex()+`.png"`)
+	c.Data(http.StatusOK, "image/png", image)
+}
+
+// findPassHolder loads the visitor whose pass is being rendered, writing
+// the error response itself
+func (h *VisitorHandler) findPassHolder(c *gin.Context) (*models.Visitor, bool) {
+	visitor, ok := h.findVisitor(c)
+	if !ok {
+		return nil, false
+	}
+	if visitor.PassExpiresAt.IsZero() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Visitor has no signed pass yet; reissue it first"})
+		return nil, false
+	}
+	return visitor, true
+}
+
+// findByPass verifies a pass and loads its visitor, writing the error
+// response when the pass cannot be honoured. Passes that were reissued no
+// longer match the visitor and read as invalid.
+func (h *VisitorHandler) findByPass(c *gin.Context, token string) (*models.Visitor, bool) {
+	claims, err := h.passes.Verify(token, time.Now())
+	switch {
+	case errors.Is(err, passes.ErrNotYetValid):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Visitor pass is not valid yet", "valid_from": claims.NotBefore})
+		return nil, false
+	case errors.Is(err, passes.ErrExpired):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Visitor pass has expired", "expires_at": claims.ExpiresAt})
+		return nil, false
+	case err != nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invalid visitor pass"})
+		return nil, false
+	}
 
-	if result.MatchedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Visitor not found in your society"})
+	visitor, err := h.store.Visitors.GetByQRCode(context.Background(), token)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid visitor pass"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}
+		return nil, false
+	}
+	if claims.Kind != passes.KindVisitor || visitor.ID != claims.SubjectID || visitor.SocietyCode != claims.SocietyCode {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invalid visitor pass"})
+		return nil, false
+	}
+	if visitor.SingleUse && visitor.PassUsedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Visitor pass has already been used", "used_at": visitor.PassUsedAt})
+		return nil, false
+	}
+	return visitor, true
+}
+
+func visitorPass(visitor *models.Visitor) models.VisitorPass {
+	return models.VisitorPass{
+		VisitorID:     visitor.ID,
+		Name:          visitor.Name,
+		Purpose:       visitor.Purpose,
+		HostName:      visitor.HostName,
+		HostUnit:      visitor.HostUnit,
+		ExpectedTime:  visitor.ExpectedTime,
+		Status:        visitor.Status,
+		VehicleNumber: visitor.VehicleNumber,
+		PhotoURL:      visitor.PhotoURL,
+		ValidFrom:     visitor.PassValidFrom,
+		ExpiresAt:     visitor.PassExpiresAt,
+		SingleUse:     visitor.SingleUse,
+		UsedAt:        visitor.PassUsedAt,
+		ParkingSlot:   visitor.ParkingSlot,
+	}
+}
+
+func (h *VisitorHandler) CheckInVisitor(c *gin.Context) {
+	visitor, ok := h.findVisitor(c)
+	if !ok {
+		return
+	}
+	response := gin.H{"message": "Visitor checked in successfully"}
+	if !h.checkIn(c, visitor, response) {
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *VisitorHandler) CheckOutVisitor(c *gin.Context) {
+	visitor, ok := h.findVisitor(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	err := h.visitors.Transition(context.Background(), visitor, visitors.StatusCompleted, actor(c), "", store.VisitorUpdate{
+		ActualDeparture: &now,
+	}, now)
+	if err != nil {
+		writeTransitionError(c, err, "Failed to check out visitor")
 		return
 	}
+	h.parking.ReleaseVisitor(context.Background(), visitor, actor(c).ID, now)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Visitor checked out successfully"})
 }
 
-func (h *VisitorHandler) GetVisitorByID(c *gin.Context) {
-	visitorID := c.Param("id")
-	objID, err := primitive.ObjectIDFromHex(visitorID)
+// checkIn moves an approved visitor to checked_in, which also spends their
+// pass, and parks the vehicle they arrive in. The visitor is screened
+// again first, as the blacklist may have changed since they were
+// registered. Blacklist matches and the parking slot are added to
+// response; errors are written as the response itself.
+func (h *VisitorHandler) checkIn(c *gin.Context, visitor *models.Visitor, response gin.H) bool {
+	var req models.VisitorCheckInRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return false
+		}
+	}
+	if req.VehicleNumber != "" {
+		visitor.VehicleNumber = req.VehicleNumber
+	}
+	// Visitors registered before plates were checked may carry anything;
+	// they still get in, only their vehicle is not parked
+	var plate string
+	if visitor.VehicleNumber != "" {
+		var err error
+		if plate, err = parking.NormalizePlate(visitor.VehicleNumber); err != nil {
+			log.Printf("⚠️ Visitors: not parking visitor %s, %q is not a licence plate", visitor.ID.Hex(), visitor.VehicleNumber)
+			response["parking_error"] = "vehicle_number is not a valid Indian licence plate; the vehicle was not parked"
+		}
+	}
+
+	screening, ok := h.screen(c, visitor, "tried to check in to visit "+visitor.HostUnit)
+	if !ok {
+		return false
+	}
+	visitor.Watchlist = screening.Matches
+	if screening.Blocked() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Visitor is blacklisted and must not be admitted", "watchlist": screening.Matches})
+		return false
+	}
+	if screening.Flagged() {
+		response["watchlist"] = screening.Matches
+	}
+
+	now := time.Now()
+	update := store.VisitorUpdate{ActualArrival: &now}
+	if visitor.PassUsedAt == nil {
+		update.PassUsedAt = &now
+	}
+	if err := h.visitors.Transition(context.Background(), visitor, visitors.StatusCheckedIn, actor(c), "", update, now); err != nil {
+		writeTransitionError(c, err, "Failed to check in visitor")
+		return false
+	}
+	visitor.ActualArrival = &now
+	if update.PassUsedAt != nil {
+		visitor.PassUsedAt = update.PassUsedAt
+	}
+
+	// The visitor is in either way; a full car park only warns the guard
+	if plate != "" {
+		vehicleType := req.VehicleType
+		if vehicleType == "" {
+			vehicleType = "car"
+		}
+		slot, err := h.parking.ParkVisitor(context.Background(), visitor, plate, vehicleType, actor(c).ID, now)
+		switch {
+		case err == nil:
+			response["parking_slot"] = slot
+		case errors.Is(err, parking.ErrNoFreeSlot):
+			response["parking_error"] = "No visitor parking slot is free"
+		default:
+			log.Printf("⚠️ Failed to park visitor %s: %v", visitor.ID.Hex(), err)
+			response["parking_error"] = "Failed to allocate a parking slot"
+		}
+	}
+	return true
+}
+
+// findVisitor loads the :id visitor of the caller's society, writing the
+// error response itself. Residents only get their own visitors.
+func (h *VisitorHandler) findVisitor(c *gin.Context) (*models.Visitor, bool) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid visitor ID"})
-		return
+		return nil, false
 	}
 
-	collection := h.db.Collection("visitors")
-	var visitor models.Visitor
-	err = collection.FindOne(context.Background(), bson.M{"_id": objID}).Decode(&visitor)
+	visitor, err := h.store.Visitors.GetByID(context.Background(), c.GetString("society_code"), objID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Visitor not found"})
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Visitor not found in your society"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 		}
+		return nil, false
+	}
+	if c.GetString("user_role") == "resident" && visitor.HostID.Hex() != c.GetString("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not your visitor"})
+		return nil, false
+	}
+	return visitor, true
+}
+
+// normalizeVehicle checks an optional vehicle number given for a visitor
+// and rewrites it in normal form, writing the error response itself
+func normalizeVehicle(c *gin.Context, number *string) bool {
+	if *number == "" {
+		return true
+	}
+	plate, err := parking.NormalizePlate(*number)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "vehicle_number is not a valid Indian licence plate"})
+		return false
+	}
+	*number = plate
+	return true
+}
+
+// staffVisitor is a visitor as secretaries and security see it, with the
+// blacklist entries it matched. Residents, including the host, never see
+// the screening notes.
+type staffVisitor struct {
+	models.Visitor
+	Watchlist []models.BlacklistMatch `json:"watchlist,omitempty"`
+}
+
+// visitorView is the visitor as the caller may see it
+func visitorView(c *gin.Context, visitor *models.Visitor) interface{} {
+	if !isGateStaff(c) {
+		return visitor
+	}
+	return staffVisitor{Visitor: *visitor, Watchlist: visitor.Watchlist}
+}
+
+// visitorViews is visitorView for a list, never nil
+func visitorViews(c *gin.Context, list []models.Visitor) interface{} {
+	if !isGateStaff(c) {
+		if list == nil {
+			list = []models.Visitor{}
+		}
+		return list
+	}
+	views := make([]staffVisitor, 0, len(list))
+	for _, visitor := range list {
+		views = append(views, staffVisitor{Visitor: visitor, Watchlist: visitor.Watchlist})
+	}
+	return views
+}
+
+func isGateStaff(c *gin.Context) bool {
+	role := c.GetString("user_role")
+	return role == "secretary" || role == "security"
+}
+
+// actor is the logged-in user making a visitor status change
+func actor(c *gin.Context) visitors.Actor {
+	id, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	return visitors.Actor{ID: id, Role: c.GetString("user_role")}
+}
+
+// writeTransitionError answers a failed status change: illegal changes are
+// 409 with the visitor's current status
+func writeTransitionError(c *gin.Context, err error, message string) {
+	var transitionErr *visitors.TransitionError
+	if errors.As(err, &transitionErr) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":  "A " + transitionErr.From + " visitor cannot become " + transitionErr.To,
+			"status": transitionErr.From,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
+
+// GetVisitorByID returns one visitor. Residents only get their own
+// visitors, as the record carries the visitor's phone and pass.
+func (h *VisitorHandler) GetVisitorByID(c *gin.Context) {
+	visitor, ok := h.findVisitor(c)
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, visitor)
+	c.JSON(http.StatusOK, visitorView(c, visitor))
 }
@@ -0,0 +1,289 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Ledger posts the entries of each unit's account and allocates payments
+// to the unit's maintenance records. Every entry carries an idempotency key
+// derived from what it records, so posting the same event twice is a no-op.
+type Ledger struct {
+	store *store.Store
+}
+
+func New(s *store.Store) *Ledger {
+	return &Ledger{store: s}
+}
+
+// PostDue debits a new maintenance record and settles it from any advance
+// credit the unit already has.
+func (l *Ledger) PostDue(ctx context.Context, record *models.MaintenanceRecord) error {
+	err := l.post(ctx, models.LedgerEntry{
+		UnitID:         record.UnitID,
+		UnitNumber:     record.UnitNumber,
+		Type:           "debit",
+		Kind:           "maintenance_due",
+		Amount:         record.Amount,
+		Description:    "Maintenance " + record.Month,
+		ReferenceID:    record.ID,
+		PostedAt:       record.CreatedAt,
+		IdempotencyKey: "due:" + record.ID.Hex(),
+		SocietyID:      record.SocietyID,
+		SocietyCode:    record.SocietyCode,
+	})
+	if err != nil {
+		return err
+	}
+	return l.ApplyCredit(ctx, record.SocietyCode, record.UnitID, primitive.NilObjectID)
+}
+
+// PostPenalty debits a late fee
+func (l *Ledger) PostPenalty(ctx context.Context, penalty *models.MaintenancePenalty) error {
+	return l.post(ctx, models.LedgerEntry{
+		UnitID:         penalty.UnitID,
+		UnitNumber:     penalty.UnitNumber,
+		Type:           "debit",
+		Kind:           "late_fee",
+		Amount:         penalty.Amount,
+		Description:    penalty.Description,
+		ReferenceID:    penalty.ID,
+		PostedAt:       penalty.CreatedAt,
+		IdempotencyKey: "penalty:" + penalty.ID.Hex(),
+		SocietyID:      penalty.SocietyID,
+		SocietyCode:    penalty.SocietyCode,
+	})
+}
+
+// PostWaiver credits back a waived late fee. Whatever had been paid toward
+// the fee becomes credit again and is allocated to the unit's open records.
+func (l *Ledger) PostWaiver(ctx context.Context, penalty *models.MaintenancePenalty) error {
+	postedAt := time.Now()
+	if penalty.WaivedAt != nil {
+		postedAt = *penalty.WaivedAt
+	}
+	err := l.post(ctx, models.LedgerEntry{
+		UnitID:         penalty.UnitID,
+		UnitNumber:     penalty.UnitNumber,
+		Type:           "credit",
+		Kind:           "waiver",
+		Amount:         penalty.Amount,
+		Description:    "Waived: " + penalty.Description,
+		ReferenceID:    penalty.ID,
+		PostedAt:       postedAt,
+		IdempotencyKey: "waiver:" + penalty.ID.Hex(),
+		SocietyID:      penalty.SocietyID,
+		SocietyCode:    penalty.SocietyCode,
+	})
+	if err != nil {
+		return err
+	}
+	return l.ApplyCredit(ctx, penalty.SocietyCode, penalty.UnitID, primitive.NilObjectID)
+}
+
+// PostPayment credits a confirmed maintenance payment and allocates it,
+// first to the record it was made for and then to the unit's other open
+// records. Whatever is left over stays on the account as advance credit.
+func (l *Ledger) PostPayment(ctx context.Context, payment *models.Payment) error {
+	if payment.UnitID == nil {
+		return errors.New("ledger: payment has no unit")
+	}
+
+	postedAt := time.Now()
+	if payment.ConfirmedAt != nil {
+		postedAt = *payment.ConfirmedAt
+	}
+
+	unitNumber := ""
+	if unit, err := l.store.Units.GetByID(ctx, payment.SocietyCode, *payment.UnitID); err == nil {
+		unitNumber = unit.Number
+	}
+
+	err := l.post(ctx, models.LedgerEntry{
+		UnitID:         *payment.UnitID,
+		UnitNumber:     unitNumber,
+		Type:           "credit",
+		Kind:           "payment",
+		Amount:         payment.Amount,
+		Description:    "Payment " + payment.ProviderRef,
+		ReferenceID:    payment.ID,
+		PostedAt:       postedAt,
+		IdempotencyKey: "payment:" + payment.ID.Hex(),
+		SocietyID:      payment.SocietyID,
+		SocietyCode:    payment.SocietyCode,
+	})
+	if err != nil {
+		return err
+	}
+	return l.ApplyCredit(ctx, payment.SocietyCode, *payment.UnitID, payment.ReferenceID)
+}
+
+// AvailableCredit is what the unit has paid but not yet allocated to any
+// maintenance record. Late fees are settled from payments first, so the
+// credit is payments and waivers less late fees and what maintenance
+// records have been paid; it is negative while late fees are outstanding.
+func (l *Ledger) AvailableCredit(ctx context.Context, societyCode string, unitID primitive.ObjectID) (float64, error) {
+	totals, err := l.store.Ledger.SumByKind(ctx, store.LedgerFilter{
+		SocietyCode: societyCode,
+		UnitID:      unitID,
+		Kinds:       []string{"payment", "waiver", "late_fee"},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	records, err := l.store.Maintenance.List(ctx, store.MaintenanceFilter{
+		SocietyCode: societyCode,
+		UnitID:      unitID,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	credit := totals["payment"] + totals["waiver"] - totals["late_fee"]
+	for _, record := range records {
+		credit -= record.AmountPaid
+	}
+	return round(credit), nil
+}
+
+// maxCreditAttempts bounds how often an allocation that collided with
+// another is worked out again
+const maxCreditAttempts = 20
+
+// unitLocks holds a mutex per unit, shared by every Ledger in the process
+var unitLocks sync.Map
+
+// lockUnit serialises credit allocation for the unit within the process
+// and returns the unlock func
+func lockUnit(unitID primitive.ObjectID) func() {
+	mu, _ := unitLocks.LoadOrStore(unitID, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// ApplyCredit allocates the unit's available credit to its open records,
+// starting with first when given and then the oldest due date. A payment
+// and a new due posted together, even by different server instances,
+// cannot both spend the same credit: see allocateCredit. Allocations in
+// one process also wait for each other, which saves them the retries.
+func (l *Ledger) ApplyCredit(ctx context.Context, societyCode string, unitID, first primitive.ObjectID) error {
+	defer lockUnit(unitID)()
+	return l.applyCredit(ctx, societyCode, unitID, first)
+}
+
+// applyCredit runs allocateCredit until it does not collide with another
+// allocation
+func (l *Ledger) applyCredit(ctx context.Context, societyCode string, unitID, first primitive.ObjectID) error {
+	for attempt := 0; attempt < maxCreditAttempts; attempt++ {
+		err := l.allocateCredit(ctx, societyCode, unitID, first)
+		if !errors.Is(err, store.ErrConflict) {
+			return err
+		}
+	}
+	return fmt.Errorf("ledger: credit of unit %s kept changing: %w", unitID.Hex(), store.ErrConflict)
+}
+
+// allocation is an amount allocateCredit added to a record
+type allocation struct {
+	recordID primitive.ObjectID
+	amount   float64
+}
+
+// allocateCredit makes one attempt at allocating the unit's credit. Each
+// amount is added only while the record's amount_paid is what the credit
+// was worked out from, and the attempt commits by moving the unit's credit
+// version on from the one read first. If another allocation committed in
+// between, both may have spent the same credit, so this one is taken back
+// and store.ErrConflict returned for the caller to try again.
+func (l *Ledger) allocateCredit(ctx context.Context, societyCode string, unitID, first primitive.ObjectID) error {
+	unit, err := l.store.Units.GetByID(ctx, societyCode, unitID)
+	if err != nil {
+		return err
+	}
+
+	credit, err := l.AvailableCredit(ctx, societyCode, unitID)
+	if err != nil || credit <= 0 {
+		return err
+	}
+
+	records, err := l.store.Maintenance.List(ctx, store.MaintenanceFilter{
+		SocietyCode: societyCode,
+		UnitID:      unitID,
+		Statuses:    []string{"pending", "partially_paid", "overdue"},
+	})
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		if (records[i].ID == first) != (records[j].ID == first) {
+			return records[i].ID == first
+		}
+		return records[i].DueDate.Before(records[j].DueDate)
+	})
+
+	now := time.Now()
+	var allocated []allocation
+	for _, record := range records {
+		if credit <= 0 {
+			break
+		}
+		amount := round(math.Min(credit, record.Amount-record.AmountPaid))
+		if amount <= 0 {
+			continue
+		}
+		_, err := l.store.Maintenance.AddPayment(ctx, societyCode, record.ID, record.AmountPaid, amount, now)
+		if err != nil {
+			// ErrConflict: the record was paid concurrently
+			l.takeBack(ctx, societyCode, allocated, now)
+			return err
+		}
+		allocated = append(allocated, allocation{recordID: record.ID, amount: amount})
+		credit = round(credit - amount)
+	}
+	if len(allocated) == 0 {
+		return nil
+	}
+
+	if err := l.store.Units.BumpCreditVersion(ctx, societyCode, unitID, unit.CreditVersion); err != nil {
+		l.takeBack(ctx, societyCode, allocated, now)
+		return err
+	}
+	return nil
+}
+
+// takeBack undoes the allocations of an attempt that did not commit
+func (l *Ledger) takeBack(ctx context.Context, societyCode string, allocated []allocation, now time.Time) {
+	for _, a := range allocated {
+		if err := l.store.Maintenance.RemovePayment(ctx, societyCode, a.recordID, a.amount, now); err != nil {
+			log.Printf("⚠️ Ledger: failed to take back %.2f allocated to record %s: %v", a.amount, a.recordID.Hex(), err)
+		}
+	}
+}
+
+func (l *Ledger) post(ctx context.Context, entry models.LedgerEntry) error {
+	entry.CreatedAt = time.Now()
+	if entry.PostedAt.IsZero() {
+		entry.PostedAt = entry.CreatedAt
+	}
+	err := l.store.Ledger.Create(ctx, &entry)
+	if errors.Is(err, store.ErrDuplicate) {
+		return nil
+	}
+	return err
+}
+
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
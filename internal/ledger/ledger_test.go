@@ -0,0 +1,160 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Dues posted while a payment is being allocated must not spend the same
+// advance credit twice
+func TestApplyCreditConcurrentDues(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	l := New(s)
+	unit := &models.Unit{Number: "A-101", SocietyCode: "TEST01"}
+	if err := s.Units.Create(ctx, unit); err != nil {
+		t.Fatal(err)
+	}
+	unitID := unit.ID
+
+	const dues, amount, paid = 20, 1000.0, 1500.0
+	records := make([]models.MaintenanceRecord, dues)
+	for i := range records {
+		records[i] = models.MaintenanceRecord{
+			ID:          primitive.NewObjectID(),
+			UnitID:      unitID,
+			Amount:      amount,
+			Month:       fmt.Sprintf("2026-%02d", i+1),
+			Status:      "pending",
+			DueDate:     time.Date(2026, time.Month(i+1), 10, 0, 0, 0, 0, time.UTC),
+			SocietyCode: "TEST01",
+			CreatedAt:   time.Now(),
+		}
+		if err := s.Maintenance.Create(ctx, &records[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	payment := &models.Payment{ID: primitive.NewObjectID(), UnitID: &unitID, Amount: paid, SocietyCode: "TEST01"}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, dues+1)
+	wg.Add(dues + 1)
+	go func() {
+		defer wg.Done()
+		errs <- l.PostPayment(ctx, payment)
+	}()
+	for i := range records {
+		go func(record *models.MaintenanceRecord) {
+			defer wg.Done()
+			errs <- l.PostDue(ctx, record)
+		}(&records[i])
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	allocated := 0.0
+	for _, record := range records {
+		stored, err := s.Maintenance.GetByID(ctx, "TEST01", record.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		allocated += stored.AmountPaid
+	}
+	if allocated != paid {
+		t.Fatalf("allocated %.2f of a %.2f payment", allocated, paid)
+	}
+	credit, err := l.AvailableCredit(ctx, "TEST01", unitID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credit != 0 {
+		t.Fatalf("credit left = %.2f, want 0", credit)
+	}
+}
+
+// Server instances do not share the unit mutex; allocations running in
+// them at once must still not spend the same credit twice
+func TestApplyCreditAcrossInstances(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	l := New(s)
+	unit := &models.Unit{Number: "A-101", SocietyCode: "TEST01"}
+	if err := s.Units.Create(ctx, unit); err != nil {
+		t.Fatal(err)
+	}
+
+	const dues, amount, paid = 10, 1000.0, 2500.0
+	ids := make([]primitive.ObjectID, dues)
+	for i := range ids {
+		record := &models.MaintenanceRecord{
+			UnitID:      unit.ID,
+			Amount:      amount,
+			Month:       fmt.Sprintf("2026-%02d", i+1),
+			Status:      "pending",
+			DueDate:     time.Date(2026, time.Month(i+1), 10, 0, 0, 0, 0, time.UTC),
+			SocietyCode: "TEST01",
+		}
+		if err := s.Maintenance.Create(ctx, record); err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = record.ID
+	}
+	// The payment's credit entry, left for the instances to allocate
+	err := l.post(ctx, models.LedgerEntry{
+		UnitID:         unit.ID,
+		Type:           "credit",
+		Kind:           "payment",
+		Amount:         paid,
+		IdempotencyKey: "payment:test",
+		SocietyCode:    "TEST01",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const instances = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, instances)
+	wg.Add(instances)
+	for i := 0; i < instances; i++ {
+		// Each starts from a different record, as payments for different
+		// dues would
+		go func(first primitive.ObjectID) {
+			defer wg.Done()
+			// Bypasses the process-wide unit mutex, as another instance would
+			errs <- l.applyCredit(ctx, "TEST01", unit.ID, first)
+		}(ids[i%dues])
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := s.Maintenance.List(ctx, store.MaintenanceFilter{SocietyCode: "TEST01", UnitID: unit.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	allocated := 0.0
+	for _, record := range records {
+		allocated += record.AmountPaid
+	}
+	if allocated != paid {
+		t.Fatalf("allocated %.2f of a %.2f payment", allocated, paid)
+	}
+}
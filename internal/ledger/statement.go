@@ -0,0 +1,95 @@
+package ledger
+
+import (
+	"context"
+	"time"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/store"
+)
+
+// StatementLine is a ledger entry with the running balance after it
+type StatementLine struct {
+	models.LedgerEntry
+	Balance float64 `json:"balance"`
+}
+
+// Statement is a unit's account over [From, To). Balances are what the
+// unit owes; negative balances are advance credit.
+type Statement struct {
+	UnitID         string          `json:"unit_id"`
+	UnitNumber     string          `json:"unit_number"`
+	From           time.Time       `json:"from"`
+	To             time.Time       `json:"to"`
+	OpeningBalance float64         `json:"opening_balance"`
+	Transactions   []StatementLine `json:"transactions"`
+	TotalDebits    float64         `json:"total_debits"`
+	TotalCredits   float64         `json:"total_credits"`
+	ClosingBalance float64         `json:"closing_balance"`
+}
+
+// Statement builds the unit's statement for [from, to)
+func (l *Ledger) Statement(ctx context.Context, unit *models.Unit, from, to time.Time) (*Statement, error) {
+	var opening float64
+	if !from.IsZero() {
+		var err error
+		if opening, err = l.Balance(ctx, unit, from); err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err := l.store.Ledger.List(ctx, store.LedgerFilter{
+		SocietyCode:  unit.SocietyCode,
+		UnitID:       unit.ID,
+		PostedFrom:   from,
+		PostedBefore: to,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	statement := &Statement{
+		UnitID:         unit.ID.Hex(),
+		UnitNumber:     unit.Number,
+		From:           from,
+		To:             to,
+		OpeningBalance: opening,
+		Transactions:   make([]StatementLine, 0, len(entries)),
+	}
+
+	balance := opening
+	for _, entry := range entries {
+		if entry.Type == "debit" {
+			balance += entry.Amount
+			statement.TotalDebits += entry.Amount
+		} else {
+			balance -= entry.Amount
+			statement.TotalCredits += entry.Amount
+		}
+		statement.Transactions = append(statement.Transactions, StatementLine{
+			LedgerEntry: entry,
+			Balance:     round(balance),
+		})
+	}
+
+	statement.TotalDebits = round(statement.TotalDebits)
+	statement.TotalCredits = round(statement.TotalCredits)
+	statement.ClosingBalance = round(balance)
+	return statement, nil
+}
+
+// Balance is what the unit owes from entries posted before the given time;
+// a zero time means all entries
+func (l *Ledger) Balance(ctx context.Context, unit *models.Unit, before time.Time) (float64, error) {
+	totals, err := l.store.Ledger.SumByKind(ctx, store.LedgerFilter{
+		SocietyCode:  unit.SocietyCode,
+		UnitID:       unit.ID,
+		PostedBefore: before,
+	})
+	if err != nil {
+		return 0, err
+	}
+	debits := totals["maintenance_due"] + totals["late_fee"]
+	credits := totals["payment"] + totals["waiver"]
+	return round(debits - credits), nil
+}
@@ -72,6 +72,20 @@ func RequireRole(roles ...string) gin.HandlerFunc {
 	}
 }
 
+// RequireSociety rejects tokens without a society context, such as platform
+// admin tokens, so society-scoped routes never run unfiltered queries.
+func RequireSociety() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("society_code") == "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Society context required"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // Society-aware data filtering helper
 func GetSocietyFilter(c *gin.Context) map[string]interface{} {
 	societyCode := c.GetString("society_code")
@@ -25,11 +25,41 @@ type Society struct {
 type Building struct {
 	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Name          string            `bson:"name" json:"name"`
+	Code          string            `bson:"code,omitempty" json:"code,omitempty"` // Prefix of its unit numbers, unique in the society
 	Floors        int               `bson:"floors" json:"floors"`
 	UnitsPerFloor int               `bson:"units_per_floor" json:"units_per_floor"`
 	SocietyID     primitive.ObjectID `bson:"society_id" json:"society_id"`
 }
 
+// Unit is a single flat inside a Building. Units are usually generated from
+// the building's Floors and UnitsPerFloor rather than created by hand.
+type Unit struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Number       string              `bson:"number" json:"number"` // e.g. A-501
+	BuildingID   primitive.ObjectID  `bson:"building_id" json:"building_id"`
+	BuildingName string              `bson:"building_name" json:"building_name"`
+	Floor        int                 `bson:"floor" json:"floor"`
+	AreaSqFt     float64             `bson:"area_sq_ft" json:"area_sq_ft"`
+	OwnerID      *primitive.ObjectID `bson:"owner_id,omitempty" json:"owner_id,omitempty"`
+	TenantID     *primitive.ObjectID `bson:"tenant_id,omitempty" json:"tenant_id,omitempty"`
+	Occupancy    []UnitOccupancy     `bson:"occupancy" json:"occupancy"` // Move-in/move-out history
+	SocietyID    primitive.ObjectID  `bson:"society_id" json:"society_id"`
+	SocietyCode  string              `bson:"society_code" json:"society_code"`
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time           `bson:"updated_at" json:"updated_at"`
+	// CreditVersion moves on with every allocation of the unit's advance
+	// credit, so allocations running at once can tell they collided
+	CreditVersion int64 `bson:"credit_version" json:"-"`
+}
+
+type UnitOccupancy struct {
+	UserID   primitive.ObjectID `bson:"user_id" json:"user_id"`
+	UserName string             `bson:"user_name" json:"user_name"`
+	Role     string             `bson:"role" json:"role"` // owner, tenant
+	MovedIn  time.Time          `bson:"moved_in" json:"moved_in"`
+	MovedOut *time.Time         `bson:"moved_out,omitempty" json:"moved_out,omitempty"`
+}
+
 type User struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Name      string            `bson:"name" json:"name" binding:"required"`
@@ -37,6 +67,7 @@ type User struct {
 	Password  string            `bson:"password" json:"-"`
 	Role      string            `bson:"role" json:"role" binding:"required"`
 	Unit      string            `bson:"unit" json:"unit"`
+	UnitID    *primitive.ObjectID `bson:"unit_id,omitempty" json:"unit_id,omitempty"` // Link to the occupied unit
 	Building  string            `bson:"building" json:"building"`
 	Phone     string            `bson:"phone" json:"phone"`
 	Avatar    string            `bson:"avatar" json:"avatar"`
@@ -55,13 +86,26 @@ type Visitor struct {
 	HostID          primitive.ObjectID  `bson:"host_id" json:"host_id"`
 	HostName        string             `bson:"host_name" json:"host_name"`
 	HostUnit        string             `bson:"host_unit" json:"host_unit"`
+	HostUnitID      *primitive.ObjectID `bson:"host_unit_id,omitempty" json:"host_unit_id,omitempty"`
 	ExpectedTime    time.Time          `bson:"expected_time" json:"expected_time"`
 	ActualArrival   *time.Time         `bson:"actual_arrival,omitempty" json:"actual_arrival,omitempty"`
 	ActualDeparture *time.Time         `bson:"actual_departure,omitempty" json:"actual_departure,omitempty"`
-	QRCode          string             `bson:"qr_code" json:"qr_code"`
-	Status          string             `bson:"status" json:"status"` // pending, approved, rejected, completed
+	OverstayedAt    *time.Time         `bson:"overstayed_at,omitempty" json:"overstayed_at,omitempty"` // When the visitor was flagged for staying past the expected duration
+	QRCode          string             `bson:"qr_code" json:"qr_code"` // Signed visitor pass
+	SingleUse       bool               `bson:"single_use" json:"single_use"`
+	PassValidFrom   time.Time          `bson:"pass_valid_from" json:"pass_valid_from"`
+	PassExpiresAt   time.Time          `bson:"pass_expires_at" json:"pass_expires_at"`
+	PassUsedAt      *time.Time         `bson:"pass_used_at,omitempty" json:"pass_used_at,omitempty"`
+	Status          string             `bson:"status" json:"status"` // pending, approved, rejected, checked_in, completed, expired, cancelled
+	History         []VisitorStatusChange `bson:"history,omitempty" json:"history,omitempty"`
+	WalkIn          bool               `bson:"walk_in" json:"walk_in"` // Registered at the gate by security
+	HostApproval    *HostApproval      `bson:"host_approval,omitempty" json:"host_approval,omitempty"`
+	Watchlist       []BlacklistMatch   `bson:"watchlist,omitempty" json:"-"` // Blacklist entries the visitor matched when registered; only shown to secretaries and security
 	VehicleNumber   string             `bson:"vehicle_number,omitempty" json:"vehicle_number,omitempty"`
+	ParkingSlotID   *primitive.ObjectID `bson:"parking_slot_id,omitempty" json:"parking_slot_id,omitempty"` // Visitor slot held while checked in
+	ParkingSlot     string             `bson:"parking_slot,omitempty" json:"parking_slot,omitempty"`
 	PhotoURL        string             `bson:"photo_url,omitempty" json:"photo_url,omitempty"`
+	IDProofURL      string             `bson:"id_proof_url,omitempty" json:"id_proof_url,omitempty"` // Only secretaries and security can download it
 	ApprovedBy      *primitive.ObjectID `bson:"approved_by,omitempty" json:"approved_by,omitempty"`
 	SocietyID       primitive.ObjectID  `bson:"society_id" json:"society_id"`       // Link to society
 	SocietyCode     string             `bson:"society_code" json:"society_code"`   // Society access code
@@ -69,22 +113,410 @@ type Visitor struct {
 	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
 }
 
+// VisitorStatusChange records one step of a visitor through the status
+// state machine. From is empty for the visitor's creation.
+type VisitorStatusChange struct {
+	From   string              `bson:"from,omitempty" json:"from,omitempty"`
+	To     string              `bson:"to" json:"to"`
+	ByID   *primitive.ObjectID `bson:"by_id,omitempty" json:"by_id,omitempty"` // Empty for automatic changes
+	ByRole string              `bson:"by_role" json:"by_role"`                   // resident, secretary, security or system
+	Reason string              `bson:"reason,omitempty" json:"reason,omitempty"`
+	At     time.Time           `bson:"at" json:"at"`
+}
+
+// HostApproval tracks a walk-in waiting for the host unit's residents. If
+// nobody answers by ExpiresAt it is escalated to the secretaries, and the
+// guard is told to call the host.
+type HostApproval struct {
+	RequestedBy primitive.ObjectID   `bson:"requested_by" json:"requested_by"`
+	RequestedAt time.Time            `bson:"requested_at" json:"requested_at"`
+	ExpiresAt   time.Time            `bson:"expires_at" json:"expires_at"`
+	NotifiedIDs []primitive.ObjectID `bson:"notified_ids" json:"notified_ids"` // Residents asked to approve
+	EscalatedAt *time.Time           `bson:"escalated_at,omitempty" json:"escalated_at,omitempty"`
+}
+
+// VisitorPass is what a guard sees when scanning a pass: enough to let the
+// visitor in, without their phone number
+type VisitorPass struct {
+	VisitorID     primitive.ObjectID `json:"visitor_id"`
+	Name          string             `json:"name"`
+	Purpose       string             `json:"purpose"`
+	HostName      string             `json:"host_name"`
+	HostUnit      string             `json:"host_unit"`
+	ExpectedTime  time.Time          `json:"expected_time"`
+	Status        string             `json:"status"`
+	VehicleNumber string             `json:"vehicle_number,omitempty"`
+	PhotoURL      string             `json:"photo_url,omitempty"`
+	ValidFrom     time.Time          `json:"valid_from"`
+	ExpiresAt     time.Time          `json:"expires_at"`
+	SingleUse     bool               `json:"single_use"`
+	UsedAt        *time.Time         `json:"used_at,omitempty"`
+	ParkingSlot   string             `json:"parking_slot,omitempty"`
+}
+
+// BlacklistEntry flags a person security should not admit, or should
+// admit only with care. A person is matched on any of phone, vehicle
+// number and name; the keys are normalised so formatting does not matter.
+type BlacklistEntry struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name          string             `bson:"name,omitempty" json:"name,omitempty"`
+	Phone         string             `bson:"phone,omitempty" json:"phone,omitempty"`
+	VehicleNumber string             `bson:"vehicle_number,omitempty" json:"vehicle_number,omitempty"`
+	NameKey       string             `bson:"name_key,omitempty" json:"-"`
+	PhoneKey      string             `bson:"phone_key,omitempty" json:"-"`
+	VehicleKey    string             `bson:"vehicle_key,omitempty" json:"-"`
+	Action        string             `bson:"action" json:"action"` // block or warn
+	Reason        string             `bson:"reason" json:"reason"`
+	IsActive      bool               `bson:"is_active" json:"is_active"`
+	AddedBy       primitive.ObjectID `bson:"added_by" json:"added_by"`
+	AddedByName   string             `bson:"added_by_name" json:"added_by_name"`
+	History       []BlacklistChange  `bson:"history" json:"history"`
+	SocietyID     primitive.ObjectID `bson:"society_id" json:"society_id"`
+	SocietyCode   string             `bson:"society_code" json:"society_code"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// BlacklistChange is one line of an entry's audit trail: who added,
+// changed or removed it, and why
+type BlacklistChange struct {
+	Change string             `bson:"change" json:"change"` // added, updated or removed
+	ByID   primitive.ObjectID `bson:"by_id" json:"by_id"`
+	ByName string             `bson:"by_name" json:"by_name"`
+	ByRole string             `bson:"by_role" json:"by_role"`
+	Reason string             `bson:"reason" json:"reason"`
+	At     time.Time          `bson:"at" json:"at"`
+}
+
+// BlacklistMatch is a blacklist entry a person matched, and on what
+type BlacklistMatch struct {
+	EntryID primitive.ObjectID `bson:"entry_id" json:"entry_id"`
+	Fields  []string           `bson:"fields" json:"fields"` // phone, vehicle_number, name
+	Action  string             `bson:"action" json:"action"` // block or warn; name-only matches only warn
+	Reason  string             `bson:"reason" json:"reason"`
+}
+
+// Staff is a domestic worker or regular delivery person registered with
+// the society, such as a maid, driver, cook or tutor. Staff serve one or
+// more units and enter on a long-lived pass within their allowed hours.
+type Staff struct {
+	ID            primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Name          string               `bson:"name" json:"name"`
+	Phone         string               `bson:"phone" json:"phone"`
+	Category      string               `bson:"category" json:"category"` // maid, driver, cook, tutor, delivery, other
+	UnitIDs       []primitive.ObjectID `bson:"unit_ids" json:"unit_ids"`
+	Units         []string             `bson:"units" json:"units"` // Unit numbers, in UnitIDs order
+	Hours         []OpeningHours       `bson:"hours,omitempty" json:"hours,omitempty"` // When entry is allowed; any time when empty
+	Timezone      string               `bson:"timezone" json:"timezone"`
+	PhotoURL      string               `bson:"photo_url,omitempty" json:"photo_url,omitempty"`
+	IDDocument    *IDDocument          `bson:"id_document,omitempty" json:"id_document,omitempty"`
+	PassToken     string               `bson:"pass_token" json:"pass_token"`
+	PassExpiresAt time.Time            `bson:"pass_expires_at" json:"pass_expires_at"`
+	IsActive      bool                 `bson:"is_active" json:"is_active"`
+	CreatedBy     primitive.ObjectID   `bson:"created_by" json:"created_by"`
+	SocietyID     primitive.ObjectID   `bson:"society_id" json:"society_id"`
+	SocietyCode   string               `bson:"society_code" json:"society_code"`
+	CreatedAt     time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time            `bson:"updated_at" json:"updated_at"`
+}
+
+// IDDocument is the identity proof kept on file for a staff member
+type IDDocument struct {
+	Type   string `bson:"type" json:"type" binding:"required"` // e.g. aadhaar, pan, driving_licence
+	Number string `bson:"number" json:"number" binding:"required"`
+	URL    string `bson:"url,omitempty" json:"url,omitempty"`
+}
+
+// StaffAttendance is one visit by a staff member, from check-in at the
+// gate to check-out. Inside is true until they leave.
+type StaffAttendance struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	StaffID      primitive.ObjectID  `bson:"staff_id" json:"staff_id"`
+	StaffName    string              `bson:"staff_name" json:"staff_name"`
+	Date         string              `bson:"date" json:"date"` // YYYY-MM-DD of the check-in, in the staff member's timezone
+	CheckIn      time.Time           `bson:"check_in" json:"check_in"`
+	CheckOut     *time.Time          `bson:"check_out,omitempty" json:"check_out,omitempty"`
+	Inside       bool                `bson:"inside" json:"inside"`
+	CheckedInBy  primitive.ObjectID  `bson:"checked_in_by" json:"checked_in_by"`
+	CheckedOutBy *primitive.ObjectID `bson:"checked_out_by,omitempty" json:"checked_out_by,omitempty"`
+	SocietyCode  string              `bson:"society_code" json:"society_code"`
+}
+
+// Parcel is a delivery logged at the security desk for a unit. It waits at
+// the desk until someone from the unit collects it with the OTP the
+// residents were sent, or signs for it.
+type Parcel struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UnitID         primitive.ObjectID `bson:"unit_id" json:"unit_id"`
+	UnitNumber     string             `bson:"unit_number" json:"unit_number"`
+	Courier        string             `bson:"courier" json:"courier"`
+	TrackingNumber string             `bson:"tracking_number,omitempty" json:"tracking_number,omitempty"`
+	Description    string             `bson:"description,omitempty" json:"description,omitempty"`
+	PhotoURL       string             `bson:"photo_url,omitempty" json:"photo_url,omitempty"`
+	Status         string             `bson:"status" json:"status"` // received, collected, returned
+	ReceivedAt     time.Time          `bson:"received_at" json:"received_at"`
+	ReceivedBy     primitive.ObjectID `bson:"received_by" json:"received_by"`
+	OTPHash        string             `bson:"otp_hash" json:"-"`
+	OTPAttempts    int                `bson:"otp_attempts" json:"-"` // Wrong OTPs entered since the last one was issued
+	Collection     *ParcelCollection  `bson:"collection,omitempty" json:"collection,omitempty"`
+	ReturnedAt     *time.Time         `bson:"returned_at,omitempty" json:"returned_at,omitempty"`
+	ReturnReason   string             `bson:"return_reason,omitempty" json:"return_reason,omitempty"`
+	RemindersSent  int                `bson:"reminders_sent" json:"reminders_sent"`
+	NextReminderAt *time.Time         `bson:"next_reminder_at,omitempty" json:"next_reminder_at,omitempty"`
+	SocietyID      primitive.ObjectID `bson:"society_id" json:"society_id"`
+	SocietyCode    string             `bson:"society_code" json:"society_code"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// ParcelCollection records who took a parcel from the desk and how they
+// proved they could
+type ParcelCollection struct {
+	CollectedAt  time.Time          `bson:"collected_at" json:"collected_at"`
+	CollectedBy  string             `bson:"collected_by" json:"collected_by"` // Name of whoever picked it up
+	Method       string             `bson:"method" json:"method"`             // otp or signature
+	SignatureURL string             `bson:"signature_url,omitempty" json:"signature_url,omitempty"`
+	HandedOverBy primitive.ObjectID `bson:"handed_over_by" json:"handed_over_by"`
+}
+
+// Vehicle is a resident vehicle registered to a unit. Number is the plate
+// as entered; Plate is its normalised form, which gate security looks it
+// up by.
+type Vehicle struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UnitID      primitive.ObjectID  `bson:"unit_id" json:"unit_id"`
+	UnitNumber  string              `bson:"unit_number" json:"unit_number"`
+	Number      string              `bson:"number" json:"number"`
+	Plate       string              `bson:"plate" json:"plate"`
+	Type        string              `bson:"type" json:"type"` // car, bike, other
+	Make        string              `bson:"make,omitempty" json:"make,omitempty"`
+	Model       string              `bson:"model,omitempty" json:"model,omitempty"`
+	Color       string              `bson:"color,omitempty" json:"color,omitempty"`
+	SlotID      *primitive.ObjectID `bson:"slot_id,omitempty" json:"slot_id,omitempty"` // One of the unit's assigned slots
+	SlotLabel   string              `bson:"slot_label,omitempty" json:"slot_label,omitempty"`
+	IsActive    bool                `bson:"is_active" json:"is_active"`
+	CreatedBy   primitive.ObjectID  `bson:"created_by" json:"created_by"`
+	SocietyID   primitive.ObjectID  `bson:"society_id" json:"society_id"`
+	SocietyCode string              `bson:"society_code" json:"society_code"`
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// ParkingSlot is one parking space in a building. Assigned slots belong to
+// a unit; visitor slots are handed out to visitor vehicles at check-in.
+type ParkingSlot struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	BuildingID   primitive.ObjectID  `bson:"building_id" json:"building_id"`
+	BuildingName string              `bson:"building_name" json:"building_name"`
+	Label        string              `bson:"label" json:"label"` // e.g. B1-12, unique in the building
+	Kind         string              `bson:"kind" json:"kind"`   // assigned, visitor
+	VehicleType  string              `bson:"vehicle_type" json:"vehicle_type"` // car, bike
+	UnitID       *primitive.ObjectID `bson:"unit_id,omitempty" json:"unit_id,omitempty"` // Assigned slots only
+	UnitNumber   string              `bson:"unit_number,omitempty" json:"unit_number,omitempty"`
+	Occupant     *ParkingOccupant    `bson:"occupant,omitempty" json:"occupant,omitempty"` // Visitor slots only
+	IsActive     bool                `bson:"is_active" json:"is_active"`
+	SocietyID    primitive.ObjectID  `bson:"society_id" json:"society_id"`
+	SocietyCode  string              `bson:"society_code" json:"society_code"`
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// ParkingOccupant is the visitor vehicle parked in a visitor slot. It
+// overstays once ParkUntil passes while the visitor is still inside.
+type ParkingOccupant struct {
+	VisitorID   primitive.ObjectID `bson:"visitor_id" json:"visitor_id"`
+	VisitorName string             `bson:"visitor_name" json:"visitor_name"`
+	HostUnit    string             `bson:"host_unit" json:"host_unit"`
+	Plate       string             `bson:"plate" json:"plate"`
+	ParkedAt    time.Time          `bson:"parked_at" json:"parked_at"`
+	ParkUntil   time.Time          `bson:"park_until" json:"park_until"`
+	AlertedAt   *time.Time         `bson:"alerted_at,omitempty" json:"alerted_at,omitempty"` // When the overstay was reported
+}
+
+// VehicleLog is one visit by a vehicle, from entry at the gate to exit.
+// Inside is true until it leaves.
+type VehicleLog struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Plate       string              `bson:"plate" json:"plate"`
+	Kind        string              `bson:"kind" json:"kind"` // resident, visitor, unregistered
+	VehicleID   *primitive.ObjectID `bson:"vehicle_id,omitempty" json:"vehicle_id,omitempty"`
+	VisitorID   *primitive.ObjectID `bson:"visitor_id,omitempty" json:"visitor_id,omitempty"`
+	UnitNumber  string              `bson:"unit_number,omitempty" json:"unit_number,omitempty"`
+	EnteredAt   time.Time           `bson:"entered_at" json:"entered_at"`
+	ExitedAt    *time.Time          `bson:"exited_at,omitempty" json:"exited_at,omitempty"`
+	Inside      bool                `bson:"inside" json:"inside"`
+	EnteredBy   primitive.ObjectID  `bson:"entered_by" json:"entered_by"`
+	ExitedBy    *primitive.ObjectID `bson:"exited_by,omitempty" json:"exited_by,omitempty"`
+	SocietyCode string              `bson:"society_code" json:"society_code"`
+}
+
+// Upload is a file a user uploaded, such as a visitor photo or an amenity
+// image. Images also get a JPEG thumbnail.
+type Upload struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Kind         string             `bson:"kind" json:"kind"`         // visitor_photo, visitor_id_proof, amenity_image, avatar
+	OwnerID      primitive.ObjectID `bson:"owner_id" json:"owner_id"` // Visitor, amenity or user the file belongs to
+	FileName     string             `bson:"file_name" json:"file_name"`
+	ContentType  string             `bson:"content_type" json:"content_type"` // Sniffed from the contents, not taken from the client
+	Size         int64              `bson:"size" json:"size"`
+	Width        int                `bson:"width,omitempty" json:"width,omitempty"`
+	Height       int                `bson:"height,omitempty" json:"height,omitempty"`
+	URL          string             `bson:"url" json:"url"`
+	ThumbnailURL string             `bson:"thumbnail_url,omitempty" json:"thumbnail_url,omitempty"`
+	StorageKey   string             `bson:"storage_key" json:"-"`
+	ThumbnailKey string             `bson:"thumbnail_key,omitempty" json:"-"`
+	UploadedBy   primitive.ObjectID `bson:"uploaded_by" json:"uploaded_by"`
+	SocietyCode  string             `bson:"society_code" json:"society_code"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+}
+
 type MaintenanceRecord struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	UnitID      primitive.ObjectID `bson:"unit_id" json:"unit_id"`
 	UnitNumber  string            `bson:"unit_number" json:"unit_number"`
 	Amount      float64           `bson:"amount" json:"amount"`
+	AmountPaid  float64           `bson:"amount_paid" json:"amount_paid"` // Sum of payments and credit allocated so far
 	Month       string            `bson:"month" json:"month"`
 	DueDate     time.Time         `bson:"due_date" json:"due_date"`
 	PaidDate    *time.Time        `bson:"paid_date,omitempty" json:"paid_date,omitempty"`
-	Status      string            `bson:"status" json:"status"` // pending, paid, overdue
+	Status      string            `bson:"status" json:"status"` // pending, partially_paid, paid, overdue
 	Description string            `bson:"description" json:"description"`
-	ReceiptURL  string            `bson:"receipt_url,omitempty" json:"receipt_url,omitempty"`
+	ReceiptURL  string            `bson:"receipt_url,omitempty" json:"receipt_url,omitempty"` // Latest payment receipt
+	InvoiceURL  string            `bson:"invoice_url,omitempty" json:"invoice_url,omitempty"`
+	BillingCycle   string         `bson:"billing_cycle,omitempty" json:"billing_cycle,omitempty"`     // YYYY-MM, set by the billing engine
+	IdempotencyKey string         `bson:"idempotency_key,omitempty" json:"idempotency_key,omitempty"` // One record per unit per cycle
 	SocietyID   primitive.ObjectID `bson:"society_id" json:"society_id"`       // Link to society
 	SocietyCode string            `bson:"society_code" json:"society_code"`   // Society access code
 	CreatedAt   time.Time         `bson:"created_at" json:"created_at"`
 }
 
+// LedgerEntry is one line of a unit's account. Dues and late fees are
+// debits, payments and waivers are credits, so the balance (debits minus
+// credits) is what the unit owes; a negative balance is advance credit.
+type LedgerEntry struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UnitID         primitive.ObjectID `bson:"unit_id" json:"unit_id"`
+	UnitNumber     string             `bson:"unit_number" json:"unit_number"`
+	Type           string             `bson:"type" json:"type"` // debit, credit
+	Kind           string             `bson:"kind" json:"kind"` // maintenance_due, late_fee, payment, waiver
+	Amount         float64            `bson:"amount" json:"amount"`
+	Description    string             `bson:"description" json:"description"`
+	ReferenceID    primitive.ObjectID `bson:"reference_id" json:"reference_id"` // Record, penalty or payment behind the entry
+	PostedAt       time.Time          `bson:"posted_at" json:"posted_at"`
+	IdempotencyKey string             `bson:"idempotency_key" json:"-"`
+	SocietyID      primitive.ObjectID `bson:"society_id" json:"society_id"`
+	SocietyCode    string             `bson:"society_code" json:"society_code"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// BillingDocument is a generated PDF: an invoice for a maintenance record
+// or a receipt for a confirmed payment. Numbers run per society and kind.
+type BillingDocument struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Kind          string              `bson:"kind" json:"kind"` // invoice, receipt
+	Number        string              `bson:"number" json:"number"`     // e.g. RCT-GREEN001-000042
+	Sequence      int64               `bson:"sequence" json:"sequence"` // Per society and kind, from 1
+	ReferenceID   primitive.ObjectID  `bson:"reference_id" json:"reference_id"` // Record for invoices, payment for receipts
+	MaintenanceID *primitive.ObjectID `bson:"maintenance_id,omitempty" json:"maintenance_id,omitempty"`
+	PaymentID     *primitive.ObjectID `bson:"payment_id,omitempty" json:"payment_id,omitempty"`
+	UnitID        *primitive.ObjectID `bson:"unit_id,omitempty" json:"unit_id,omitempty"`
+	UnitNumber    string              `bson:"unit_number,omitempty" json:"unit_number,omitempty"`
+	UserID        *primitive.ObjectID `bson:"user_id,omitempty" json:"user_id,omitempty"` // Payer, for receipts
+	Amount        float64             `bson:"amount" json:"amount"`
+	StorageKey    string              `bson:"storage_key" json:"-"`
+	IssuedAt      time.Time           `bson:"issued_at" json:"issued_at"`
+	SocietyID     primitive.ObjectID  `bson:"society_id" json:"society_id"`
+	SocietyCode   string              `bson:"society_code" json:"society_code"`
+}
+
+// Payment tracks one attempt to collect money through a payment provider.
+// The record or booking it pays for only changes once the provider
+// confirms the payment.
+type Payment struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Purpose       string              `bson:"purpose" json:"purpose"`           // maintenance, amenity_booking
+	ReferenceID   primitive.ObjectID  `bson:"reference_id" json:"reference_id"` // Maintenance record or booking being paid
+	UserID        primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	UnitID        *primitive.ObjectID `bson:"unit_id,omitempty" json:"unit_id,omitempty"`
+	Amount        float64             `bson:"amount" json:"amount"`
+	Currency      string              `bson:"currency" json:"currency"`
+	Method        string              `bson:"method,omitempty" json:"method,omitempty"`
+	Provider      string              `bson:"provider" json:"provider"`
+	ProviderRef   string              `bson:"provider_ref" json:"provider_ref"`
+	CheckoutURL   string              `bson:"checkout_url,omitempty" json:"checkout_url,omitempty"`
+	Status        string              `bson:"status" json:"status"` // pending, succeeded, failed, expired, refund_pending, refunded
+	FailureReason string              `bson:"failure_reason,omitempty" json:"failure_reason,omitempty"`
+	ExpiresAt     *time.Time          `bson:"expires_at,omitempty" json:"expires_at,omitempty"` // A pending payment is given up after this
+	ConfirmedAt   *time.Time          `bson:"confirmed_at,omitempty" json:"confirmed_at,omitempty"`
+	RefundAmount  float64             `bson:"refund_amount,omitempty" json:"refund_amount,omitempty"` // May be less than Amount
+	RefundRef     string              `bson:"refund_ref,omitempty" json:"refund_ref,omitempty"`
+	RefundedAt    *time.Time          `bson:"refunded_at,omitempty" json:"refunded_at,omitempty"`
+	SocietyID     primitive.ObjectID  `bson:"society_id" json:"society_id"`
+	SocietyCode   string              `bson:"society_code" json:"society_code"`
+	CreatedAt     time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// BillingPlan describes how a society's monthly maintenance is computed.
+// Each society has at most one plan.
+type BillingPlan struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Type          string             `bson:"type" json:"type"` // flat, per_sq_ft, per_building
+	FlatAmount    float64            `bson:"flat_amount" json:"flat_amount"`
+	RatePerSqFt   float64            `bson:"rate_per_sq_ft" json:"rate_per_sq_ft"`
+	BuildingRates []BuildingRate     `bson:"building_rates" json:"building_rates"`
+	DueDay        int                `bson:"due_day" json:"due_day"` // Day of the month dues fall due
+	Description   string             `bson:"description" json:"description"`
+	IsActive      bool               `bson:"is_active" json:"is_active"`
+	SocietyID     primitive.ObjectID `bson:"society_id" json:"society_id"`
+	SocietyCode   string             `bson:"society_code" json:"society_code"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type BuildingRate struct {
+	BuildingID primitive.ObjectID `bson:"building_id" json:"building_id"`
+	Amount     float64            `bson:"amount" json:"amount"`
+}
+
+// LateFeeRule configures the penalty a society charges on overdue dues.
+// Amount is a fixed sum for "fixed", and a percentage of the due for
+// "percentage" (charged once) and "daily_interest" (charged every day).
+type LateFeeRule struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Type        string             `bson:"type" json:"type"` // fixed, percentage, daily_interest
+	Amount      float64            `bson:"amount" json:"amount"`
+	GraceDays   int                `bson:"grace_days" json:"grace_days"`     // Days after the due date before any penalty
+	MaxPenalty  float64            `bson:"max_penalty" json:"max_penalty"`   // Cap per record, 0 means no cap
+	IsActive    bool               `bson:"is_active" json:"is_active"`
+	SocietyID   primitive.ObjectID `bson:"society_id" json:"society_id"`
+	SocietyCode string             `bson:"society_code" json:"society_code"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// MaintenancePenalty is a late fee charged on a maintenance record. It is
+// kept as its own line item, never folded into the record's amount, so
+// every penalty stays auditable and can be waived individually.
+type MaintenancePenalty struct {
+	ID             primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	MaintenanceID  primitive.ObjectID  `bson:"maintenance_id" json:"maintenance_id"`
+	UnitID         primitive.ObjectID  `bson:"unit_id" json:"unit_id"`
+	UnitNumber     string              `bson:"unit_number" json:"unit_number"`
+	RuleType       string              `bson:"rule_type" json:"rule_type"`
+	Amount         float64             `bson:"amount" json:"amount"`
+	Description    string              `bson:"description" json:"description"`
+	AccruedOn      time.Time           `bson:"accrued_on" json:"accrued_on"` // Day the penalty is charged for
+	Status         string              `bson:"status" json:"status"`         // applied, waived
+	WaivedBy       *primitive.ObjectID `bson:"waived_by,omitempty" json:"waived_by,omitempty"`
+	WaivedAt       *time.Time          `bson:"waived_at,omitempty" json:"waived_at,omitempty"`
+	WaiveReason    string              `bson:"waive_reason,omitempty" json:"waive_reason,omitempty"`
+	IdempotencyKey string              `bson:"idempotency_key" json:"-"`
+	SocietyID      primitive.ObjectID  `bson:"society_id" json:"society_id"`
+	SocietyCode    string              `bson:"society_code" json:"society_code"`
+	CreatedAt      time.Time           `bson:"created_at" json:"created_at"`
+}
+
 type Amenity struct {
 	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Name           string            `bson:"name" json:"name" binding:"required"`
@@ -92,29 +524,147 @@ type Amenity struct {
 	BookingFee     float64           `bson:"booking_fee" json:"booking_fee"`
 	Capacity       int               `bson:"capacity" json:"capacity"`
 	Facilities     []string          `bson:"facilities" json:"facilities"`
-	AvailableHours string            `bson:"available_hours" json:"available_hours"`
+	AvailableHours string            `bson:"available_hours" json:"available_hours"` // Free text for display
+	Schedule       *AmenitySchedule  `bson:"schedule,omitempty" json:"schedule,omitempty"` // Bookable slots; a default applies when unset
+	Policy         *BookingPolicy    `bson:"policy,omitempty" json:"policy,omitempty"`     // Booking rules; no limits when unset
 	Images         []string          `bson:"images,omitempty" json:"images,omitempty"`
 	SocietyID      primitive.ObjectID `bson:"society_id" json:"society_id"`       // Link to society
 	SocietyCode    string            `bson:"society_code" json:"society_code"`   // Society access code
 	IsActive       bool              `bson:"is_active" json:"is_active"`
 }
 
+// AmenitySchedule defines when an amenity can be booked. Each opening
+// window is cut into slots of SlotMinutes with BufferMinutes between them.
+type AmenitySchedule struct {
+	Timezone      string         `bson:"timezone" json:"timezone"` // IANA name, e.g. Asia/Kolkata
+	Hours         []OpeningHours `bson:"hours" json:"hours"`
+	SlotMinutes   int            `bson:"slot_minutes" json:"slot_minutes"`
+	BufferMinutes int            `bson:"buffer_minutes" json:"buffer_minutes"`
+	BlackoutDates []string       `bson:"blackout_dates,omitempty" json:"blackout_dates,omitempty"` // YYYY-MM-DD
+}
+
+// BookingPolicy limits how residents book an amenity. Zero values mean no
+// limit. Refunds use the first tier, from the highest HoursBefore down,
+// that the cancellation is early enough for; without tiers cancellations
+// are refunded in full.
+type BookingPolicy struct {
+	MaxPerUnitPerWeek int          `bson:"max_per_unit_per_week" json:"max_per_unit_per_week"`
+	MinAdvanceDays    int          `bson:"min_advance_days" json:"min_advance_days"` // 0 allows same-day bookings
+	MaxAdvanceDays    int          `bson:"max_advance_days" json:"max_advance_days"`
+	CancelCutoffHours int          `bson:"cancel_cutoff_hours" json:"cancel_cutoff_hours"` // Residents cannot cancel later than this before the start
+	RefundTiers       []RefundTier `bson:"refund_tiers,omitempty" json:"refund_tiers,omitempty"`
+}
+
+// RefundTier refunds Percent of the fee when a booking is cancelled at
+// least HoursBefore hours before it starts
+type RefundTier struct {
+	HoursBefore int     `bson:"hours_before" json:"hours_before"`
+	Percent     float64 `bson:"percent" json:"percent"`
+}
+
+// OpeningHours is one opening window on a weekday. Times are HH:MM in the
+// schedule's timezone; a day may have several windows.
+type OpeningHours struct {
+	Weekday string `bson:"weekday" json:"weekday"` // monday ... sunday
+	Open    string `bson:"open" json:"open"`
+	Close   string `bson:"close" json:"close"`
+}
+
 type AmenityBooking struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	AmenityID   primitive.ObjectID `bson:"amenity_id" json:"amenity_id"`
 	AmenityName string            `bson:"amenity_name" json:"amenity_name"`
 	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`
 	UserName    string            `bson:"user_name" json:"user_name"`
+	UnitID      *primitive.ObjectID `bson:"unit_id,omitempty" json:"unit_id,omitempty"`
 	Date        time.Time         `bson:"date" json:"date"`
-	TimeSlot    string            `bson:"time_slot" json:"time_slot"`
-	Status      string            `bson:"status" json:"status"` // confirmed, cancelled, completed
+	TimeSlot    string            `bson:"time_slot" json:"time_slot"` // HH:MM-HH:MM label
+	StartTime   time.Time         `bson:"start_time" json:"start_time"`
+	EndTime     time.Time         `bson:"end_time" json:"end_time"`
+	Status      string            `bson:"status" json:"status"` // held, pending_payment, confirmed, cancelled, completed
 	TotalAmount float64           `bson:"total_amount" json:"total_amount"`
 	PaymentID   string            `bson:"payment_id,omitempty" json:"payment_id,omitempty"`
+	RefundPercent *float64        `bson:"refund_percent,omitempty" json:"refund_percent,omitempty"` // Share of the fee refunded when cancelled, also applied to a payment that completes afterwards
+	SeriesID    *primitive.ObjectID `bson:"series_id,omitempty" json:"series_id,omitempty"` // Recurring series this occurrence belongs to
 	SocietyID   primitive.ObjectID `bson:"society_id" json:"society_id"`       // Link to society
 	SocietyCode string            `bson:"society_code" json:"society_code"`   // Society access code
 	CreatedAt   time.Time         `bson:"created_at" json:"created_at"`
 }
 
+// BookingSeries is a recurring booking. It is expanded into one
+// AmenityBooking per occurrence when it is created; occurrences that could
+// not be booked then are reported and not retried.
+type BookingSeries struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	AmenityID   primitive.ObjectID  `bson:"amenity_id" json:"amenity_id"`
+	AmenityName string              `bson:"amenity_name" json:"amenity_name"`
+	UserID      primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	UserName    string              `bson:"user_name" json:"user_name"`
+	UnitID      *primitive.ObjectID `bson:"unit_id,omitempty" json:"unit_id,omitempty"`
+	RRule       string              `bson:"rrule" json:"rrule"` // e.g. FREQ=WEEKLY;BYDAY=TU;UNTIL=20261231
+	StartDate   time.Time           `bson:"start_date" json:"start_date"`
+	TimeSlot    string              `bson:"time_slot" json:"time_slot"` // HH:MM-HH:MM of every occurrence
+	Booked      int                 `bson:"booked" json:"booked"`
+	Conflicts   int                 `bson:"conflicts" json:"conflicts"`
+	Status      string              `bson:"status" json:"status"` // pending, active, failed, cancelled
+	SocietyID   primitive.ObjectID  `bson:"society_id" json:"society_id"`
+	SocietyCode string              `bson:"society_code" json:"society_code"`
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// WaitlistEntry queues a resident for a full slot. When a seat frees up
+// the first waiting entry is offered a held booking, which the resident
+// confirms before OfferExpiresAt or loses to the next in line.
+type WaitlistEntry struct {
+	ID             primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	AmenityID      primitive.ObjectID  `bson:"amenity_id" json:"amenity_id"`
+	AmenityName    string              `bson:"amenity_name" json:"amenity_name"`
+	UserID         primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	UserName       string              `bson:"user_name" json:"user_name"`
+	UnitID         *primitive.ObjectID `bson:"unit_id,omitempty" json:"unit_id,omitempty"`
+	Date           time.Time           `bson:"date" json:"date"`
+	TimeSlot       string              `bson:"time_slot" json:"time_slot"`
+	StartTime      time.Time           `bson:"start_time" json:"start_time"`
+	EndTime        time.Time           `bson:"end_time" json:"end_time"`
+	Status         string              `bson:"status" json:"status"` // waiting, offered, accepted, expired, cancelled
+	BookingID      *primitive.ObjectID `bson:"booking_id,omitempty" json:"booking_id,omitempty"` // Held booking while offered
+	OfferedAt      *time.Time          `bson:"offered_at,omitempty" json:"offered_at,omitempty"`
+	OfferExpiresAt *time.Time          `bson:"offer_expires_at,omitempty" json:"offer_expires_at,omitempty"`
+	SocietyID      primitive.ObjectID  `bson:"society_id" json:"society_id"`
+	SocietyCode    string              `bson:"society_code" json:"society_code"`
+	CreatedAt      time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// CalendarFeed is a subscribable iCalendar URL. Calendar apps cannot send
+// the JWT, so each feed carries its own secret token, kept only as a hash,
+// which can be revoked without touching the user's login.
+type CalendarFeed struct {
+	ID             primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Kind           string              `bson:"kind" json:"kind"` // user, amenity, society
+	Name           string              `bson:"name" json:"name"`
+	UserID         primitive.ObjectID  `bson:"user_id" json:"user_id"` // Owner; a user feed shows their bookings and visitors
+	AmenityID      *primitive.ObjectID `bson:"amenity_id,omitempty" json:"amenity_id,omitempty"`
+	TokenHash      string              `bson:"token_hash" json:"-"`
+	RevokedAt      *time.Time          `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	LastAccessedAt *time.Time          `bson:"last_accessed_at,omitempty" json:"last_accessed_at,omitempty"`
+	SocietyID      primitive.ObjectID  `bson:"society_id" json:"society_id"`
+	SocietyCode    string              `bson:"society_code" json:"society_code"`
+	CreatedAt      time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// AmenityReservation holds one seat of one slot for a booking. A unique
+// index on (amenity, slot, seat) is what stops two bookings taking the
+// same seat, so an amenity never holds more bookings than its capacity.
+type AmenityReservation struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	AmenityID   primitive.ObjectID `bson:"amenity_id" json:"amenity_id"`
+	BookingID   primitive.ObjectID `bson:"booking_id" json:"booking_id"`
+	SlotStart   time.Time          `bson:"slot_start" json:"slot_start"`
+	Seat        int                `bson:"seat" json:"seat"` // 0 to capacity-1
+	SocietyCode string             `bson:"society_code" json:"society_code"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}
+
 type Notice struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Title       string            `bson:"title" json:"title" binding:"required"`
@@ -129,6 +679,20 @@ type Notice struct {
 	CreatedAt   time.Time         `bson:"created_at" json:"created_at"`
 }
 
+// Notification is an in-app message to one user, such as a waitlist offer.
+// Kind lets clients group or route them.
+type Notification struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	Kind        string              `bson:"kind" json:"kind"`
+	Title       string              `bson:"title" json:"title"`
+	Message     string              `bson:"message" json:"message"`
+	ReferenceID *primitive.ObjectID `bson:"reference_id,omitempty" json:"reference_id,omitempty"` // What the notification is about
+	ReadAt      *time.Time          `bson:"read_at,omitempty" json:"read_at,omitempty"`
+	SocietyCode string              `bson:"society_code" json:"society_code"`
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+}
+
 // Request/Response models
 type SocietyCodeRequest struct {
 	Code string `json:"code" binding:"required"`
@@ -143,6 +707,149 @@ type SocietyResponse struct {
 	State   string            `json:"state"`
 }
 
+type CreateSocietyRequest struct {
+	Name         string     `json:"name" binding:"required"`
+	Code         string     `json:"code"` // Optional, generated from the name when empty
+	Address      string     `json:"address"`
+	City         string     `json:"city"`
+	State        string     `json:"state"`
+	PinCode      string     `json:"pin_code"`
+	ContactEmail string     `json:"contact_email"`
+	ContactPhone string     `json:"contact_phone"`
+	Buildings    []Building `json:"buildings"`
+}
+
+type UpdateSocietyRequest struct {
+	Name         *string `json:"name"`
+	Address      *string `json:"address"`
+	City         *string `json:"city"`
+	State        *string `json:"state"`
+	PinCode      *string `json:"pin_code"`
+	ContactEmail *string `json:"contact_email"`
+	ContactPhone *string `json:"contact_phone"`
+}
+
+type SocietyStatusRequest struct {
+	IsActive *bool `json:"is_active" binding:"required"`
+}
+
+type BuildingRequest struct {
+	Name          string `json:"name" binding:"required"`
+	Code          string `json:"code"` // Optional, derived from the name when empty
+	Floors        int    `json:"floors" binding:"required,min=1"`
+	UnitsPerFloor int    `json:"units_per_floor" binding:"required,min=1"`
+}
+
+type GenerateUnitsRequest struct {
+	BuildingID string  `json:"building_id" binding:"required"`
+	AreaSqFt   float64 `json:"area_sq_ft"` // Default area applied to every generated unit
+}
+
+type CreateUnitRequest struct {
+	BuildingID string  `json:"building_id" binding:"required"`
+	Number     string  `json:"number" binding:"required"`
+	Floor      int     `json:"floor"`
+	AreaSqFt   float64 `json:"area_sq_ft"`
+}
+
+type UnitOccupantRequest struct {
+	UserID  string     `json:"user_id" binding:"required"`
+	Role    string     `json:"role" binding:"required,oneof=owner tenant"`
+	MovedIn *time.Time `json:"moved_in"`
+}
+
+type UnitMoveOutRequest struct {
+	Role     string     `json:"role" binding:"required,oneof=owner tenant"`
+	MovedOut *time.Time `json:"moved_out"`
+}
+
+type BillingPlanRequest struct {
+	Type          string         `json:"type" binding:"required,oneof=flat per_sq_ft per_building"`
+	FlatAmount    float64        `json:"flat_amount"`
+	RatePerSqFt   float64        `json:"rate_per_sq_ft"`
+	BuildingRates []BuildingRate `json:"building_rates"`
+	DueDay        int            `json:"due_day"`
+	Description   string         `json:"description"`
+	IsActive      *bool          `json:"is_active"` // Defaults to true
+}
+
+type LateFeeRuleRequest struct {
+	Type       string  `json:"type" binding:"required,oneof=fixed percentage daily_interest"`
+	Amount     float64 `json:"amount" binding:"required,gt=0"`
+	GraceDays  int     `json:"grace_days" binding:"min=0"`
+	MaxPenalty float64 `json:"max_penalty" binding:"min=0"`
+	IsActive   *bool   `json:"is_active"` // Defaults to true
+}
+
+type AmenityScheduleRequest struct {
+	Timezone      string         `json:"timezone"` // Defaults to Asia/Kolkata
+	Hours         []OpeningHours `json:"hours" binding:"required,min=1,dive"`
+	SlotMinutes   int            `json:"slot_minutes" binding:"required,gt=0"`
+	BufferMinutes int            `json:"buffer_minutes" binding:"min=0"`
+	BlackoutDates []string       `json:"blackout_dates"`
+}
+
+type BookingPolicyRequest struct {
+	MaxPerUnitPerWeek int          `json:"max_per_unit_per_week" binding:"min=0"`
+	MinAdvanceDays    int          `json:"min_advance_days" binding:"min=0"`
+	MaxAdvanceDays    int          `json:"max_advance_days" binding:"min=0"`
+	CancelCutoffHours int          `json:"cancel_cutoff_hours" binding:"min=0"`
+	RefundTiers       []RefundTier `json:"refund_tiers"`
+}
+
+// BookAmenityRequest books one or more consecutive slots on a date. The
+// range can be given as start_time and end_time, or as a "HH:MM-HH:MM"
+// time_slot; end_time defaults to the end of the starting slot.
+type BookAmenityRequest struct {
+	AmenityID primitive.ObjectID `json:"amenity_id" binding:"required"`
+	Date      string             `json:"date" binding:"required"` // YYYY-MM-DD
+	StartTime string             `json:"start_time"`              // HH:MM
+	EndTime   string             `json:"end_time"`
+	TimeSlot  string             `json:"time_slot"`
+}
+
+type CalendarFeedRequest struct {
+	Kind      string              `json:"kind" binding:"required,oneof=user amenity society"`
+	AmenityID *primitive.ObjectID `json:"amenity_id"` // Required for amenity feeds
+	Name      string              `json:"name"`
+}
+
+// RecurringBookingRequest books the same range on every date of an RRULE,
+// starting on start_date. The series must end, either through UNTIL or
+// COUNT in the rule or through until.
+type RecurringBookingRequest struct {
+	AmenityID primitive.ObjectID `json:"amenity_id" binding:"required"`
+	StartDate string             `json:"start_date" binding:"required"` // YYYY-MM-DD
+	StartTime string             `json:"start_time"`                    // HH:MM
+	EndTime   string             `json:"end_time"`
+	TimeSlot  string             `json:"time_slot"`
+	RRule     string             `json:"rrule" binding:"required"` // FREQ=WEEKLY or MONTHLY, INTERVAL, BYDAY, BYMONTHDAY, UNTIL, COUNT
+	Until     string             `json:"until"`                    // YYYY-MM-DD, instead of UNTIL
+}
+
+// CancelSeriesRequest cancels one occurrence by date, or the whole series
+// when date is empty
+type CancelSeriesRequest struct {
+	Date string `json:"date"` // YYYY-MM-DD
+}
+
+type SimulatePaymentRequest struct {
+	Outcome string `json:"outcome" binding:"required,oneof=succeeded failed pending"`
+}
+
+type WaivePenaltyRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type BillingRunRequest struct {
+	Cycle string `json:"cycle"` // YYYY-MM, defaults to the current month
+}
+
+type AdminLoginRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
 type LoginRequest struct {
 	Email       string `json:"email" binding:"required,email"`
 	Password    string `json:"password" binding:"required"`
@@ -167,12 +874,166 @@ type LoginResponse struct {
 }
 
 type VisitorApprovalRequest struct {
-	Status     string `json:"status" binding:"required"`
+	Status     string `json:"status" binding:"required,oneof=approved rejected"`
 	ApprovedBy string `json:"approved_by,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// WalkInVisitorRequest registers an unannounced visitor at the gate
+type WalkInVisitorRequest struct {
+	Name          string             `json:"name" binding:"required"`
+	Phone         string             `json:"phone" binding:"required"`
+	Purpose       string             `json:"purpose" binding:"required"`
+	VehicleNumber string             `json:"vehicle_number"`
+	UnitID        primitive.ObjectID `json:"unit_id" binding:"required"`
+}
+
+// HostContact is a resident the guard can call about a walk-in
+type HostContact struct {
+	UserID primitive.ObjectID `json:"user_id"`
+	Name   string             `json:"name"`
+	Phone  string             `json:"phone"`
+}
+
+type StaffRequest struct {
+	Name       string               `json:"name" binding:"required"`
+	Phone      string               `json:"phone" binding:"required"`
+	Category   string               `json:"category" binding:"required,oneof=maid driver cook tutor delivery other"`
+	UnitIDs    []primitive.ObjectID `json:"unit_ids"` // Defaults to the resident's own unit
+	Hours      []OpeningHours       `json:"hours" binding:"dive"`
+	Timezone   string               `json:"timezone"` // Defaults to Asia/Kolkata
+	PhotoURL   string               `json:"photo_url"`
+	IDDocument *IDDocument          `json:"id_document"`
+}
+
+type UpdateStaffRequest struct {
+	Name       *string         `json:"name"`
+	Phone      *string         `json:"phone"`
+	Hours      *[]OpeningHours `json:"hours"`
+	Timezone   *string         `json:"timezone"`
+	PhotoURL   *string         `json:"photo_url"`
+	IDDocument *IDDocument     `json:"id_document"`
+	IsActive   *bool           `json:"is_active"`
+}
+
+// StaffUnitRequest adds a unit to the ones a staff member serves
+type StaffUnitRequest struct {
+	UnitID primitive.ObjectID `json:"unit_id"` // Defaults to the resident's own unit
+}
+
+// VisitorCancelRequest cancels an expected visit
+type VisitorCancelRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+type BlacklistRequest struct {
+	Name          string `json:"name"`
+	Phone         string `json:"phone"`
+	VehicleNumber string `json:"vehicle_number"`
+	Action        string `json:"action" binding:"required,oneof=block warn"`
+	Reason        string `json:"reason" binding:"required"`
+}
+
+// UpdateBlacklistRequest changes an entry; the reason for the change is
+// required and kept in its history
+type UpdateBlacklistRequest struct {
+	Name          *string `json:"name"`
+	Phone         *string `json:"phone"`
+	VehicleNumber *string `json:"vehicle_number"`
+	Action        *string `json:"action" binding:"omitempty,oneof=block warn"`
+	Reason        string  `json:"reason" binding:"required"`
+}
+
+// RemoveBlacklistRequest lifts an entry
+type RemoveBlacklistRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// BlacklistCheckRequest screens a person at the gate
+type BlacklistCheckRequest struct {
+	Name          string `json:"name"`
+	Phone         string `json:"phone"`
+	VehicleNumber string `json:"vehicle_number"`
+}
+
+type ParcelRequest struct {
+	UnitID         primitive.ObjectID `json:"unit_id" binding:"required"`
+	Courier        string             `json:"courier" binding:"required"`
+	TrackingNumber string             `json:"tracking_number"`
+	Description    string             `json:"description"`
+	PhotoURL       string             `json:"photo_url"`
+}
+
+// CollectParcelRequest hands a parcel over. Either the OTP sent to the
+// unit or a signature uploaded for the parcel is required.
+type CollectParcelRequest struct {
+	CollectedBy       string             `json:"collected_by" binding:"required"`
+	OTP               string             `json:"otp"`
+	SignatureUploadID primitive.ObjectID `json:"signature_upload_id,omitempty"` // From POST /parcels/:id/signature
+}
+
+// ReturnParcelRequest sends an uncollected parcel back with the courier
+type ReturnParcelRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// VisitorCheckInRequest optionally names the vehicle a visitor arrives in,
+// which is given a visitor parking slot
+type VisitorCheckInRequest struct {
+	VehicleNumber string `json:"vehicle_number"` // Defaults to the visitor's registered vehicle
+	VehicleType   string `json:"vehicle_type" binding:"omitempty,oneof=car bike"` // Defaults to car
+}
+
+type VehicleRequest struct {
+	UnitID primitive.ObjectID  `json:"unit_id"` // Defaults to the resident's own unit
+	Number string              `json:"number" binding:"required"`
+	Type   string              `json:"type" binding:"required,oneof=car bike other"`
+	Make   string              `json:"make"`
+	Model  string              `json:"model"`
+	Color  string              `json:"color"`
+	SlotID *primitive.ObjectID `json:"slot_id"`
+}
+
+type UpdateVehicleRequest struct {
+	Make   *string             `json:"make"`
+	Model  *string             `json:"model"`
+	Color  *string             `json:"color"`
+	SlotID *primitive.ObjectID `json:"slot_id"`
+	// ClearSlot unassigns the vehicle's slot
+	ClearSlot bool `json:"clear_slot"`
+}
+
+type ParkingSlotRequest struct {
+	BuildingID  primitive.ObjectID  `json:"building_id" binding:"required"`
+	Label       string              `json:"label" binding:"required"`
+	Kind        string              `json:"kind" binding:"required,oneof=assigned visitor"`
+	VehicleType string              `json:"vehicle_type" binding:"required,oneof=car bike"`
+	UnitID      *primitive.ObjectID `json:"unit_id"` // Assigned slots only
+}
+
+type UpdateParkingSlotRequest struct {
+	Label       *string             `json:"label"`
+	VehicleType *string             `json:"vehicle_type" binding:"omitempty,oneof=car bike"`
+	UnitID      *primitive.ObjectID `json:"unit_id"`
+	// ClearUnit takes an assigned slot away from its unit
+	ClearUnit bool  `json:"clear_unit"`
+	IsActive  *bool `json:"is_active"`
+}
+
+// GateVehicleRequest records a vehicle passing the gate
+type GateVehicleRequest struct {
+	VehicleNumber string `json:"vehicle_number" binding:"required"`
 }
 
 type PaymentRequest struct {
 	MaintenanceID string  `json:"maintenance_id" binding:"required"`
-	Amount       float64 `json:"amount" binding:"required"`
+	Amount       float64 `json:"amount" binding:"required,gt=0"` // May be partial, or more than due to leave credit
 	PaymentMethod string `json:"payment_method"`
-}
\ No newline at end of file
+}
+type UpdateNoticeRequest struct {
+	Title     *string    `json:"title"`
+	Content   *string    `json:"content"`
+	Type      *string    `json:"type"`
+	IsActive  *bool      `json:"is_active"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
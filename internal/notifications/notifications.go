@@ -0,0 +1,54 @@
+package notifications
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Service delivers in-app notifications. Users read them through the
+// notifications API; nothing is pushed to devices yet.
+type Service struct {
+	store *store.Store
+}
+
+func NewService(s *store.Store) *Service {
+	return &Service{store: s}
+}
+
+// Message is what to tell a user
+type Message struct {
+	Kind        string
+	Title       string
+	Body        string
+	ReferenceID primitive.ObjectID // Optional
+}
+
+// Notify stores a notification for a user. Failing to notify should not
+// undo whatever the notification is about, so callers usually only log
+// the error.
+func (s *Service) Notify(ctx context.Context, societyCode string, userID primitive.ObjectID, msg Message) error {
+	notification := &models.Notification{
+		UserID:      userID,
+		Kind:        msg.Kind,
+		Title:       msg.Title,
+		Message:     msg.Body,
+		SocietyCode: societyCode,
+		CreatedAt:   time.Now(),
+	}
+	if !msg.ReferenceID.IsZero() {
+		ref := msg.ReferenceID
+		notification.ReferenceID = &ref
+	}
+
+	if err := s.store.Notifications.Create(ctx, notification); err != nil {
+		return err
+	}
+	log.Printf("🔔 Notified user %s (%s): %s", userID.Hex(), msg.Kind, msg.Title)
+	return nil
+}
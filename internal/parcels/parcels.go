@@ -0,0 +1,299 @@
+package parcels
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/notifications"
+	"bms-backend/internal/store"
+	"bms-backend/internal/uploads"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Parcel statuses. Collected and returned parcels are final.
+const (
+	StatusReceived  = "received"
+	StatusCollected = "collected"
+	StatusReturned  = "returned"
+)
+
+// Collection methods
+const (
+	MethodOTP       = "otp"
+	MethodSignature = "signature"
+)
+
+// Notification kinds sent about parcels
+const (
+	KindReceived  = "parcel_received"
+	KindReminder  = "parcel_reminder"
+	KindCollected = "parcel_collected"
+)
+
+const (
+	// MaxOTPAttempts wrong OTPs lock a parcel until the unit asks for a new one
+	MaxOTPAttempts = 5
+	// MaxReminders is how many times residents are reminded about a parcel
+	MaxReminders = 5
+)
+
+var (
+	ErrProofRequired = errors.New("parcels: an OTP or a signature is required")
+	ErrWrongOTP      = errors.New("parcels: wrong OTP")
+	ErrOTPLocked     = errors.New("parcels: too many wrong OTPs")
+	ErrBadSignature  = errors.New("parcels: signature was not uploaded for this parcel")
+	ErrNotWaiting    = errors.New("parcels: parcel is no longer at the desk")
+)
+
+// Service logs parcels at the security desk, hands them over and reminds
+// residents about the ones they leave there
+type Service struct {
+	store    *store.Store
+	notifier *notifications.Service
+	// reminderInterval is how long a parcel waits before, and between,
+	// reminders
+	reminderInterval time.Duration
+}
+
+func NewService(s *store.Store, notifier *notifications.Service, reminderInterval time.Duration) *Service {
+	return &Service{store: s, notifier: notifier, reminderInterval: reminderInterval}
+}
+
+// Receive logs a parcel for unit and sends its residents the OTP to
+// collect it with. Only the residents see the OTP.
+func (s *Service) Receive(ctx context.Context, parcel *models.Parcel, unit *models.Unit, guard primitive.ObjectID, now time.Time) error {
+	if parcel.ID.IsZero() {
+		parcel.ID = primitive.NewObjectID()
+	}
+	otp, err := newOTP()
+	if err != nil {
+		return err
+	}
+
+	next := now.Add(s.reminderInterval)
+	parcel.UnitID = unit.ID
+	parcel.UnitNumber = unit.Number
+	parcel.Status = StatusReceived
+	parcel.ReceivedAt = now
+	parcel.ReceivedBy = guard
+	parcel.OTPHash = hashOTP(parcel.ID, otp)
+	parcel.NextReminderAt = &next
+	parcel.CreatedAt = now
+	parcel.UpdatedAt = now
+	if err := s.store.Parcels.Create(ctx, parcel); err != nil {
+		return err
+	}
+
+	s.notifyUnit(ctx, parcel, notifications.Message{
+		Kind:        KindReceived,
+		Title:       "Parcel from " + parcel.Courier + " at the security desk",
+		Body:        fmt.Sprintf("A parcel from %s for %s is waiting at the security desk. Collect it with OTP %s.", parcel.Courier, parcel.UnitNumber, otp),
+		ReferenceID: parcel.ID,
+	})
+	return nil
+}
+
+// NewOTP replaces the parcel's OTP, for residents who lost theirs or
+// after too many wrong attempts, and returns it
+func (s *Service) NewOTP(ctx context.Context, parcel *models.Parcel, now time.Time) (string, error) {
+	otp, err := newOTP()
+	if err != nil {
+		return "", err
+	}
+	hash, attempts := hashOTP(parcel.ID, otp), 0
+	err = s.store.Parcels.Transition(ctx, parcel.SocietyCode, parcel.ID, []string{StatusReceived}, store.ParcelUpdate{
+		OTPHash:     &hash,
+		OTPAttempts: &attempts,
+		UpdatedAt:   now,
+	})
+	if errors.Is(err, store.ErrConflict) {
+		return "", ErrNotWaiting
+	}
+	if err != nil {
+		return "", err
+	}
+	return otp, nil
+}
+
+// Collect hands the parcel over to collectedBy, who proves they may take
+// it with the unit's OTP or a signature uploaded for the parcel, and tells
+// the unit. A parcel locked by wrong OTPs cannot be signed for either.
+func (s *Service) Collect(ctx context.Context, parcel *models.Parcel, req models.CollectParcelRequest, guard primitive.ObjectID, now time.Time) error {
+	if parcel.Status != StatusReceived {
+		return ErrNotWaiting
+	}
+	if parcel.OTPAttempts >= MaxOTPAttempts {
+		return ErrOTPLocked
+	}
+
+	collection := &models.ParcelCollection{
+		CollectedAt:  now,
+		CollectedBy:  req.CollectedBy,
+		HandedOverBy: guard,
+	}
+	switch {
+	case req.OTP != "":
+		if subtle.ConstantTimeCompare([]byte(hashOTP(parcel.ID, req.OTP)), []byte(parcel.OTPHash)) != 1 {
+			err := s.store.Parcels.Update(ctx, parcel.SocietyCode, parcel.ID, store.ParcelUpdate{FailedOTP: true, UpdatedAt: now})
+			if err != nil {
+				return err
+			}
+			return ErrWrongOTP
+		}
+		collection.Method = MethodOTP
+	case !req.SignatureUploadID.IsZero():
+		signature, err := s.store.Uploads.GetByID(ctx, parcel.SocietyCode, req.SignatureUploadID)
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrBadSignature
+		}
+		if err != nil {
+			return err
+		}
+		if signature.Kind != uploads.KindParcelSignature || signature.OwnerID != parcel.ID {
+			return ErrBadSignature
+		}
+		collection.Method = MethodSignature
+		collection.SignatureURL = signature.URL
+	default:
+		return ErrProofRequired
+	}
+
+	status := StatusCollected
+	err := s.store.Parcels.Transition(ctx, parcel.SocietyCode, parcel.ID, []string{StatusReceived}, store.ParcelUpdate{
+		Status:        &status,
+		Collection:    collection,
+		StopReminders: true,
+		UpdatedAt:     now,
+	})
+	if errors.Is(err, store.ErrConflict) {
+		return ErrNotWaiting
+	}
+	if err != nil {
+		return err
+	}
+	parcel.Status = status
+	parcel.Collection = collection
+	parcel.NextReminderAt = nil
+	parcel.UpdatedAt = now
+
+	s.notifyUnit(ctx, parcel, notifications.Message{
+		Kind:        KindCollected,
+		Title:       "Parcel from " + parcel.Courier + " collected",
+		Body:        fmt.Sprintf("The parcel from %s for %s was collected by %s.", parcel.Courier, parcel.UnitNumber, req.CollectedBy),
+		ReferenceID: parcel.ID,
+	})
+	return nil
+}
+
+// Return records that an uncollected parcel went back with the courier
+func (s *Service) Return(ctx context.Context, parcel *models.Parcel, reason string, now time.Time) error {
+	status := StatusReturned
+	err := s.store.Parcels.Transition(ctx, parcel.SocietyCode, parcel.ID, []string{StatusReceived}, store.ParcelUpdate{
+		Status:        &status,
+		ReturnedAt:    &now,
+		ReturnReason:  &reason,
+		StopReminders: true,
+		UpdatedAt:     now,
+	})
+	if errors.Is(err, store.ErrConflict) {
+		return ErrNotWaiting
+	}
+	if err != nil {
+		return err
+	}
+	parcel.Status = status
+	parcel.ReturnedAt = &now
+	parcel.ReturnReason = reason
+	parcel.NextReminderAt = nil
+	parcel.UpdatedAt = now
+	return nil
+}
+
+// RemindUncollected reminds units about parcels waiting at the desk for
+// another reminder interval, up to MaxReminders times per parcel. It
+// returns how many reminders were sent.
+func (s *Service) RemindUncollected(ctx context.Context, now time.Time) (int, error) {
+	due, err := s.store.Parcels.List(ctx, store.ParcelFilter{
+		Statuses:      []string{StatusReceived},
+		ReminderDueBy: now,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	reminded := 0
+	for i := range due {
+		parcel := &due[i]
+		sent := parcel.RemindersSent + 1
+		update := store.ParcelUpdate{RemindersSent: &sent, UpdatedAt: now}
+		if sent >= MaxReminders {
+			update.StopReminders = true
+		} else {
+			next := now.Add(s.reminderInterval)
+			update.NextReminderAt = &next
+		}
+		if err := s.store.Parcels.Update(ctx, parcel.SocietyCode, parcel.ID, update); err != nil {
+			log.Printf("⚠️ Parcels: failed to schedule reminder for parcel %s: %v", parcel.ID.Hex(), err)
+			continue
+		}
+
+		hours := int(now.Sub(parcel.ReceivedAt).Hours())
+		waiting := fmt.Sprintf("for %d hours", hours)
+		if hours >= 48 {
+			waiting = fmt.Sprintf("for %d days", hours/24)
+		}
+		s.notifyUnit(ctx, parcel, notifications.Message{
+			Kind:        KindReminder,
+			Title:       "Parcel from " + parcel.Courier + " still waiting",
+			Body:        fmt.Sprintf("A parcel from %s for %s has been at the security desk %s. Please collect it.", parcel.Courier, parcel.UnitNumber, waiting),
+			ReferenceID: parcel.ID,
+		})
+		reminded++
+	}
+	return reminded, nil
+}
+
+// notifyUnit tells every active resident of the parcel's unit
+func (s *Service) notifyUnit(ctx context.Context, parcel *models.Parcel, msg notifications.Message) {
+	residents, err := s.store.Users.List(ctx, store.UserFilter{
+		SocietyCode: parcel.SocietyCode,
+		Roles:       []string{"resident"},
+		ActiveOnly:  true,
+		UnitID:      parcel.UnitID,
+	})
+	if err != nil {
+		log.Printf("⚠️ Parcels: failed to load residents of %s: %v", parcel.UnitNumber, err)
+		return
+	}
+	for _, resident := range residents {
+		if err := s.notifier.Notify(ctx, parcel.SocietyCode, resident.ID, msg); err != nil {
+			log.Printf("⚠️ Parcels: failed to notify user %s: %v", resident.ID.Hex(), err)
+		}
+	}
+}
+
+// newOTP returns a random six digit code
+func newOTP() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// hashOTP is how OTPs are stored, so guards reading the register cannot
+// collect parcels themselves. The parcel ID salts the hash.
+func hashOTP(parcelID primitive.ObjectID, otp string) string {
+	sum := sha256.Sum256([]byte(parcelID.Hex() + ":" + otp))
+	return hex.EncodeToString(sum[:])
+}
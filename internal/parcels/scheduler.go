@@ -0,0 +1,44 @@
+package parcels
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Scheduler reminds residents about parcels left at the security desk
+type Scheduler struct {
+	service  *Service
+	interval time.Duration
+}
+
+func NewScheduler(service *Service, interval time.Duration) *Scheduler {
+	return &Scheduler{service: service, interval: interval}
+}
+
+// Start runs a pass immediately and then on every tick until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.RunOnce(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce performs one reminder pass
+func (s *Scheduler) RunOnce(ctx context.Context, now time.Time) {
+	reminded, err := s.service.RemindUncollected(ctx, now)
+	if err != nil {
+		log.Printf("⚠️ Parcels: failed to send reminders: %v", err)
+	}
+	if reminded > 0 {
+		log.Printf("📦 Parcels: %d uncollected parcel reminders sent", reminded)
+	}
+}
@@ -0,0 +1,302 @@
+package parking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/notifications"
+	"bms-backend/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Slot kinds
+const (
+	SlotAssigned = "assigned"
+	SlotVisitor  = "visitor"
+)
+
+// Kinds of vehicle in the gate log
+const (
+	VehicleResident     = "resident"
+	VehicleVisitor      = "visitor"
+	VehicleUnregistered = "unregistered"
+)
+
+// KindOverstay is the notification sent when a visitor vehicle stays in
+// its slot past the limit
+const KindOverstay = "parking_overstay"
+
+var (
+	ErrPlateTaken  = errors.New("parking: plate is already registered in the society")
+	ErrNotUnitSlot = errors.New("parking: slot is not assigned to the unit")
+	ErrNoFreeSlot  = errors.New("parking: no visitor slot is free")
+	ErrInside      = errors.New("parking: vehicle is already inside")
+	ErrNotInside   = errors.New("parking: vehicle is not inside")
+)
+
+// Service registers resident vehicles, hands out visitor parking and keeps
+// the gate's vehicle log
+type Service struct {
+	store    *store.Store
+	notifier *notifications.Service
+	// visitorLimit is how long a visitor vehicle may stay in its slot
+	visitorLimit time.Duration
+}
+
+func NewService(s *store.Store, notifier *notifications.Service, visitorLimit time.Duration) *Service {
+	return &Service{store: s, notifier: notifier, visitorLimit: visitorLimit}
+}
+
+// RegisterVehicle stores a vehicle for unit under its normalised plate,
+// checking that any slot asked for is one of the unit's
+func (s *Service) RegisterVehicle(ctx context.Context, vehicle *models.Vehicle, unit *models.Unit, now time.Time) error {
+	plate, err := NormalizePlate(vehicle.Number)
+	if err != nil {
+		return err
+	}
+	if vehicle.SlotID != nil {
+		slot, err := s.UnitSlot(ctx, unit.SocietyCode, *vehicle.SlotID, unit.ID)
+		if err != nil {
+			return err
+		}
+		vehicle.SlotLabel = slot.Label
+	}
+
+	vehicle.Plate = plate
+	vehicle.UnitID = unit.ID
+	vehicle.UnitNumber = unit.Number
+	vehicle.IsActive = true
+	vehicle.SocietyCode = unit.SocietyCode
+	vehicle.CreatedAt = now
+	vehicle.UpdatedAt = now
+	err = s.store.Vehicles.Create(ctx, vehicle)
+	if errors.Is(err, store.ErrDuplicate) {
+		return ErrPlateTaken
+	}
+	return err
+}
+
+// UnitSlot loads one of the unit's assigned slots
+func (s *Service) UnitSlot(ctx context.Context, societyCode string, slotID, unitID primitive.ObjectID) (*models.ParkingSlot, error) {
+	slot, err := s.store.ParkingSlots.GetByID(ctx, societyCode, slotID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrNotUnitSlot
+	}
+	if err != nil {
+		return nil, err
+	}
+	if slot.Kind != SlotAssigned || slot.UnitID == nil || *slot.UnitID != unitID {
+		return nil, ErrNotUnitSlot
+	}
+	return slot, nil
+}
+
+// ParkVisitor gives a checked-in visitor's vehicle a free visitor slot,
+// preferring the host's building, and logs it in at the gate. The vehicle
+// is logged even when every slot is taken, in which case ErrNoFreeSlot is
+// returned.
+func (s *Service) ParkVisitor(ctx context.Context, visitor *models.Visitor, plate, vehicleType string, guard primitive.ObjectID, now time.Time) (*models.ParkingSlot, error) {
+	entry := models.VehicleLog{
+		Plate:       plate,
+		Kind:        VehicleVisitor,
+		VisitorID:   &visitor.ID,
+		UnitNumber:  visitor.HostUnit,
+		EnteredAt:   now,
+		Inside:      true,
+		EnteredBy:   guard,
+		SocietyCode: visitor.SocietyCode,
+	}
+	if err := s.store.VehicleLogs.Enter(ctx, &entry); err != nil && !errors.Is(err, store.ErrDuplicate) {
+		log.Printf("⚠️ Parking: failed to log visitor vehicle %s: %v", plate, err)
+	}
+
+	free, err := s.store.ParkingSlots.List(ctx, store.ParkingSlotFilter{
+		SocietyCode: visitor.SocietyCode,
+		Kind:        SlotVisitor,
+		VehicleType: vehicleType,
+		FreeOnly:    true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if visitor.HostUnitID != nil {
+		if unit, err := s.store.Units.GetByID(ctx, visitor.SocietyCode, *visitor.HostUnitID); err == nil {
+			free = hostBuildingFirst(free, unit.BuildingID)
+		}
+	}
+
+	occupant := models.ParkingOccupant{
+		VisitorID:   visitor.ID,
+		VisitorName: visitor.Name,
+		HostUnit:    visitor.HostUnit,
+		Plate:       plate,
+		ParkedAt:    now,
+		ParkUntil:   now.Add(s.visitorLimit),
+	}
+	for i := range free {
+		slot := &free[i]
+		err := s.store.ParkingSlots.Occupy(ctx, visitor.SocietyCode, slot.ID, occupant)
+		if errors.Is(err, store.ErrConflict) {
+			// Another guard took it first
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		slot.Occupant = &occupant
+
+		err = s.store.Visitors.Update(ctx, visitor.SocietyCode, visitor.ID, store.VisitorUpdate{
+			VehicleNumber: &plate,
+			ParkingSlotID: &slot.ID,
+			ParkingSlot:   &slot.Label,
+		})
+		if err != nil {
+			return nil, err
+		}
+		visitor.VehicleNumber = plate
+		visitor.ParkingSlotID = &slot.ID
+		visitor.ParkingSlot = slot.Label
+		return slot, nil
+	}
+	return nil, ErrNoFreeSlot
+}
+
+// ReleaseVisitor frees the checked-out visitor's slot and logs their
+// vehicle out at the gate
+func (s *Service) ReleaseVisitor(ctx context.Context, visitor *models.Visitor, guard primitive.ObjectID, now time.Time) {
+	if visitor.ParkingSlotID != nil {
+		err := s.store.ParkingSlots.Vacate(ctx, visitor.SocietyCode, *visitor.ParkingSlotID, visitor.ID, now)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			log.Printf("⚠️ Parking: failed to free slot %s: %v", visitor.ParkingSlot, err)
+		}
+	}
+	if plate, err := NormalizePlate(visitor.VehicleNumber); err == nil {
+		_, err := s.store.VehicleLogs.Exit(ctx, visitor.SocietyCode, plate, now, guard)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			log.Printf("⚠️ Parking: failed to log visitor vehicle %s out: %v", plate, err)
+		}
+	}
+}
+
+// Enter logs a vehicle in at the gate, recognising resident vehicles by
+// their plate. Visitor vehicles are logged when their visitor checks in.
+func (s *Service) Enter(ctx context.Context, societyCode, number string, guard primitive.ObjectID, now time.Time) (*models.VehicleLog, error) {
+	plate, err := NormalizePlate(number)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := models.VehicleLog{
+		Plate:       plate,
+		Kind:        VehicleUnregistered,
+		EnteredAt:   now,
+		Inside:      true,
+		EnteredBy:   guard,
+		SocietyCode: societyCode,
+	}
+	vehicles, err := s.store.Vehicles.List(ctx, store.VehicleFilter{SocietyCode: societyCode, Plate: plate, ActiveOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	if len(vehicles) > 0 {
+		entry.Kind = VehicleResident
+		entry.VehicleID = &vehicles[0].ID
+		entry.UnitNumber = vehicles[0].UnitNumber
+	}
+
+	err = s.store.VehicleLogs.Enter(ctx, &entry)
+	if errors.Is(err, store.ErrDuplicate) {
+		return nil, ErrInside
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Exit logs a vehicle out at the gate
+func (s *Service) Exit(ctx context.Context, societyCode, number string, guard primitive.ObjectID, now time.Time) (*models.VehicleLog, error) {
+	plate, err := NormalizePlate(number)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := s.store.VehicleLogs.Exit(ctx, societyCode, plate, now, guard)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrNotInside
+	}
+	return entry, err
+}
+
+// AlertOverstays reports visitor vehicles still parked past their limit to
+// the society's secretaries and security, once per stay. It returns how
+// many overstays were reported.
+func (s *Service) AlertOverstays(ctx context.Context, now time.Time) (int, error) {
+	slots, err := s.store.ParkingSlots.List(ctx, store.ParkingSlotFilter{
+		OverstayBy:    now,
+		UnalertedOnly: true,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	alerted := 0
+	for _, slot := range slots {
+		err := s.store.ParkingSlots.Update(ctx, slot.SocietyCode, slot.ID, store.ParkingSlotUpdate{AlertedAt: &now, UpdatedAt: now})
+		if err != nil {
+			log.Printf("⚠️ Parking: failed to mark overstay in slot %s: %v", slot.Label, err)
+			continue
+		}
+
+		occupant := slot.Occupant
+		parked := int(now.Sub(occupant.ParkedAt).Hours())
+		s.notifyStaff(ctx, slot.SocietyCode, notifications.Message{
+			Kind:  KindOverstay,
+			Title: "Visitor vehicle " + occupant.Plate + " overstaying in " + slot.Label,
+			Body: fmt.Sprintf("%s, visiting %s, has been parked in %s (%s) for %d hours, past the %d hour limit.",
+				occupant.VisitorName, occupant.HostUnit, slot.Label, slot.BuildingName, parked, int(s.visitorLimit.Hours())),
+			ReferenceID: occupant.VisitorID,
+		})
+		alerted++
+	}
+	return alerted, nil
+}
+
+// notifyStaff tells the society's secretaries and security guards
+func (s *Service) notifyStaff(ctx context.Context, societyCode string, msg notifications.Message) {
+	users, err := s.store.Users.List(ctx, store.UserFilter{
+		SocietyCode: societyCode,
+		Roles:       []string{"secretary", "security"},
+		ActiveOnly:  true,
+	})
+	if err != nil {
+		log.Printf("⚠️ Parking: failed to load secretaries and security of %s: %v", societyCode, err)
+		return
+	}
+	for _, user := range users {
+		if err := s.notifier.Notify(ctx, societyCode, user.ID, msg); err != nil {
+			log.Printf("⚠️ Parking: failed to notify user %s: %v", user.ID.Hex(), err)
+		}
+	}
+}
+
+// hostBuildingFirst moves the slots in the host's building to the front,
+// keeping the order within each group
+func hostBuildingFirst(slots []models.ParkingSlot, buildingID primitive.ObjectID) []models.ParkingSlot {
+	ordered := make([]models.ParkingSlot, 0, len(slots))
+	for _, slot := range slots {
+		if slot.BuildingID == buildingID {
+			ordered = append(ordered, slot)
+		}
+	}
+	for _, slot := range slots {
+		if slot.BuildingID != buildingID {
+			ordered = append(ordered, slot)
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,67 @@
+package parking
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var ErrInvalidPlate = errors.New("parking: not a valid Indian licence plate")
+
+var (
+	// State plates: state code, district, up to three series letters and a
+	// number of up to four digits, e.g. MH 12 AB 1234 or DL 3C AB 1234
+	statePlate = regexp.MustCompile(`^([A-Z]{2})([0-9]{1,2})([A-Z]{0,3})([0-9]{1,4})$`)
+	// Bharat series plates: year of registration, BH, number and series,
+	// e.g. 22 BH 1234 AB
+	bharatPlate = regexp.MustCompile(`^([0-9]{2})BH([0-9]{4})([A-Z]{1,2})$`)
+)
+
+// stateCodes are the state and union territory codes plates are issued
+// under, including the retired ones still on the road
+var stateCodes = map[string]bool{
+	"AN": true, "AP": true, "AR": true, "AS": true, "BR": true, "CG": true, "CH": true,
+	"DD": true, "DL": true, "DN": true, "GA": true, "GJ": true, "HP": true, "HR": true,
+	"JH": true, "JK": true, "KA": true, "KL": true, "LA": true, "LD": true, "MH": true,
+	"ML": true, "MN": true, "MP": true, "MZ": true, "NL": true, "OD": true, "OR": true,
+	"PB": true, "PY": true, "RJ": true, "SK": true, "TG": true, "TN": true, "TR": true,
+	"TS": true, "UA": true, "UK": true, "UP": true, "WB": true,
+}
+
+// NormalizePlate validates an Indian licence plate and returns it in one
+// canonical form: upper case without spaces or dashes, with the district
+// padded to two digits and the number to four. "mh-1-ab-234" becomes
+// "MH01AB0234".
+func NormalizePlate(number string) (string, error) {
+	var compact strings.Builder
+	for _, r := range number {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			compact.WriteRune(unicode.ToUpper(r))
+		case r == ' ' || r == '-' || r == '.':
+		default:
+			return "", ErrInvalidPlate
+		}
+	}
+	plate := compact.String()
+
+	if bharatPlate.MatchString(plate) {
+		return plate, nil
+	}
+	m := statePlate.FindStringSubmatch(plate)
+	if m == nil || !stateCodes[m[1]] {
+		return "", ErrInvalidPlate
+	}
+	district, series, serial := m[2], m[3], m[4]
+	if strings.Trim(district, "0") == "" || strings.Trim(serial, "0") == "" {
+		return "", ErrInvalidPlate
+	}
+	// Without series letters nothing separates the district from the
+	// number, so the number must be written out in full
+	if series == "" && len(serial) != 4 {
+		return "", ErrInvalidPlate
+	}
+	return fmt.Sprintf("%s%02s%s%04s", m[1], district, series, serial), nil
+}
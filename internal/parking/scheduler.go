@@ -0,0 +1,44 @@
+package parking
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Scheduler reports visitor vehicles overstaying in their parking slots
+type Scheduler struct {
+	service  *Service
+	interval time.Duration
+}
+
+func NewScheduler(service *Service, interval time.Duration) *Scheduler {
+	return &Scheduler{service: service, interval: interval}
+}
+
+// Start runs a pass immediately and then on every tick until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.RunOnce(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce performs one overstay pass
+func (s *Scheduler) RunOnce(ctx context.Context, now time.Time) {
+	alerted, err := s.service.AlertOverstays(ctx, now)
+	if err != nil {
+		log.Printf("⚠️ Parking: failed to report overstays: %v", err)
+	}
+	if alerted > 0 {
+		log.Printf("🚗 Parking: %d visitor vehicle overstays reported", alerted)
+	}
+}
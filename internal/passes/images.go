@@ -0,0 +1,200 @@
+package passes
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Pass images are sized in pixels, quiet zone included
+const (
+	DefaultQRSize = 256
+	MinQRSize     = 128
+	MaxQRSize     = 1024
+)
+
+// Pass card layout, in pixels
+const (
+	cardWidth    = 640
+	cardHeight   = 980
+	cardHeader   = 130
+	cardQRSize   = 440
+	cardQRTop    = 160
+	cardMargin   = 40
+	cardTimeForm = "02 Jan 2006, 3:04 PM"
+)
+
+var (
+	cardInk    = color.RGBA{R: 0x1f, G: 0x29, B: 0x37, A: 0xff}
+	cardMuted  = color.RGBA{R: 0x6b, G: 0x72, B: 0x80, A: 0xff}
+	cardAccent = color.RGBA{R: 0x0f, G: 0x76, B: 0x6e, A: 0xff}
+)
+
+// Card is what a shareable pass card shows besides the QR code. Times are
+// printed in Location.
+type Card struct {
+	SocietyName string
+	VisitorName string
+	HostName    string
+	HostUnit    string
+	ValidFrom   time.Time
+	ExpiresAt   time.Time
+	SingleUse   bool
+	Location    *time.Location
+}
+
+// PNG encodes the pass token as a square QR code image of size pixels
+func PNG(token string, size int) ([]byte, error) {
+	return qrcode.Encode(token, qrcode.Medium, size)
+}
+
+// SVG encodes the pass token as a QR code drawn with one path, one unit
+// per module, so it scales to any size
+func SVG(token string) ([]byte, error) {
+	code, err := qrcode.New(token, qrcode.Medium)
+	if err != nil {
+		return nil, err
+	}
+	bitmap := code.Bitmap()
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, len(bitmap), len(bitmap))
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, len(bitmap), len(bitmap))
+	for y, row := range bitmap {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			// Runs of dark modules become one rectangle
+			run := 1
+			for x+run < len(row) && row[x+run] {
+				run++
+			}
+			fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", x, y, run, run)
+			x += run - 1
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes(), nil
+}
+
+// RenderCard draws a pass card a resident can forward to their guest: the
+// society, the QR code, who is visiting whom and when the pass is valid
+func RenderCard(token string, card Card) ([]byte, error) {
+	code, err := qrcode.New(token, qrcode.Medium)
+	if err != nil {
+		return nil, err
+	}
+	faces, err := newCardFaces()
+	if err != nil {
+		return nil, err
+	}
+	loc := card.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, cardWidth, cardHeight))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, cardWidth, cardHeader), image.NewUniform(cardAccent), image.Point{}, draw.Src)
+
+	drawCentered(img, faces.title, color.White, card.SocietyName, 62)
+	drawCentered(img, faces.body, color.White, "Visitor pass", 104)
+
+	qrLeft := (cardWidth - cardQRSize) / 2
+	draw.Draw(img, image.Rect(qrLeft, cardQRTop, qrLeft+cardQRSize, cardQRTop+cardQRSize), code.Image(cardQRSize), image.Point{}, draw.Src)
+
+	y := cardQRTop + cardQRSize + 60
+	drawCentered(img, faces.title, cardInk, card.VisitorName, y)
+	y += 46
+	host := card.HostName
+	if card.HostUnit != "" {
+		host += ", " + card.HostUnit
+	}
+	drawCentered(img, faces.body, cardInk, "Visiting "+host, y)
+	y += 56
+	drawCentered(img, faces.small, cardMuted, "Valid from", y)
+	y += 34
+	drawCentered(img, faces.body, cardInk, card.ValidFrom.In(loc).Format(cardTimeForm)+" "+zoneName(card.ValidFrom.In(loc)), y)
+	y += 40
+	drawCentered(img, faces.small, cardMuted, "until", y)
+	y += 34
+	drawCentered(img, faces.body, cardInk, card.ExpiresAt.In(loc).Format(cardTimeForm)+" "+zoneName(card.ExpiresAt.In(loc)), y)
+
+	footer := "Show this code at the gate"
+	if card.SingleUse {
+		footer = "Single entry. Show this code at the gate"
+	}
+	drawCentered(img, faces.small, cardMuted, footer, cardHeight-cardMargin)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type cardFaces struct {
+	title font.Face
+	body  font.Face
+	small font.Face
+}
+
+func newCardFaces() (*cardFaces, error) {
+	bold, err := opentype.Parse(gobold.TTF)
+	if err != nil {
+		return nil, err
+	}
+	regular, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		return nil, err
+	}
+	face := func(f *opentype.Font, size float64) (font.Face, error) {
+		return opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	}
+
+	faces := &cardFaces{}
+	if faces.title, err = face(bold, 32); err != nil {
+		return nil, err
+	}
+	if faces.body, err = face(regular, 24); err != nil {
+		return nil, err
+	}
+	if faces.small, err = face(regular, 18); err != nil {
+		return nil, err
+	}
+	return faces, nil
+}
+
+// drawCentered writes text centred on the card with its baseline at y,
+// shortening it to fit between the margins
+func drawCentered(img draw.Image, face font.Face, c color.Color, text string, y int) {
+	drawer := &font.Drawer{Dst: img, Src: image.NewUniform(c), Face: face}
+	limit := fixed.I(cardWidth - 2*cardMargin)
+	if drawer.MeasureString(text) > limit {
+		runes := []rune(text)
+		for len(runes) > 0 && drawer.MeasureString(string(runes)+"…") > limit {
+			runes = runes[:len(runes)-1]
+		}
+		text = string(runes) + "…"
+	}
+	width := drawer.MeasureString(text)
+	drawer.Dot = fixed.Point26_6{X: (fixed.I(cardWidth) - width) / 2, Y: fixed.I(y)}
+	drawer.DrawString(text)
+}
+
+func zoneName(t time.Time) string {
+	name, _ := t.Zone()
+	return name
+}
@@ -0,0 +1,176 @@
+package passes
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Pass kinds. Every token starts with its kind and a format version, so
+// the format can change without misreading passes already handed out.
+const (
+	KindVisitor = "visitor"
+	KindStaff   = "staff"
+)
+
+var prefixes = map[string]string{
+	KindVisitor: "v1",
+	KindStaff:   "s1",
+}
+
+var (
+	ErrInvalid     = errors.New("passes: invalid pass")
+	ErrNotYetValid = errors.New("passes: pass is not valid yet")
+	ErrExpired     = errors.New("passes: pass has expired")
+)
+
+// Claims is what a pass vouches for: one visitor or staff member of one
+// society, between NotBefore and ExpiresAt
+type Claims struct {
+	Kind        string
+	SocietyCode string
+	SubjectID   primitive.ObjectID
+	NotBefore   time.Time
+	ExpiresAt   time.Time
+	SingleUse   bool
+}
+
+// Signer issues and verifies visitor and staff passes. A pass is the claims
+// in plain text followed by their HMAC-SHA256, so it can be checked without
+// a database round trip and cannot be guessed or altered.
+type Signer struct {
+	secret []byte
+	// early and late open a visitor pass's window around the expected time
+	early time.Duration
+	late  time.Duration
+}
+
+func NewSigner(secret string, early, late time.Duration) *Signer {
+	return &Signer{secret: []byte(secret), early: early, late: late}
+}
+
+// Issue signs a pass for the visitor, valid from early before its
+// ExpectedTime until late after it
+func (s *Signer) Issue(visitor *models.Visitor) (string, *Claims, error) {
+	return s.issue(&Claims{
+		Kind:        KindVisitor,
+		SocietyCode: visitor.SocietyCode,
+		SubjectID:   visitor.ID,
+		NotBefore:   visitor.ExpectedTime.Add(-s.early),
+		ExpiresAt:   visitor.ExpectedTime.Add(s.late),
+		SingleUse:   visitor.SingleUse,
+	})
+}
+
+// IssueStaff signs a long-lived pass for a staff member, valid between
+// from and until. Staff passes can be used any number of times.
+func (s *Signer) IssueStaff(staff *models.Staff, from, until time.Time) (string, *Claims, error) {
+	return s.issue(&Claims{
+		Kind:        KindStaff,
+		SocietyCode: staff.SocietyCode,
+		SubjectID:   staff.ID,
+		NotBefore:   from,
+		ExpiresAt:   until,
+	})
+}
+
+// issue signs the claims. Every pass carries a random nonce, so reissuing
+// gives a new pass even when nothing else changed.
+func (s *Signer) issue(claims *Claims) (string, *Claims, error) {
+	nonce := make([]byte, 6)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, err
+	}
+	claims.NotBefore = claims.NotBefore.Truncate(time.Second)
+	claims.ExpiresAt = claims.ExpiresAt.Truncate(time.Second)
+
+	payload := strings.Join([]string{
+		prefixes[claims.Kind],
+		claims.SocietyCode,
+		claims.SubjectID.Hex(),
+		strconv.FormatInt(claims.NotBefore.Unix(), 10),
+		strconv.FormatInt(claims.ExpiresAt.Unix(), 10),
+		singleUseFlag(claims.SingleUse),
+		base64.RawURLEncoding.EncodeToString(nonce),
+	}, ".")
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.sign(payload)), claims, nil
+}
+
+// Verify checks the pass's signature and that now is inside its window.
+// Claims are returned with ErrNotYetValid and ErrExpired so callers can
+// say when the pass was good for.
+func (s *Signer) Verify(token string, now time.Time) (*Claims, error) {
+	cut := strings.LastIndex(token, ".")
+	if cut < 0 {
+		return nil, ErrInvalid
+	}
+	payload := token[:cut]
+	signature, err := base64.RawURLEncoding.DecodeString(token[cut+1:])
+	if err != nil || !hmac.Equal(signature, s.sign(payload)) {
+		return nil, ErrInvalid
+	}
+
+	parts := strings.Split(payload, ".")
+	if len(parts) != 7 {
+		return nil, ErrInvalid
+	}
+	kind := ""
+	for k, prefix := range prefixes {
+		if parts[0] == prefix {
+			kind = k
+		}
+	}
+	if kind == "" {
+		return nil, ErrInvalid
+	}
+	subjectID, err := primitive.ObjectIDFromHex(parts[2])
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	notBefore, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	expiresAt, err := strconv.ParseInt(parts[4], 10, 64)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	claims := &Claims{
+		Kind:        kind,
+		SocietyCode: parts[1],
+		SubjectID:   subjectID,
+		NotBefore:   time.Unix(notBefore, 0),
+		ExpiresAt:   time.Unix(expiresAt, 0),
+		SingleUse:   parts[5] == singleUseFlag(true),
+	}
+
+	if now.Before(claims.NotBefore) {
+		return claims, ErrNotYetValid
+	}
+	if !now.Before(claims.ExpiresAt) {
+		return claims, ErrExpired
+	}
+	return claims, nil
+}
+
+func (s *Signer) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+func singleUseFlag(singleUse bool) string {
+	if singleUse {
+		return "1"
+	}
+	return "0"
+}
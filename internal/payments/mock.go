@@ -0,0 +1,85 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockSignatureHeader carries the hex HMAC-SHA256 of a mock webhook body
+const MockSignatureHeader = "X-Mock-Signature"
+
+// MockProvider is an offline gateway for development. Every intent starts
+// pending; its outcome is decided by calling Simulate, which produces the
+// same signed webhook a real gateway would deliver.
+type MockProvider struct {
+	secret []byte
+}
+
+func NewMockProvider(webhookSecret string) *MockProvider {
+	return &MockProvider{secret: []byte(webhookSecret)}
+}
+
+func (p *MockProvider) Name() string {
+	return "mock"
+}
+
+func (p *MockProvider) CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("payments: amount must be positive")
+	}
+	ref := "mock_" + primitive.NewObjectID().Hex()
+	return &Intent{
+		Ref:         ref,
+		Status:      "pending",
+		CheckoutURL: "/mock-checkout/" + ref,
+	}, nil
+}
+
+// Refund accepts every refund straight away
+func (p *MockProvider) Refund(ctx context.Context, req RefundRequest) (*Refund, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("payments: refund amount must be positive")
+	}
+	return &Refund{Ref: "mock_refund_" + primitive.NewObjectID().Hex()}, nil
+}
+
+func (p *MockProvider) ParseWebhook(payload []byte, header http.Header) (*Event, error) {
+	signature, err := hex.DecodeString(header.Get(MockSignatureHeader))
+	if err != nil || !hmac.Equal(signature, p.sign(payload)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedWebhook, err)
+	}
+	return &event, nil
+}
+
+func (p *MockProvider) Simulate(ref, outcome string) ([]byte, http.Header, error) {
+	event := Event{Ref: ref, Status: outcome}
+	if outcome == "failed" {
+		event.FailureReason = "Simulated decline"
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := http.Header{}
+	header.Set(MockSignatureHeader, hex.EncodeToString(p.sign(payload)))
+	return payload, header, nil
+}
+
+func (p *MockProvider) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,83 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	ErrInvalidSignature = errors.New("payments: invalid webhook signature")
+	ErrUnknownProvider  = errors.New("payments: unknown provider")
+	ErrNoWebhookSecret  = errors.New("payments: webhook secret is required")
+	ErrMalformedWebhook = errors.New("payments: malformed webhook")
+	ErrNotSimulated     = errors.New("payments: provider cannot simulate payments")
+)
+
+// IntentRequest asks a provider to start collecting a payment
+type IntentRequest struct {
+	PaymentID   string
+	Amount      float64
+	Currency    string
+	Method      string
+	Description string
+}
+
+// Intent is the provider's handle on a payment it is collecting
+type Intent struct {
+	Ref         string
+	Status      string // pending, succeeded, failed
+	CheckoutURL string
+}
+
+// RefundRequest asks a provider to return part or all of a collected payment
+type RefundRequest struct {
+	PaymentRef string
+	Amount     float64
+	Currency   string
+	Reason     string
+}
+
+// Refund is the provider's handle on a refund it has accepted
+type Refund struct {
+	Ref string
+}
+
+// Event is a verified status change reported by a provider webhook
+type Event struct {
+	Ref           string `json:"ref"`
+	Status        string `json:"status"` // pending, succeeded, failed
+	FailureReason string `json:"failure_reason,omitempty"`
+}
+
+// Provider is a payment gateway. Payments are two-phase: CreateIntent starts
+// one, and the outcome only becomes final when the provider reports it
+// through a signed webhook.
+type Provider interface {
+	Name() string
+	CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error)
+	// Refund returns money from a succeeded payment to the payer
+	Refund(ctx context.Context, req RefundRequest) (*Refund, error)
+	// ParseWebhook verifies the webhook signature and decodes its event
+	ParseWebhook(payload []byte, header http.Header) (*Event, error)
+}
+
+// Simulator is implemented by offline providers that can play the
+// gateway's part and produce the signed webhook it would send.
+type Simulator interface {
+	Simulate(ref, outcome string) (payload []byte, header http.Header, err error)
+}
+
+// NewProvider builds the provider configured by name
+func NewProvider(name, webhookSecret string) (Provider, error) {
+	if webhookSecret == "" {
+		return nil, ErrNoWebhookSecret
+	}
+	switch name {
+	case "mock":
+		return NewMockProvider(webhookSecret), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, name)
+	}
+}
@@ -0,0 +1,45 @@
+package payments
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Scheduler gives up payments whose webhook never arrived, releasing what
+// they held so the payer can try again
+type Scheduler struct {
+	service  *Service
+	interval time.Duration
+}
+
+func NewScheduler(service *Service, interval time.Duration) *Scheduler {
+	return &Scheduler{service: service, interval: interval}
+}
+
+// Start runs a pass immediately and then on every tick until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.RunOnce(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce performs one expiry pass
+func (s *Scheduler) RunOnce(ctx context.Context, now time.Time) {
+	expired, err := s.service.ExpireIntents(ctx, now)
+	if err != nil {
+		log.Printf("⚠️ Payments: failed to expire payments: %v", err)
+	}
+	if expired > 0 {
+		log.Printf("⏳ Payments: %d pending payments expired", expired)
+	}
+}
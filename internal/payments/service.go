@@ -0,0 +1,376 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"bms-backend/internal/amenities"
+	"bms-backend/internal/ledger"
+	"bms-backend/internal/models"
+	"bms-backend/internal/receipts"
+	"bms-backend/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Service records payments and applies provider outcomes to whatever the
+// payment was for, issuing a receipt once a payment succeeds.
+type Service struct {
+	store     *store.Store
+	ledger    *ledger.Ledger
+	amenities *amenities.Service
+	documents *receipts.Service
+	provider  Provider
+	// intentLifetime is how long a payment may stay pending before it is
+	// given up
+	intentLifetime time.Duration
+}
+
+func NewService(s *store.Store, provider Provider, documents *receipts.Service, amenityService *amenities.Service, intentLifetime time.Duration) *Service {
+	return &Service{
+		store:          s,
+		ledger:         ledger.New(s),
+		amenities:      amenityService,
+		documents:      documents,
+		provider:       provider,
+		intentLifetime: intentLifetime,
+	}
+}
+
+func (s *Service) Provider() Provider {
+	return s.provider
+}
+
+// Start creates the provider intent for a payment and stores the payment.
+// The caller fills in purpose, reference, payer, amount and society.
+func (s *Service) Start(ctx context.Context, payment *models.Payment, description string) error {
+	if payment.ID.IsZero() {
+		return errors.New("payments: payment ID must be set before starting")
+	}
+	if payment.Currency == "" {
+		payment.Currency = "INR"
+	}
+
+	intent, err := s.provider.CreateIntent(ctx, IntentRequest{
+		PaymentID:   payment.ID.Hex(),
+		Amount:      payment.Amount,
+		Currency:    payment.Currency,
+		Method:      payment.Method,
+		Description: description,
+	})
+	if err != nil {
+		return err
+	}
+
+	payment.Provider = s.provider.Name()
+	payment.ProviderRef = intent.Ref
+	payment.CheckoutURL = intent.CheckoutURL
+	payment.Status = "pending"
+	payment.CreatedAt = time.Now()
+	payment.UpdatedAt = time.Now()
+	expiresAt := payment.CreatedAt.Add(s.intentLifetime)
+	payment.ExpiresAt = &expiresAt
+	return s.store.Payments.Create(ctx, payment)
+}
+
+// InProgress returns the pending payment for referenceID that may still
+// complete, or nil. Pending payments past their expiry are given up on the
+// way, so a webhook that never arrives does not block new attempts.
+func (s *Service) InProgress(ctx context.Context, societyCode string, referenceID primitive.ObjectID, now time.Time) (*models.Payment, error) {
+	pending, err := s.store.Payments.List(ctx, store.PaymentFilter{
+		SocietyCode: societyCode,
+		ReferenceID: referenceID,
+		Statuses:    []string{"pending"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range pending {
+		payment := &pending[i]
+		if payment.ExpiresAt == nil || now.Before(*payment.ExpiresAt) {
+			return payment, nil
+		}
+		if err := s.Expire(ctx, payment); err != nil && !errors.Is(err, store.ErrConflict) {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// ExpireIntents gives up pending payments whose intent expired by now and
+// returns how many it expired
+func (s *Service) ExpireIntents(ctx context.Context, now time.Time) (int, error) {
+	stale, err := s.store.Payments.List(ctx, store.PaymentFilter{
+		Statuses:  []string{"pending"},
+		ExpiredBy: now,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for i := range stale {
+		err := s.Expire(ctx, &stale[i])
+		if errors.Is(err, store.ErrConflict) {
+			// Completed meanwhile
+			continue
+		}
+		if err != nil {
+			log.Printf("⚠️ Payments: failed to expire payment %s: %v", stale[i].ID.Hex(), err)
+			continue
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// Expire gives up a pending payment and releases what it held, like a
+// failed one. The provider may still complete it; HandleWebhook then
+// applies it after all. It returns store.ErrConflict when the payment is
+// no longer pending.
+func (s *Service) Expire(ctx context.Context, payment *models.Payment) error {
+	return s.expire(ctx, payment, "Payment was not completed in time")
+}
+
+// Cancel gives up a pending payment whose purchase was withdrawn before it
+// was paid, such as a booking series rolled back half way. It is recorded
+// as expired, so a late success still reaches HandleWebhook, which refunds
+// it once it finds the booking gone.
+func (s *Service) Cancel(ctx context.Context, payment *models.Payment, reason string) error {
+	return s.expire(ctx, payment, reason)
+}
+
+func (s *Service) expire(ctx context.Context, payment *models.Payment, reason string) error {
+	status := "expired"
+	err := s.store.Payments.Transition(ctx, payment.ID, "pending", store.PaymentUpdate{
+		Status:        &status,
+		FailureReason: &reason,
+	})
+	if err != nil {
+		return err
+	}
+	payment.Status = status
+	payment.FailureReason = reason
+	return s.release(ctx, payment)
+}
+
+// HandleWebhook verifies a provider webhook and applies its outcome.
+// Replayed webhooks leave the payment untouched.
+func (s *Service) HandleWebhook(ctx context.Context, payload []byte, header http.Header) (*models.Payment, error) {
+	event, err := s.provider.ParseWebhook(payload, header)
+	if err != nil {
+		return nil, err
+	}
+
+	payment, err := s.store.Payments.GetByProviderRef(ctx, s.provider.Name(), event.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	switch event.Status {
+	case "succeeded":
+		now := time.Now()
+		update := store.PaymentUpdate{Status: &event.Status, ConfirmedAt: &now}
+		err = s.store.Payments.Transition(ctx, payment.ID, "pending", update)
+		if errors.Is(err, store.ErrConflict) {
+			// The money was taken after the payment expired here; it
+			// counts all the same
+			err = s.store.Payments.Transition(ctx, payment.ID, "expired", update)
+		}
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			// Replayed or concurrent webhook. Settle again when the payment
+			// did succeed, in case an earlier attempt failed half way;
+			// settling is idempotent.
+			payment, err = s.store.Payments.GetByProviderRef(ctx, s.provider.Name(), event.Ref)
+			if err != nil {
+				return nil, err
+			}
+			if payment.Status != "succeeded" {
+				return payment, nil
+			}
+		case err != nil:
+			return nil, err
+		default:
+			payment.Status = event.Status
+			payment.ConfirmedAt = &now
+		}
+		if err := s.settle(ctx, payment); err != nil {
+			return nil, err
+		}
+		// The payment stands even if its receipt cannot be produced; a
+		// replayed webhook retries. Payments refunded on settling get none.
+		if payment.Status != "succeeded" {
+			return payment, nil
+		}
+		if _, err := s.documents.IssueReceipt(ctx, payment); err != nil {
+			log.Printf("⚠️ Failed to issue receipt for payment %s: %v", payment.ID.Hex(), err)
+		}
+
+	case "failed":
+		err = s.store.Payments.Transition(ctx, payment.ID, "pending", store.PaymentUpdate{
+			Status:        &event.Status,
+			FailureReason: &event.FailureReason,
+		})
+		if errors.Is(err, store.ErrConflict) {
+			return payment, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		payment.Status = event.Status
+		payment.FailureReason = event.FailureReason
+		if err := s.release(ctx, payment); err != nil {
+			return nil, err
+		}
+	}
+
+	return payment, nil
+}
+
+// settle applies a confirmed payment to what it was for
+func (s *Service) settle(ctx context.Context, payment *models.Payment) error {
+	switch payment.Purpose {
+	case "maintenance":
+		// The ledger allocates the amount to the record, leaving it partially
+		// paid or turning any excess into advance credit
+		return s.ledger.PostPayment(ctx, payment)
+	case "amenity_booking":
+		status := "confirmed"
+		err := s.store.Bookings.Transition(ctx, payment.SocietyCode, payment.ReferenceID, []string{"pending_payment", "confirmed"}, store.BookingUpdate{
+			Status: &status,
+		})
+		if errors.Is(err, store.ErrConflict) {
+			// The booking was cancelled and its seats released while the
+			// payment was pending; it must not come back to life, so the
+			// money goes back, by the refund tier of the cancellation
+			booking, err := s.store.Bookings.GetByID(ctx, payment.SocietyCode, payment.ReferenceID)
+			if err != nil {
+				return err
+			}
+			percent := 100.0
+			if booking.RefundPercent != nil {
+				percent = *booking.RefundPercent
+			}
+			amount := amenities.RefundAmount(payment.Amount, percent)
+			if amount <= 0 {
+				log.Printf("⚠️ Payment %s succeeded for cancelled booking %s, which refunds nothing", payment.ID.Hex(), booking.ID.Hex())
+				return nil
+			}
+			log.Printf("⚠️ Payment %s succeeded for booking %s which is no longer active, refunding %.0f%%", payment.ID.Hex(), booking.ID.Hex(), percent)
+			err = s.Refund(ctx, payment, amount, "Booking cancelled before payment completed")
+			if errors.Is(err, store.ErrConflict) {
+				// Refunded by an earlier delivery of this webhook
+				return nil
+			}
+			return err
+		}
+		return err
+	}
+	return nil
+}
+
+// RefundBooking refunds percent of the fee paid for a cancelled booking and
+// returns the refunded payment. It returns nil when nothing was collected
+// for the booking, or when the percentage leaves nothing to refund.
+func (s *Service) RefundBooking(ctx context.Context, booking *models.AmenityBooking, percent float64) (*models.Payment, error) {
+	if booking.PaymentID == "" {
+		return nil, nil
+	}
+	paymentID, err := primitive.ObjectIDFromHex(booking.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+	payment, err := s.store.Payments.GetByID(ctx, booking.SocietyCode, paymentID)
+	if errors.Is(err, store.ErrNotFound) {
+		// The payment could not be started
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if payment.Status != "succeeded" {
+		// A pending payment is refunded by the booking's RefundPercent if
+		// it ever succeeds
+		return nil, nil
+	}
+
+	amount := amenities.RefundAmount(payment.Amount, percent)
+	if amount <= 0 {
+		return nil, nil
+	}
+	if err := s.Refund(ctx, payment, amount, "Booking "+booking.AmenityName+" "+booking.TimeSlot+" cancelled"); err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// Refund returns amount of a succeeded payment through its provider and
+// records it on the payment. The payment is claimed as refund_pending
+// first, so a payment is refunded at most once; it returns
+// store.ErrConflict when the payment is not refundable.
+func (s *Service) Refund(ctx context.Context, payment *models.Payment, amount float64, reason string) error {
+	if amount <= 0 || amount > payment.Amount {
+		return fmt.Errorf("payments: cannot refund %.2f of %.2f", amount, payment.Amount)
+	}
+
+	pending := "refund_pending"
+	err := s.store.Payments.Transition(ctx, payment.ID, "succeeded", store.PaymentUpdate{
+		Status:       &pending,
+		RefundAmount: &amount,
+	})
+	if err != nil {
+		return err
+	}
+
+	refund, err := s.provider.Refund(ctx, RefundRequest{
+		PaymentRef: payment.ProviderRef,
+		Amount:     amount,
+		Currency:   payment.Currency,
+		Reason:     reason,
+	})
+	if err != nil {
+		// Put the payment back so the refund can be retried
+		succeeded, none := "succeeded", 0.0
+		s.store.Payments.Transition(ctx, payment.ID, pending, store.PaymentUpdate{
+			Status:       &succeeded,
+			RefundAmount: &none,
+		})
+		return err
+	}
+
+	refunded := "refunded"
+	now := time.Now()
+	err = s.store.Payments.Transition(ctx, payment.ID, pending, store.PaymentUpdate{
+		Status:     &refunded,
+		RefundRef:  &refund.Ref,
+		RefundedAt: &now,
+	})
+	if err != nil {
+		return err
+	}
+	payment.Status = refunded
+	payment.RefundAmount = amount
+	payment.RefundRef = refund.Ref
+	payment.RefundedAt = &now
+	return nil
+}
+
+// release undoes holds taken while the payment was pending
+func (s *Service) release(ctx context.Context, payment *models.Payment) error {
+	if payment.Purpose == "amenity_booking" {
+		// Free the slot so someone else can book it
+		booking, err := s.store.Bookings.GetByID(ctx, payment.SocietyCode, payment.ReferenceID)
+		if err != nil {
+			return err
+		}
+		if err := s.amenities.Cancel(ctx, booking); err != nil && !errors.Is(err, store.ErrConflict) {
+			return err
+		}
+	}
+	return nil
+}
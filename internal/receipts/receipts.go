@@ -0,0 +1,265 @@
+package receipts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/storage"
+	"bms-backend/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// abandonedAfter is how long a document may stay unnumbered before its
+// issue is taken to have died half way
+const abandonedAfter = 5 * time.Minute
+
+// Service issues the PDF invoices and receipts of a society's billing.
+// Every maintenance record gets one invoice and every successful payment
+// one receipt; issuing again returns the existing document.
+type Service struct {
+	store   *store.Store
+	storage storage.Storage
+}
+
+func NewService(s *store.Store, files storage.Storage) *Service {
+	return &Service{store: s, storage: files}
+}
+
+// URL is where clients download the document
+func URL(doc *models.BillingDocument) string {
+	return "/api/v1/receipts/" + doc.ID.Hex()
+}
+
+// IssueInvoice renders the invoice for a maintenance record and links it
+// from the record
+func (s *Service) IssueInvoice(ctx context.Context, record *models.MaintenanceRecord) (*models.BillingDocument, error) {
+	existing, err := s.existing(ctx, record.SocietyCode, "invoice", record.ID)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+
+	recordID, unitID := record.ID, record.UnitID
+	doc := &models.BillingDocument{
+		Kind:          "invoice",
+		ReferenceID:   record.ID,
+		MaintenanceID: &recordID,
+		UnitID:        &unitID,
+		UnitNumber:    record.UnitNumber,
+		Amount:        record.Amount,
+		SocietyID:     record.SocietyID,
+		SocietyCode:   record.SocietyCode,
+	}
+
+	description := "Maintenance charges for " + record.Month
+	if record.Description != "" {
+		description += " - " + record.Description
+	}
+	content := page{
+		title: "MAINTENANCE INVOICE",
+		details: [][2]string{
+			{"Unit", record.UnitNumber},
+			{"Billing Month", record.Month},
+			{"Due Date", record.DueDate.Format("02 Jan 2006")},
+		},
+		items: []item{{description: description, amount: record.Amount}},
+	}
+
+	doc, err = s.issue(ctx, doc, content)
+	if err != nil {
+		return nil, err
+	}
+
+	url := URL(doc)
+	if err := s.store.Maintenance.Update(ctx, record.SocietyCode, record.ID, store.MaintenanceUpdate{InvoiceURL: &url}); err != nil {
+		return nil, err
+	}
+	record.InvoiceURL = url
+	return doc, nil
+}
+
+// IssueReceipt renders the receipt for a successful payment. Maintenance
+// payments are also linked from the record they were made for.
+func (s *Service) IssueReceipt(ctx context.Context, payment *models.Payment) (*models.BillingDocument, error) {
+	if payment.Status != "succeeded" {
+		return nil, fmt.Errorf("receipts: payment %s has not succeeded", payment.ID.Hex())
+	}
+
+	existing, err := s.existing(ctx, payment.SocietyCode, "receipt", payment.ID)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+
+	paymentID, userID := payment.ID, payment.UserID
+	doc := &models.BillingDocument{
+		Kind:        "receipt",
+		ReferenceID: payment.ID,
+		PaymentID:   &paymentID,
+		UnitID:      payment.UnitID,
+		UserID:      &userID,
+		Amount:      payment.Amount,
+		SocietyID:   payment.SocietyID,
+		SocietyCode: payment.SocietyCode,
+	}
+	if payment.UnitID != nil {
+		if unit, err := s.store.Units.GetByID(ctx, payment.SocietyCode, *payment.UnitID); err == nil {
+			doc.UnitNumber = unit.Number
+		}
+	}
+
+	payer := ""
+	if user, err := s.store.Users.GetByID(ctx, payment.SocietyCode, payment.UserID); err == nil {
+		payer = user.Name
+	}
+
+	description := "Payment"
+	switch payment.Purpose {
+	case "maintenance":
+		recordID := payment.ReferenceID
+		doc.MaintenanceID = &recordID
+		description = "Maintenance payment"
+		if record, err := s.store.Maintenance.GetByID(ctx, payment.SocietyCode, payment.ReferenceID); err == nil {
+			description = "Maintenance payment for " + record.Month
+		}
+	case "amenity_booking":
+		description = "Amenity booking"
+		if booking, err := s.store.Bookings.GetByID(ctx, payment.SocietyCode, payment.ReferenceID); err == nil {
+			description = fmt.Sprintf("Amenity booking: %s on %s, %s",
+				booking.AmenityName, booking.Date.Format("02 Jan 2006"), booking.TimeSlot)
+		}
+	}
+
+	paidOn := payment.CreatedAt
+	if payment.ConfirmedAt != nil {
+		paidOn = *payment.ConfirmedAt
+	}
+	content := page{
+		title: "PAYMENT RECEIPT",
+		details: [][2]string{
+			{"Received From", payer},
+			{"Unit", doc.UnitNumber},
+			{"Payment Date", paidOn.Format("02 Jan 2006")},
+			{"Transaction Ref", payment.ProviderRef},
+			{"Payment Method", payment.Method},
+		},
+		items: []item{{description: description, amount: payment.Amount}},
+	}
+
+	doc, err = s.issue(ctx, doc, content)
+	if err != nil {
+		return nil, err
+	}
+
+	if payment.Purpose == "maintenance" {
+		url := URL(doc)
+		if err := s.store.Maintenance.Update(ctx, payment.SocietyCode, payment.ReferenceID, store.MaintenanceUpdate{ReceiptURL: &url}); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// Open returns the stored PDF of a document
+func (s *Service) Open(ctx context.Context, doc *models.BillingDocument) (io.ReadCloser, error) {
+	return s.storage.Get(ctx, doc.StorageKey)
+}
+
+// issue records, numbers, renders and stores a document. The document is
+// recorded before it takes a number: a concurrent issue for the same
+// reference loses the race through the unique index without burning one,
+// and returns the winner's document. A number taken by an issue that fails
+// later is released for the next document, so numbers have no gaps.
+func (s *Service) issue(ctx context.Context, doc *models.BillingDocument, content page) (*models.BillingDocument, error) {
+	society, err := s.store.Societies.GetByCode(ctx, doc.SocietyCode)
+	if err != nil {
+		return nil, err
+	}
+
+	doc.ID = primitive.NewObjectID()
+	doc.IssuedAt = time.Now()
+	doc.StorageKey = fmt.Sprintf("documents/%s/%ss/%s.pdf", doc.SocietyCode, doc.Kind, doc.ID.Hex())
+	err = s.store.Documents.Create(ctx, doc)
+	if errors.Is(err, store.ErrDuplicate) {
+		return s.store.Documents.GetByReference(ctx, doc.SocietyCode, doc.Kind, doc.ReferenceID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	counter := doc.Kind + ":" + doc.SocietyCode
+	sequence, err := s.store.Counters.Next(ctx, counter)
+	if err != nil {
+		s.abandon(ctx, doc, counter, 0)
+		return nil, err
+	}
+	number := fmt.Sprintf("%s-%s-%06d", prefixes[doc.Kind], doc.SocietyCode, sequence)
+	if err := s.store.Documents.SetNumber(ctx, doc.SocietyCode, doc.ID, sequence, number); err != nil {
+		s.abandon(ctx, doc, counter, sequence)
+		return nil, err
+	}
+	doc.Sequence = sequence
+	doc.Number = number
+
+	content.number = doc.Number
+	content.issuedAt = doc.IssuedAt
+	var buf bytes.Buffer
+	if err := render(&buf, society, content); err != nil {
+		s.abandon(ctx, doc, counter, sequence)
+		return nil, err
+	}
+	if err := s.storage.Put(ctx, doc.StorageKey, &buf); err != nil {
+		s.abandon(ctx, doc, counter, sequence)
+		return nil, err
+	}
+	return doc, nil
+}
+
+// abandon removes a document whose issue failed, so it can be issued
+// again, and releases its number, if it took one, for the next document
+func (s *Service) abandon(ctx context.Context, doc *models.BillingDocument, counter string, sequence int64) {
+	if err := s.store.Documents.Delete(ctx, doc.SocietyCode, doc.ID); err != nil {
+		log.Printf("⚠️ Failed to remove unissued %s for %s: %v", doc.Kind, doc.ReferenceID.Hex(), err)
+		// The number stays on the document that could not be removed
+		return
+	}
+	if sequence == 0 {
+		return
+	}
+	if err := s.store.Counters.Release(ctx, counter, sequence); err != nil {
+		log.Printf("⚠️ Failed to release unused %s number %d of %s: %v", doc.Kind, sequence, doc.SocietyCode, err)
+	}
+}
+
+// existing returns the document of kind already issued for referenceID,
+// or store.ErrNotFound. A document left without a number by an issue that
+// died half way is removed, so it is issued afresh.
+func (s *Service) existing(ctx context.Context, societyCode, kind string, referenceID primitive.ObjectID) (*models.BillingDocument, error) {
+	doc, err := s.store.Documents.GetByReference(ctx, societyCode, kind, referenceID)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Sequence == 0 && time.Since(doc.IssuedAt) > abandonedAfter {
+		if err := s.store.Documents.Delete(ctx, societyCode, doc.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+			return nil, err
+		}
+		return nil, store.ErrNotFound
+	}
+	return doc, nil
+}
+
+var prefixes = map[string]string{
+	"invoice": "INV",
+	"receipt": "RCT",
+}
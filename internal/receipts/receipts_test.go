@@ -0,0 +1,97 @@
+package receipts
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/storage"
+	"bms-backend/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Concurrent issues of one payment's receipt must record a single document
+// and must not burn a receipt number: the losers' numbers go to the next
+// receipts, leaving no gap
+func TestIssueReceiptConcurrentNoGap(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	files, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewService(s, files)
+
+	society := &models.Society{Name: "Race Society", Code: "RACE001", IsActive: true}
+	if err := s.Societies.Create(ctx, society); err != nil {
+		t.Fatal(err)
+	}
+	newPayment := func() *models.Payment {
+		now := time.Now()
+		payment := &models.Payment{
+			ID:          primitive.NewObjectID(),
+			Purpose:     "amenity_booking",
+			ReferenceID: primitive.NewObjectID(),
+			UserID:      primitive.NewObjectID(),
+			Amount:      500,
+			Currency:    "INR",
+			Status:      "succeeded",
+			ConfirmedAt: &now,
+			SocietyID:   society.ID,
+			SocietyCode: society.Code,
+			CreatedAt:   now,
+		}
+		if err := s.Payments.Create(ctx, payment); err != nil {
+			t.Fatal(err)
+		}
+		return payment
+	}
+
+	payment := newPayment()
+	const issuers = 8
+	docs := make([]*models.BillingDocument, issuers)
+	errs := make([]error, issuers)
+	var wg sync.WaitGroup
+	wg.Add(issuers)
+	for i := 0; i < issuers; i++ {
+		go func(i int) {
+			defer wg.Done()
+			docs[i], errs[i] = service.IssueReceipt(ctx, payment)
+		}(i)
+	}
+	wg.Wait()
+	for i := range docs {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if docs[i].ID != docs[0].ID {
+			t.Fatalf("issue %d returned document %s, want %s", i, docs[i].ID.Hex(), docs[0].ID.Hex())
+		}
+	}
+
+	receipts, err := s.Documents.List(ctx, store.DocumentFilter{SocietyCode: society.Code, Kind: "receipt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(receipts) != 1 {
+		t.Fatalf("%d receipts recorded for one payment, want 1", len(receipts))
+	}
+
+	if _, err := service.IssueReceipt(ctx, newPayment()); err != nil {
+		t.Fatal(err)
+	}
+	receipts, err = s.Documents.List(ctx, store.DocumentFilter{SocietyCode: society.Code, Kind: "receipt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	numbered := map[int64]bool{}
+	for _, receipt := range receipts {
+		numbered[receipt.Sequence] = true
+	}
+	if len(receipts) != 2 || !numbered[1] || !numbered[2] {
+		t.Fatalf("receipts numbered %v, want 1 and 2", numbered)
+	}
+}
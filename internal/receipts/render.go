@@ -0,0 +1,146 @@
+package receipts
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/utils"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// page is what a document shows below the society letterhead
+type page struct {
+	title    string
+	number   string
+	issuedAt time.Time
+	details  [][2]string // Label and value pairs; empty values are left out
+	items    []item
+}
+
+type item struct {
+	description string
+	amount      float64
+}
+
+const (
+	pageWidth   = 210.0
+	margin      = 15.0
+	amountWidth = 45.0
+)
+
+// render writes an A4 PDF with the society letterhead, the document details,
+// the line items and the total spelled out in words
+func render(w io.Writer, society *models.Society, content page) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(true, margin)
+	pdf.SetTitle(content.title+" "+content.number, true)
+	pdf.SetCreator(society.Name, true)
+	pdf.AddPage()
+
+	// The core fonts are cp1252; translate so accented names still print
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	width := pageWidth - 2*margin
+
+	// Letterhead
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(width, 9, tr(society.Name), "", 1, "C", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	if address := joinNonEmpty(", ", society.Address, society.City, joinNonEmpty(" - ", society.State, society.PinCode)); address != "" {
+		pdf.CellFormat(width, 5, tr(address), "", 1, "C", false, 0, "")
+	}
+	if contact := joinNonEmpty("  |  ", society.ContactEmail, society.ContactPhone); contact != "" {
+		pdf.CellFormat(width, 5, tr(contact), "", 1, "C", false, 0, "")
+	}
+	pdf.Ln(2)
+	pdf.SetLineWidth(0.5)
+	pdf.Line(margin, pdf.GetY(), pageWidth-margin, pdf.GetY())
+	pdf.Ln(6)
+
+	// Title and number
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.CellFormat(width, 8, content.title, "", 1, "C", false, 0, "")
+	pdf.Ln(4)
+
+	details := append([][2]string{
+		{"Number", content.number},
+		{"Date", content.issuedAt.Format("02 Jan 2006")},
+	}, content.details...)
+	for _, detail := range details {
+		if detail[1] == "" {
+			continue
+		}
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(40, 6, detail[0], "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.CellFormat(width-40, 6, tr(detail[1]), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(6)
+
+	// Line items
+	pdf.SetFillColor(235, 235, 235)
+	pdf.SetLineWidth(0.2)
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(width-amountWidth, 8, "Description", "1", 0, "L", true, 0, "")
+	pdf.CellFormat(amountWidth, 8, "Amount (Rs.)", "1", 1, "R", true, 0, "")
+
+	var total float64
+	pdf.SetFont("Helvetica", "", 10)
+	for _, line := range content.items {
+		pdf.CellFormat(width-amountWidth, 8, tr(line.description), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(amountWidth, 8, formatAmount(line.amount), "1", 1, "R", false, 0, "")
+		total += line.amount
+	}
+
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(width-amountWidth, 8, "Total", "1", 0, "R", false, 0, "")
+	pdf.CellFormat(amountWidth, 8, formatAmount(total), "1", 1, "R", false, 0, "")
+	pdf.Ln(4)
+
+	pdf.SetFont("Helvetica", "I", 10)
+	pdf.MultiCell(width, 6, "Amount in words: "+utils.AmountInWords(total), "", "L", false)
+
+	// Footer
+	pdf.Ln(12)
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.SetTextColor(110, 110, 110)
+	pdf.MultiCell(width, 4, "This is a computer generated document and does not require a signature.", "", "C", false)
+
+	return pdf.Output(w)
+}
+
+// formatAmount prints an amount with Indian digit grouping, e.g. 1,25,050.50
+func formatAmount(amount float64) string {
+	s := fmt.Sprintf("%.2f", amount)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, fraction := s[:len(s)-3], s[len(s)-3:]
+
+	if len(whole) > 3 {
+		head, tail := whole[:len(whole)-3], whole[len(whole)-3:]
+		var groups []string
+		for len(head) > 2 {
+			groups = append([]string{head[len(head)-2:]}, groups...)
+			head = head[:len(head)-2]
+		}
+		groups = append([]string{head}, groups...)
+		whole = strings.Join(groups, ",") + "," + tail
+	}
+	return sign + whole + fraction
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	var kept []string
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, sep)
+}
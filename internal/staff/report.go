@@ -0,0 +1,86 @@
+package staff
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrInvalidMonth = errors.New("staff: month must be YYYY-MM")
+
+// Report is a staff member's attendance over one month
+type Report struct {
+	StaffID      primitive.ObjectID `json:"staff_id"`
+	Name         string             `json:"name"`
+	Category     string             `json:"category"`
+	Units        []string           `json:"units"`
+	Month        string             `json:"month"`
+	DaysPresent  int                `json:"days_present"`
+	TotalMinutes int                `json:"total_minutes"`
+	Days         []Day              `json:"days"`
+}
+
+// Day is the attendance of one calendar day in the staff member's
+// timezone. Entries still open count towards presence but not minutes.
+type Day struct {
+	Date    string                   `json:"date"`
+	Minutes int                      `json:"minutes"`
+	Entries []models.StaffAttendance `json:"entries"`
+}
+
+// MonthRange returns the first instant of the month and of the next one
+// in loc
+func MonthRange(month string, loc *time.Location) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation(MonthLayout, month, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, ErrInvalidMonth
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// Report builds the staff member's attendance for the month, one Day per
+// day with at least one check-in
+func (s *Service) Report(ctx context.Context, staff *models.Staff, month string) (*Report, error) {
+	from, to, err := MonthRange(month, Location(staff))
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.store.Attendance.List(ctx, store.AttendanceFilter{
+		SocietyCode: staff.SocietyCode,
+		StaffID:     staff.ID,
+		From:        from,
+		To:          to,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		StaffID:  staff.ID,
+		Name:     staff.Name,
+		Category: staff.Category,
+		Units:    staff.Units,
+		Month:    month,
+		Days:     []Day{},
+	}
+	// Entries come in check-in order, so each day's entries are together
+	for _, entry := range entries {
+		if len(report.Days) == 0 || report.Days[len(report.Days)-1].Date != entry.Date {
+			report.Days = append(report.Days, Day{Date: entry.Date})
+		}
+		day := &report.Days[len(report.Days)-1]
+		day.Entries = append(day.Entries, entry)
+		if entry.CheckOut != nil {
+			minutes := int(entry.CheckOut.Sub(entry.CheckIn).Minutes())
+			day.Minutes += minutes
+			report.TotalMinutes += minutes
+		}
+	}
+	report.DaysPresent = len(report.Days)
+	return report, nil
+}
@@ -0,0 +1,155 @@
+package staff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"bms-backend/internal/amenities"
+	"bms-backend/internal/models"
+	"bms-backend/internal/passes"
+	"bms-backend/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MonthLayout is the format of report months
+const MonthLayout = "2006-01"
+
+const clockLayout = "15:04"
+
+var (
+	ErrInvalidHours = errors.New("staff: invalid hours")
+	ErrInactive     = errors.New("staff: staff member is not active")
+	ErrOutsideHours = errors.New("staff: outside the staff member's allowed hours")
+	ErrInside       = errors.New("staff: staff member is already inside")
+	ErrNotInside    = errors.New("staff: staff member is not inside")
+)
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// Service issues staff passes and keeps their attendance
+type Service struct {
+	store  *store.Store
+	passes *passes.Signer
+	// passLifetime is how long a staff pass stays valid once issued
+	passLifetime time.Duration
+}
+
+func NewService(s *store.Store, signer *passes.Signer, passLifetime time.Duration) *Service {
+	return &Service{store: s, passes: signer, passLifetime: passLifetime}
+}
+
+// ValidateHours checks the timezone and entry windows of a staff member.
+// Windows may not run past midnight.
+func ValidateHours(timezone string, hours []models.OpeningHours) error {
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", ErrInvalidHours, timezone)
+	}
+	for _, window := range hours {
+		if _, ok := weekdays[strings.ToLower(window.Weekday)]; !ok {
+			return fmt.Errorf("%w: unknown weekday %q", ErrInvalidHours, window.Weekday)
+		}
+		open, err := time.Parse(clockLayout, window.Open)
+		if err != nil {
+			return fmt.Errorf("%w: open must be HH:MM", ErrInvalidHours)
+		}
+		closes, err := time.Parse(clockLayout, window.Close)
+		if err != nil {
+			return fmt.Errorf("%w: close must be HH:MM", ErrInvalidHours)
+		}
+		if !closes.After(open) {
+			return fmt.Errorf("%w: %s %s-%s closes before it opens", ErrInvalidHours, window.Weekday, window.Open, window.Close)
+		}
+	}
+	return nil
+}
+
+// Location loads the staff member's timezone
+func Location(staff *models.Staff) *time.Location {
+	loc, err := time.LoadLocation(staff.Timezone)
+	if err != nil || staff.Timezone == "" {
+		loc, _ = time.LoadLocation(amenities.DefaultTimezone)
+	}
+	return loc
+}
+
+// AllowedAt reports whether the staff member may enter at t. Staff without
+// hours may enter at any time.
+func AllowedAt(staff *models.Staff, t time.Time) bool {
+	if len(staff.Hours) == 0 {
+		return true
+	}
+	local := t.In(Location(staff))
+	clock := local.Format(clockLayout)
+	for _, window := range staff.Hours {
+		if weekdays[strings.ToLower(window.Weekday)] != local.Weekday() {
+			continue
+		}
+		// HH:MM strings order the same way as the times they name
+		if clock >= window.Open && clock < window.Close {
+			return true
+		}
+	}
+	return false
+}
+
+// IssuePass gives the staff member a new pass valid from now, replacing
+// any earlier one. The caller saves it.
+func (s *Service) IssuePass(staff *models.Staff, now time.Time) error {
+	token, claims, err := s.passes.IssueStaff(staff, now, now.Add(s.passLifetime))
+	if err != nil {
+		return err
+	}
+	staff.PassToken = token
+	staff.PassExpiresAt = claims.ExpiresAt
+	return nil
+}
+
+// CheckIn lets the staff member in if they are active and within their
+// hours, and opens the day's attendance entry
+func (s *Service) CheckIn(ctx context.Context, staff *models.Staff, guard primitive.ObjectID, now time.Time) (*models.StaffAttendance, error) {
+	if !staff.IsActive {
+		return nil, ErrInactive
+	}
+	if !AllowedAt(staff, now) {
+		return nil, ErrOutsideHours
+	}
+
+	entry := &models.StaffAttendance{
+		StaffID:     staff.ID,
+		StaffName:   staff.Name,
+		Date:        now.In(Location(staff)).Format(amenities.DateLayout),
+		CheckIn:     now,
+		Inside:      true,
+		CheckedInBy: guard,
+		SocietyCode: staff.SocietyCode,
+	}
+	if err := s.store.Attendance.CheckIn(ctx, entry); err != nil {
+		if errors.Is(err, store.ErrDuplicate) {
+			return nil, ErrInside
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+// CheckOut closes the staff member's open attendance entry. Leaving is
+// never refused, whatever the hours.
+func (s *Service) CheckOut(ctx context.Context, staff *models.Staff, guard primitive.ObjectID, now time.Time) (*models.StaffAttendance, error) {
+	entry, err := s.store.Attendance.CheckOut(ctx, staff.SocietyCode, staff.ID, now, guard)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrNotInside
+	}
+	return entry, err
+}
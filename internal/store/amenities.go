@@ -0,0 +1,237 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AmenityFilter narrows List. Zero-valued fields are ignored.
+type AmenityFilter struct {
+	SocietyCode string
+	ActiveOnly  bool
+}
+
+type AmenityRepository interface {
+	Create(ctx context.Context, amenity *models.Amenity) error
+	GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Amenity, error)
+	List(ctx context.Context, filter AmenityFilter) ([]models.Amenity, error)
+	SetSchedule(ctx context.Context, societyCode string, id primitive.ObjectID, schedule *models.AmenitySchedule) error
+	SetPolicy(ctx context.Context, societyCode string, id primitive.ObjectID, policy *models.BookingPolicy) error
+	// AddImage appends url to the amenity's images unless it already has
+	// max of them, in which case it returns ErrConflict
+	AddImage(ctx context.Context, societyCode string, id primitive.ObjectID, url string, max int) error
+	// RemoveImage takes url out of the amenity's images and returns the
+	// images left. It returns ErrNotFound when the amenity has no such image.
+	RemoveImage(ctx context.Context, societyCode string, id primitive.ObjectID, url string) ([]string, error)
+}
+
+func (f AmenityFilter) toBSON() bson.M {
+	filter := bson.M{}
+	if f.SocietyCode != "" {
+		filter["society_code"] = f.SocietyCode
+	}
+	if f.ActiveOnly {
+		filter["is_active"] = true
+	}
+	return filter
+}
+
+func (f AmenityFilter) matches(a models.Amenity) bool {
+	if f.SocietyCode != "" && a.SocietyCode != f.SocietyCode {
+		return false
+	}
+	if f.ActiveOnly && !a.IsActive {
+		return false
+	}
+	return true
+}
+
+type mongoAmenityRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoAmenityRepository) Create(ctx context.Context, amenity *models.Amenity) error {
+	if amenity.ID.IsZero() {
+		amenity.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, amenity)
+	return translateError(err)
+}
+
+func (r *mongoAmenityRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Amenity, error) {
+	var amenity models.Amenity
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "society_code": societyCode}).Decode(&amenity)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &amenity, nil
+}
+
+func (r *mongoAmenityRepository) List(ctx context.Context, filter AmenityFilter) ([]models.Amenity, error) {
+	cursor, err := r.collection.Find(ctx, filter.toBSON())
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var amenities []models.Amenity
+	if err = cursor.All(ctx, &amenities); err != nil {
+		return nil, err
+	}
+	return amenities, nil
+}
+
+func (r *mongoAmenityRepository) SetSchedule(ctx context.Context, societyCode string, id primitive.ObjectID, schedule *models.AmenitySchedule) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "society_code": societyCode},
+		bson.M{"$set": bson.M{"schedule": schedule}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoAmenityRepository) SetPolicy(ctx context.Context, societyCode string, id primitive.ObjectID, policy *models.BookingPolicy) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "society_code": societyCode},
+		bson.M{"$set": bson.M{"policy": policy}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoAmenityRepository) AddImage(ctx context.Context, societyCode string, id primitive.ObjectID, url string, max int) error {
+	// The gallery is full once its last allowed position is taken
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "society_code": societyCode, fmt.Sprintf("images.%d", max-1): bson.M{"$exists": false}},
+		bson.M{"$push": bson.M{"images": url}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		n, err := r.collection.CountDocuments(ctx, bson.M{"_id": id, "society_code": societyCode})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrNotFound
+		}
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r *mongoAmenityRepository) RemoveImage(ctx context.Context, societyCode string, id primitive.ObjectID, url string) ([]string, error) {
+	var amenity models.Amenity
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "society_code": societyCode, "images": url},
+		bson.M{"$pull": bson.M{"images": url}},
+		opts,
+	).Decode(&amenity)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return amenity.Images, nil
+}
+
+type memoryAmenityRepository struct {
+	table *memoryTable[models.Amenity]
+}
+
+func newMemoryAmenityRepository() *memoryAmenityRepository {
+	return &memoryAmenityRepository{table: newMemoryTable[models.Amenity]()}
+}
+
+func (r *memoryAmenityRepository) Create(ctx context.Context, amenity *models.Amenity) error {
+	if amenity.ID.IsZero() {
+		amenity.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(amenity.ID, *amenity, nil)
+}
+
+func (r *memoryAmenityRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Amenity, error) {
+	amenity, err := r.table.find(func(a models.Amenity) bool {
+		return a.ID == id && a.SocietyCode == societyCode
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &amenity, nil
+}
+
+func (r *memoryAmenityRepository) List(ctx context.Context, filter AmenityFilter) ([]models.Amenity, error) {
+	return r.table.filter(filter.matches), nil
+}
+
+func (r *memoryAmenityRepository) SetSchedule(ctx context.Context, societyCode string, id primitive.ObjectID, schedule *models.AmenitySchedule) error {
+	return r.table.update(id, func(a models.Amenity) bool { return a.SocietyCode == societyCode }, func(a *models.Amenity) {
+		copied := *schedule
+		a.Schedule = &copied
+	})
+}
+
+func (r *memoryAmenityRepository) SetPolicy(ctx context.Context, societyCode string, id primitive.ObjectID, policy *models.BookingPolicy) error {
+	return r.table.update(id, func(a models.Amenity) bool { return a.SocietyCode == societyCode }, func(a *models.Amenity) {
+		copied := *policy
+		copied.RefundTiers = append([]models.RefundTier(nil), policy.RefundTiers...)
+		a.Policy = &copied
+	})
+}
+
+func (r *memoryAmenityRepository) AddImage(ctx context.Context, societyCode string, id primitive.ObjectID, url string, max int) error {
+	full := false
+	err := r.table.update(id, func(a models.Amenity) bool { return a.SocietyCode == societyCode }, func(a *models.Amenity) {
+		if len(a.Images) >= max {
+			full = true
+			return
+		}
+		a.Images = append(append([]string(nil), a.Images...), url)
+	})
+	if err == nil && full {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *memoryAmenityRepository) RemoveImage(ctx context.Context, societyCode string, id primitive.ObjectID, url string) ([]string, error) {
+	var images []string
+	removed := false
+	err := r.table.update(id, func(a models.Amenity) bool { return a.SocietyCode == societyCode }, func(a *models.Amenity) {
+		images = make([]string, 0, len(a.Images))
+		for _, image := range a.Images {
+			if image == url {
+				removed = true
+				continue
+			}
+			images = append(images, image)
+		}
+		if removed {
+			a.Images = images
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !removed {
+		return nil, ErrNotFound
+	}
+	return append([]string(nil), images...), nil
+}
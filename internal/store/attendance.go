@@ -0,0 +1,163 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AttendanceFilter narrows List. Zero-valued fields are ignored; From and
+// To bound the check-in time, From inclusive and To exclusive.
+type AttendanceFilter struct {
+	SocietyCode string
+	StaffID     primitive.ObjectID
+	From        time.Time
+	To          time.Time
+	InsideOnly  bool
+}
+
+type AttendanceRepository interface {
+	// CheckIn records a staff member entering. It returns ErrDuplicate if
+	// they are already inside.
+	CheckIn(ctx context.Context, entry *models.StaffAttendance) error
+	// CheckOut closes the staff member's open entry and returns it. It
+	// returns ErrNotFound if they are not inside.
+	CheckOut(ctx context.Context, societyCode string, staffID primitive.ObjectID, at time.Time, by primitive.ObjectID) (*models.StaffAttendance, error)
+	// List returns matching entries in check-in order
+	List(ctx context.Context, filter AttendanceFilter) ([]models.StaffAttendance, error)
+}
+
+func (f AttendanceFilter) toBSON() bson.M {
+	filter := bson.M{}
+	if f.SocietyCode != "" {
+		filter["society_code"] = f.SocietyCode
+	}
+	if !f.StaffID.IsZero() {
+		filter["staff_id"] = f.StaffID
+	}
+	checkIn := bson.M{}
+	if !f.From.IsZero() {
+		checkIn["$gte"] = f.From
+	}
+	if !f.To.IsZero() {
+		checkIn["$lt"] = f.To
+	}
+	if len(checkIn) > 0 {
+		filter["check_in"] = checkIn
+	}
+	if f.InsideOnly {
+		filter["inside"] = true
+	}
+	return filter
+}
+
+func (f AttendanceFilter) matches(entry models.StaffAttendance) bool {
+	if f.SocietyCode != "" && entry.SocietyCode != f.SocietyCode {
+		return false
+	}
+	if !f.StaffID.IsZero() && entry.StaffID != f.StaffID {
+		return false
+	}
+	if !f.From.IsZero() && entry.CheckIn.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !entry.CheckIn.Before(f.To) {
+		return false
+	}
+	if f.InsideOnly && !entry.Inside {
+		return false
+	}
+	return true
+}
+
+type mongoAttendanceRepository struct {
+	collection *mongo.Collection
+}
+
+// CheckIn relies on the partial unique index on staff_id over open entries
+func (r *mongoAttendanceRepository) CheckIn(ctx context.Context, entry *models.StaffAttendance) error {
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, entry)
+	return translateError(err)
+}
+
+func (r *mongoAttendanceRepository) CheckOut(ctx context.Context, societyCode string, staffID primitive.ObjectID, at time.Time, by primitive.ObjectID) (*models.StaffAttendance, error) {
+	filter := bson.M{"society_code": societyCode, "staff_id": staffID, "inside": true}
+	update := bson.M{"$set": bson.M{"inside": false, "check_out": at, "checked_out_by": by}}
+
+	var entry models.StaffAttendance
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&entry)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &entry, nil
+}
+
+func (r *mongoAttendanceRepository) List(ctx context.Context, filter AttendanceFilter) ([]models.StaffAttendance, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "check_in", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter.toBSON(), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.StaffAttendance
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+type memoryAttendanceRepository struct {
+	table *memoryTable[models.StaffAttendance]
+}
+
+func newMemoryAttendanceRepository() *memoryAttendanceRepository {
+	return &memoryAttendanceRepository{table: newMemoryTable[models.StaffAttendance]()}
+}
+
+func (r *memoryAttendanceRepository) CheckIn(ctx context.Context, entry *models.StaffAttendance) error {
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(entry.ID, *entry, func(existing models.StaffAttendance) bool {
+		return existing.StaffID == entry.StaffID && existing.Inside
+	})
+}
+
+func (r *memoryAttendanceRepository) CheckOut(ctx context.Context, societyCode string, staffID primitive.ObjectID, at time.Time, by primitive.ObjectID) (*models.StaffAttendance, error) {
+	open := func(e models.StaffAttendance) bool {
+		return e.SocietyCode == societyCode && e.StaffID == staffID && e.Inside
+	}
+	entry, err := r.table.find(open)
+	if err != nil {
+		return nil, err
+	}
+	err = r.table.update(entry.ID, open, func(e *models.StaffAttendance) {
+		checkOut, checkedOutBy := at, by
+		e.Inside = false
+		e.CheckOut = &checkOut
+		e.CheckedOutBy = &checkedOutBy
+		entry = *e
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *memoryAttendanceRepository) List(ctx context.Context, filter AttendanceFilter) ([]models.StaffAttendance, error) {
+	entries := r.table.filter(filter.matches)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].CheckIn.Before(entries[j].CheckIn) })
+	return entries, nil
+}
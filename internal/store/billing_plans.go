@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type BillingPlanRepository interface {
+	Get(ctx context.Context, societyCode string) (*models.BillingPlan, error)
+	// Upsert replaces the society's plan, creating it if there is none yet
+	Upsert(ctx context.Context, plan *models.BillingPlan) error
+	ListActive(ctx context.Context) ([]models.BillingPlan, error)
+}
+
+type mongoBillingPlanRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoBillingPlanRepository) Get(ctx context.Context, societyCode string) (*models.BillingPlan, error) {
+	var plan models.BillingPlan
+	if err := r.collection.FindOne(ctx, bson.M{"society_code": societyCode}).Decode(&plan); err != nil {
+		return nil, translateError(err)
+	}
+	return &plan, nil
+}
+
+func (r *mongoBillingPlanRepository) Upsert(ctx context.Context, plan *models.BillingPlan) error {
+	existing, err := r.Get(ctx, plan.SocietyCode)
+	switch {
+	case err == nil:
+		plan.ID = existing.ID
+		plan.CreatedAt = existing.CreatedAt
+	case errors.Is(err, ErrNotFound):
+		if plan.ID.IsZero() {
+			plan.ID = primitive.NewObjectID()
+		}
+	default:
+		return err
+	}
+
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"society_code": plan.SocietyCode}, plan, options.Replace().SetUpsert(true))
+	return translateError(err)
+}
+
+func (r *mongoBillingPlanRepository) ListActive(ctx context.Context) ([]models.BillingPlan, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"is_active": true})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var plans []models.BillingPlan
+	if err = cursor.All(ctx, &plans); err != nil {
+		return nil, err
+	}
+	return plans, nil
+}
+
+type memoryBillingPlanRepository struct {
+	table *memoryTable[models.BillingPlan]
+}
+
+func newMemoryBillingPlanRepository() *memoryBillingPlanRepository {
+	return &memoryBillingPlanRepository{table: newMemoryTable[models.BillingPlan]()}
+}
+
+func (r *memoryBillingPlanRepository) Get(ctx context.Context, societyCode string) (*models.BillingPlan, error) {
+	plan, err := r.table.find(func(p models.BillingPlan) bool { return p.SocietyCode == societyCode })
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+func (r *memoryBillingPlanRepository) Upsert(ctx context.Context, plan *models.BillingPlan) error {
+	existing, err := r.Get(ctx, plan.SocietyCode)
+	if err == nil {
+		plan.ID = existing.ID
+		plan.CreatedAt = existing.CreatedAt
+		return r.table.update(plan.ID, nil, func(p *models.BillingPlan) { *p = *plan })
+	}
+
+	if plan.ID.IsZero() {
+		plan.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(plan.ID, *plan, func(existing models.BillingPlan) bool {
+		return existing.SocietyCode == plan.SocietyCode
+	})
+}
+
+func (r *memoryBillingPlanRepository) ListActive(ctx context.Context) ([]models.BillingPlan, error) {
+	return r.table.filter(func(p models.BillingPlan) bool { return p.IsActive }), nil
+}
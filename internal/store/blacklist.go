@@ -0,0 +1,262 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BlacklistKeys are the normalised keys a person is screened on. Empty
+// keys never match.
+type BlacklistKeys struct {
+	Phone   string
+	Vehicle string
+	Name    string
+}
+
+// BlacklistFilter narrows List. Zero-valued fields are ignored; Matching
+// keeps entries sharing any key with the person.
+type BlacklistFilter struct {
+	SocietyCode string
+	ActiveOnly  bool
+	Action      string
+	Matching    *BlacklistKeys
+}
+
+// BlacklistUpdate carries the fields to change. Nil fields are left
+// untouched; Event is appended to the entry's history.
+type BlacklistUpdate struct {
+	Name          *string
+	Phone         *string
+	VehicleNumber *string
+	NameKey       *string
+	PhoneKey      *string
+	VehicleKey    *string
+	Action        *string
+	Reason        *string
+	IsActive      *bool
+	Event         *models.BlacklistChange
+	UpdatedAt     time.Time
+}
+
+type BlacklistRepository interface {
+	Create(ctx context.Context, entry *models.BlacklistEntry) error
+	GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.BlacklistEntry, error)
+	// List returns matching entries, newest first
+	List(ctx context.Context, filter BlacklistFilter) ([]models.BlacklistEntry, error)
+	Update(ctx context.Context, societyCode string, id primitive.ObjectID, update BlacklistUpdate) error
+}
+
+func (f BlacklistFilter) toBSON() bson.M {
+	filter := bson.M{}
+	if f.SocietyCode != "" {
+		filter["society_code"] = f.SocietyCode
+	}
+	if f.ActiveOnly {
+		filter["is_active"] = true
+	}
+	if f.Action != "" {
+		filter["action"] = f.Action
+	}
+	if f.Matching != nil {
+		or := bson.A{}
+		if f.Matching.Phone != "" {
+			or = append(or, bson.M{"phone_key": f.Matching.Phone})
+		}
+		if f.Matching.Vehicle != "" {
+			or = append(or, bson.M{"vehicle_key": f.Matching.Vehicle})
+		}
+		if f.Matching.Name != "" {
+			or = append(or, bson.M{"name_key": f.Matching.Name})
+		}
+		if len(or) == 0 {
+			// Nobody matches a person without keys
+			filter["_id"] = primitive.NilObjectID
+		} else {
+			filter["$or"] = or
+		}
+	}
+	return filter
+}
+
+func (f BlacklistFilter) matches(entry models.BlacklistEntry) bool {
+	if f.SocietyCode != "" && entry.SocietyCode != f.SocietyCode {
+		return false
+	}
+	if f.ActiveOnly && !entry.IsActive {
+		return false
+	}
+	if f.Action != "" && entry.Action != f.Action {
+		return false
+	}
+	if f.Matching != nil {
+		keys := f.Matching
+		if !(keys.Phone != "" && entry.PhoneKey == keys.Phone) &&
+			!(keys.Vehicle != "" && entry.VehicleKey == keys.Vehicle) &&
+			!(keys.Name != "" && entry.NameKey == keys.Name) {
+			return false
+		}
+	}
+	return true
+}
+
+func (u BlacklistUpdate) toBSON() bson.M {
+	set := bson.M{"updated_at": u.UpdatedAt}
+	if u.Name != nil {
+		set["name"] = *u.Name
+	}
+	if u.Phone != nil {
+		set["phone"] = *u.Phone
+	}
+	if u.VehicleNumber != nil {
+		set["vehicle_number"] = *u.VehicleNumber
+	}
+	if u.NameKey != nil {
+		set["name_key"] = *u.NameKey
+	}
+	if u.PhoneKey != nil {
+		set["phone_key"] = *u.PhoneKey
+	}
+	if u.VehicleKey != nil {
+		set["vehicle_key"] = *u.VehicleKey
+	}
+	if u.Action != nil {
+		set["action"] = *u.Action
+	}
+	if u.Reason != nil {
+		set["reason"] = *u.Reason
+	}
+	if u.IsActive != nil {
+		set["is_active"] = *u.IsActive
+	}
+
+	update := bson.M{"$set": set}
+	if u.Event != nil {
+		update["$push"] = bson.M{"history": *u.Event}
+	}
+	return update
+}
+
+func (u BlacklistUpdate) apply(entry *models.BlacklistEntry) {
+	entry.UpdatedAt = u.UpdatedAt
+	if u.Name != nil {
+		entry.Name = *u.Name
+	}
+	if u.Phone != nil {
+		entry.Phone = *u.Phone
+	}
+	if u.VehicleNumber != nil {
+		entry.VehicleNumber = *u.VehicleNumber
+	}
+	if u.NameKey != nil {
+		entry.NameKey = *u.NameKey
+	}
+	if u.PhoneKey != nil {
+		entry.PhoneKey = *u.PhoneKey
+	}
+	if u.VehicleKey != nil {
+		entry.VehicleKey = *u.VehicleKey
+	}
+	if u.Action != nil {
+		entry.Action = *u.Action
+	}
+	if u.Reason != nil {
+		entry.Reason = *u.Reason
+	}
+	if u.IsActive != nil {
+		entry.IsActive = *u.IsActive
+	}
+	if u.Event != nil {
+		// Copy so the stored row never shares a backing array with a caller
+		entry.History = append(append([]models.BlacklistChange(nil), entry.History...), *u.Event)
+	}
+}
+
+type mongoBlacklistRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoBlacklistRepository) Create(ctx context.Context, entry *models.BlacklistEntry) error {
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, entry)
+	return translateError(err)
+}
+
+func (r *mongoBlacklistRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.BlacklistEntry, error) {
+	var entry models.BlacklistEntry
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "society_code": societyCode}).Decode(&entry)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &entry, nil
+}
+
+func (r *mongoBlacklistRepository) List(ctx context.Context, filter BlacklistFilter) ([]models.BlacklistEntry, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, filter.toBSON(), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.BlacklistEntry
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *mongoBlacklistRepository) Update(ctx context.Context, societyCode string, id primitive.ObjectID, update BlacklistUpdate) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "society_code": societyCode}, update.toBSON())
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryBlacklistRepository struct {
+	table *memoryTable[models.BlacklistEntry]
+}
+
+func newMemoryBlacklistRepository() *memoryBlacklistRepository {
+	return &memoryBlacklistRepository{table: newMemoryTable[models.BlacklistEntry]()}
+}
+
+func (r *memoryBlacklistRepository) Create(ctx context.Context, entry *models.BlacklistEntry) error {
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(entry.ID, *entry, nil)
+}
+
+func (r *memoryBlacklistRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.BlacklistEntry, error) {
+	entry, err := r.table.find(func(e models.BlacklistEntry) bool {
+		return e.ID == id && e.SocietyCode == societyCode
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *memoryBlacklistRepository) List(ctx context.Context, filter BlacklistFilter) ([]models.BlacklistEntry, error) {
+	entries := r.table.filter(filter.matches)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].CreatedAt.After(entries[j].CreatedAt) })
+	return entries, nil
+}
+
+func (r *memoryBlacklistRepository) Update(ctx context.Context, societyCode string, id primitive.ObjectID, update BlacklistUpdate) error {
+	return r.table.update(id, func(e models.BlacklistEntry) bool { return e.SocietyCode == societyCode }, update.apply)
+}
@@ -0,0 +1,279 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BookingFilter narrows List and Count. Zero-valued fields are ignored.
+type BookingFilter struct {
+	SocietyCode     string
+	UserID          primitive.ObjectID
+	UnitID          primitive.ObjectID
+	AmenityID       primitive.ObjectID
+	SeriesID        primitive.ObjectID
+	Date            time.Time
+	TimeSlot        string
+	Statuses        []string
+	ExcludeStatuses []string
+	// OverlapsStart and OverlapsEnd select bookings whose time range
+	// overlaps [start, end)
+	OverlapsStart time.Time
+	OverlapsEnd   time.Time
+}
+
+// BookingUpdate carries the fields to change. Nil fields are left untouched.
+type BookingUpdate struct {
+	Status        *string
+	PaymentID     *string
+	RefundPercent *float64
+}
+
+type AmenityBookingRepository interface {
+	Create(ctx context.Context, booking *models.AmenityBooking) error
+	GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.AmenityBooking, error)
+	// List returns matching bookings, latest date first
+	List(ctx context.Context, filter BookingFilter) ([]models.AmenityBooking, error)
+	Count(ctx context.Context, filter BookingFilter) (int64, error)
+	Update(ctx context.Context, societyCode string, id primitive.ObjectID, update BookingUpdate) error
+	// Transition applies the update only while the booking is in one of the
+	// from statuses. It returns ErrConflict when the booking has moved on.
+	Transition(ctx context.Context, societyCode string, id primitive.ObjectID, from []string, update BookingUpdate) error
+}
+
+func (f BookingFilter) toBSON() bson.M {
+	filter := bson.M{}
+	if f.SocietyCode != "" {
+		filter["society_code"] = f.SocietyCode
+	}
+	if !f.UserID.IsZero() {
+		filter["user_id"] = f.UserID
+	}
+	if !f.UnitID.IsZero() {
+		filter["unit_id"] = f.UnitID
+	}
+	if !f.AmenityID.IsZero() {
+		filter["amenity_id"] = f.AmenityID
+	}
+	if !f.SeriesID.IsZero() {
+		filter["series_id"] = f.SeriesID
+	}
+	if !f.Date.IsZero() {
+		filter["date"] = f.Date
+	}
+	if f.TimeSlot != "" {
+		filter["time_slot"] = f.TimeSlot
+	}
+	if !f.OverlapsEnd.IsZero() {
+		filter["start_time"] = bson.M{"$lt": f.OverlapsEnd}
+	}
+	if !f.OverlapsStart.IsZero() {
+		filter["end_time"] = bson.M{"$gt": f.OverlapsStart}
+	}
+	status := bson.M{}
+	if len(f.Statuses) > 0 {
+		status["$in"] = f.Statuses
+	}
+	if len(f.ExcludeStatuses) > 0 {
+		status["$nin"] = f.ExcludeStatuses
+	}
+	if len(status) > 0 {
+		filter["status"] = status
+	}
+	return filter
+}
+
+func (f BookingFilter) matches(b models.AmenityBooking) bool {
+	if f.SocietyCode != "" && b.SocietyCode != f.SocietyCode {
+		return false
+	}
+	if !f.UserID.IsZero() && b.UserID != f.UserID {
+		return false
+	}
+	if !f.UnitID.IsZero() && (b.UnitID == nil || *b.UnitID != f.UnitID) {
+		return false
+	}
+	if !f.AmenityID.IsZero() && b.AmenityID != f.AmenityID {
+		return false
+	}
+	if !f.SeriesID.IsZero() && (b.SeriesID == nil || *b.SeriesID != f.SeriesID) {
+		return false
+	}
+	if !f.Date.IsZero() && !b.Date.Equal(f.Date) {
+		return false
+	}
+	if f.TimeSlot != "" && b.TimeSlot != f.TimeSlot {
+		return false
+	}
+	if !f.OverlapsEnd.IsZero() && !b.StartTime.Before(f.OverlapsEnd) {
+		return false
+	}
+	if !f.OverlapsStart.IsZero() && !b.EndTime.After(f.OverlapsStart) {
+		return false
+	}
+	if len(f.Statuses) > 0 && !containsString(f.Statuses, b.Status) {
+		return false
+	}
+	if containsString(f.ExcludeStatuses, b.Status) {
+		return false
+	}
+	return true
+}
+
+func (u BookingUpdate) toBSON() bson.M {
+	set := bson.M{}
+	if u.Status != nil {
+		set["status"] = *u.Status
+	}
+	if u.PaymentID != nil {
+		set["payment_id"] = *u.PaymentID
+	}
+	if u.RefundPercent != nil {
+		set["refund_percent"] = *u.RefundPercent
+	}
+	return bson.M{"$set": set}
+}
+
+func (u BookingUpdate) apply(b *models.AmenityBooking) {
+	if u.Status != nil {
+		b.Status = *u.Status
+	}
+	if u.PaymentID != nil {
+		b.PaymentID = *u.PaymentID
+	}
+	if u.RefundPercent != nil {
+		percent := *u.RefundPercent
+		b.RefundPercent = &percent
+	}
+}
+
+type mongoAmenityBookingRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoAmenityBookingRepository) Create(ctx context.Context, booking *models.AmenityBooking) error {
+	if booking.ID.IsZero() {
+		booking.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, booking)
+	return translateError(err)
+}
+
+func (r *mongoAmenityBookingRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.AmenityBooking, error) {
+	var booking models.AmenityBooking
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "society_code": societyCode}).Decode(&booking)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &booking, nil
+}
+
+func (r *mongoAmenityBookingRepository) List(ctx context.Context, filter BookingFilter) ([]models.AmenityBooking, error) {
+	cursor, err := r.collection.Find(ctx, filter.toBSON(), options.Find().SetSort(bson.M{"date": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var bookings []models.AmenityBooking
+	if err = cursor.All(ctx, &bookings); err != nil {
+		return nil, err
+	}
+	return bookings, nil
+}
+
+func (r *mongoAmenityBookingRepository) Count(ctx context.Context, filter BookingFilter) (int64, error) {
+	return r.collection.CountDocuments(ctx, filter.toBSON())
+}
+
+func (r *mongoAmenityBookingRepository) Update(ctx context.Context, societyCode string, id primitive.ObjectID, update BookingUpdate) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "society_code": societyCode}, update.toBSON())
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoAmenityBookingRepository) Transition(ctx context.Context, societyCode string, id primitive.ObjectID, from []string, update BookingUpdate) error {
+	filter := bson.M{"_id": id, "society_code": societyCode, "status": bson.M{"$in": from}}
+	result, err := r.collection.UpdateOne(ctx, filter, update.toBSON())
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		n, err := r.collection.CountDocuments(ctx, bson.M{"_id": id, "society_code": societyCode})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrNotFound
+		}
+		return ErrConflict
+	}
+	return nil
+}
+
+type memoryAmenityBookingRepository struct {
+	table *memoryTable[models.AmenityBooking]
+}
+
+func newMemoryAmenityBookingRepository() *memoryAmenityBookingRepository {
+	return &memoryAmenityBookingRepository{table: newMemoryTable[models.AmenityBooking]()}
+}
+
+func (r *memoryAmenityBookingRepository) Create(ctx context.Context, booking *models.AmenityBooking) error {
+	if booking.ID.IsZero() {
+		booking.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(booking.ID, *booking, nil)
+}
+
+func (r *memoryAmenityBookingRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.AmenityBooking, error) {
+	booking, err := r.table.find(func(b models.AmenityBooking) bool {
+		return b.ID == id && b.SocietyCode == societyCode
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
+func (r *memoryAmenityBookingRepository) List(ctx context.Context, filter BookingFilter) ([]models.AmenityBooking, error) {
+	bookings := r.table.filter(filter.matches)
+	sort.Slice(bookings, func(i, j int) bool { return bookings[i].Date.After(bookings[j].Date) })
+	return bookings, nil
+}
+
+func (r *memoryAmenityBookingRepository) Count(ctx context.Context, filter BookingFilter) (int64, error) {
+	return r.table.count(filter.matches), nil
+}
+
+func (r *memoryAmenityBookingRepository) Update(ctx context.Context, societyCode string, id primitive.ObjectID, update BookingUpdate) error {
+	return r.table.update(id, func(b models.AmenityBooking) bool { return b.SocietyCode == societyCode }, update.apply)
+}
+
+func (r *memoryAmenityBookingRepository) Transition(ctx context.Context, societyCode string, id primitive.ObjectID, from []string, update BookingUpdate) error {
+	conflict := false
+	err := r.table.update(id, func(b models.AmenityBooking) bool { return b.SocietyCode == societyCode }, func(b *models.AmenityBooking) {
+		if !containsString(from, b.Status) {
+			conflict = true
+			return
+		}
+		update.apply(b)
+	})
+	if err == nil && conflict {
+		return ErrConflict
+	}
+	return err
+}
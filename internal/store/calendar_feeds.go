@@ -0,0 +1,188 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FeedFilter narrows List. Zero-valued fields are ignored.
+type FeedFilter struct {
+	SocietyCode string
+	UserID      primitive.ObjectID
+	ActiveOnly  bool
+}
+
+// FeedUpdate carries the fields to change. Nil fields are left untouched.
+type FeedUpdate struct {
+	RevokedAt      *time.Time
+	LastAccessedAt *time.Time
+}
+
+type CalendarFeedRepository interface {
+	Create(ctx context.Context, feed *models.CalendarFeed) error
+	GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.CalendarFeed, error)
+	// GetByTokenHash finds the feed a calendar app is polling, which
+	// carries no society context
+	GetByTokenHash(ctx context.Context, hash string) (*models.CalendarFeed, error)
+	// List returns matching feeds, newest first
+	List(ctx context.Context, filter FeedFilter) ([]models.CalendarFeed, error)
+	Update(ctx context.Context, id primitive.ObjectID, update FeedUpdate) error
+}
+
+func (f FeedFilter) toBSON() bson.M {
+	filter := bson.M{}
+	if f.SocietyCode != "" {
+		filter["society_code"] = f.SocietyCode
+	}
+	if !f.UserID.IsZero() {
+		filter["user_id"] = f.UserID
+	}
+	if f.ActiveOnly {
+		filter["revoked_at"] = bson.M{"$exists": false}
+	}
+	return filter
+}
+
+func (f FeedFilter) matches(feed models.CalendarFeed) bool {
+	if f.SocietyCode != "" && feed.SocietyCode != f.SocietyCode {
+		return false
+	}
+	if !f.UserID.IsZero() && feed.UserID != f.UserID {
+		return false
+	}
+	if f.ActiveOnly && feed.RevokedAt != nil {
+		return false
+	}
+	return true
+}
+
+func (u FeedUpdate) toBSON() bson.M {
+	set := bson.M{}
+	if u.RevokedAt != nil {
+		set["revoked_at"] = *u.RevokedAt
+	}
+	if u.LastAccessedAt != nil {
+		set["last_accessed_at"] = *u.LastAccessedAt
+	}
+	return bson.M{"$set": set}
+}
+
+func (u FeedUpdate) apply(feed *models.CalendarFeed) {
+	if u.RevokedAt != nil {
+		revokedAt := *u.RevokedAt
+		feed.RevokedAt = &revokedAt
+	}
+	if u.LastAccessedAt != nil {
+		accessedAt := *u.LastAccessedAt
+		feed.LastAccessedAt = &accessedAt
+	}
+}
+
+type mongoCalendarFeedRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoCalendarFeedRepository) Create(ctx context.Context, feed *models.CalendarFeed) error {
+	if feed.ID.IsZero() {
+		feed.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, feed)
+	return translateError(err)
+}
+
+func (r *mongoCalendarFeedRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "society_code": societyCode}).Decode(&feed)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &feed, nil
+}
+
+func (r *mongoCalendarFeedRepository) GetByTokenHash(ctx context.Context, hash string) (*models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	err := r.collection.FindOne(ctx, bson.M{"token_hash": hash}).Decode(&feed)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &feed, nil
+}
+
+func (r *mongoCalendarFeedRepository) List(ctx context.Context, filter FeedFilter) ([]models.CalendarFeed, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, filter.toBSON(), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var feeds []models.CalendarFeed
+	if err = cursor.All(ctx, &feeds); err != nil {
+		return nil, err
+	}
+	return feeds, nil
+}
+
+func (r *mongoCalendarFeedRepository) Update(ctx context.Context, id primitive.ObjectID, update FeedUpdate) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update.toBSON())
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryCalendarFeedRepository struct {
+	table *memoryTable[models.CalendarFeed]
+}
+
+func newMemoryCalendarFeedRepository() *memoryCalendarFeedRepository {
+	return &memoryCalendarFeedRepository{table: newMemoryTable[models.CalendarFeed]()}
+}
+
+func (r *memoryCalendarFeedRepository) Create(ctx context.Context, feed *models.CalendarFeed) error {
+	if feed.ID.IsZero() {
+		feed.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(feed.ID, *feed, func(existing models.CalendarFeed) bool {
+		return existing.TokenHash == feed.TokenHash
+	})
+}
+
+func (r *memoryCalendarFeedRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.CalendarFeed, error) {
+	feed, err := r.table.find(func(f models.CalendarFeed) bool {
+		return f.ID == id && f.SocietyCode == societyCode
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+func (r *memoryCalendarFeedRepository) GetByTokenHash(ctx context.Context, hash string) (*models.CalendarFeed, error) {
+	feed, err := r.table.find(func(f models.CalendarFeed) bool { return f.TokenHash == hash })
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+func (r *memoryCalendarFeedRepository) List(ctx context.Context, filter FeedFilter) ([]models.CalendarFeed, error) {
+	feeds := r.table.filter(filter.matches)
+	sort.Slice(feeds, func(i, j int) bool { return feeds[i].CreatedAt.After(feeds[j].CreatedAt) })
+	return feeds, nil
+}
+
+func (r *memoryCalendarFeedRepository) Update(ctx context.Context, id primitive.ObjectID, update FeedUpdate) error {
+	return r.table.update(id, nil, update.apply)
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CounterRepository hands out gap-free sequence numbers, such as receipt
+// numbers, one named sequence at a time.
+type CounterRepository interface {
+	// Next returns the lowest released value of the named counter, or else
+	// atomically increments it and returns its new value. Counters start
+	// at 1.
+	Next(ctx context.Context, name string) (int64, error)
+	// Release hands back a value taken with Next that ended up unused, so
+	// the next caller gets it and the sequence has no gap
+	Release(ctx context.Context, name string, value int64) error
+}
+
+type mongoCounterRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoCounterRepository) Next(ctx context.Context, name string) (int64, error) {
+	// Released values come first; popping the lowest is atomic, so each
+	// goes to one caller only
+	var released struct {
+		Free []int64 `bson:"free"`
+	}
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": name, "free.0": bson.M{"$exists": true}},
+		bson.M{"$pop": bson.M{"free": -1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&released)
+	if err == nil && len(released.Free) > 0 {
+		return released.Free[0], nil
+	}
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, err
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var counter struct {
+		Value int64 `bson:"value"`
+	}
+	err = r.collection.FindOneAndUpdate(ctx, bson.M{"_id": name}, bson.M{"$inc": bson.M{"value": 1}}, opts).Decode(&counter)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return counter.Value, nil
+}
+
+func (r *mongoCounterRepository) Release(ctx context.Context, name string, value int64) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": name}, bson.M{
+		"$push": bson.M{"free": bson.M{"$each": bson.A{value}, "$sort": 1}},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryCounterRepository struct {
+	mu     sync.Mutex
+	values map[string]int64
+	free   map[string][]int64
+}
+
+func newMemoryCounterRepository() *memoryCounterRepository {
+	return &memoryCounterRepository{values: make(map[string]int64), free: make(map[string][]int64)}
+}
+
+func (r *memoryCounterRepository) Next(ctx context.Context, name string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if free := r.free[name]; len(free) > 0 {
+		r.free[name] = free[1:]
+		return free[0], nil
+	}
+	r.values[name]++
+	return r.values[name], nil
+}
+
+func (r *memoryCounterRepository) Release(ctx context.Context, name string, value int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.values[name]; !ok {
+		return ErrNotFound
+	}
+	free := append(append([]int64(nil), r.free[name]...), value)
+	sort.Slice(free, func(i, j int) bool { return free[i] < free[j] })
+	r.free[name] = free
+	return nil
+}
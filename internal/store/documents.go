@@ -0,0 +1,228 @@
+package store
+
+import (
+	"context"
+	"sort"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DocumentFilter narrows List. Zero-valued fields are ignored.
+type DocumentFilter struct {
+	SocietyCode   string
+	Kind          string
+	MaintenanceID primitive.ObjectID
+	PaymentID     primitive.ObjectID
+	UnitID        primitive.ObjectID
+	UserID        primitive.ObjectID
+}
+
+type DocumentRepository interface {
+	// Create returns ErrDuplicate when a document of the same kind was
+	// already issued for the reference
+	Create(ctx context.Context, doc *models.BillingDocument) error
+	GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.BillingDocument, error)
+	GetByReference(ctx context.Context, societyCode, kind string, referenceID primitive.ObjectID) (*models.BillingDocument, error)
+	// List returns matching documents that have been numbered, newest first
+	List(ctx context.Context, filter DocumentFilter) ([]models.BillingDocument, error)
+	// SetNumber numbers a document created without one. It returns
+	// ErrConflict when the document is already numbered.
+	SetNumber(ctx context.Context, societyCode string, id primitive.ObjectID, sequence int64, number string) error
+	Delete(ctx context.Context, societyCode string, id primitive.ObjectID) error
+}
+
+func (f DocumentFilter) toBSON() bson.M {
+	filter := bson.M{"sequence": bson.M{"$gt": 0}}
+	if f.SocietyCode != "" {
+		filter["society_code"] = f.SocietyCode
+	}
+	if f.Kind != "" {
+		filter["kind"] = f.Kind
+	}
+	if !f.MaintenanceID.IsZero() {
+		filter["maintenance_id"] = f.MaintenanceID
+	}
+	if !f.PaymentID.IsZero() {
+		filter["payment_id"] = f.PaymentID
+	}
+	if !f.UnitID.IsZero() {
+		filter["unit_id"] = f.UnitID
+	}
+	if !f.UserID.IsZero() {
+		filter["user_id"] = f.UserID
+	}
+	return filter
+}
+
+func (f DocumentFilter) matches(d models.BillingDocument) bool {
+	if d.Sequence == 0 {
+		return false
+	}
+	if f.SocietyCode != "" && d.SocietyCode != f.SocietyCode {
+		return false
+	}
+	if f.Kind != "" && d.Kind != f.Kind {
+		return false
+	}
+	if !f.MaintenanceID.IsZero() && (d.MaintenanceID == nil || *d.MaintenanceID != f.MaintenanceID) {
+		return false
+	}
+	if !f.PaymentID.IsZero() && (d.PaymentID == nil || *d.PaymentID != f.PaymentID) {
+		return false
+	}
+	if !f.UnitID.IsZero() && (d.UnitID == nil || *d.UnitID != f.UnitID) {
+		return false
+	}
+	if !f.UserID.IsZero() && (d.UserID == nil || *d.UserID != f.UserID) {
+		return false
+	}
+	return true
+}
+
+type mongoDocumentRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoDocumentRepository) Create(ctx context.Context, doc *models.BillingDocument) error {
+	if doc.ID.IsZero() {
+		doc.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, doc)
+	return translateError(err)
+}
+
+func (r *mongoDocumentRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.BillingDocument, error) {
+	var doc models.BillingDocument
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "society_code": societyCode}).Decode(&doc)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &doc, nil
+}
+
+func (r *mongoDocumentRepository) GetByReference(ctx context.Context, societyCode, kind string, referenceID primitive.ObjectID) (*models.BillingDocument, error) {
+	var doc models.BillingDocument
+	filter := bson.M{"society_code": societyCode, "kind": kind, "reference_id": referenceID}
+	if err := r.collection.FindOne(ctx, filter).Decode(&doc); err != nil {
+		return nil, translateError(err)
+	}
+	return &doc, nil
+}
+
+func (r *mongoDocumentRepository) List(ctx context.Context, filter DocumentFilter) ([]models.BillingDocument, error) {
+	cursor, err := r.collection.Find(ctx, filter.toBSON(), options.Find().SetSort(bson.M{"issued_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []models.BillingDocument
+	if err = cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+func (r *mongoDocumentRepository) SetNumber(ctx context.Context, societyCode string, id primitive.ObjectID, sequence int64, number string) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "society_code": societyCode, "sequence": 0},
+		bson.M{"$set": bson.M{"sequence": sequence, "number": number}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		n, err := r.collection.CountDocuments(ctx, bson.M{"_id": id, "society_code": societyCode})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrNotFound
+		}
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r *mongoDocumentRepository) Delete(ctx context.Context, societyCode string, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "society_code": societyCode})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryDocumentRepository struct {
+	table *memoryTable[models.BillingDocument]
+}
+
+func newMemoryDocumentRepository() *memoryDocumentRepository {
+	return &memoryDocumentRepository{table: newMemoryTable[models.BillingDocument]()}
+}
+
+func (r *memoryDocumentRepository) Create(ctx context.Context, doc *models.BillingDocument) error {
+	if doc.ID.IsZero() {
+		doc.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(doc.ID, *doc, func(existing models.BillingDocument) bool {
+		return existing.SocietyCode == doc.SocietyCode && existing.Kind == doc.Kind && existing.ReferenceID == doc.ReferenceID
+	})
+}
+
+func (r *memoryDocumentRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.BillingDocument, error) {
+	doc, err := r.table.find(func(d models.BillingDocument) bool {
+		return d.ID == id && d.SocietyCode == societyCode
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (r *memoryDocumentRepository) GetByReference(ctx context.Context, societyCode, kind string, referenceID primitive.ObjectID) (*models.BillingDocument, error) {
+	doc, err := r.table.find(func(d models.BillingDocument) bool {
+		return d.SocietyCode == societyCode && d.Kind == kind && d.ReferenceID == referenceID
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (r *memoryDocumentRepository) List(ctx context.Context, filter DocumentFilter) ([]models.BillingDocument, error) {
+	docs := r.table.filter(filter.matches)
+	sort.Slice(docs, func(i, j int) bool { return docs[i].IssuedAt.After(docs[j].IssuedAt) })
+	return docs, nil
+}
+
+func (r *memoryDocumentRepository) SetNumber(ctx context.Context, societyCode string, id primitive.ObjectID, sequence int64, number string) error {
+	numbered := false
+	err := r.table.update(id, func(d models.BillingDocument) bool { return d.SocietyCode == societyCode }, func(d *models.BillingDocument) {
+		if d.Sequence != 0 {
+			numbered = true
+			return
+		}
+		d.Sequence = sequence
+		d.Number = number
+	})
+	if err == nil && numbered {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *memoryDocumentRepository) Delete(ctx context.Context, societyCode string, id primitive.ObjectID) error {
+	n := r.table.deleteAll(func(d models.BillingDocument) bool { return d.ID == id && d.SocietyCode == societyCode })
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
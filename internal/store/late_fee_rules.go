@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type LateFeeRuleRepository interface {
+	Get(ctx context.Context, societyCode string) (*models.LateFeeRule, error)
+	// Upsert replaces the society's rule, creating it if there is none yet
+	Upsert(ctx context.Context, rule *models.LateFeeRule) error
+	ListActive(ctx context.Context) ([]models.LateFeeRule, error)
+}
+
+type mongoLateFeeRuleRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoLateFeeRuleRepository) Get(ctx context.Context, societyCode string) (*models.LateFeeRule, error) {
+	var rule models.LateFeeRule
+	if err := r.collection.FindOne(ctx, bson.M{"society_code": societyCode}).Decode(&rule); err != nil {
+		return nil, translateError(err)
+	}
+	return &rule, nil
+}
+
+func (r *mongoLateFeeRuleRepository) Upsert(ctx context.Context, rule *models.LateFeeRule) error {
+	existing, err := r.Get(ctx, rule.SocietyCode)
+	switch {
+	case err == nil:
+		rule.ID = existing.ID
+		rule.CreatedAt = existing.CreatedAt
+	case errors.Is(err, ErrNotFound):
+		if rule.ID.IsZero() {
+			rule.ID = primitive.NewObjectID()
+		}
+	default:
+		return err
+	}
+
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"society_code": rule.SocietyCode}, rule, options.Replace().SetUpsert(true))
+	return translateError(err)
+}
+
+func (r *mongoLateFeeRuleRepository) ListActive(ctx context.Context) ([]models.LateFeeRule, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"is_active": true})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rules []models.LateFeeRule
+	if err = cursor.All(ctx, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+type memoryLateFeeRuleRepository struct {
+	table *memoryTable[models.LateFeeRule]
+}
+
+func newMemoryLateFeeRuleRepository() *memoryLateFeeRuleRepository {
+	return &memoryLateFeeRuleRepository{table: newMemoryTable[models.LateFeeRule]()}
+}
+
+func (r *memoryLateFeeRuleRepository) Get(ctx context.Context, societyCode string) (*models.LateFeeRule, error) {
+	rule, err := r.table.find(func(p models.LateFeeRule) bool { return p.SocietyCode == societyCode })
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *memoryLateFeeRuleRepository) Upsert(ctx context.Context, rule *models.LateFeeRule) error {
+	existing, err := r.Get(ctx, rule.SocietyCode)
+	if err == nil {
+		rule.ID = existing.ID
+		rule.CreatedAt = existing.CreatedAt
+		return r.table.update(rule.ID, nil, func(p *models.LateFeeRule) { *p = *rule })
+	}
+
+	if rule.ID.IsZero() {
+		rule.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(rule.ID, *rule, func(existing models.LateFeeRule) bool {
+		return existing.SocietyCode == rule.SocietyCode
+	})
+}
+
+func (r *memoryLateFeeRuleRepository) ListActive(ctx context.Context) ([]models.LateFeeRule, error) {
+	return r.table.filter(func(p models.LateFeeRule) bool { return p.IsActive }), nil
+}
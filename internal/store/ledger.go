@@ -0,0 +1,178 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LedgerFilter narrows List and SumByKind. Zero-valued fields are ignored.
+type LedgerFilter struct {
+	SocietyCode string
+	UnitID      primitive.ObjectID
+	Kinds       []string
+	// ReferenceIDs matches entries recording any of the given records
+	ReferenceIDs []primitive.ObjectID
+	// PostedFrom and PostedBefore bound posted_at as [from, before)
+	PostedFrom   time.Time
+	PostedBefore time.Time
+}
+
+type LedgerRepository interface {
+	// Create returns ErrDuplicate when the entry's idempotency key was already used
+	Create(ctx context.Context, entry *models.LedgerEntry) error
+	// List returns matching entries in posting order
+	List(ctx context.Context, filter LedgerFilter) ([]models.LedgerEntry, error)
+	// SumByKind totals the amount of matching entries grouped by kind
+	SumByKind(ctx context.Context, filter LedgerFilter) (map[string]float64, error)
+}
+
+func (f LedgerFilter) toBSON() bson.M {
+	filter := bson.M{}
+	if f.SocietyCode != "" {
+		filter["society_code"] = f.SocietyCode
+	}
+	if !f.UnitID.IsZero() {
+		filter["unit_id"] = f.UnitID
+	}
+	if len(f.Kinds) == 1 {
+		filter["kind"] = f.Kinds[0]
+	} else if len(f.Kinds) > 1 {
+		filter["kind"] = bson.M{"$in": f.Kinds}
+	}
+	if f.ReferenceIDs != nil {
+		filter["reference_id"] = bson.M{"$in": f.ReferenceIDs}
+	}
+	posted := bson.M{}
+	if !f.PostedFrom.IsZero() {
+		posted["$gte"] = f.PostedFrom
+	}
+	if !f.PostedBefore.IsZero() {
+		posted["$lt"] = f.PostedBefore
+	}
+	if len(posted) > 0 {
+		filter["posted_at"] = posted
+	}
+	return filter
+}
+
+func (f LedgerFilter) matches(e models.LedgerEntry) bool {
+	if f.SocietyCode != "" && e.SocietyCode != f.SocietyCode {
+		return false
+	}
+	if !f.UnitID.IsZero() && e.UnitID != f.UnitID {
+		return false
+	}
+	if len(f.Kinds) > 0 && !containsString(f.Kinds, e.Kind) {
+		return false
+	}
+	if f.ReferenceIDs != nil && !containsObjectID(f.ReferenceIDs, e.ReferenceID) {
+		return false
+	}
+	if !f.PostedFrom.IsZero() && e.PostedAt.Before(f.PostedFrom) {
+		return false
+	}
+	if !f.PostedBefore.IsZero() && !e.PostedAt.Before(f.PostedBefore) {
+		return false
+	}
+	return true
+}
+
+type mongoLedgerRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoLedgerRepository) Create(ctx context.Context, entry *models.LedgerEntry) error {
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, entry)
+	return translateError(err)
+}
+
+func (r *mongoLedgerRepository) List(ctx context.Context, filter LedgerFilter) ([]models.LedgerEntry, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "posted_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter.toBSON(), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.LedgerEntry
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *mongoLedgerRepository) SumByKind(ctx context.Context, filter LedgerFilter) (map[string]float64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter.toBSON()}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$kind",
+			"total": bson.M{"$sum": "$amount"},
+		}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Kind  string  `bson:"_id"`
+		Total float64 `bson:"total"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	totals := make(map[string]float64, len(results))
+	for _, result := range results {
+		totals[result.Kind] = result.Total
+	}
+	return totals, nil
+}
+
+type memoryLedgerRepository struct {
+	table *memoryTable[models.LedgerEntry]
+}
+
+func newMemoryLedgerRepository() *memoryLedgerRepository {
+	return &memoryLedgerRepository{table: newMemoryTable[models.LedgerEntry]()}
+}
+
+func (r *memoryLedgerRepository) Create(ctx context.Context, entry *models.LedgerEntry) error {
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(entry.ID, *entry, func(existing models.LedgerEntry) bool {
+		return existing.IdempotencyKey == entry.IdempotencyKey
+	})
+}
+
+func (r *memoryLedgerRepository) List(ctx context.Context, filter LedgerFilter) ([]models.LedgerEntry, error) {
+	entries := r.table.filter(filter.matches)
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].PostedAt.Equal(entries[j].PostedAt) {
+			return entries[i].ID.Hex() < entries[j].ID.Hex()
+		}
+		return entries[i].PostedAt.Before(entries[j].PostedAt)
+	})
+	return entries, nil
+}
+
+func (r *memoryLedgerRepository) SumByKind(ctx context.Context, filter LedgerFilter) (map[string]float64, error) {
+	totals := make(map[string]float64)
+	for _, entry := range r.table.filter(filter.matches) {
+		totals[entry.Kind] += entry.Amount
+	}
+	return totals, nil
+}
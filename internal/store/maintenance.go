@@ -0,0 +1,446 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MaintenanceFilter narrows List, Count and SumByStatus. Zero-valued fields are ignored.
+type MaintenanceFilter struct {
+	SocietyCode string
+	UnitID      primitive.ObjectID
+	UnitNumber  string
+	Statuses    []string
+	DueBefore   time.Time
+	// BillingCycle matches records generated by the billing engine for a YYYY-MM cycle
+	BillingCycle string
+}
+
+// MaintenanceUpdate carries the fields to change. Nil fields are left untouched.
+type MaintenanceUpdate struct {
+	Status     *string
+	PaidDate   *time.Time
+	ReceiptURL *string
+	InvoiceURL *string
+}
+
+type MaintenanceRepository interface {
+	// Create returns ErrDuplicate when the record's idempotency key was already used
+	Create(ctx context.Context, record *models.MaintenanceRecord) error
+	GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.MaintenanceRecord, error)
+	// List returns matching records, latest due date first
+	List(ctx context.Context, filter MaintenanceFilter) ([]models.MaintenanceRecord, error)
+	Count(ctx context.Context, filter MaintenanceFilter) (int64, error)
+	// SumByStatus totals the amount and amount paid of matching records
+	// grouped by status
+	SumByStatus(ctx context.Context, filter MaintenanceFilter) (map[string]MaintenanceSum, error)
+	Update(ctx context.Context, societyCode string, id primitive.ObjectID, update MaintenanceUpdate) error
+	// MarkOverdue moves pending and partially paid records whose due date is
+	// before now to overdue, across all societies, and returns how many changed
+	MarkOverdue(ctx context.Context, now time.Time) (int64, error)
+	// AddPayment allocates amount to an unpaid record whose amount_paid is
+	// still paid, moving it to partially_paid, or to paid once fully
+	// covered. It returns ErrConflict when the record is paid, its
+	// amount_paid has changed or the amount exceeds what is outstanding.
+	AddPayment(ctx context.Context, societyCode string, id primitive.ObjectID, paid, amount float64, at time.Time) (*models.MaintenanceRecord, error)
+	// RemovePayment takes back amount allocated with AddPayment, returning
+	// the record to pending, partially_paid or, past its due date at now,
+	// overdue. It returns ErrConflict when less than amount was paid.
+	RemovePayment(ctx context.Context, societyCode string, id primitive.ObjectID, amount float64, now time.Time) error
+}
+
+// MaintenanceSum totals a group of records: what they were billed and what
+// has been paid toward them
+type MaintenanceSum struct {
+	Amount     float64 `bson:"amount"`
+	AmountPaid float64 `bson:"amount_paid"`
+}
+
+// paymentTolerance absorbs floating point drift when comparing amounts
+const paymentTolerance = 0.005
+
+func (f MaintenanceFilter) toBSON() bson.M {
+	filter := bson.M{}
+	if f.SocietyCode != "" {
+		filter["society_code"] = f.SocietyCode
+	}
+	if !f.UnitID.IsZero() {
+		filter["unit_id"] = f.UnitID
+	}
+	if f.UnitNumber != "" {
+		filter["unit_number"] = f.UnitNumber
+	}
+	if len(f.Statuses) == 1 {
+		filter["status"] = f.Statuses[0]
+	} else if len(f.Statuses) > 1 {
+		filter["status"] = bson.M{"$in": f.Statuses}
+	}
+	if !f.DueBefore.IsZero() {
+		filter["due_date"] = bson.M{"$lt": f.DueBefore}
+	}
+	if f.BillingCycle != "" {
+		filter["billing_cycle"] = f.BillingCycle
+	}
+	return filter
+}
+
+func (f MaintenanceFilter) matches(m models.MaintenanceRecord) bool {
+	if f.SocietyCode != "" && m.SocietyCode != f.SocietyCode {
+		return false
+	}
+	if !f.UnitID.IsZero() && m.UnitID != f.UnitID {
+		return false
+	}
+	if f.UnitNumber != "" && m.UnitNumber != f.UnitNumber {
+		return false
+	}
+	if len(f.Statuses) > 0 && !containsString(f.Statuses, m.Status) {
+		return false
+	}
+	if !f.DueBefore.IsZero() && !m.DueDate.Before(f.DueBefore) {
+		return false
+	}
+	if f.BillingCycle != "" && m.BillingCycle != f.BillingCycle {
+		return false
+	}
+	return true
+}
+
+func (u MaintenanceUpdate) toBSON() bson.M {
+	set := bson.M{}
+	if u.Status != nil {
+		set["status"] = *u.Status
+	}
+	if u.PaidDate != nil {
+		set["paid_date"] = *u.PaidDate
+	}
+	if u.ReceiptURL != nil {
+		set["receipt_url"] = *u.ReceiptURL
+	}
+	if u.InvoiceURL != nil {
+		set["invoice_url"] = *u.InvoiceURL
+	}
+	return bson.M{"$set": set}
+}
+
+func (u MaintenanceUpdate) apply(m *models.MaintenanceRecord) {
+	if u.Status != nil {
+		m.Status = *u.Status
+	}
+	if u.PaidDate != nil {
+		paidDate := *u.PaidDate
+		m.PaidDate = &paidDate
+	}
+	if u.ReceiptURL != nil {
+		m.ReceiptURL = *u.ReceiptURL
+	}
+	if u.InvoiceURL != nil {
+		m.InvoiceURL = *u.InvoiceURL
+	}
+}
+
+type mongoMaintenanceRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoMaintenanceRepository) Create(ctx context.Context, record *models.MaintenanceRecord) error {
+	if record.ID.IsZero() {
+		record.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, record)
+	return translateError(err)
+}
+
+func (r *mongoMaintenanceRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.MaintenanceRecord, error) {
+	var record models.MaintenanceRecord
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "society_code": societyCode}).Decode(&record)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &record, nil
+}
+
+func (r *mongoMaintenanceRepository) List(ctx context.Context, filter MaintenanceFilter) ([]models.MaintenanceRecord, error) {
+	cursor, err := r.collection.Find(ctx, filter.toBSON(), options.Find().SetSort(bson.M{"due_date": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []models.MaintenanceRecord
+	if err = cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (r *mongoMaintenanceRepository) Count(ctx context.Context, filter MaintenanceFilter) (int64, error) {
+	return r.collection.CountDocuments(ctx, filter.toBSON())
+}
+
+func (r *mongoMaintenanceRepository) SumByStatus(ctx context.Context, filter MaintenanceFilter) (map[string]MaintenanceSum, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter.toBSON()}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         "$status",
+			"amount":      bson.M{"$sum": "$amount"},
+			"amount_paid": bson.M{"$sum": bson.M{"$ifNull": bson.A{"$amount_paid", 0}}},
+		}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Status         string `bson:"_id"`
+		MaintenanceSum `bson:",inline"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	totals := make(map[string]MaintenanceSum, len(results))
+	for _, result := range results {
+		totals[result.Status] = result.MaintenanceSum
+	}
+	return totals, nil
+}
+
+func (r *mongoMaintenanceRepository) Update(ctx context.Context, societyCode string, id primitive.ObjectID, update MaintenanceUpdate) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "society_code": societyCode}, update.toBSON())
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoMaintenanceRepository) MarkOverdue(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.collection.UpdateMany(ctx,
+		bson.M{"status": bson.M{"$in": []string{"pending", "partially_paid"}}, "due_date": bson.M{"$lt": now}},
+		bson.M{"$set": bson.M{"status": "overdue"}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (r *mongoMaintenanceRepository) AddPayment(ctx context.Context, societyCode string, id primitive.ObjectID, paid, amount float64, at time.Time) (*models.MaintenanceRecord, error) {
+	amountPaid := bson.M{"$ifNull": bson.A{"$amount_paid", 0}}
+	filter := bson.M{
+		"_id":          id,
+		"society_code": societyCode,
+		"status":       bson.M{"$ne": "paid"},
+		"$expr": bson.M{"$and": bson.A{
+			bson.M{"$eq": bson.A{amountPaid, paid}},
+			bson.M{"$lte": bson.A{
+				bson.M{"$add": bson.A{amountPaid, amount}},
+				bson.M{"$add": bson.A{"$amount", paymentTolerance}},
+			}},
+		}},
+	}
+	covered := bson.M{"$gte": bson.A{"$amount_paid", bson.M{"$subtract": bson.A{"$amount", paymentTolerance}}}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"amount_paid": bson.M{"$add": bson.A{amountPaid, amount}}}}},
+		{{Key: "$set", Value: bson.M{
+			"status": bson.M{"$cond": bson.A{covered, "paid",
+				bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$status", "overdue"}}, "overdue", "partially_paid"}},
+			}},
+			"paid_date": bson.M{"$cond": bson.A{covered, at, "$$REMOVE"}},
+		}}},
+	}
+
+	var record models.MaintenanceRecord
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&record)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		n, err := r.collection.CountDocuments(ctx, bson.M{"_id": id, "society_code": societyCode})
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return nil, ErrNotFound
+		}
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *mongoMaintenanceRepository) RemovePayment(ctx context.Context, societyCode string, id primitive.ObjectID, amount float64, now time.Time) error {
+	amountPaid := bson.M{"$ifNull": bson.A{"$amount_paid", 0}}
+	filter := bson.M{
+		"_id":          id,
+		"society_code": societyCode,
+		"$expr": bson.M{"$gte": bson.A{
+			amountPaid,
+			bson.M{"$subtract": bson.A{amount, paymentTolerance}},
+		}},
+	}
+	covered := bson.M{"$gte": bson.A{"$amount_paid", bson.M{"$subtract": bson.A{"$amount", paymentTolerance}}}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"amount_paid": bson.M{"$max": bson.A{
+			bson.M{"$subtract": bson.A{amountPaid, amount}}, 0,
+		}}}}},
+		{{Key: "$set", Value: bson.M{
+			"status": bson.M{"$switch": bson.M{
+				"branches": bson.A{
+					bson.M{"case": covered, "then": "paid"},
+					bson.M{"case": bson.M{"$lt": bson.A{"$due_date", now}}, "then": "overdue"},
+					bson.M{"case": bson.M{"$gt": bson.A{"$amount_paid", paymentTolerance}}, "then": "partially_paid"},
+				},
+				"default": "pending",
+			}},
+			"paid_date": bson.M{"$cond": bson.A{covered, "$paid_date", "$$REMOVE"}},
+		}}},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		n, err := r.collection.CountDocuments(ctx, bson.M{"_id": id, "society_code": societyCode})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrNotFound
+		}
+		return ErrConflict
+	}
+	return nil
+}
+
+type memoryMaintenanceRepository struct {
+	table *memoryTable[models.MaintenanceRecord]
+}
+
+func newMemoryMaintenanceRepository() *memoryMaintenanceRepository {
+	return &memoryMaintenanceRepository{table: newMemoryTable[models.MaintenanceRecord]()}
+}
+
+func (r *memoryMaintenanceRepository) Create(ctx context.Context, record *models.MaintenanceRecord) error {
+	if record.ID.IsZero() {
+		record.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(record.ID, *record, func(existing models.MaintenanceRecord) bool {
+		return record.IdempotencyKey != "" && existing.IdempotencyKey == record.IdempotencyKey
+	})
+}
+
+func (r *memoryMaintenanceRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.MaintenanceRecord, error) {
+	record, err := r.table.find(func(m models.MaintenanceRecord) bool {
+		return m.ID == id && m.SocietyCode == societyCode
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *memoryMaintenanceRepository) List(ctx context.Context, filter MaintenanceFilter) ([]models.MaintenanceRecord, error) {
+	records := r.table.filter(filter.matches)
+	sort.Slice(records, func(i, j int) bool { return records[i].DueDate.After(records[j].DueDate) })
+	return records, nil
+}
+
+func (r *memoryMaintenanceRepository) Count(ctx context.Context, filter MaintenanceFilter) (int64, error) {
+	return r.table.count(filter.matches), nil
+}
+
+func (r *memoryMaintenanceRepository) SumByStatus(ctx context.Context, filter MaintenanceFilter) (map[string]MaintenanceSum, error) {
+	totals := make(map[string]MaintenanceSum)
+	for _, record := range r.table.filter(filter.matches) {
+		sum := totals[record.Status]
+		sum.Amount += record.Amount
+		sum.AmountPaid += record.AmountPaid
+		totals[record.Status] = sum
+	}
+	return totals, nil
+}
+
+func (r *memoryMaintenanceRepository) Update(ctx context.Context, societyCode string, id primitive.ObjectID, update MaintenanceUpdate) error {
+	return r.table.update(id, func(m models.MaintenanceRecord) bool { return m.SocietyCode == societyCode }, update.apply)
+}
+
+func (r *memoryMaintenanceRepository) MarkOverdue(ctx context.Context, now time.Time) (int64, error) {
+	n := r.table.updateAll(func(m models.MaintenanceRecord) bool {
+		return (m.Status == "pending" || m.Status == "partially_paid") && m.DueDate.Before(now)
+	}, func(m *models.MaintenanceRecord) {
+		m.Status = "overdue"
+	})
+	return n, nil
+}
+
+func (r *memoryMaintenanceRepository) AddPayment(ctx context.Context, societyCode string, id primitive.ObjectID, paid, amount float64, at time.Time) (*models.MaintenanceRecord, error) {
+	var record models.MaintenanceRecord
+	conflict := false
+	err := r.table.update(id, func(m models.MaintenanceRecord) bool { return m.SocietyCode == societyCode }, func(m *models.MaintenanceRecord) {
+		if m.Status == "paid" || m.AmountPaid != paid || m.AmountPaid+amount > m.Amount+paymentTolerance {
+			conflict = true
+			return
+		}
+		m.AmountPaid += amount
+		switch {
+		case m.AmountPaid >= m.Amount-paymentTolerance:
+			paidDate := at
+			m.Status = "paid"
+			m.PaidDate = &paidDate
+		case m.Status != "overdue":
+			m.Status = "partially_paid"
+		}
+		record = *m
+	})
+	if err == nil && conflict {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *memoryMaintenanceRepository) RemovePayment(ctx context.Context, societyCode string, id primitive.ObjectID, amount float64, now time.Time) error {
+	conflict := false
+	err := r.table.update(id, func(m models.MaintenanceRecord) bool { return m.SocietyCode == societyCode }, func(m *models.MaintenanceRecord) {
+		if m.AmountPaid < amount-paymentTolerance {
+			conflict = true
+			return
+		}
+		m.AmountPaid = math.Max(m.AmountPaid-amount, 0)
+		switch {
+		case m.AmountPaid >= m.Amount-paymentTolerance:
+			m.Status = "paid"
+			return
+		case m.DueDate.Before(now):
+			m.Status = "overdue"
+		case m.AmountPaid > paymentTolerance:
+			m.Status = "partially_paid"
+		default:
+			m.Status = "pending"
+		}
+		m.PaidDate = nil
+	})
+	if err == nil && conflict {
+		return ErrConflict
+	}
+	return err
+}
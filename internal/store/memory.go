@@ -0,0 +1,126 @@
+package store
+
+import (
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryTable is the shared building block of the in-memory repositories.
+// Rows are stored by value so callers can never mutate stored state.
+type memoryTable[T any] struct {
+	mu   sync.RWMutex
+	rows map[primitive.ObjectID]T
+}
+
+func newMemoryTable[T any]() *memoryTable[T] {
+	return &memoryTable[T]{rows: make(map[primitive.ObjectID]T)}
+}
+
+// insert adds a row unless any existing row conflicts with it
+func (t *memoryTable[T]) insert(id primitive.ObjectID, row T, conflicts func(existing T) bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, exists := t.rows[id]; exists {
+		return ErrDuplicate
+	}
+	if conflicts != nil {
+		for _, existing := range t.rows {
+			if conflicts(existing) {
+				return ErrDuplicate
+			}
+		}
+	}
+	t.rows[id] = row
+	return nil
+}
+
+// find returns the first row accepted by match
+func (t *memoryTable[T]) find(match func(T) bool) (T, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, row := range t.rows {
+		if match(row) {
+			return row, nil
+		}
+	}
+	var zero T
+	return zero, ErrNotFound
+}
+
+// filter returns every row accepted by match, in no particular order
+func (t *memoryTable[T]) filter(match func(T) bool) []T {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var rows []T
+	for _, row := range t.rows {
+		if match(row) {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// count returns the number of rows accepted by match
+func (t *memoryTable[T]) count(match func(T) bool) int64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var n int64
+	for _, row := range t.rows {
+		if match(row) {
+			n++
+		}
+	}
+	return n
+}
+
+// update applies fn to the row with the given id if scope accepts it
+func (t *memoryTable[T]) update(id primitive.ObjectID, scope func(T) bool, fn func(*T)) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	row, ok := t.rows[id]
+	if !ok || (scope != nil && !scope(row)) {
+		return ErrNotFound
+	}
+	fn(&row)
+	t.rows[id] = row
+	return nil
+}
+
+// updateAll applies fn to every row accepted by match and returns how many
+// rows it touched
+func (t *memoryTable[T]) updateAll(match func(T) bool, fn func(*T)) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var n int64
+	for id, row := range t.rows {
+		if match(row) {
+			fn(&row)
+			t.rows[id] = row
+			n++
+		}
+	}
+	return n
+}
+
+// deleteAll removes every row accepted by match and returns how many it
+// removed
+func (t *memoryTable[T]) deleteAll(match func(T) bool) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var n int64
+	for id, row := range t.rows {
+		if match(row) {
+			delete(t.rows, id)
+			n++
+		}
+	}
+	return n
+}
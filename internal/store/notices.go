@@ -0,0 +1,187 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NoticeFilter narrows List and Count. Zero-valued fields are ignored.
+type NoticeFilter struct {
+	SocietyCode string
+	ActiveOnly  bool
+}
+
+// NoticeUpdate carries the fields to change. Nil fields are left untouched.
+type NoticeUpdate struct {
+	Title     *string
+	Content   *string
+	Type      *string
+	IsActive  *bool
+	ExpiresAt *time.Time
+}
+
+type NoticeRepository interface {
+	Create(ctx context.Context, notice *models.Notice) error
+	GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Notice, error)
+	// List returns matching notices, newest first
+	List(ctx context.Context, filter NoticeFilter) ([]models.Notice, error)
+	Count(ctx context.Context, filter NoticeFilter) (int64, error)
+	Update(ctx context.Context, societyCode string, id primitive.ObjectID, update NoticeUpdate) error
+}
+
+func (f NoticeFilter) toBSON() bson.M {
+	filter := bson.M{}
+	if f.SocietyCode != "" {
+		filter["society_code"] = f.SocietyCode
+	}
+	if f.ActiveOnly {
+		filter["is_active"] = true
+	}
+	return filter
+}
+
+func (f NoticeFilter) matches(n models.Notice) bool {
+	if f.SocietyCode != "" && n.SocietyCode != f.SocietyCode {
+		return false
+	}
+	if f.ActiveOnly && !n.IsActive {
+		return false
+	}
+	return true
+}
+
+func (u NoticeUpdate) toBSON() bson.M {
+	set := bson.M{}
+	if u.Title != nil {
+		set["title"] = *u.Title
+	}
+	if u.Content != nil {
+		set["content"] = *u.Content
+	}
+	if u.Type != nil {
+		set["type"] = *u.Type
+	}
+	if u.IsActive != nil {
+		set["is_active"] = *u.IsActive
+	}
+	if u.ExpiresAt != nil {
+		set["expires_at"] = *u.ExpiresAt
+	}
+	return bson.M{"$set": set}
+}
+
+func (u NoticeUpdate) apply(n *models.Notice) {
+	if u.Title != nil {
+		n.Title = *u.Title
+	}
+	if u.Content != nil {
+		n.Content = *u.Content
+	}
+	if u.Type != nil {
+		n.Type = *u.Type
+	}
+	if u.IsActive != nil {
+		n.IsActive = *u.IsActive
+	}
+	if u.ExpiresAt != nil {
+		expiresAt := *u.ExpiresAt
+		n.ExpiresAt = &expiresAt
+	}
+}
+
+type mongoNoticeRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoNoticeRepository) Create(ctx context.Context, notice *models.Notice) error {
+	if notice.ID.IsZero() {
+		notice.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, notice)
+	return translateError(err)
+}
+
+func (r *mongoNoticeRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Notice, error) {
+	var notice models.Notice
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "society_code": societyCode}).Decode(&notice)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &notice, nil
+}
+
+func (r *mongoNoticeRepository) List(ctx context.Context, filter NoticeFilter) ([]models.Notice, error) {
+	cursor, err := r.collection.Find(ctx, filter.toBSON(), options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var notices []models.Notice
+	if err = cursor.All(ctx, &notices); err != nil {
+		return nil, err
+	}
+	return notices, nil
+}
+
+func (r *mongoNoticeRepository) Count(ctx context.Context, filter NoticeFilter) (int64, error) {
+	return r.collection.CountDocuments(ctx, filter.toBSON())
+}
+
+func (r *mongoNoticeRepository) Update(ctx context.Context, societyCode string, id primitive.ObjectID, update NoticeUpdate) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "society_code": societyCode}, update.toBSON())
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryNoticeRepository struct {
+	table *memoryTable[models.Notice]
+}
+
+func newMemoryNoticeRepository() *memoryNoticeRepository {
+	return &memoryNoticeRepository{table: newMemoryTable[models.Notice]()}
+}
+
+func (r *memoryNoticeRepository) Create(ctx context.Context, notice *models.Notice) error {
+	if notice.ID.IsZero() {
+		notice.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(notice.ID, *notice, nil)
+}
+
+func (r *memoryNoticeRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Notice, error) {
+	notice, err := r.table.find(func(n models.Notice) bool {
+		return n.ID == id && n.SocietyCode == societyCode
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &notice, nil
+}
+
+func (r *memoryNoticeRepository) List(ctx context.Context, filter NoticeFilter) ([]models.Notice, error) {
+	notices := r.table.filter(filter.matches)
+	sort.Slice(notices, func(i, j int) bool { return notices[i].CreatedAt.After(notices[j].CreatedAt) })
+	return notices, nil
+}
+
+func (r *memoryNoticeRepository) Count(ctx context.Context, filter NoticeFilter) (int64, error) {
+	return r.table.count(filter.matches), nil
+}
+
+func (r *memoryNoticeRepository) Update(ctx context.Context, societyCode string, id primitive.ObjectID, update NoticeUpdate) error {
+	return r.table.update(id, func(n models.Notice) bool { return n.SocietyCode == societyCode }, update.apply)
+}
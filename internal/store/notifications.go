@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NotificationFilter narrows List and Count. Zero-valued fields are ignored.
+type NotificationFilter struct {
+	SocietyCode string
+	UserID      primitive.ObjectID
+	UnreadOnly  bool
+}
+
+type NotificationRepository interface {
+	Create(ctx context.Context, notification *models.Notification) error
+	// List returns matching notifications, newest first
+	List(ctx context.Context, filter NotificationFilter) ([]models.Notification, error)
+	Count(ctx context.Context, filter NotificationFilter) (int64, error)
+	// MarkRead marks one of the user's notifications as read; reading it
+	// again keeps the first read time
+	MarkRead(ctx context.Context, societyCode string, userID, id primitive.ObjectID, at time.Time) error
+}
+
+func (f NotificationFilter) toBSON() bson.M {
+	filter := bson.M{}
+	if f.SocietyCode != "" {
+		filter["society_code"] = f.SocietyCode
+	}
+	if !f.UserID.IsZero() {
+		filter["user_id"] = f.UserID
+	}
+	if f.UnreadOnly {
+		filter["read_at"] = bson.M{"$exists": false}
+	}
+	return filter
+}
+
+func (f NotificationFilter) matches(n models.Notification) bool {
+	if f.SocietyCode != "" && n.SocietyCode != f.SocietyCode {
+		return false
+	}
+	if !f.UserID.IsZero() && n.UserID != f.UserID {
+		return false
+	}
+	if f.UnreadOnly && n.ReadAt != nil {
+		return false
+	}
+	return true
+}
+
+type mongoNotificationRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoNotificationRepository) Create(ctx context.Context, notification *models.Notification) error {
+	if notification.ID.IsZero() {
+		notification.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, notification)
+	return translateError(err)
+}
+
+func (r *mongoNotificationRepository) List(ctx context.Context, filter NotificationFilter) ([]models.Notification, error) {
+	cursor, err := r.collection.Find(ctx, filter.toBSON(), options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var notifications []models.Notification
+	if err = cursor.All(ctx, &notifications); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+func (r *mongoNotificationRepository) Count(ctx context.Context, filter NotificationFilter) (int64, error) {
+	return r.collection.CountDocuments(ctx, filter.toBSON())
+}
+
+func (r *mongoNotificationRepository) MarkRead(ctx context.Context, societyCode string, userID, id primitive.ObjectID, at time.Time) error {
+	filter := bson.M{"_id": id, "society_code": societyCode, "user_id": userID}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.A{
+		bson.M{"$set": bson.M{"read_at": bson.M{"$ifNull": bson.A{"$read_at", at}}}},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryNotificationRepository struct {
+	table *memoryTable[models.Notification]
+}
+
+func newMemoryNotificationRepository() *memoryNotificationRepository {
+	return &memoryNotificationRepository{table: newMemoryTable[models.Notification]()}
+}
+
+func (r *memoryNotificationRepository) Create(ctx context.Context, notification *models.Notification) error {
+	if notification.ID.IsZero() {
+		notification.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(notification.ID, *notification, nil)
+}
+
+func (r *memoryNotificationRepository) List(ctx context.Context, filter NotificationFilter) ([]models.Notification, error) {
+	notifications := r.table.filter(filter.matches)
+	sort.Slice(notifications, func(i, j int) bool { return notifications[i].CreatedAt.After(notifications[j].CreatedAt) })
+	return notifications, nil
+}
+
+func (r *memoryNotificationRepository) Count(ctx context.Context, filter NotificationFilter) (int64, error) {
+	return r.table.count(filter.matches), nil
+}
+
+func (r *memoryNotificationRepository) MarkRead(ctx context.Context, societyCode string, userID, id primitive.ObjectID, at time.Time) error {
+	return r.table.update(id, func(n models.Notification) bool {
+		return n.SocietyCode == societyCode && n.UserID == userID
+	}, func(n *models.Notification) {
+		if n.ReadAt == nil {
+			readAt := at
+			n.ReadAt = &readAt
+		}
+	})
+}
@@ -0,0 +1,293 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ParcelFilter narrows List. Zero-valued fields are ignored; ReceivedFrom
+// is inclusive and ReceivedTo exclusive.
+type ParcelFilter struct {
+	SocietyCode  string
+	UnitID       primitive.ObjectID
+	Statuses     []string
+	ReceivedFrom time.Time
+	ReceivedTo   time.Time
+	// ReminderDueBy matches parcels whose next reminder is due at or before it
+	ReminderDueBy time.Time
+}
+
+// ParcelUpdate carries the fields to change. Nil fields are left untouched.
+type ParcelUpdate struct {
+	Status         *string
+	OTPHash        *string
+	OTPAttempts    *int
+	FailedOTP      bool // Counts one more wrong OTP
+	Collection     *models.ParcelCollection
+	ReturnedAt     *time.Time
+	ReturnReason   *string
+	RemindersSent  *int
+	NextReminderAt *time.Time
+	StopReminders  bool // Clears the next reminder
+	UpdatedAt      time.Time
+}
+
+type ParcelRepository interface {
+	Create(ctx context.Context, parcel *models.Parcel) error
+	GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Parcel, error)
+	// List returns matching parcels, most recently received first
+	List(ctx context.Context, filter ParcelFilter) ([]models.Parcel, error)
+	Update(ctx context.Context, societyCode string, id primitive.ObjectID, update ParcelUpdate) error
+	// Transition applies the update only while the parcel is in one of the
+	// from statuses, returning ErrConflict otherwise
+	Transition(ctx context.Context, societyCode string, id primitive.ObjectID, from []string, update ParcelUpdate) error
+}
+
+func (f ParcelFilter) toBSON() bson.M {
+	filter := bson.M{}
+	if f.SocietyCode != "" {
+		filter["society_code"] = f.SocietyCode
+	}
+	if !f.UnitID.IsZero() {
+		filter["unit_id"] = f.UnitID
+	}
+	if len(f.Statuses) == 1 {
+		filter["status"] = f.Statuses[0]
+	} else if len(f.Statuses) > 1 {
+		filter["status"] = bson.M{"$in": f.Statuses}
+	}
+	received := bson.M{}
+	if !f.ReceivedFrom.IsZero() {
+		received["$gte"] = f.ReceivedFrom
+	}
+	if !f.ReceivedTo.IsZero() {
+		received["$lt"] = f.ReceivedTo
+	}
+	if len(received) > 0 {
+		filter["received_at"] = received
+	}
+	if !f.ReminderDueBy.IsZero() {
+		filter["next_reminder_at"] = bson.M{"$lte": f.ReminderDueBy}
+	}
+	return filter
+}
+
+func (f ParcelFilter) matches(parcel models.Parcel) bool {
+	if f.SocietyCode != "" && parcel.SocietyCode != f.SocietyCode {
+		return false
+	}
+	if !f.UnitID.IsZero() && parcel.UnitID != f.UnitID {
+		return false
+	}
+	if len(f.Statuses) > 0 && !containsString(f.Statuses, parcel.Status) {
+		return false
+	}
+	if !f.ReceivedFrom.IsZero() && parcel.ReceivedAt.Before(f.ReceivedFrom) {
+		return false
+	}
+	if !f.ReceivedTo.IsZero() && !parcel.ReceivedAt.Before(f.ReceivedTo) {
+		return false
+	}
+	if !f.ReminderDueBy.IsZero() && (parcel.NextReminderAt == nil || parcel.NextReminderAt.After(f.ReminderDueBy)) {
+		return false
+	}
+	return true
+}
+
+func (u ParcelUpdate) toBSON() bson.M {
+	set := bson.M{"updated_at": u.UpdatedAt}
+	if u.Status != nil {
+		set["status"] = *u.Status
+	}
+	if u.OTPHash != nil {
+		set["otp_hash"] = *u.OTPHash
+	}
+	if u.OTPAttempts != nil {
+		set["otp_attempts"] = *u.OTPAttempts
+	}
+	if u.Collection != nil {
+		set["collection"] = *u.Collection
+	}
+	if u.ReturnedAt != nil {
+		set["returned_at"] = *u.ReturnedAt
+	}
+	if u.ReturnReason != nil {
+		set["return_reason"] = *u.ReturnReason
+	}
+	if u.RemindersSent != nil {
+		set["reminders_sent"] = *u.RemindersSent
+	}
+	if u.NextReminderAt != nil {
+		set["next_reminder_at"] = *u.NextReminderAt
+	}
+
+	update := bson.M{"$set": set}
+	if u.FailedOTP {
+		update["$inc"] = bson.M{"otp_attempts": 1}
+	}
+	if u.StopReminders {
+		update["$unset"] = bson.M{"next_reminder_at": ""}
+	}
+	return update
+}
+
+func (u ParcelUpdate) apply(parcel *models.Parcel) {
+	parcel.UpdatedAt = u.UpdatedAt
+	if u.Status != nil {
+		parcel.Status = *u.Status
+	}
+	if u.OTPHash != nil {
+		parcel.OTPHash = *u.OTPHash
+	}
+	if u.OTPAttempts != nil {
+		parcel.OTPAttempts = *u.OTPAttempts
+	}
+	if u.FailedOTP {
+		parcel.OTPAttempts++
+	}
+	if u.Collection != nil {
+		collection := *u.Collection
+		parcel.Collection = &collection
+	}
+	if u.ReturnedAt != nil {
+		returnedAt := *u.ReturnedAt
+		parcel.ReturnedAt = &returnedAt
+	}
+	if u.ReturnReason != nil {
+		parcel.ReturnReason = *u.ReturnReason
+	}
+	if u.RemindersSent != nil {
+		parcel.RemindersSent = *u.RemindersSent
+	}
+	if u.NextReminderAt != nil {
+		next := *u.NextReminderAt
+		parcel.NextReminderAt = &next
+	}
+	if u.StopReminders {
+		parcel.NextReminderAt = nil
+	}
+}
+
+type mongoParcelRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoParcelRepository) Create(ctx context.Context, parcel *models.Parcel) error {
+	if parcel.ID.IsZero() {
+		parcel.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, parcel)
+	return translateError(err)
+}
+
+func (r *mongoParcelRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Parcel, error) {
+	var parcel models.Parcel
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "society_code": societyCode}).Decode(&parcel)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &parcel, nil
+}
+
+func (r *mongoParcelRepository) List(ctx context.Context, filter ParcelFilter) ([]models.Parcel, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "received_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, filter.toBSON(), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var parcels []models.Parcel
+	if err = cursor.All(ctx, &parcels); err != nil {
+		return nil, err
+	}
+	return parcels, nil
+}
+
+func (r *mongoParcelRepository) Update(ctx context.Context, societyCode string, id primitive.ObjectID, update ParcelUpdate) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "society_code": societyCode}, update.toBSON())
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoParcelRepository) Transition(ctx context.Context, societyCode string, id primitive.ObjectID, from []string, update ParcelUpdate) error {
+	filter := bson.M{"_id": id, "society_code": societyCode, "status": bson.M{"$in": from}}
+	result, err := r.collection.UpdateOne(ctx, filter, update.toBSON())
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		n, err := r.collection.CountDocuments(ctx, bson.M{"_id": id, "society_code": societyCode})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrNotFound
+		}
+		return ErrConflict
+	}
+	return nil
+}
+
+type memoryParcelRepository struct {
+	table *memoryTable[models.Parcel]
+}
+
+func newMemoryParcelRepository() *memoryParcelRepository {
+	return &memoryParcelRepository{table: newMemoryTable[models.Parcel]()}
+}
+
+func (r *memoryParcelRepository) Create(ctx context.Context, parcel *models.Parcel) error {
+	if parcel.ID.IsZero() {
+		parcel.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(parcel.ID, *parcel, nil)
+}
+
+func (r *memoryParcelRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Parcel, error) {
+	parcel, err := r.table.find(func(p models.Parcel) bool {
+		return p.ID == id && p.SocietyCode == societyCode
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &parcel, nil
+}
+
+func (r *memoryParcelRepository) List(ctx context.Context, filter ParcelFilter) ([]models.Parcel, error) {
+	parcels := r.table.filter(filter.matches)
+	sort.Slice(parcels, func(i, j int) bool { return parcels[i].ReceivedAt.After(parcels[j].ReceivedAt) })
+	return parcels, nil
+}
+
+func (r *memoryParcelRepository) Update(ctx context.Context, societyCode string, id primitive.ObjectID, update ParcelUpdate) error {
+	return r.table.update(id, func(p models.Parcel) bool { return p.SocietyCode == societyCode }, update.apply)
+}
+
+func (r *memoryParcelRepository) Transition(ctx context.Context, societyCode string, id primitive.ObjectID, from []string, update ParcelUpdate) error {
+	conflict := false
+	err := r.table.update(id, func(p models.Parcel) bool { return p.SocietyCode == societyCode }, func(p *models.Parcel) {
+		if !containsString(from, p.Status) {
+			conflict = true
+			return
+		}
+		update.apply(p)
+	})
+	if err == nil && conflict {
+		return ErrConflict
+	}
+	return err
+}
@@ -0,0 +1,341 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ParkingSlotFilter narrows List. Zero-valued fields are ignored.
+type ParkingSlotFilter struct {
+	SocietyCode string
+	BuildingID  primitive.ObjectID
+	Kind        string
+	VehicleType string
+	UnitID      primitive.ObjectID
+	Label       string
+	ActiveOnly  bool
+	// FreeOnly keeps active slots nobody is parked in
+	FreeOnly bool
+	// OverstayBy keeps occupied slots whose occupant should have left by it
+	OverstayBy time.Time
+	// UnalertedOnly keeps occupied slots whose overstay was not reported yet
+	UnalertedOnly bool
+}
+
+// ParkingSlotUpdate carries the fields to change. Nil fields are left
+// untouched.
+type ParkingSlotUpdate struct {
+	Label       *string
+	VehicleType *string
+	UnitID      *primitive.ObjectID
+	UnitNumber  *string
+	ClearUnit   bool // Takes an assigned slot away from its unit
+	IsActive    *bool
+	// AlertedAt records when the occupant's overstay was reported
+	AlertedAt *time.Time
+	UpdatedAt time.Time
+}
+
+type ParkingSlotRepository interface {
+	// Create returns ErrDuplicate if the building already has the label
+	Create(ctx context.Context, slot *models.ParkingSlot) error
+	GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.ParkingSlot, error)
+	// List returns matching slots ordered by building and label
+	List(ctx context.Context, filter ParkingSlotFilter) ([]models.ParkingSlot, error)
+	Update(ctx context.Context, societyCode string, id primitive.ObjectID, update ParkingSlotUpdate) error
+	// Occupy parks the occupant in an active, empty visitor slot. It
+	// returns ErrConflict if the slot is taken or out of use.
+	Occupy(ctx context.Context, societyCode string, id primitive.ObjectID, occupant models.ParkingOccupant) error
+	// Vacate frees the slot held by the visitor. It returns ErrNotFound if
+	// the visitor is not parked there.
+	Vacate(ctx context.Context, societyCode string, id primitive.ObjectID, visitorID primitive.ObjectID, at time.Time) error
+}
+
+func (f ParkingSlotFilter) toBSON() bson.M {
+	filter := bson.M{}
+	if f.SocietyCode != "" {
+		filter["society_code"] = f.SocietyCode
+	}
+	if !f.BuildingID.IsZero() {
+		filter["building_id"] = f.BuildingID
+	}
+	if f.Kind != "" {
+		filter["kind"] = f.Kind
+	}
+	if f.VehicleType != "" {
+		filter["vehicle_type"] = f.VehicleType
+	}
+	if !f.UnitID.IsZero() {
+		filter["unit_id"] = f.UnitID
+	}
+	if f.Label != "" {
+		filter["label"] = f.Label
+	}
+	if f.ActiveOnly || f.FreeOnly {
+		filter["is_active"] = true
+	}
+	if f.FreeOnly {
+		filter["occupant"] = bson.M{"$exists": false}
+	}
+	if !f.OverstayBy.IsZero() {
+		filter["occupant.park_until"] = bson.M{"$lte": f.OverstayBy}
+	}
+	if f.UnalertedOnly {
+		filter["occupant"] = bson.M{"$exists": true}
+		filter["occupant.alerted_at"] = bson.M{"$exists": false}
+	}
+	return filter
+}
+
+func (f ParkingSlotFilter) matches(slot models.ParkingSlot) bool {
+	if f.SocietyCode != "" && slot.SocietyCode != f.SocietyCode {
+		return false
+	}
+	if !f.BuildingID.IsZero() && slot.BuildingID != f.BuildingID {
+		return false
+	}
+	if f.Kind != "" && slot.Kind != f.Kind {
+		return false
+	}
+	if f.VehicleType != "" && slot.VehicleType != f.VehicleType {
+		return false
+	}
+	if !f.UnitID.IsZero() && (slot.UnitID == nil || *slot.UnitID != f.UnitID) {
+		return false
+	}
+	if f.Label != "" && slot.Label != f.Label {
+		return false
+	}
+	if (f.ActiveOnly || f.FreeOnly) && !slot.IsActive {
+		return false
+	}
+	if f.FreeOnly && slot.Occupant != nil {
+		return false
+	}
+	if !f.OverstayBy.IsZero() && (slot.Occupant == nil || slot.Occupant.ParkUntil.After(f.OverstayBy)) {
+		return false
+	}
+	if f.UnalertedOnly && (slot.Occupant == nil || slot.Occupant.AlertedAt != nil) {
+		return false
+	}
+	return true
+}
+
+func (u ParkingSlotUpdate) toBSON() bson.M {
+	set := bson.M{"updated_at": u.UpdatedAt}
+	if u.Label != nil {
+		set["label"] = *u.Label
+	}
+	if u.VehicleType != nil {
+		set["vehicle_type"] = *u.VehicleType
+	}
+	if u.UnitID != nil {
+		set["unit_id"] = *u.UnitID
+	}
+	if u.UnitNumber != nil {
+		set["unit_number"] = *u.UnitNumber
+	}
+	if u.IsActive != nil {
+		set["is_active"] = *u.IsActive
+	}
+	if u.AlertedAt != nil {
+		set["occupant.alerted_at"] = *u.AlertedAt
+	}
+
+	update := bson.M{"$set": set}
+	if u.ClearUnit {
+		update["$unset"] = bson.M{"unit_id": "", "unit_number": ""}
+	}
+	return update
+}
+
+func (u ParkingSlotUpdate) apply(slot *models.ParkingSlot) {
+	slot.UpdatedAt = u.UpdatedAt
+	if u.Label != nil {
+		slot.Label = *u.Label
+	}
+	if u.VehicleType != nil {
+		slot.VehicleType = *u.VehicleType
+	}
+	if u.UnitID != nil {
+		unitID := *u.UnitID
+		slot.UnitID = &unitID
+	}
+	if u.UnitNumber != nil {
+		slot.UnitNumber = *u.UnitNumber
+	}
+	if u.ClearUnit {
+		slot.UnitID = nil
+		slot.UnitNumber = ""
+	}
+	if u.IsActive != nil {
+		slot.IsActive = *u.IsActive
+	}
+	if u.AlertedAt != nil && slot.Occupant != nil {
+		// Copy so the stored row never shares the occupant with a caller
+		occupant, alertedAt := *slot.Occupant, *u.AlertedAt
+		occupant.AlertedAt = &alertedAt
+		slot.Occupant = &occupant
+	}
+}
+
+type mongoParkingSlotRepository struct {
+	collection *mongo.Collection
+}
+
+// Create relies on the unique index on building and label
+func (r *mongoParkingSlotRepository) Create(ctx context.Context, slot *models.ParkingSlot) error {
+	if slot.ID.IsZero() {
+		slot.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, slot)
+	return translateError(err)
+}
+
+func (r *mongoParkingSlotRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.ParkingSlot, error) {
+	var slot models.ParkingSlot
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "society_code": societyCode}).Decode(&slot)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &slot, nil
+}
+
+func (r *mongoParkingSlotRepository) List(ctx context.Context, filter ParkingSlotFilter) ([]models.ParkingSlot, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "building_name", Value: 1}, {Key: "label", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter.toBSON(), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var slots []models.ParkingSlot
+	if err = cursor.All(ctx, &slots); err != nil {
+		return nil, err
+	}
+	return slots, nil
+}
+
+func (r *mongoParkingSlotRepository) Update(ctx context.Context, societyCode string, id primitive.ObjectID, update ParkingSlotUpdate) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "society_code": societyCode}, update.toBSON())
+	if err != nil {
+		return translateError(err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoParkingSlotRepository) Occupy(ctx context.Context, societyCode string, id primitive.ObjectID, occupant models.ParkingOccupant) error {
+	filter := bson.M{
+		"_id":          id,
+		"society_code": societyCode,
+		"kind":         "visitor",
+		"is_active":    true,
+		"occupant":     bson.M{"$exists": false},
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"occupant": occupant, "updated_at": occupant.ParkedAt}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		n, err := r.collection.CountDocuments(ctx, bson.M{"_id": id, "society_code": societyCode})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrNotFound
+		}
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r *mongoParkingSlotRepository) Vacate(ctx context.Context, societyCode string, id primitive.ObjectID, visitorID primitive.ObjectID, at time.Time) error {
+	filter := bson.M{"_id": id, "society_code": societyCode, "occupant.visitor_id": visitorID}
+	update := bson.M{"$unset": bson.M{"occupant": ""}, "$set": bson.M{"updated_at": at}}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryParkingSlotRepository struct {
+	table *memoryTable[models.ParkingSlot]
+}
+
+func newMemoryParkingSlotRepository() *memoryParkingSlotRepository {
+	return &memoryParkingSlotRepository{table: newMemoryTable[models.ParkingSlot]()}
+}
+
+func (r *memoryParkingSlotRepository) Create(ctx context.Context, slot *models.ParkingSlot) error {
+	if slot.ID.IsZero() {
+		slot.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(slot.ID, *slot, func(existing models.ParkingSlot) bool {
+		return existing.SocietyCode == slot.SocietyCode && existing.BuildingID == slot.BuildingID && existing.Label == slot.Label
+	})
+}
+
+func (r *memoryParkingSlotRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.ParkingSlot, error) {
+	slot, err := r.table.find(func(s models.ParkingSlot) bool {
+		return s.ID == id && s.SocietyCode == societyCode
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &slot, nil
+}
+
+func (r *memoryParkingSlotRepository) List(ctx context.Context, filter ParkingSlotFilter) ([]models.ParkingSlot, error) {
+	slots := r.table.filter(filter.matches)
+	sort.Slice(slots, func(i, j int) bool {
+		if slots[i].BuildingName != slots[j].BuildingName {
+			return slots[i].BuildingName < slots[j].BuildingName
+		}
+		return slots[i].Label < slots[j].Label
+	})
+	return slots, nil
+}
+
+func (r *memoryParkingSlotRepository) Update(ctx context.Context, societyCode string, id primitive.ObjectID, update ParkingSlotUpdate) error {
+	return r.table.update(id, func(s models.ParkingSlot) bool { return s.SocietyCode == societyCode }, update.apply)
+}
+
+func (r *memoryParkingSlotRepository) Occupy(ctx context.Context, societyCode string, id primitive.ObjectID, occupant models.ParkingOccupant) error {
+	conflict := false
+	err := r.table.update(id, func(s models.ParkingSlot) bool { return s.SocietyCode == societyCode }, func(s *models.ParkingSlot) {
+		if s.Kind != "visitor" || !s.IsActive || s.Occupant != nil {
+			conflict = true
+			return
+		}
+		s.Occupant = &occupant
+		s.UpdatedAt = occupant.ParkedAt
+	})
+	if err == nil && conflict {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *memoryParkingSlotRepository) Vacate(ctx context.Context, societyCode string, id primitive.ObjectID, visitorID primitive.ObjectID, at time.Time) error {
+	return r.table.update(id, func(s models.ParkingSlot) bool {
+		return s.SocietyCode == societyCode && s.Occupant != nil && s.Occupant.VisitorID == visitorID
+	}, func(s *models.ParkingSlot) {
+		s.Occupant = nil
+		s.UpdatedAt = at
+	})
+}
@@ -0,0 +1,256 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PaymentFilter narrows List. Zero-valued fields are ignored.
+type PaymentFilter struct {
+	SocietyCode string
+	UserID      primitive.ObjectID
+	ReferenceID primitive.ObjectID
+	Statuses    []string
+	// ExpiredBy matches payments whose intent expires at or before it
+	ExpiredBy time.Time
+}
+
+// PaymentUpdate carries the fields to change. Nil fields are left untouched.
+type PaymentUpdate struct {
+	Status        *string
+	FailureReason *string
+	ConfirmedAt   *time.Time
+	RefundAmount  *float64
+	RefundRef     *string
+	RefundedAt    *time.Time
+}
+
+type PaymentRepository interface {
+	Create(ctx context.Context, payment *models.Payment) error
+	GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Payment, error)
+	// GetByProviderRef finds a payment from a provider webhook, which carries
+	// no society context
+	GetByProviderRef(ctx context.Context, provider, ref string) (*models.Payment, error)
+	// List returns matching payments, newest first
+	List(ctx context.Context, filter PaymentFilter) ([]models.Payment, error)
+	// Transition applies the update only while the payment is still in the
+	// from status. It returns ErrConflict when the payment has moved on,
+	// which makes replayed webhooks harmless.
+	Transition(ctx context.Context, id primitive.ObjectID, from string, update PaymentUpdate) error
+}
+
+func (f PaymentFilter) toBSON() bson.M {
+	filter := bson.M{}
+	if f.SocietyCode != "" {
+		filter["society_code"] = f.SocietyCode
+	}
+	if !f.UserID.IsZero() {
+		filter["user_id"] = f.UserID
+	}
+	if !f.ReferenceID.IsZero() {
+		filter["reference_id"] = f.ReferenceID
+	}
+	if len(f.Statuses) == 1 {
+		filter["status"] = f.Statuses[0]
+	} else if len(f.Statuses) > 1 {
+		filter["status"] = bson.M{"$in": f.Statuses}
+	}
+	if !f.ExpiredBy.IsZero() {
+		filter["expires_at"] = bson.M{"$lte": f.ExpiredBy}
+	}
+	return filter
+}
+
+func (f PaymentFilter) matches(p models.Payment) bool {
+	if f.SocietyCode != "" && p.SocietyCode != f.SocietyCode {
+		return false
+	}
+	if !f.UserID.IsZero() && p.UserID != f.UserID {
+		return false
+	}
+	if !f.ReferenceID.IsZero() && p.ReferenceID != f.ReferenceID {
+		return false
+	}
+	if len(f.Statuses) > 0 && !containsString(f.Statuses, p.Status) {
+		return false
+	}
+	if !f.ExpiredBy.IsZero() && (p.ExpiresAt == nil || p.ExpiresAt.After(f.ExpiredBy)) {
+		return false
+	}
+	return true
+}
+
+func (u PaymentUpdate) toBSON() bson.M {
+	set := bson.M{"updated_at": time.Now()}
+	if u.Status != nil {
+		set["status"] = *u.Status
+	}
+	if u.FailureReason != nil {
+		set["failure_reason"] = *u.FailureReason
+	}
+	if u.ConfirmedAt != nil {
+		set["confirmed_at"] = *u.ConfirmedAt
+	}
+	if u.RefundAmount != nil {
+		set["refund_amount"] = *u.RefundAmount
+	}
+	if u.RefundRef != nil {
+		set["refund_ref"] = *u.RefundRef
+	}
+	if u.RefundedAt != nil {
+		set["refunded_at"] = *u.RefundedAt
+	}
+	return bson.M{"$set": set}
+}
+
+func (u PaymentUpdate) apply(p *models.Payment) {
+	if u.Status != nil {
+		p.Status = *u.Status
+	}
+	if u.FailureReason != nil {
+		p.FailureReason = *u.FailureReason
+	}
+	if u.ConfirmedAt != nil {
+		confirmedAt := *u.ConfirmedAt
+		p.ConfirmedAt = &confirmedAt
+	}
+	if u.RefundAmount != nil {
+		p.RefundAmount = *u.RefundAmount
+	}
+	if u.RefundRef != nil {
+		p.RefundRef = *u.RefundRef
+	}
+	if u.RefundedAt != nil {
+		refundedAt := *u.RefundedAt
+		p.RefundedAt = &refundedAt
+	}
+	p.UpdatedAt = time.Now()
+}
+
+type mongoPaymentRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoPaymentRepository) Create(ctx context.Context, payment *models.Payment) error {
+	if payment.ID.IsZero() {
+		payment.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, payment)
+	return translateError(err)
+}
+
+func (r *mongoPaymentRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Payment, error) {
+	var payment models.Payment
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "society_code": societyCode}).Decode(&payment)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &payment, nil
+}
+
+func (r *mongoPaymentRepository) GetByProviderRef(ctx context.Context, provider, ref string) (*models.Payment, error) {
+	var payment models.Payment
+	err := r.collection.FindOne(ctx, bson.M{"provider": provider, "provider_ref": ref}).Decode(&payment)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &payment, nil
+}
+
+func (r *mongoPaymentRepository) List(ctx context.Context, filter PaymentFilter) ([]models.Payment, error) {
+	cursor, err := r.collection.Find(ctx, filter.toBSON(), options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var payments []models.Payment
+	if err = cursor.All(ctx, &payments); err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+func (r *mongoPaymentRepository) Transition(ctx context.Context, id primitive.ObjectID, from string, update PaymentUpdate) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "status": from}, update.toBSON())
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		n, err := r.collection.CountDocuments(ctx, bson.M{"_id": id})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrNotFound
+		}
+		return ErrConflict
+	}
+	return nil
+}
+
+type memoryPaymentRepository struct {
+	table *memoryTable[models.Payment]
+}
+
+func newMemoryPaymentRepository() *memoryPaymentRepository {
+	return &memoryPaymentRepository{table: newMemoryTable[models.Payment]()}
+}
+
+func (r *memoryPaymentRepository) Create(ctx context.Context, payment *models.Payment) error {
+	if payment.ID.IsZero() {
+		payment.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(payment.ID, *payment, func(existing models.Payment) bool {
+		return payment.ProviderRef != "" && existing.Provider == payment.Provider && existing.ProviderRef == payment.ProviderRef
+	})
+}
+
+func (r *memoryPaymentRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Payment, error) {
+	payment, err := r.table.find(func(p models.Payment) bool {
+		return p.ID == id && p.SocietyCode == societyCode
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *memoryPaymentRepository) GetByProviderRef(ctx context.Context, provider, ref string) (*models.Payment, error) {
+	payment, err := r.table.find(func(p models.Payment) bool {
+		return p.Provider == provider && p.ProviderRef == ref
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *memoryPaymentRepository) List(ctx context.Context, filter PaymentFilter) ([]models.Payment, error) {
+	payments := r.table.filter(filter.matches)
+	sort.Slice(payments, func(i, j int) bool { return payments[i].CreatedAt.After(payments[j].CreatedAt) })
+	return payments, nil
+}
+
+func (r *memoryPaymentRepository) Transition(ctx context.Context, id primitive.ObjectID, from string, update PaymentUpdate) error {
+	conflict := false
+	err := r.table.update(id, nil, func(p *models.Payment) {
+		if p.Status != from {
+			conflict = true
+			return
+		}
+		update.apply(p)
+	})
+	if err == nil && conflict {
+		return ErrConflict
+	}
+	return err
+}
@@ -0,0 +1,180 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PenaltyFilter narrows List. Zero-valued fields are ignored.
+type PenaltyFilter struct {
+	SocietyCode   string
+	MaintenanceID primitive.ObjectID
+	UnitID        primitive.ObjectID
+	Statuses      []string
+}
+
+type PenaltyRepository interface {
+	// Create returns ErrDuplicate when the penalty's idempotency key was already used
+	Create(ctx context.Context, penalty *models.MaintenancePenalty) error
+	GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.MaintenancePenalty, error)
+	// List returns matching penalties, oldest accrual first
+	List(ctx context.Context, filter PenaltyFilter) ([]models.MaintenancePenalty, error)
+	// Waive marks an applied penalty as waived. It returns ErrConflict when
+	// the penalty was already waived.
+	Waive(ctx context.Context, societyCode string, id, waivedBy primitive.ObjectID, reason string, at time.Time) error
+}
+
+func (f PenaltyFilter) toBSON() bson.M {
+	filter := bson.M{}
+	if f.SocietyCode != "" {
+		filter["society_code"] = f.SocietyCode
+	}
+	if !f.MaintenanceID.IsZero() {
+		filter["maintenance_id"] = f.MaintenanceID
+	}
+	if !f.UnitID.IsZero() {
+		filter["unit_id"] = f.UnitID
+	}
+	if len(f.Statuses) == 1 {
+		filter["status"] = f.Statuses[0]
+	} else if len(f.Statuses) > 1 {
+		filter["status"] = bson.M{"$in": f.Statuses}
+	}
+	return filter
+}
+
+func (f PenaltyFilter) matches(p models.MaintenancePenalty) bool {
+	if f.SocietyCode != "" && p.SocietyCode != f.SocietyCode {
+		return false
+	}
+	if !f.MaintenanceID.IsZero() && p.MaintenanceID != f.MaintenanceID {
+		return false
+	}
+	if !f.UnitID.IsZero() && p.UnitID != f.UnitID {
+		return false
+	}
+	if len(f.Statuses) > 0 && !containsString(f.Statuses, p.Status) {
+		return false
+	}
+	return true
+}
+
+type mongoPenaltyRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoPenaltyRepository) Create(ctx context.Context, penalty *models.MaintenancePenalty) error {
+	if penalty.ID.IsZero() {
+		penalty.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, penalty)
+	return translateError(err)
+}
+
+func (r *mongoPenaltyRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.MaintenancePenalty, error) {
+	var penalty models.MaintenancePenalty
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "society_code": societyCode}).Decode(&penalty)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &penalty, nil
+}
+
+func (r *mongoPenaltyRepository) List(ctx context.Context, filter PenaltyFilter) ([]models.MaintenancePenalty, error) {
+	cursor, err := r.collection.Find(ctx, filter.toBSON(), options.Find().SetSort(bson.M{"accrued_on": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var penalties []models.MaintenancePenalty
+	if err = cursor.All(ctx, &penalties); err != nil {
+		return nil, err
+	}
+	return penalties, nil
+}
+
+func (r *mongoPenaltyRepository) Waive(ctx context.Context, societyCode string, id, waivedBy primitive.ObjectID, reason string, at time.Time) error {
+	filter := bson.M{"_id": id, "society_code": societyCode, "status": "applied"}
+	update := bson.M{"$set": bson.M{
+		"status":       "waived",
+		"waived_by":    waivedBy,
+		"waived_at":    at,
+		"waive_reason": reason,
+	}}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		n, err := r.collection.CountDocuments(ctx, bson.M{"_id": id, "society_code": societyCode})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrNotFound
+		}
+		return ErrConflict
+	}
+	return nil
+}
+
+type memoryPenaltyRepository struct {
+	table *memoryTable[models.MaintenancePenalty]
+}
+
+func newMemoryPenaltyRepository() *memoryPenaltyRepository {
+	return &memoryPenaltyRepository{table: newMemoryTable[models.MaintenancePenalty]()}
+}
+
+func (r *memoryPenaltyRepository) Create(ctx context.Context, penalty *models.MaintenancePenalty) error {
+	if penalty.ID.IsZero() {
+		penalty.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(penalty.ID, *penalty, func(existing models.MaintenancePenalty) bool {
+		return existing.IdempotencyKey == penalty.IdempotencyKey
+	})
+}
+
+func (r *memoryPenaltyRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.MaintenancePenalty, error) {
+	penalty, err := r.table.find(func(p models.MaintenancePenalty) bool {
+		return p.ID == id && p.SocietyCode == societyCode
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &penalty, nil
+}
+
+func (r *memoryPenaltyRepository) List(ctx context.Context, filter PenaltyFilter) ([]models.MaintenancePenalty, error) {
+	penalties := r.table.filter(filter.matches)
+	sort.Slice(penalties, func(i, j int) bool { return penalties[i].AccruedOn.Before(penalties[j].AccruedOn) })
+	return penalties, nil
+}
+
+func (r *memoryPenaltyRepository) Waive(ctx context.Context, societyCode string, id, waivedBy primitive.ObjectID, reason string, at time.Time) error {
+	conflict := false
+	err := r.table.update(id, func(p models.MaintenancePenalty) bool { return p.SocietyCode == societyCode }, func(p *models.MaintenancePenalty) {
+		if p.Status != "applied" {
+			conflict = true
+			return
+		}
+		by, waivedAt := waivedBy, at
+		p.Status = "waived"
+		p.WaivedBy = &by
+		p.WaivedAt = &waivedAt
+		p.WaiveReason = reason
+	})
+	if err == nil && conflict {
+		return ErrConflict
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ReservationRepository interface {
+	// Create returns ErrDuplicate when the seat of the slot is already held
+	Create(ctx context.Context, reservation *models.AmenityReservation) error
+	// TakenSeats returns the seats held in one slot of an amenity
+	TakenSeats(ctx context.Context, amenityID primitive.ObjectID, slotStart time.Time) ([]int, error)
+	// DeleteByBooking frees every seat held by a booking
+	DeleteByBooking(ctx context.Context, bookingID primitive.ObjectID) error
+}
+
+type mongoReservationRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoReservationRepository) Create(ctx context.Context, reservation *models.AmenityReservation) error {
+	if reservation.ID.IsZero() {
+		reservation.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, reservation)
+	return translateError(err)
+}
+
+func (r *mongoReservationRepository) TakenSeats(ctx context.Context, amenityID primitive.ObjectID, slotStart time.Time) ([]int, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"amenity_id": amenityID, "slot_start": slotStart})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var reservations []models.AmenityReservation
+	if err = cursor.All(ctx, &reservations); err != nil {
+		return nil, err
+	}
+	seats := make([]int, 0, len(reservations))
+	for _, reservation := range reservations {
+		seats = append(seats, reservation.Seat)
+	}
+	return seats, nil
+}
+
+func (r *mongoReservationRepository) DeleteByBooking(ctx context.Context, bookingID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"booking_id": bookingID})
+	return err
+}
+
+type memoryReservationRepository struct {
+	table *memoryTable[models.AmenityReservation]
+}
+
+func newMemoryReservationRepository() *memoryReservationRepository {
+	return &memoryReservationRepository{table: newMemoryTable[models.AmenityReservation]()}
+}
+
+func (r *memoryReservationRepository) Create(ctx context.Context, reservation *models.AmenityReservation) error {
+	if reservation.ID.IsZero() {
+		reservation.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(reservation.ID, *reservation, func(existing models.AmenityReservation) bool {
+		return existing.AmenityID == reservation.AmenityID &&
+			existing.SlotStart.Equal(reservation.SlotStart) &&
+			existing.Seat == reservation.Seat
+	})
+}
+
+func (r *memoryReservationRepository) TakenSeats(ctx context.Context, amenityID primitive.ObjectID, slotStart time.Time) ([]int, error) {
+	seats := []int{}
+	for _, reservation := range r.table.filter(func(res models.AmenityReservation) bool {
+		return res.AmenityID == amenityID && res.SlotStart.Equal(slotStart)
+	}) {
+		seats = append(seats, reservation.Seat)
+	}
+	return seats, nil
+}
+
+func (r *memoryReservationRepository) DeleteByBooking(ctx context.Context, bookingID primitive.ObjectID) error {
+	r.table.deleteAll(func(res models.AmenityReservation) bool { return res.BookingID == bookingID })
+	return nil
+}
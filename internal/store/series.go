@@ -0,0 +1,184 @@
+package store
+
+import (
+	"context"
+	"sort"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SeriesFilter narrows List. Zero-valued fields are ignored.
+type SeriesFilter struct {
+	SocietyCode string
+	AmenityID   primitive.ObjectID
+	UserID      primitive.ObjectID
+	Status      string
+}
+
+// SeriesUpdate carries the fields to change. Nil fields are left untouched.
+type SeriesUpdate struct {
+	TimeSlot  *string
+	Booked    *int
+	Conflicts *int
+	Status    *string
+}
+
+type BookingSeriesRepository interface {
+	Create(ctx context.Context, series *models.BookingSeries) error
+	GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.BookingSeries, error)
+	// List returns matching series, newest first
+	List(ctx context.Context, filter SeriesFilter) ([]models.BookingSeries, error)
+	Update(ctx context.Context, societyCode string, id primitive.ObjectID, update SeriesUpdate) error
+}
+
+func (f SeriesFilter) toBSON() bson.M {
+	filter := bson.M{}
+	if f.SocietyCode != "" {
+		filter["society_code"] = f.SocietyCode
+	}
+	if !f.AmenityID.IsZero() {
+		filter["amenity_id"] = f.AmenityID
+	}
+	if !f.UserID.IsZero() {
+		filter["user_id"] = f.UserID
+	}
+	if f.Status != "" {
+		filter["status"] = f.Status
+	}
+	return filter
+}
+
+func (f SeriesFilter) matches(s models.BookingSeries) bool {
+	if f.SocietyCode != "" && s.SocietyCode != f.SocietyCode {
+		return false
+	}
+	if !f.AmenityID.IsZero() && s.AmenityID != f.AmenityID {
+		return false
+	}
+	if !f.UserID.IsZero() && s.UserID != f.UserID {
+		return false
+	}
+	if f.Status != "" && s.Status != f.Status {
+		return false
+	}
+	return true
+}
+
+func (u SeriesUpdate) toBSON() bson.M {
+	set := bson.M{}
+	if u.TimeSlot != nil {
+		set["time_slot"] = *u.TimeSlot
+	}
+	if u.Booked != nil {
+		set["booked"] = *u.Booked
+	}
+	if u.Conflicts != nil {
+		set["conflicts"] = *u.Conflicts
+	}
+	if u.Status != nil {
+		set["status"] = *u.Status
+	}
+	return bson.M{"$set": set}
+}
+
+func (u SeriesUpdate) apply(s *models.BookingSeries) {
+	if u.TimeSlot != nil {
+		s.TimeSlot = *u.TimeSlot
+	}
+	if u.Booked != nil {
+		s.Booked = *u.Booked
+	}
+	if u.Conflicts != nil {
+		s.Conflicts = *u.Conflicts
+	}
+	if u.Status != nil {
+		s.Status = *u.Status
+	}
+}
+
+type mongoBookingSeriesRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoBookingSeriesRepository) Create(ctx context.Context, series *models.BookingSeries) error {
+	if series.ID.IsZero() {
+		series.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, series)
+	return translateError(err)
+}
+
+func (r *mongoBookingSeriesRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.BookingSeries, error) {
+	var series models.BookingSeries
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "society_code": societyCode}).Decode(&series)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &series, nil
+}
+
+func (r *mongoBookingSeriesRepository) List(ctx context.Context, filter SeriesFilter) ([]models.BookingSeries, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, filter.toBSON(), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var series []models.BookingSeries
+	if err = cursor.All(ctx, &series); err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+func (r *mongoBookingSeriesRepository) Update(ctx context.Context, societyCode string, id primitive.ObjectID, update SeriesUpdate) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "society_code": societyCode}, update.toBSON())
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryBookingSeriesRepository struct {
+	table *memoryTable[models.BookingSeries]
+}
+
+func newMemoryBookingSeriesRepository() *memoryBookingSeriesRepository {
+	return &memoryBookingSeriesRepository{table: newMemoryTable[models.BookingSeries]()}
+}
+
+func (r *memoryBookingSeriesRepository) Create(ctx context.Context, series *models.BookingSeries) error {
+	if series.ID.IsZero() {
+		series.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(series.ID, *series, nil)
+}
+
+func (r *memoryBookingSeriesRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.BookingSeries, error) {
+	series, err := r.table.find(func(s models.BookingSeries) bool {
+		return s.ID == id && s.SocietyCode == societyCode
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+func (r *memoryBookingSeriesRepository) List(ctx context.Context, filter SeriesFilter) ([]models.BookingSeries, error) {
+	series := r.table.filter(filter.matches)
+	sort.Slice(series, func(i, j int) bool { return series[i].CreatedAt.After(series[j].CreatedAt) })
+	return series, nil
+}
+
+func (r *memoryBookingSeriesRepository) Update(ctx context.Context, societyCode string, id primitive.ObjectID, update SeriesUpdate) error {
+	return r.table.update(id, func(s models.BookingSeries) bool { return s.SocietyCode == societyCode }, update.apply)
+}
@@ -0,0 +1,300 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SocietyFilter narrows List. Zero-valued fields are ignored.
+type SocietyFilter struct {
+	ActiveOnly bool
+}
+
+// SocietyUpdate carries the fields to change. Nil fields are left untouched.
+// The access code is deliberately absent: every other collection refers to it.
+type SocietyUpdate struct {
+	Name         *string
+	Address      *string
+	City         *string
+	State        *string
+	PinCode      *string
+	ContactEmail *string
+	ContactPhone *string
+	IsActive     *bool
+}
+
+type SocietyRepository interface {
+	// Create returns ErrDuplicate when the access code is already taken
+	Create(ctx context.Context, society *models.Society) error
+	GetByID(ctx context.Context, id primitive.ObjectID) (*models.Society, error)
+	// GetByCode returns the society with the given access code, active or not
+	GetByCode(ctx context.Context, code string) (*models.Society, error)
+	// GetActiveByCode only returns societies that are currently active
+	GetActiveByCode(ctx context.Context, code string) (*models.Society, error)
+	// List returns matching societies sorted by name
+	List(ctx context.Context, filter SocietyFilter) ([]models.Society, error)
+	Update(ctx context.Context, id primitive.ObjectID, update SocietyUpdate) error
+	// AddBuilding returns ErrDuplicate when another building of the society
+	// already has the building's code
+	AddBuilding(ctx context.Context, id primitive.ObjectID, building models.Building) error
+	RemoveBuilding(ctx context.Context, id, buildingID primitive.ObjectID) error
+}
+
+func (f SocietyFilter) toBSON() bson.M {
+	filter := bson.M{}
+	if f.ActiveOnly {
+		filter["is_active"] = true
+	}
+	return filter
+}
+
+func (f SocietyFilter) matches(s models.Society) bool {
+	return !f.ActiveOnly || s.IsActive
+}
+
+func (u SocietyUpdate) toBSON(now time.Time) bson.M {
+	set := bson.M{"updated_at": now}
+	if u.Name != nil {
+		set["name"] = *u.Name
+	}
+	if u.Address != nil {
+		set["address"] = *u.Address
+	}
+	if u.City != nil {
+		set["city"] = *u.City
+	}
+	if u.State != nil {
+		set["state"] = *u.State
+	}
+	if u.PinCode != nil {
+		set["pin_code"] = *u.PinCode
+	}
+	if u.ContactEmail != nil {
+		set["contact_email"] = *u.ContactEmail
+	}
+	if u.ContactPhone != nil {
+		set["contact_phone"] = *u.ContactPhone
+	}
+	if u.IsActive != nil {
+		set["is_active"] = *u.IsActive
+	}
+	return bson.M{"$set": set}
+}
+
+func (u SocietyUpdate) apply(s *models.Society, now time.Time) {
+	s.UpdatedAt = now
+	if u.Name != nil {
+		s.Name = *u.Name
+	}
+	if u.Address != nil {
+		s.Address = *u.Address
+	}
+	if u.City != nil {
+		s.City = *u.City
+	}
+	if u.State != nil {
+		s.State = *u.State
+	}
+	if u.PinCode != nil {
+		s.PinCode = *u.PinCode
+	}
+	if u.ContactEmail != nil {
+		s.ContactEmail = *u.ContactEmail
+	}
+	if u.ContactPhone != nil {
+		s.ContactPhone = *u.ContactPhone
+	}
+	if u.IsActive != nil {
+		s.IsActive = *u.IsActive
+	}
+}
+
+type mongoSocietyRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoSocietyRepository) Create(ctx context.Context, society *models.Society) error {
+	if society.ID.IsZero() {
+		society.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, society)
+	return translateError(err)
+}
+
+func (r *mongoSocietyRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Society, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *mongoSocietyRepository) GetByCode(ctx context.Context, code string) (*models.Society, error) {
+	return r.findOne(ctx, bson.M{"code": code})
+}
+
+func (r *mongoSocietyRepository) GetActiveByCode(ctx context.Context, code string) (*models.Society, error) {
+	return r.findOne(ctx, bson.M{"code": code, "is_active": true})
+}
+
+func (r *mongoSocietyRepository) findOne(ctx context.Context, filter bson.M) (*models.Society, error) {
+	var society models.Society
+	if err := r.collection.FindOne(ctx, filter).Decode(&society); err != nil {
+		return nil, translateError(err)
+	}
+	return &society, nil
+}
+
+func (r *mongoSocietyRepository) List(ctx context.Context, filter SocietyFilter) ([]models.Society, error) {
+	cursor, err := r.collection.Find(ctx, filter.toBSON(), options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var societies []models.Society
+	if err = cursor.All(ctx, &societies); err != nil {
+		return nil, err
+	}
+	return societies, nil
+}
+
+func (r *mongoSocietyRepository) Update(ctx context.Context, id primitive.ObjectID, update SocietyUpdate) error {
+	return r.updateOne(ctx, bson.M{"_id": id}, update.toBSON(time.Now()))
+}
+
+func (r *mongoSocietyRepository) AddBuilding(ctx context.Context, id primitive.ObjectID, building models.Building) error {
+	err := r.updateOne(ctx, bson.M{"_id": id, "buildings.code": bson.M{"$ne": building.Code}}, bson.M{
+		"$push": bson.M{"buildings": building},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	if errors.Is(err, ErrNotFound) {
+		n, err := r.collection.CountDocuments(ctx, bson.M{"_id": id})
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrDuplicate
+		}
+	}
+	return err
+}
+
+func (r *mongoSocietyRepository) RemoveBuilding(ctx context.Context, id, buildingID primitive.ObjectID) error {
+	// Matching on the building id as well makes an unknown building a not-found
+	return r.updateOne(ctx, bson.M{"_id": id, "buildings._id": buildingID}, bson.M{
+		"$pull": bson.M{"buildings": bson.M{"_id": buildingID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+}
+
+func (r *mongoSocietyRepository) updateOne(ctx context.Context, filter, update bson.M) error {
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memorySocietyRepository struct {
+	table *memoryTable[models.Society]
+}
+
+func newMemorySocietyRepository() *memorySocietyRepository {
+	return &memorySocietyRepository{table: newMemoryTable[models.Society]()}
+}
+
+func (r *memorySocietyRepository) Create(ctx context.Context, society *models.Society) error {
+	if society.ID.IsZero() {
+		society.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(society.ID, *society, func(existing models.Society) bool {
+		return existing.Code == society.Code
+	})
+}
+
+func (r *memorySocietyRepository) GetByCode(ctx context.Context, code string) (*models.Society, error) {
+	society, err := r.table.find(func(s models.Society) bool { return s.Code == code })
+	if err != nil {
+		return nil, err
+	}
+	return &society, nil
+}
+
+func (r *memorySocietyRepository) GetActiveByCode(ctx context.Context, code string) (*models.Society, error) {
+	society, err := r.table.find(func(s models.Society) bool { return s.Code == code && s.IsActive })
+	if err != nil {
+		return nil, err
+	}
+	return &society, nil
+}
+
+func (r *memorySocietyRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Society, error) {
+	society, err := r.table.find(func(s models.Society) bool { return s.ID == id })
+	if err != nil {
+		return nil, err
+	}
+	return &society, nil
+}
+
+func (r *memorySocietyRepository) List(ctx context.Context, filter SocietyFilter) ([]models.Society, error) {
+	societies := r.table.filter(filter.matches)
+	sort.Slice(societies, func(i, j int) bool { return societies[i].Name < societies[j].Name })
+	return societies, nil
+}
+
+func (r *memorySocietyRepository) Update(ctx context.Context, id primitive.ObjectID, update SocietyUpdate) error {
+	now := time.Now()
+	return r.table.update(id, nil, func(s *models.Society) {
+		update.apply(s, now)
+	})
+}
+
+func (r *memorySocietyRepository) AddBuilding(ctx context.Context, id primitive.ObjectID, building models.Building) error {
+	duplicate := false
+	err := r.table.update(id, nil, func(s *models.Society) {
+		for _, b := range s.Buildings {
+			if b.Code == building.Code {
+				duplicate = true
+				return
+			}
+		}
+		// Copy before appending so earlier readers keep their own slice
+		buildings := make([]models.Building, 0, len(s.Buildings)+1)
+		s.Buildings = append(append(buildings, s.Buildings...), building)
+		s.UpdatedAt = time.Now()
+	})
+	if err == nil && duplicate {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (r *memorySocietyRepository) RemoveBuilding(ctx context.Context, id, buildingID primitive.ObjectID) error {
+	hasBuilding := func(s models.Society) bool {
+		for _, b := range s.Buildings {
+			if b.ID == buildingID {
+				return true
+			}
+		}
+		return false
+	}
+	return r.table.update(id, hasBuilding, func(s *models.Society) {
+		buildings := make([]models.Building, 0, len(s.Buildings))
+		for _, b := range s.Buildings {
+			if b.ID != buildingID {
+				buildings = append(buildings, b)
+			}
+		}
+		s.Buildings = buildings
+		s.UpdatedAt = time.Now()
+	})
+}
@@ -0,0 +1,254 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// StaffFilter narrows List. Zero-valued fields are ignored.
+type StaffFilter struct {
+	SocietyCode string
+	UnitID      primitive.ObjectID // Staff serving this unit
+	Category    string
+	Phone       string
+	ActiveOnly  bool
+}
+
+// StaffUpdate carries the fields to change. Nil fields are left untouched.
+// UnitIDs and Units replace the whole list and are set together.
+type StaffUpdate struct {
+	Name          *string
+	Phone         *string
+	UnitIDs       *[]primitive.ObjectID
+	Units         *[]string
+	Hours         *[]models.OpeningHours
+	Timezone      *string
+	PhotoURL      *string
+	IDDocument    *models.IDDocument
+	PassToken     *string
+	PassExpiresAt *time.Time
+	IsActive      *bool
+	UpdatedAt     time.Time
+}
+
+type StaffRepository interface {
+	Create(ctx context.Context, staff *models.Staff) error
+	GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Staff, error)
+	// List returns matching staff sorted by name
+	List(ctx context.Context, filter StaffFilter) ([]models.Staff, error)
+	Update(ctx context.Context, societyCode string, id primitive.ObjectID, update StaffUpdate) error
+}
+
+func (f StaffFilter) toBSON() bson.M {
+	filter := bson.M{}
+	if f.SocietyCode != "" {
+		filter["society_code"] = f.SocietyCode
+	}
+	if !f.UnitID.IsZero() {
+		filter["unit_ids"] = f.UnitID
+	}
+	if f.Category != "" {
+		filter["category"] = f.Category
+	}
+	if f.Phone != "" {
+		filter["phone"] = f.Phone
+	}
+	if f.ActiveOnly {
+		filter["is_active"] = true
+	}
+	return filter
+}
+
+func (f StaffFilter) matches(staff models.Staff) bool {
+	if f.SocietyCode != "" && staff.SocietyCode != f.SocietyCode {
+		return false
+	}
+	if !f.UnitID.IsZero() && !containsObjectID(staff.UnitIDs, f.UnitID) {
+		return false
+	}
+	if f.Category != "" && staff.Category != f.Category {
+		return false
+	}
+	if f.Phone != "" && staff.Phone != f.Phone {
+		return false
+	}
+	if f.ActiveOnly && !staff.IsActive {
+		return false
+	}
+	return true
+}
+
+func (u StaffUpdate) toBSON() bson.M {
+	set := bson.M{"updated_at": u.UpdatedAt}
+	if u.Name != nil {
+		set["name"] = *u.Name
+	}
+	if u.Phone != nil {
+		set["phone"] = *u.Phone
+	}
+	if u.UnitIDs != nil {
+		set["unit_ids"] = *u.UnitIDs
+	}
+	if u.Units != nil {
+		set["units"] = *u.Units
+	}
+	if u.Hours != nil {
+		set["hours"] = *u.Hours
+	}
+	if u.Timezone != nil {
+		set["timezone"] = *u.Timezone
+	}
+	if u.PhotoURL != nil {
+		set["photo_url"] = *u.PhotoURL
+	}
+	if u.IDDocument != nil {
+		set["id_document"] = *u.IDDocument
+	}
+	if u.PassToken != nil {
+		set["pass_token"] = *u.PassToken
+	}
+	if u.PassExpiresAt != nil {
+		set["pass_expires_at"] = *u.PassExpiresAt
+	}
+	if u.IsActive != nil {
+		set["is_active"] = *u.IsActive
+	}
+	return bson.M{"$set": set}
+}
+
+func (u StaffUpdate) apply(staff *models.Staff) {
+	staff.UpdatedAt = u.UpdatedAt
+	if u.Name != nil {
+		staff.Name = *u.Name
+	}
+	if u.Phone != nil {
+		staff.Phone = *u.Phone
+	}
+	if u.UnitIDs != nil {
+		staff.UnitIDs = append([]primitive.ObjectID(nil), *u.UnitIDs...)
+	}
+	if u.Units != nil {
+		staff.Units = append([]string(nil), *u.Units...)
+	}
+	if u.Hours != nil {
+		staff.Hours = append([]models.OpeningHours(nil), *u.Hours...)
+	}
+	if u.Timezone != nil {
+		staff.Timezone = *u.Timezone
+	}
+	if u.PhotoURL != nil {
+		staff.PhotoURL = *u.PhotoURL
+	}
+	if u.IDDocument != nil {
+		document := *u.IDDocument
+		staff.IDDocument = &document
+	}
+	if u.PassToken != nil {
+		staff.PassToken = *u.PassToken
+	}
+	if u.PassExpiresAt != nil {
+		staff.PassExpiresAt = *u.PassExpiresAt
+	}
+	if u.IsActive != nil {
+		staff.IsActive = *u.IsActive
+	}
+}
+
+func containsObjectID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+type mongoStaffRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoStaffRepository) Create(ctx context.Context, staff *models.Staff) error {
+	if staff.ID.IsZero() {
+		staff.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, staff)
+	return translateError(err)
+}
+
+func (r *mongoStaffRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Staff, error) {
+	var staff models.Staff
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "society_code": societyCode}).Decode(&staff)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &staff, nil
+}
+
+func (r *mongoStaffRepository) List(ctx context.Context, filter StaffFilter) ([]models.Staff, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter.toBSON(), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var staff []models.Staff
+	if err = cursor.All(ctx, &staff); err != nil {
+		return nil, err
+	}
+	return staff, nil
+}
+
+func (r *mongoStaffRepository) Update(ctx context.Context, societyCode string, id primitive.ObjectID, update StaffUpdate) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "society_code": societyCode}, update.toBSON())
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryStaffRepository struct {
+	table *memoryTable[models.Staff]
+}
+
+func newMemoryStaffRepository() *memoryStaffRepository {
+	return &memoryStaffRepository{table: newMemoryTable[models.Staff]()}
+}
+
+func (r *memoryStaffRepository) Create(ctx context.Context, staff *models.Staff) error {
+	if staff.ID.IsZero() {
+		staff.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(staff.ID, *staff, nil)
+}
+
+func (r *memoryStaffRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Staff, error) {
+	staff, err := r.table.find(func(s models.Staff) bool {
+		return s.ID == id && s.SocietyCode == societyCode
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &staff, nil
+}
+
+func (r *memoryStaffRepository) List(ctx context.Context, filter StaffFilter) ([]models.Staff, error) {
+	staff := r.table.filter(filter.matches)
+	sort.Slice(staff, func(i, j int) bool { return staff[i].Name < staff[j].Name })
+	return staff, nil
+}
+
+func (r *memoryStaffRepository) Update(ctx context.Context, societyCode string, id primitive.ObjectID, update StaffUpdate) error {
+	return r.table.update(id, func(s models.Staff) bool { return s.SocietyCode == societyCode }, update.apply)
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrNotFound is returned when no document matches the lookup or update
+	ErrNotFound = errors.New("store: not found")
+	// ErrDuplicate is returned when an insert violates a unique constraint
+	ErrDuplicate = errors.New("store: duplicate key")
+	// ErrConflict is returned when the document exists but is not in a state
+	// that allows the requested change
+	ErrConflict = errors.New("store: conflicting state")
+)
+
+// Store groups one repository per model so handlers never touch the
+// underlying database directly.
+type Store struct {
+	Societies     SocietyRepository
+	Users         UserRepository
+	Units         UnitRepository
+	Visitors      VisitorRepository
+	Maintenance   MaintenanceRepository
+	BillingPlans  BillingPlanRepository
+	LateFeeRules  LateFeeRuleRepository
+	Penalties     PenaltyRepository
+	Payments      PaymentRepository
+	Ledger        LedgerRepository
+	Documents     DocumentRepository
+	Counters      CounterRepository
+	Amenities     AmenityRepository
+	Bookings      AmenityBookingRepository
+	Series        BookingSeriesRepository
+	Reservations  ReservationRepository
+	Waitlist      WaitlistRepository
+	Notices       NoticeRepository
+	Notifications NotificationRepository
+	CalendarFeeds CalendarFeedRepository
+	Staff         StaffRepository
+	Attendance    AttendanceRepository
+	Parcels       ParcelRepository
+	Blacklist     BlacklistRepository
+	Vehicles      VehicleRepository
+	ParkingSlots  ParkingSlotRepository
+	VehicleLogs   VehicleLogRepository
+	Uploads       UploadRepository
+}
+
+// NewMongoStore returns a Store backed by MongoDB collections
+func NewMongoStore(db *mongo.Database) *Store {
+	return &Store{
+		Societies:     &mongoSocietyRepository{collection: db.Collection("societies")},
+		Users:         &mongoUserRepository{collection: db.Collection("users")},
+		Units:         &mongoUnitRepository{collection: db.Collection("units")},
+		Visitors:      &mongoVisitorRepository{collection: db.Collection("visitors")},
+		Maintenance:   &mongoMaintenanceRepository{collection: db.Collection("maintenance")},
+		BillingPlans:  &mongoBillingPlanRepository{collection: db.Collection("billing_plans")},
+		LateFeeRules:  &mongoLateFeeRuleRepository{collection: db.Collection("late_fee_rules")},
+		Penalties:     &mongoPenaltyRepository{collection: db.Collection("maintenance_penalties")},
+		Payments:      &mongoPaymentRepository{collection: db.Collection("payments")},
+		Ledger:        &mongoLedgerRepository{collection: db.Collection("ledger_entries")},
+		Documents:     &mongoDocumentRepository{collection: db.Collection("billing_documents")},
+		Counters:      &mongoCounterRepository{collection: db.Collection("counters")},
+		Amenities:     &mongoAmenityRepository{collection: db.Collection("amenities")},
+		Bookings:      &mongoAmenityBookingRepository{collection: db.Collection("amenity_bookings")},
+		Series:        &mongoBookingSeriesRepository{collection: db.Collection("amenity_booking_series")},
+		Reservations:  &mongoReservationRepository{collection: db.Collection("amenity_reservations")},
+		Waitlist:      &mongoWaitlistRepository{collection: db.Collection("amenity_waitlist")},
+		Notices:       &mongoNoticeRepository{collection: db.Collection("notices")},
+		Notifications: &mongoNotificationRepository{collection: db.Collection("notifications")},
+		CalendarFeeds: &mongoCalendarFeedRepository{collection: db.Collection("calendar_feeds")},
+		Staff:         &mongoStaffRepository{collection: db.Collection("staff")},
+		Attendance:    &mongoAttendanceRepository{collection: db.Collection("staff_attendance")},
+		Parcels:       &mongoParcelRepository{collection: db.Collection("parcels")},
+		Blacklist:     &mongoBlacklistRepository{collection: db.Collection("blacklist")},
+		Vehicles:      &mongoVehicleRepository{collection: db.Collection("vehicles")},
+		ParkingSlots:  &mongoParkingSlotRepository{collection: db.Collection("parking_slots")},
+		VehicleLogs:   &mongoVehicleLogRepository{collection: db.Collection("vehicle_logs")},
+		Uploads:       &mongoUploadRepository{collection: db.Collection("uploads")},
+	}
+}
+
+// NewMemoryStore returns a Store that keeps everything in process memory.
+// It is meant for tests and local experiments, not production use.
+func NewMemoryStore() *Store {
+	return &Store{
+		Societies:     newMemorySocietyRepository(),
+		Users:         newMemoryUserRepository(),
+		Units:         newMemoryUnitRepository(),
+		Visitors:      newMemoryVisitorRepository(),
+		Maintenance:   newMemoryMaintenanceRepository(),
+		BillingPlans:  newMemoryBillingPlanRepository(),
+		LateFeeRules:  newMemoryLateFeeRuleRepository(),
+		Penalties:     newMemoryPenaltyRepository(),
+		Payments:      newMemoryPaymentRepository(),
+		Ledger:        newMemoryLedgerRepository(),
+		Documents:     newMemoryDocumentRepository(),
+		Counters:      newMemoryCounterRepository(),
+		Amenities:     newMemoryAmenityRepository(),
+		Bookings:      newMemoryAmenityBookingRepository(),
+		Series:        newMemoryBookingSeriesRepository(),
+		Reservations:  newMemoryReservationRepository(),
+		Waitlist:      newMemoryWaitlistRepository(),
+		Notices:       newMemoryNoticeRepository(),
+		Notifications: newMemoryNotificationRepository(),
+		CalendarFeeds: newMemoryCalendarFeedRepository(),
+		Staff:         newMemoryStaffRepository(),
+		Attendance:    newMemoryAttendanceRepository(),
+		Parcels:       newMemoryParcelRepository(),
+		Blacklist:     newMemoryBlacklistRepository(),
+		Vehicles:      newMemoryVehicleRepository(),
+		ParkingSlots:  newMemoryParkingSlotRepository(),
+		VehicleLogs:   newMemoryVehicleLogRepository(),
+		Uploads:       newMemoryUploadRepository(),
+	}
+}
+
+// translateError maps driver errors onto the store sentinel errors
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
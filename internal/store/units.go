@@ -0,0 +1,331 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UnitFilter narrows List and Count. Zero-valued fields are ignored.
+type UnitFilter struct {
+	SocietyCode string
+	BuildingID  primitive.ObjectID
+	// OccupantID matches units where the user is the current owner or tenant
+	OccupantID primitive.ObjectID
+}
+
+type UnitRepository interface {
+	// Create returns ErrDuplicate when the unit number is already used in the society
+	Create(ctx context.Context, unit *models.Unit) error
+	GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Unit, error)
+	GetByNumber(ctx context.Context, societyCode, number string) (*models.Unit, error)
+	// List returns matching units sorted by number
+	List(ctx context.Context, filter UnitFilter) ([]models.Unit, error)
+	Count(ctx context.Context, filter UnitFilter) (int64, error)
+	// SetOccupant links a user as owner or tenant and opens a history entry.
+	// It returns ErrConflict when that role is already occupied.
+	SetOccupant(ctx context.Context, societyCode string, id primitive.ObjectID, occupancy models.UnitOccupancy) error
+	// ClearOccupant unlinks the current owner or tenant and closes their
+	// history entry. It returns ErrConflict when nobody holds that role.
+	ClearOccupant(ctx context.Context, societyCode string, id primitive.ObjectID, role string, movedOut time.Time) error
+	// BumpCreditVersion moves the unit's credit version on from from. It
+	// returns ErrConflict when the version has already moved on.
+	BumpCreditVersion(ctx context.Context, societyCode string, id primitive.ObjectID, from int64) error
+}
+
+// occupantField maps an occupancy role onto the unit field holding the link
+func occupantField(role string) string {
+	if role == "owner" {
+		return "owner_id"
+	}
+	return "tenant_id"
+}
+
+func (f UnitFilter) toBSON() bson.M {
+	filter := bson.M{}
+	if f.SocietyCode != "" {
+		filter["society_code"] = f.SocietyCode
+	}
+	if !f.BuildingID.IsZero() {
+		filter["building_id"] = f.BuildingID
+	}
+	if !f.OccupantID.IsZero() {
+		filter["$or"] = []bson.M{
+			{"owner_id": f.OccupantID},
+			{"tenant_id": f.OccupantID},
+		}
+	}
+	return filter
+}
+
+func (f UnitFilter) matches(u models.Unit) bool {
+	if f.SocietyCode != "" && u.SocietyCode != f.SocietyCode {
+		return false
+	}
+	if !f.BuildingID.IsZero() && u.BuildingID != f.BuildingID {
+		return false
+	}
+	if !f.OccupantID.IsZero() {
+		isOwner := u.OwnerID != nil && *u.OwnerID == f.OccupantID
+		isTenant := u.TenantID != nil && *u.TenantID == f.OccupantID
+		if !isOwner && !isTenant {
+			return false
+		}
+	}
+	return true
+}
+
+type mongoUnitRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoUnitRepository) Create(ctx context.Context, unit *models.Unit) error {
+	if unit.ID.IsZero() {
+		unit.ID = primitive.NewObjectID()
+	}
+	if unit.Occupancy == nil {
+		unit.Occupancy = []models.UnitOccupancy{}
+	}
+	_, err := r.collection.InsertOne(ctx, unit)
+	return translateError(err)
+}
+
+func (r *mongoUnitRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Unit, error) {
+	return r.findOne(ctx, bson.M{"_id": id, "society_code": societyCode})
+}
+
+func (r *mongoUnitRepository) GetByNumber(ctx context.Context, societyCode, number string) (*models.Unit, error) {
+	return r.findOne(ctx, bson.M{"number": number, "society_code": societyCode})
+}
+
+func (r *mongoUnitRepository) findOne(ctx context.Context, filter bson.M) (*models.Unit, error) {
+	var unit models.Unit
+	if err := r.collection.FindOne(ctx, filter).Decode(&unit); err != nil {
+		return nil, translateError(err)
+	}
+	return &unit, nil
+}
+
+func (r *mongoUnitRepository) List(ctx context.Context, filter UnitFilter) ([]models.Unit, error) {
+	cursor, err := r.collection.Find(ctx, filter.toBSON(), options.Find().SetSort(bson.M{"number": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var units []models.Unit
+	if err = cursor.All(ctx, &units); err != nil {
+		return nil, err
+	}
+	return units, nil
+}
+
+func (r *mongoUnitRepository) Count(ctx context.Context, filter UnitFilter) (int64, error) {
+	return r.collection.CountDocuments(ctx, filter.toBSON())
+}
+
+func (r *mongoUnitRepository) SetOccupant(ctx context.Context, societyCode string, id primitive.ObjectID, occupancy models.UnitOccupancy) error {
+	field := occupantField(occupancy.Role)
+	filter := bson.M{
+		"_id":          id,
+		"society_code": societyCode,
+		field:          bson.M{"$exists": false},
+	}
+	update := bson.M{
+		"$set":  bson.M{field: occupancy.UserID, "updated_at": time.Now()},
+		"$push": bson.M{"occupancy": occupancy},
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return r.missingOrConflict(ctx, societyCode, id)
+	}
+	return nil
+}
+
+func (r *mongoUnitRepository) ClearOccupant(ctx context.Context, societyCode string, id primitive.ObjectID, role string, movedOut time.Time) error {
+	field := occupantField(role)
+	filter := bson.M{
+		"_id":          id,
+		"society_code": societyCode,
+		field:          bson.M{"$exists": true},
+	}
+	update := bson.M{
+		"$unset": bson.M{field: ""},
+		"$set": bson.M{
+			"occupancy.$[open].moved_out": movedOut,
+			"updated_at":                  time.Now(),
+		},
+	}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{bson.M{
+			"open.role":      role,
+			"open.moved_out": bson.M{"$exists": false},
+		}},
+	})
+	result, err := r.collection.UpdateOne(ctx, filter, update, opts)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return r.missingOrConflict(ctx, societyCode, id)
+	}
+	return nil
+}
+
+func (r *mongoUnitRepository) BumpCreditVersion(ctx context.Context, societyCode string, id primitive.ObjectID, from int64) error {
+	version := bson.M{"credit_version": from}
+	if from == 0 {
+		// Units created before credit versions have none
+		version = bson.M{"credit_version": bson.M{"$in": bson.A{0, nil}}}
+	}
+	filter := bson.M{"_id": id, "society_code": societyCode}
+	for key, value := range version {
+		filter[key] = value
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"credit_version": from + 1}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return r.missingOrConflict(ctx, societyCode, id)
+	}
+	return nil
+}
+
+// missingOrConflict tells apart a missing unit from one in the wrong state
+// after a conditional update matched nothing.
+func (r *mongoUnitRepository) missingOrConflict(ctx context.Context, societyCode string, id primitive.ObjectID) error {
+	n, err := r.collection.CountDocuments(ctx, bson.M{"_id": id, "society_code": societyCode})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return ErrConflict
+}
+
+type memoryUnitRepository struct {
+	table *memoryTable[models.Unit]
+}
+
+func newMemoryUnitRepository() *memoryUnitRepository {
+	return &memoryUnitRepository{table: newMemoryTable[models.Unit]()}
+}
+
+func (r *memoryUnitRepository) Create(ctx context.Context, unit *models.Unit) error {
+	if unit.ID.IsZero() {
+		unit.ID = primitive.NewObjectID()
+	}
+	if unit.Occupancy == nil {
+		unit.Occupancy = []models.UnitOccupancy{}
+	}
+	return r.table.insert(unit.ID, *unit, func(existing models.Unit) bool {
+		return existing.SocietyCode == unit.SocietyCode && existing.Number == unit.Number
+	})
+}
+
+func (r *memoryUnitRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Unit, error) {
+	unit, err := r.table.find(func(u models.Unit) bool {
+		return u.ID == id && u.SocietyCode == societyCode
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &unit, nil
+}
+
+func (r *memoryUnitRepository) GetByNumber(ctx context.Context, societyCode, number string) (*models.Unit, error) {
+	unit, err := r.table.find(func(u models.Unit) bool {
+		return u.Number == number && u.SocietyCode == societyCode
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &unit, nil
+}
+
+func (r *memoryUnitRepository) List(ctx context.Context, filter UnitFilter) ([]models.Unit, error) {
+	units := r.table.filter(filter.matches)
+	sort.Slice(units, func(i, j int) bool { return units[i].Number < units[j].Number })
+	return units, nil
+}
+
+func (r *memoryUnitRepository) Count(ctx context.Context, filter UnitFilter) (int64, error) {
+	return r.table.count(filter.matches), nil
+}
+
+func (r *memoryUnitRepository) SetOccupant(ctx context.Context, societyCode string, id primitive.ObjectID, occupancy models.UnitOccupancy) error {
+	conflict := false
+	err := r.table.update(id, func(u models.Unit) bool { return u.SocietyCode == societyCode }, func(u *models.Unit) {
+		current := &u.TenantID
+		if occupancy.Role == "owner" {
+			current = &u.OwnerID
+		}
+		if *current != nil {
+			conflict = true
+			return
+		}
+		userID := occupancy.UserID
+		*current = &userID
+		u.Occupancy = append(append([]models.UnitOccupancy{}, u.Occupancy...), occupancy)
+		u.UpdatedAt = time.Now()
+	})
+	if err == nil && conflict {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *memoryUnitRepository) ClearOccupant(ctx context.Context, societyCode string, id primitive.ObjectID, role string, movedOut time.Time) error {
+	conflict := false
+	err := r.table.update(id, func(u models.Unit) bool { return u.SocietyCode == societyCode }, func(u *models.Unit) {
+		current := &u.TenantID
+		if role == "owner" {
+			current = &u.OwnerID
+		}
+		if *current == nil {
+			conflict = true
+			return
+		}
+		*current = nil
+		history := append([]models.UnitOccupancy{}, u.Occupancy...)
+		for i := range history {
+			if history[i].Role == role && history[i].MovedOut == nil {
+				at := movedOut
+				history[i].MovedOut = &at
+			}
+		}
+		u.Occupancy = history
+		u.UpdatedAt = time.Now()
+	})
+	if err == nil && conflict {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *memoryUnitRepository) BumpCreditVersion(ctx context.Context, societyCode string, id primitive.ObjectID, from int64) error {
+	conflict := false
+	err := r.table.update(id, func(u models.Unit) bool { return u.SocietyCode == societyCode }, func(u *models.Unit) {
+		if u.CreditVersion != from {
+			conflict = true
+			return
+		}
+		u.CreditVersion++
+	})
+	if err == nil && conflict {
+		return ErrConflict
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type UploadRepository interface {
+	Create(ctx context.Context, upload *models.Upload) error
+	GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Upload, error)
+	// Delete removes the record; the caller removes the stored files
+	Delete(ctx context.Context, societyCode string, id primitive.ObjectID) error
+}
+
+type mongoUploadRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoUploadRepository) Create(ctx context.Context, upload *models.Upload) error {
+	if upload.ID.IsZero() {
+		upload.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, upload)
+	return translateError(err)
+}
+
+func (r *mongoUploadRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Upload, error) {
+	var upload models.Upload
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "society_code": societyCode}).Decode(&upload)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &upload, nil
+}
+
+func (r *mongoUploadRepository) Delete(ctx context.Context, societyCode string, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "society_code": societyCode})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryUploadRepository struct {
+	table *memoryTable[models.Upload]
+}
+
+func newMemoryUploadRepository() *memoryUploadRepository {
+	return &memoryUploadRepository{table: newMemoryTable[models.Upload]()}
+}
+
+func (r *memoryUploadRepository) Create(ctx context.Context, upload *models.Upload) error {
+	if upload.ID.IsZero() {
+		upload.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(upload.ID, *upload, nil)
+}
+
+func (r *memoryUploadRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Upload, error) {
+	upload, err := r.table.find(func(u models.Upload) bool {
+		return u.ID == id && u.SocietyCode == societyCode
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+func (r *memoryUploadRepository) Delete(ctx context.Context, societyCode string, id primitive.ObjectID) error {
+	deleted := r.table.deleteAll(func(u models.Upload) bool {
+		return u.ID == id && u.SocietyCode == societyCode
+	})
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UserFilter narrows List and Count. Zero-valued fields are ignored.
+type UserFilter struct {
+	SocietyCode string
+	Roles       []string
+	ActiveOnly  bool
+	UnitID      primitive.ObjectID
+}
+
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.User, error)
+	GetActiveByEmail(ctx context.Context, societyCode, email string) (*models.User, error)
+	// List returns matching users sorted by name
+	List(ctx context.Context, filter UserFilter) ([]models.User, error)
+	Count(ctx context.Context, filter UserFilter) (int64, error)
+	// SetUnit links the user to a unit, or unlinks them when unit is nil
+	SetUnit(ctx context.Context, societyCode string, id primitive.ObjectID, unit *models.Unit) error
+	SetAvatar(ctx context.Context, societyCode string, id primitive.ObjectID, avatar string) error
+}
+
+func (f UserFilter) toBSON() bson.M {
+	filter := bson.M{}
+	if f.SocietyCode != "" {
+		filter["society_code"] = f.SocietyCode
+	}
+	if len(f.Roles) == 1 {
+		filter["role"] = f.Roles[0]
+	} else if len(f.Roles) > 1 {
+		filter["role"] = bson.M{"$in": f.Roles}
+	}
+	if f.ActiveOnly {
+		filter["is_active"] = true
+	}
+	if !f.UnitID.IsZero() {
+		filter["unit_id"] = f.UnitID
+	}
+	return filter
+}
+
+func (f UserFilter) matches(u models.User) bool {
+	if f.SocietyCode != "" && u.SocietyCode != f.SocietyCode {
+		return false
+	}
+	if len(f.Roles) > 0 && !containsString(f.Roles, u.Role) {
+		return false
+	}
+	if f.ActiveOnly && !u.IsActive {
+		return false
+	}
+	if !f.UnitID.IsZero() && (u.UnitID == nil || *u.UnitID != f.UnitID) {
+		return false
+	}
+	return true
+}
+
+type mongoUserRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoUserRepository) Create(ctx context.Context, user *models.User) error {
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, user)
+	return translateError(err)
+}
+
+func (r *mongoUserRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.User, error) {
+	return r.findOne(ctx, bson.M{"_id": id, "society_code": societyCode})
+}
+
+func (r *mongoUserRepository) GetActiveByEmail(ctx context.Context, societyCode, email string) (*models.User, error) {
+	return r.findOne(ctx, bson.M{
+		"email":        email,
+		"society_code": societyCode,
+		"is_active":    true,
+	})
+}
+
+func (r *mongoUserRepository) findOne(ctx context.Context, filter bson.M) (*models.User, error) {
+	var user models.User
+	if err := r.collection.FindOne(ctx, filter).Decode(&user); err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+func (r *mongoUserRepository) List(ctx context.Context, filter UserFilter) ([]models.User, error) {
+	cursor, err := r.collection.Find(ctx, filter.toBSON(), options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *mongoUserRepository) Count(ctx context.Context, filter UserFilter) (int64, error) {
+	return r.collection.CountDocuments(ctx, filter.toBSON())
+}
+
+func (r *mongoUserRepository) SetUnit(ctx context.Context, societyCode string, id primitive.ObjectID, unit *models.Unit) error {
+	update := bson.M{
+		"$set":   bson.M{"unit": "", "building": "", "updated_at": time.Now()},
+		"$unset": bson.M{"unit_id": ""},
+	}
+	if unit != nil {
+		update = bson.M{"$set": bson.M{
+			"unit":       unit.Number,
+			"unit_id":    unit.ID,
+			"building":   unit.BuildingName,
+			"updated_at": time.Now(),
+		}}
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "society_code": societyCode}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoUserRepository) SetAvatar(ctx context.Context, societyCode string, id primitive.ObjectID, avatar string) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "society_code": societyCode},
+		bson.M{"$set": bson.M{"avatar": avatar, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryUserRepository struct {
+	table *memoryTable[models.User]
+}
+
+func newMemoryUserRepository() *memoryUserRepository {
+	return &memoryUserRepository{table: newMemoryTable[models.User]()}
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, user *models.User) error {
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(user.ID, *user, func(existing models.User) bool {
+		return existing.Email == user.Email && existing.SocietyCode == user.SocietyCode
+	})
+}
+
+func (r *memoryUserRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.User, error) {
+	user, err := r.table.find(func(u models.User) bool {
+		return u.ID == id && u.SocietyCode == societyCode
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *memoryUserRepository) GetActiveByEmail(ctx context.Context, societyCode, email string) (*models.User, error) {
+	user, err := r.table.find(func(u models.User) bool {
+		return u.Email == email && u.SocietyCode == societyCode && u.IsActive
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *memoryUserRepository) List(ctx context.Context, filter UserFilter) ([]models.User, error) {
+	users := r.table.filter(filter.matches)
+	sort.Slice(users, func(i, j int) bool { return users[i].Name < users[j].Name })
+	return users, nil
+}
+
+func (r *memoryUserRepository) Count(ctx context.Context, filter UserFilter) (int64, error) {
+	return r.table.count(filter.matches), nil
+}
+
+func (r *memoryUserRepository) SetUnit(ctx context.Context, societyCode string, id primitive.ObjectID, unit *models.Unit) error {
+	return r.table.update(id, func(u models.User) bool { return u.SocietyCode == societyCode }, func(u *models.User) {
+		u.UpdatedAt = time.Now()
+		if unit == nil {
+			u.Unit, u.UnitID, u.Building = "", nil, ""
+			return
+		}
+		unitID := unit.ID
+		u.Unit, u.UnitID, u.Building = unit.Number, &unitID, unit.BuildingName
+	})
+}
+
+func (r *memoryUserRepository) SetAvatar(ctx context.Context, societyCode string, id primitive.ObjectID, avatar string) error {
+	return r.table.update(id, func(u models.User) bool { return u.SocietyCode == societyCode }, func(u *models.User) {
+		u.Avatar = avatar
+		u.UpdatedAt = time.Now()
+	})
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
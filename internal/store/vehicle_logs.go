@@ -0,0 +1,170 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// VehicleLogFilter narrows List. Zero-valued fields are ignored; From and
+// To bound the entry time, From inclusive and To exclusive.
+type VehicleLogFilter struct {
+	SocietyCode string
+	Plate       string
+	Kind        string
+	From        time.Time
+	To          time.Time
+	InsideOnly  bool
+}
+
+type VehicleLogRepository interface {
+	// Enter records a vehicle coming in. It returns ErrDuplicate if the
+	// plate is already inside.
+	Enter(ctx context.Context, entry *models.VehicleLog) error
+	// Exit closes the plate's open entry and returns it. It returns
+	// ErrNotFound if the plate is not inside.
+	Exit(ctx context.Context, societyCode, plate string, at time.Time, by primitive.ObjectID) (*models.VehicleLog, error)
+	// List returns matching entries, most recent entry first
+	List(ctx context.Context, filter VehicleLogFilter) ([]models.VehicleLog, error)
+}
+
+func (f VehicleLogFilter) toBSON() bson.M {
+	filter := bson.M{}
+	if f.SocietyCode != "" {
+		filter["society_code"] = f.SocietyCode
+	}
+	if f.Plate != "" {
+		filter["plate"] = f.Plate
+	}
+	if f.Kind != "" {
+		filter["kind"] = f.Kind
+	}
+	entered := bson.M{}
+	if !f.From.IsZero() {
+		entered["$gte"] = f.From
+	}
+	if !f.To.IsZero() {
+		entered["$lt"] = f.To
+	}
+	if len(entered) > 0 {
+		filter["entered_at"] = entered
+	}
+	if f.InsideOnly {
+		filter["inside"] = true
+	}
+	return filter
+}
+
+func (f VehicleLogFilter) matches(entry models.VehicleLog) bool {
+	if f.SocietyCode != "" && entry.SocietyCode != f.SocietyCode {
+		return false
+	}
+	if f.Plate != "" && entry.Plate != f.Plate {
+		return false
+	}
+	if f.Kind != "" && entry.Kind != f.Kind {
+		return false
+	}
+	if !f.From.IsZero() && entry.EnteredAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !entry.EnteredAt.Before(f.To) {
+		return false
+	}
+	if f.InsideOnly && !entry.Inside {
+		return false
+	}
+	return true
+}
+
+type mongoVehicleLogRepository struct {
+	collection *mongo.Collection
+}
+
+// Enter relies on the partial unique index on plate over open entries
+func (r *mongoVehicleLogRepository) Enter(ctx context.Context, entry *models.VehicleLog) error {
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, entry)
+	return translateError(err)
+}
+
+func (r *mongoVehicleLogRepository) Exit(ctx context.Context, societyCode, plate string, at time.Time, by primitive.ObjectID) (*models.VehicleLog, error) {
+	filter := bson.M{"society_code": societyCode, "plate": plate, "inside": true}
+	update := bson.M{"$set": bson.M{"inside": false, "exited_at": at, "exited_by": by}}
+
+	var entry models.VehicleLog
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&entry)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &entry, nil
+}
+
+func (r *mongoVehicleLogRepository) List(ctx context.Context, filter VehicleLogFilter) ([]models.VehicleLog, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "entered_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, filter.toBSON(), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.VehicleLog
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+type memoryVehicleLogRepository struct {
+	table *memoryTable[models.VehicleLog]
+}
+
+func newMemoryVehicleLogRepository() *memoryVehicleLogRepository {
+	return &memoryVehicleLogRepository{table: newMemoryTable[models.VehicleLog]()}
+}
+
+func (r *memoryVehicleLogRepository) Enter(ctx context.Context, entry *models.VehicleLog) error {
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(entry.ID, *entry, func(existing models.VehicleLog) bool {
+		return existing.SocietyCode == entry.SocietyCode && existing.Plate == entry.Plate && existing.Inside
+	})
+}
+
+func (r *memoryVehicleLogRepository) Exit(ctx context.Context, societyCode, plate string, at time.Time, by primitive.ObjectID) (*models.VehicleLog, error) {
+	open := func(e models.VehicleLog) bool {
+		return e.SocietyCode == societyCode && e.Plate == plate && e.Inside
+	}
+	entry, err := r.table.find(open)
+	if err != nil {
+		return nil, err
+	}
+	err = r.table.update(entry.ID, open, func(e *models.VehicleLog) {
+		exitedAt, exitedBy := at, by
+		e.Inside = false
+		e.ExitedAt = &exitedAt
+		e.ExitedBy = &exitedBy
+		entry = *e
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *memoryVehicleLogRepository) List(ctx context.Context, filter VehicleLogFilter) ([]models.VehicleLog, error) {
+	entries := r.table.filter(filter.matches)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].EnteredAt.After(entries[j].EnteredAt) })
+	return entries, nil
+}
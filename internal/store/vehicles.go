@@ -0,0 +1,229 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// VehicleFilter narrows List. Zero-valued fields are ignored.
+type VehicleFilter struct {
+	SocietyCode string
+	UnitID      primitive.ObjectID
+	Plate       string
+	SlotID      primitive.ObjectID
+	ActiveOnly  bool
+}
+
+// VehicleUpdate carries the fields to change. Nil fields are left untouched.
+type VehicleUpdate struct {
+	Make      *string
+	Model     *string
+	Color     *string
+	SlotID    *primitive.ObjectID
+	SlotLabel *string
+	ClearSlot bool // Unassigns the vehicle's slot
+	IsActive  *bool
+	UpdatedAt time.Time
+}
+
+type VehicleRepository interface {
+	// Create returns ErrDuplicate if the society already has an active
+	// vehicle with the same plate
+	Create(ctx context.Context, vehicle *models.Vehicle) error
+	GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Vehicle, error)
+	// List returns matching vehicles ordered by unit and plate
+	List(ctx context.Context, filter VehicleFilter) ([]models.Vehicle, error)
+	Update(ctx context.Context, societyCode string, id primitive.ObjectID, update VehicleUpdate) error
+}
+
+func (f VehicleFilter) toBSON() bson.M {
+	filter := bson.M{}
+	if f.SocietyCode != "" {
+		filter["society_code"] = f.SocietyCode
+	}
+	if !f.UnitID.IsZero() {
+		filter["unit_id"] = f.UnitID
+	}
+	if f.Plate != "" {
+		filter["plate"] = f.Plate
+	}
+	if !f.SlotID.IsZero() {
+		filter["slot_id"] = f.SlotID
+	}
+	if f.ActiveOnly {
+		filter["is_active"] = true
+	}
+	return filter
+}
+
+func (f VehicleFilter) matches(vehicle models.Vehicle) bool {
+	if f.SocietyCode != "" && vehicle.SocietyCode != f.SocietyCode {
+		return false
+	}
+	if !f.UnitID.IsZero() && vehicle.UnitID != f.UnitID {
+		return false
+	}
+	if f.Plate != "" && vehicle.Plate != f.Plate {
+		return false
+	}
+	if !f.SlotID.IsZero() && (vehicle.SlotID == nil || *vehicle.SlotID != f.SlotID) {
+		return false
+	}
+	if f.ActiveOnly && !vehicle.IsActive {
+		return false
+	}
+	return true
+}
+
+func (u VehicleUpdate) toBSON() bson.M {
+	set := bson.M{"updated_at": u.UpdatedAt}
+	if u.Make != nil {
+		set["make"] = *u.Make
+	}
+	if u.Model != nil {
+		set["model"] = *u.Model
+	}
+	if u.Color != nil {
+		set["color"] = *u.Color
+	}
+	if u.SlotID != nil {
+		set["slot_id"] = *u.SlotID
+	}
+	if u.SlotLabel != nil {
+		set["slot_label"] = *u.SlotLabel
+	}
+	if u.IsActive != nil {
+		set["is_active"] = *u.IsActive
+	}
+
+	update := bson.M{"$set": set}
+	if u.ClearSlot {
+		update["$unset"] = bson.M{"slot_id": "", "slot_label": ""}
+	}
+	return update
+}
+
+func (u VehicleUpdate) apply(vehicle *models.Vehicle) {
+	vehicle.UpdatedAt = u.UpdatedAt
+	if u.Make != nil {
+		vehicle.Make = *u.Make
+	}
+	if u.Model != nil {
+		vehicle.Model = *u.Model
+	}
+	if u.Color != nil {
+		vehicle.Color = *u.Color
+	}
+	if u.SlotID != nil {
+		slotID := *u.SlotID
+		vehicle.SlotID = &slotID
+	}
+	if u.SlotLabel != nil {
+		vehicle.SlotLabel = *u.SlotLabel
+	}
+	if u.ClearSlot {
+		vehicle.SlotID = nil
+		vehicle.SlotLabel = ""
+	}
+	if u.IsActive != nil {
+		vehicle.IsActive = *u.IsActive
+	}
+}
+
+type mongoVehicleRepository struct {
+	collection *mongo.Collection
+}
+
+// Create relies on the partial unique index on plate over active vehicles
+func (r *mongoVehicleRepository) Create(ctx context.Context, vehicle *models.Vehicle) error {
+	if vehicle.ID.IsZero() {
+		vehicle.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, vehicle)
+	return translateError(err)
+}
+
+func (r *mongoVehicleRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Vehicle, error) {
+	var vehicle models.Vehicle
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "society_code": societyCode}).Decode(&vehicle)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &vehicle, nil
+}
+
+func (r *mongoVehicleRepository) List(ctx context.Context, filter VehicleFilter) ([]models.Vehicle, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "unit_number", Value: 1}, {Key: "plate", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter.toBSON(), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var vehicles []models.Vehicle
+	if err = cursor.All(ctx, &vehicles); err != nil {
+		return nil, err
+	}
+	return vehicles, nil
+}
+
+func (r *mongoVehicleRepository) Update(ctx context.Context, societyCode string, id primitive.ObjectID, update VehicleUpdate) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "society_code": societyCode}, update.toBSON())
+	if err != nil {
+		return translateError(err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryVehicleRepository struct {
+	table *memoryTable[models.Vehicle]
+}
+
+func newMemoryVehicleRepository() *memoryVehicleRepository {
+	return &memoryVehicleRepository{table: newMemoryTable[models.Vehicle]()}
+}
+
+func (r *memoryVehicleRepository) Create(ctx context.Context, vehicle *models.Vehicle) error {
+	if vehicle.ID.IsZero() {
+		vehicle.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(vehicle.ID, *vehicle, func(existing models.Vehicle) bool {
+		return existing.SocietyCode == vehicle.SocietyCode && existing.Plate == vehicle.Plate && existing.IsActive
+	})
+}
+
+func (r *memoryVehicleRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Vehicle, error) {
+	vehicle, err := r.table.find(func(v models.Vehicle) bool {
+		return v.ID == id && v.SocietyCode == societyCode
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &vehicle, nil
+}
+
+func (r *memoryVehicleRepository) List(ctx context.Context, filter VehicleFilter) ([]models.Vehicle, error) {
+	vehicles := r.table.filter(filter.matches)
+	sort.Slice(vehicles, func(i, j int) bool {
+		if vehicles[i].UnitNumber != vehicles[j].UnitNumber {
+			return vehicles[i].UnitNumber < vehicles[j].UnitNumber
+		}
+		return vehicles[i].Plate < vehicles[j].Plate
+	})
+	return vehicles, nil
+}
+
+func (r *memoryVehicleRepository) Update(ctx context.Context, societyCode string, id primitive.ObjectID, update VehicleUpdate) error {
+	return r.table.update(id, func(v models.Vehicle) bool { return v.SocietyCode == societyCode }, update.apply)
+}
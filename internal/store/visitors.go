@@ -0,0 +1,504 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// VisitorFilter narrows List and Count. Zero-valued fields are ignored.
+type VisitorFilter struct {
+	SocietyCode string
+	HostID      primitive.ObjectID
+	HostUnitID  primitive.ObjectID
+	// HostUnitIDs matches visitors of any of the units, such as every unit
+	// of a building
+	HostUnitIDs  []primitive.ObjectID
+	Statuses     []string
+	CreatedSince time.Time
+	// HostApprovalDueBy matches walk-ins whose host approval ran out by
+	// then without being escalated
+	HostApprovalDueBy time.Time
+	// ExpectedBefore and ArrivedBefore match visitors expected, or checked
+	// in, before then; ExpectedFrom those expected from then on
+	ExpectedFrom   time.Time
+	ExpectedBefore time.Time
+	ArrivedBefore  time.Time
+	// UnflaggedOnly leaves out visitors already flagged as overstaying
+	UnflaggedOnly bool
+}
+
+// VisitorUpdate carries the fields to change. Nil fields are left untouched.
+type VisitorUpdate struct {
+	Status          *string
+	ApprovedBy      *primitive.ObjectID
+	ActualArrival   *time.Time
+	ActualDeparture *time.Time
+	PassUsedAt      *time.Time
+	// QRCode, PassValidFrom and PassExpiresAt are set together when a pass
+	// is reissued
+	QRCode        *string
+	PassValidFrom *time.Time
+	PassExpiresAt *time.Time
+	// Event is appended to the visitor's status history
+	Event *models.VisitorStatusChange
+	// EscalatedAt marks the visitor's host approval as escalated
+	EscalatedAt *time.Time
+	// OverstayedAt flags a checked-in visitor as staying too long
+	OverstayedAt *time.Time
+	// VehicleNumber, ParkingSlotID and ParkingSlot record where the
+	// visitor's vehicle was parked at check-in
+	VehicleNumber *string
+	ParkingSlotID *primitive.ObjectID
+	ParkingSlot   *string
+	PhotoURL      *string
+	IDProofURL    *string
+}
+
+type VisitorRepository interface {
+	Create(ctx context.Context, visitor *models.Visitor) error
+	GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Visitor, error)
+	GetByQRCode(ctx context.Context, qrCode string) (*models.Visitor, error)
+	// List returns matching visitors, newest first
+	List(ctx context.Context, filter VisitorFilter) ([]models.Visitor, error)
+	// Each calls fn for every matching visitor in expected time order,
+	// without loading them all at once. An error from fn stops the walk
+	// and is returned.
+	Each(ctx context.Context, filter VisitorFilter, fn func(*models.Visitor) error) error
+	Count(ctx context.Context, filter VisitorFilter) (int64, error)
+	Update(ctx context.Context, societyCode string, id primitive.ObjectID, update VisitorUpdate) error
+	// Transition applies the update only while the visitor is in one of the
+	// from statuses, returning ErrConflict otherwise
+	Transition(ctx context.Context, societyCode string, id primitive.ObjectID, from []string, update VisitorUpdate) error
+	// Escalate marks a pending visitor's host approval as escalated at,
+	// returning ErrConflict when the visitor is no longer pending or was
+	// escalated already
+	Escalate(ctx context.Context, societyCode string, id primitive.ObjectID, at time.Time) error
+}
+
+func (f VisitorFilter) toBSON() bson.M {
+	filter := bson.M{}
+	if f.SocietyCode != "" {
+		filter["society_code"] = f.SocietyCode
+	}
+	if !f.HostID.IsZero() {
+		filter["host_id"] = f.HostID
+	}
+	if len(f.Statuses) == 1 {
+		filter["status"] = f.Statuses[0]
+	} else if len(f.Statuses) > 1 {
+		filter["status"] = bson.M{"$in": f.Statuses}
+	}
+	if !f.CreatedSince.IsZero() {
+		filter["created_at"] = bson.M{"$gte": f.CreatedSince}
+	}
+	if !f.HostUnitID.IsZero() {
+		filter["host_unit_id"] = f.HostUnitID
+	}
+	if f.HostUnitIDs != nil {
+		filter["host_unit_id"] = bson.M{"$in": f.HostUnitIDs}
+	}
+	if !f.HostApprovalDueBy.IsZero() {
+		filter["host_approval.expires_at"] = bson.M{"$lte": f.HostApprovalDueBy}
+		filter["host_approval.escalated_at"] = bson.M{"$exists": false}
+	}
+	expected := bson.M{}
+	if !f.ExpectedFrom.IsZero() {
+		expected["$gte"] = f.ExpectedFrom
+	}
+	if !f.ExpectedBefore.IsZero() {
+		expected["$lt"] = f.ExpectedBefore
+	}
+	if len(expected) > 0 {
+		filter["expected_time"] = expected
+	}
+	if !f.ArrivedBefore.IsZero() {
+		filter["actual_arrival"] = bson.M{"$lt": f.ArrivedBefore}
+	}
+	if f.UnflaggedOnly {
+		filter["overstayed_at"] = bson.M{"$exists": false}
+	}
+	return filter
+}
+
+func (f VisitorFilter) matches(v models.Visitor) bool {
+	if f.SocietyCode != "" && v.SocietyCode != f.SocietyCode {
+		return false
+	}
+	if !f.HostID.IsZero() && v.HostID != f.HostID {
+		return false
+	}
+	if len(f.Statuses) > 0 && !containsString(f.Statuses, v.Status) {
+		return false
+	}
+	if !f.CreatedSince.IsZero() && v.CreatedAt.Before(f.CreatedSince) {
+		return false
+	}
+	if !f.HostUnitID.IsZero() && (v.HostUnitID == nil || *v.HostUnitID != f.HostUnitID) {
+		return false
+	}
+	if f.HostUnitIDs != nil && (v.HostUnitID == nil || !containsObjectID(f.HostUnitIDs, *v.HostUnitID)) {
+		return false
+	}
+	if !f.HostApprovalDueBy.IsZero() && (v.HostApproval == nil || v.HostApproval.ExpiresAt.After(f.HostApprovalDueBy) || v.HostApproval.EscalatedAt != nil) {
+		return false
+	}
+	if !f.ExpectedFrom.IsZero() && v.ExpectedTime.Before(f.ExpectedFrom) {
+		return false
+	}
+	if !f.ExpectedBefore.IsZero() && !v.ExpectedTime.Before(f.ExpectedBefore) {
+		return false
+	}
+	if !f.ArrivedBefore.IsZero() && (v.ActualArrival == nil || !v.ActualArrival.Before(f.ArrivedBefore)) {
+		return false
+	}
+	if f.UnflaggedOnly && v.OverstayedAt != nil {
+		return false
+	}
+	return true
+}
+
+func (u VisitorUpdate) toBSON(now time.Time) bson.M {
+	set := bson.M{"updated_at": now}
+	if u.Status != nil {
+		set["status"] = *u.Status
+	}
+	if u.ApprovedBy != nil {
+		set["approved_by"] = *u.ApprovedBy
+	}
+	if u.ActualArrival != nil {
+		set["actual_arrival"] = *u.ActualArrival
+	}
+	if u.ActualDeparture != nil {
+		set["actual_departure"] = *u.ActualDeparture
+	}
+	if u.PassUsedAt != nil {
+		set["pass_used_at"] = *u.PassUsedAt
+	}
+	if u.QRCode != nil {
+		set["qr_code"] = *u.QRCode
+	}
+	if u.PassValidFrom != nil {
+		set["pass_valid_from"] = *u.PassValidFrom
+	}
+	if u.PassExpiresAt != nil {
+		set["pass_expires_at"] = *u.PassExpiresAt
+	}
+	if u.EscalatedAt != nil {
+		set["host_approval.escalated_at"] = *u.EscalatedAt
+	}
+	if u.OverstayedAt != nil {
+		set["overstayed_at"] = *u.OverstayedAt
+	}
+	if u.VehicleNumber != nil {
+		set["vehicle_number"] = *u.VehicleNumber
+	}
+	if u.ParkingSlotID != nil {
+		set["parking_slot_id"] = *u.ParkingSlotID
+	}
+	if u.ParkingSlot != nil {
+		set["parking_slot"] = *u.ParkingSlot
+	}
+	if u.PhotoURL != nil {
+		set["photo_url"] = *u.PhotoURL
+	}
+	if u.IDProofURL != nil {
+		set["id_proof_url"] = *u.IDProofURL
+	}
+	if u.Event != nil {
+		return bson.M{"$set": set, "$push": bson.M{"history": *u.Event}}
+	}
+	return bson.M{"$set": set}
+}
+
+func (u VisitorUpdate) apply(v *models.Visitor, now time.Time) {
+	v.UpdatedAt = now
+	if u.Status != nil {
+		v.Status = *u.Status
+	}
+	if u.ApprovedBy != nil {
+		approvedBy := *u.ApprovedBy
+		v.ApprovedBy = &approvedBy
+	}
+	if u.ActualArrival != nil {
+		arrival := *u.ActualArrival
+		v.ActualArrival = &arrival
+	}
+	if u.ActualDeparture != nil {
+		departure := *u.ActualDeparture
+		v.ActualDeparture = &departure
+	}
+	if u.PassUsedAt != nil {
+		usedAt := *u.PassUsedAt
+		v.PassUsedAt = &usedAt
+	}
+	if u.QRCode != nil {
+		v.QRCode = *u.QRCode
+	}
+	if u.PassValidFrom != nil {
+		v.PassValidFrom = *u.PassValidFrom
+	}
+	if u.PassExpiresAt != nil {
+		v.PassExpiresAt = *u.PassExpiresAt
+	}
+	if u.EscalatedAt != nil && v.HostApproval != nil {
+		approval := *v.HostApproval
+		escalatedAt := *u.EscalatedAt
+		approval.EscalatedAt = &escalatedAt
+		v.HostApproval = &approval
+	}
+	if u.OverstayedAt != nil {
+		overstayedAt := *u.OverstayedAt
+		v.OverstayedAt = &overstayedAt
+	}
+	if u.VehicleNumber != nil {
+		v.VehicleNumber = *u.VehicleNumber
+	}
+	if u.ParkingSlotID != nil {
+		slotID := *u.ParkingSlotID
+		v.ParkingSlotID = &slotID
+	}
+	if u.ParkingSlot != nil {
+		v.ParkingSlot = *u.ParkingSlot
+	}
+	if u.PhotoURL != nil {
+		v.PhotoURL = *u.PhotoURL
+	}
+	if u.IDProofURL != nil {
+		v.IDProofURL = *u.IDProofURL
+	}
+	if u.Event != nil {
+		// Copy so rows never share a backing array
+		v.History = append(append([]models.VisitorStatusChange(nil), v.History...), *u.Event)
+	}
+}
+
+type mongoVisitorRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoVisitorRepository) Create(ctx context.Context, visitor *models.Visitor) error {
+	if visitor.ID.IsZero() {
+		visitor.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, visitor)
+	return translateError(err)
+}
+
+func (r *mongoVisitorRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Visitor, error) {
+	return r.findOne(ctx, bson.M{"_id": id, "society_code": societyCode})
+}
+
+func (r *mongoVisitorRepository) GetByQRCode(ctx context.Context, qrCode string) (*models.Visitor, error) {
+	return r.findOne(ctx, bson.M{"qr_code": qrCode})
+}
+
+func (r *mongoVisitorRepository) findOne(ctx context.Context, filter bson.M) (*models.Visitor, error) {
+	var visitor models.Visitor
+	if err := r.collection.FindOne(ctx, filter).Decode(&visitor); err != nil {
+		return nil, translateError(err)
+	}
+	return &visitor, nil
+}
+
+func (r *mongoVisitorRepository) List(ctx context.Context, filter VisitorFilter) ([]models.Visitor, error) {
+	cursor, err := r.collection.Find(ctx, filter.toBSON(), options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var visitors []models.Visitor
+	if err = cursor.All(ctx, &visitors); err != nil {
+		return nil, err
+	}
+	return visitors, nil
+}
+
+func (r *mongoVisitorRepository) Each(ctx context.Context, filter VisitorFilter, fn func(*models.Visitor) error) error {
+	cursor, err := r.collection.Find(ctx, filter.toBSON(), options.Find().SetSort(bson.D{
+		{Key: "expected_time", Value: 1},
+		{Key: "_id", Value: 1},
+	}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var visitor models.Visitor
+		if err := cursor.Decode(&visitor); err != nil {
+			return err
+		}
+		if err := fn(&visitor); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+func (r *mongoVisitorRepository) Count(ctx context.Context, filter VisitorFilter) (int64, error) {
+	return r.collection.CountDocuments(ctx, filter.toBSON())
+}
+
+func (r *mongoVisitorRepository) Update(ctx context.Context, societyCode string, id primitive.ObjectID, update VisitorUpdate) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "society_code": societyCode}, update.toBSON(time.Now()))
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoVisitorRepository) Transition(ctx context.Context, societyCode string, id primitive.ObjectID, from []string, update VisitorUpdate) error {
+	filter := bson.M{"_id": id, "society_code": societyCode, "status": bson.M{"$in": from}}
+	result, err := r.collection.UpdateOne(ctx, filter, update.toBSON(time.Now()))
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		n, err := r.collection.CountDocuments(ctx, bson.M{"_id": id, "society_code": societyCode})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrNotFound
+		}
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r *mongoVisitorRepository) Escalate(ctx context.Context, societyCode string, id primitive.ObjectID, at time.Time) error {
+	filter := bson.M{
+		"_id":                        id,
+		"society_code":               societyCode,
+		"status":                     "pending",
+		"host_approval":              bson.M{"$ne": nil},
+		"host_approval.escalated_at": bson.M{"$exists": false},
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, VisitorUpdate{EscalatedAt: &at}.toBSON(time.Now()))
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		n, err := r.collection.CountDocuments(ctx, bson.M{"_id": id, "society_code": societyCode})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrNotFound
+		}
+		return ErrConflict
+	}
+	return nil
+}
+
+type memoryVisitorRepository struct {
+	table *memoryTable[models.Visitor]
+}
+
+func newMemoryVisitorRepository() *memoryVisitorRepository {
+	return &memoryVisitorRepository{table: newMemoryTable[models.Visitor]()}
+}
+
+func (r *memoryVisitorRepository) Create(ctx context.Context, visitor *models.Visitor) error {
+	if visitor.ID.IsZero() {
+		visitor.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(visitor.ID, *visitor, func(existing models.Visitor) bool {
+		return existing.QRCode == visitor.QRCode
+	})
+}
+
+func (r *memoryVisitorRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.Visitor, error) {
+	visitor, err := r.table.find(func(v models.Visitor) bool {
+		return v.ID == id && v.SocietyCode == societyCode
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &visitor, nil
+}
+
+func (r *memoryVisitorRepository) GetByQRCode(ctx context.Context, qrCode string) (*models.Visitor, error) {
+	visitor, err := r.table.find(func(v models.Visitor) bool { return v.QRCode == qrCode })
+	if err != nil {
+		return nil, err
+	}
+	return &visitor, nil
+}
+
+func (r *memoryVisitorRepository) List(ctx context.Context, filter VisitorFilter) ([]models.Visitor, error) {
+	visitors := r.table.filter(filter.matches)
+	sort.Slice(visitors, func(i, j int) bool { return visitors[i].CreatedAt.After(visitors[j].CreatedAt) })
+	return visitors, nil
+}
+
+func (r *memoryVisitorRepository) Each(ctx context.Context, filter VisitorFilter, fn func(*models.Visitor) error) error {
+	visitors := r.table.filter(filter.matches)
+	sort.Slice(visitors, func(i, j int) bool {
+		if !visitors[i].ExpectedTime.Equal(visitors[j].ExpectedTime) {
+			return visitors[i].ExpectedTime.Before(visitors[j].ExpectedTime)
+		}
+		return visitors[i].ID.Hex() < visitors[j].ID.Hex()
+	})
+	for i := range visitors {
+		if err := fn(&visitors[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryVisitorRepository) Count(ctx context.Context, filter VisitorFilter) (int64, error) {
+	return r.table.count(filter.matches), nil
+}
+
+func (r *memoryVisitorRepository) Update(ctx context.Context, societyCode string, id primitive.ObjectID, update VisitorUpdate) error {
+	now := time.Now()
+	return r.table.update(id, func(v models.Visitor) bool { return v.SocietyCode == societyCode }, func(v *models.Visitor) {
+		update.apply(v, now)
+	})
+}
+
+func (r *memoryVisitorRepository) Transition(ctx context.Context, societyCode string, id primitive.ObjectID, from []string, update VisitorUpdate) error {
+	now := time.Now()
+	conflict := false
+	err := r.table.update(id, func(v models.Visitor) bool { return v.SocietyCode == societyCode }, func(v *models.Visitor) {
+		if !containsString(from, v.Status) {
+			conflict = true
+			return
+		}
+		update.apply(v, now)
+	})
+	if err == nil && conflict {
+		return ErrConflict
+	}
+	return err
+}
+
+func (r *memoryVisitorRepository) Escalate(ctx context.Context, societyCode string, id primitive.ObjectID, at time.Time) error {
+	now := time.Now()
+	conflict := false
+	err := r.table.update(id, func(v models.Visitor) bool { return v.SocietyCode == societyCode }, func(v *models.Visitor) {
+		if v.Status != "pending" || v.HostApproval == nil || v.HostApproval.EscalatedAt != nil {
+			conflict = true
+			return
+		}
+		VisitorUpdate{EscalatedAt: &at}.apply(v, now)
+	})
+	if err == nil && conflict {
+		return ErrConflict
+	}
+	return err
+}
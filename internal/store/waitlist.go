@@ -0,0 +1,248 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"bms-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WaitlistFilter narrows List and Count. Zero-valued fields are ignored.
+type WaitlistFilter struct {
+	SocietyCode string
+	AmenityID   primitive.ObjectID
+	UserID      primitive.ObjectID
+	Statuses    []string
+	// OverlapsStart and OverlapsEnd select entries whose time range
+	// overlaps [start, end)
+	OverlapsStart time.Time
+	OverlapsEnd   time.Time
+	// OfferExpiredBefore selects entries whose offer expires before it
+	OfferExpiredBefore time.Time
+}
+
+// WaitlistUpdate carries the fields to change. Nil fields are left untouched.
+type WaitlistUpdate struct {
+	Status         *string
+	BookingID      *primitive.ObjectID
+	OfferedAt      *time.Time
+	OfferExpiresAt *time.Time
+}
+
+type WaitlistRepository interface {
+	Create(ctx context.Context, entry *models.WaitlistEntry) error
+	GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.WaitlistEntry, error)
+	// List returns matching entries in queue order, oldest first
+	List(ctx context.Context, filter WaitlistFilter) ([]models.WaitlistEntry, error)
+	Count(ctx context.Context, filter WaitlistFilter) (int64, error)
+	// Transition applies the update only while the entry is in the from
+	// status. It returns ErrConflict when the entry has moved on.
+	Transition(ctx context.Context, id primitive.ObjectID, from string, update WaitlistUpdate) error
+}
+
+func (f WaitlistFilter) toBSON() bson.M {
+	filter := bson.M{}
+	if f.SocietyCode != "" {
+		filter["society_code"] = f.SocietyCode
+	}
+	if !f.AmenityID.IsZero() {
+		filter["amenity_id"] = f.AmenityID
+	}
+	if !f.UserID.IsZero() {
+		filter["user_id"] = f.UserID
+	}
+	if len(f.Statuses) == 1 {
+		filter["status"] = f.Statuses[0]
+	} else if len(f.Statuses) > 1 {
+		filter["status"] = bson.M{"$in": f.Statuses}
+	}
+	if !f.OverlapsEnd.IsZero() {
+		filter["start_time"] = bson.M{"$lt": f.OverlapsEnd}
+	}
+	if !f.OverlapsStart.IsZero() {
+		filter["end_time"] = bson.M{"$gt": f.OverlapsStart}
+	}
+	if !f.OfferExpiredBefore.IsZero() {
+		filter["offer_expires_at"] = bson.M{"$lt": f.OfferExpiredBefore}
+	}
+	return filter
+}
+
+func (f WaitlistFilter) matches(e models.WaitlistEntry) bool {
+	if f.SocietyCode != "" && e.SocietyCode != f.SocietyCode {
+		return false
+	}
+	if !f.AmenityID.IsZero() && e.AmenityID != f.AmenityID {
+		return false
+	}
+	if !f.UserID.IsZero() && e.UserID != f.UserID {
+		return false
+	}
+	if len(f.Statuses) > 0 && !containsString(f.Statuses, e.Status) {
+		return false
+	}
+	if !f.OverlapsEnd.IsZero() && !e.StartTime.Before(f.OverlapsEnd) {
+		return false
+	}
+	if !f.OverlapsStart.IsZero() && !e.EndTime.After(f.OverlapsStart) {
+		return false
+	}
+	if !f.OfferExpiredBefore.IsZero() && (e.OfferExpiresAt == nil || !e.OfferExpiresAt.Before(f.OfferExpiredBefore)) {
+		return false
+	}
+	return true
+}
+
+func (u WaitlistUpdate) toBSON() bson.M {
+	set := bson.M{}
+	if u.Status != nil {
+		set["status"] = *u.Status
+	}
+	if u.BookingID != nil {
+		set["booking_id"] = *u.BookingID
+	}
+	if u.OfferedAt != nil {
+		set["offered_at"] = *u.OfferedAt
+	}
+	if u.OfferExpiresAt != nil {
+		set["offer_expires_at"] = *u.OfferExpiresAt
+	}
+	return bson.M{"$set": set}
+}
+
+func (u WaitlistUpdate) apply(e *models.WaitlistEntry) {
+	if u.Status != nil {
+		e.Status = *u.Status
+	}
+	if u.BookingID != nil {
+		bookingID := *u.BookingID
+		e.BookingID = &bookingID
+	}
+	if u.OfferedAt != nil {
+		offeredAt := *u.OfferedAt
+		e.OfferedAt = &offeredAt
+	}
+	if u.OfferExpiresAt != nil {
+		expiresAt := *u.OfferExpiresAt
+		e.OfferExpiresAt = &expiresAt
+	}
+}
+
+type mongoWaitlistRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoWaitlistRepository) Create(ctx context.Context, entry *models.WaitlistEntry) error {
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, entry)
+	return translateError(err)
+}
+
+func (r *mongoWaitlistRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.WaitlistEntry, error) {
+	var entry models.WaitlistEntry
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "society_code": societyCode}).Decode(&entry)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &entry, nil
+}
+
+func (r *mongoWaitlistRepository) List(ctx context.Context, filter WaitlistFilter) ([]models.WaitlistEntry, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter.toBSON(), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.WaitlistEntry
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *mongoWaitlistRepository) Count(ctx context.Context, filter WaitlistFilter) (int64, error) {
+	return r.collection.CountDocuments(ctx, filter.toBSON())
+}
+
+func (r *mongoWaitlistRepository) Transition(ctx context.Context, id primitive.ObjectID, from string, update WaitlistUpdate) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "status": from}, update.toBSON())
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		n, err := r.collection.CountDocuments(ctx, bson.M{"_id": id})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrNotFound
+		}
+		return ErrConflict
+	}
+	return nil
+}
+
+type memoryWaitlistRepository struct {
+	table *memoryTable[models.WaitlistEntry]
+}
+
+func newMemoryWaitlistRepository() *memoryWaitlistRepository {
+	return &memoryWaitlistRepository{table: newMemoryTable[models.WaitlistEntry]()}
+}
+
+func (r *memoryWaitlistRepository) Create(ctx context.Context, entry *models.WaitlistEntry) error {
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	return r.table.insert(entry.ID, *entry, nil)
+}
+
+func (r *memoryWaitlistRepository) GetByID(ctx context.Context, societyCode string, id primitive.ObjectID) (*models.WaitlistEntry, error) {
+	entry, err := r.table.find(func(e models.WaitlistEntry) bool {
+		return e.ID == id && e.SocietyCode == societyCode
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *memoryWaitlistRepository) List(ctx context.Context, filter WaitlistFilter) ([]models.WaitlistEntry, error) {
+	entries := r.table.filter(filter.matches)
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].ID.Hex() < entries[j].ID.Hex()
+		}
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	return entries, nil
+}
+
+func (r *memoryWaitlistRepository) Count(ctx context.Context, filter WaitlistFilter) (int64, error) {
+	return r.table.count(filter.matches), nil
+}
+
+func (r *memoryWaitlistRepository) Transition(ctx context.Context, id primitive.ObjectID, from string, update WaitlistUpdate) error {
+	conflict := false
+	err := r.table.update(id, nil, func(e *models.WaitlistEntry) {
+		if e.Status != from {
+			conflict = true
+			return
+		}
+		update.apply(e)
+	})
+	if err == nil && conflict {
+		return ErrConflict
+	}
+	return err
+}
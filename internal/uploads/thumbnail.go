@@ -0,0 +1,59 @@
+package uploads
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+
+	// Decoders for the image types uploads accept
+	_ "image/gif"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// ThumbnailSize bounds the longer side of a thumbnail, in pixels
+const ThumbnailSize = 320
+
+// maxPixels refuses to decode images that would take too much memory,
+// whatever their file size
+const maxPixels = 50_000_000
+
+// thumbnail scales an image down to fit ThumbnailSize, never up, and
+// encodes it as a JPEG on white. It also returns the original dimensions.
+func thumbnail(data []byte) ([]byte, int, int, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, 0, 0, image.ErrFormat
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	thumbWidth, thumbHeight := width, height
+	if width > ThumbnailSize || height > ThumbnailSize {
+		if width >= height {
+			thumbWidth, thumbHeight = ThumbnailSize, max(1, height*ThumbnailSize/width)
+		} else {
+			thumbWidth, thumbHeight = max(1, width*ThumbnailSize/height), ThumbnailSize
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, thumbWidth, thumbHeight))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, 0, 0, err
+	}
+	return buf.Bytes(), width, height, nil
+}
@@ -0,0 +1,190 @@
+package uploads
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/storage"
+	"bms-backend/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Upload kinds
+const (
+	KindVisitorPhoto    = "visitor_photo"
+	KindVisitorIDProof  = "visitor_id_proof"
+	KindAmenityImage    = "amenity_image"
+	KindAvatar          = "avatar"
+	KindParcelSignature = "parcel_signature"
+)
+
+var (
+	ErrTooLarge    = errors.New("uploads: file is too large")
+	ErrUnsupported = errors.New("uploads: file type is not allowed")
+	ErrNoThumbnail = errors.New("uploads: file has no thumbnail")
+)
+
+// extensions are the types each kind accepts, by sniffed content type
+var (
+	imageTypes = map[string]string{
+		"image/jpeg": ".jpg",
+		"image/png":  ".png",
+		"image/gif":  ".gif",
+		"image/webp": ".webp",
+	}
+	documentTypes = map[string]string{
+		"image/jpeg":      ".jpg",
+		"image/png":       ".png",
+		"image/webp":      ".webp",
+		"application/pdf": ".pdf",
+	}
+	extensions = map[string]map[string]string{
+		KindVisitorPhoto:    imageTypes,
+		KindVisitorIDProof:  documentTypes,
+		KindAmenityImage:    imageTypes,
+		KindAvatar:          imageTypes,
+		KindParcelSignature: imageTypes,
+	}
+)
+
+// Service keeps uploaded files in file storage and their records in the
+// store
+type Service struct {
+	store   *store.Store
+	storage storage.Storage
+	maxSize int64
+}
+
+func NewService(s *store.Store, files storage.Storage, maxSize int64) *Service {
+	return &Service{store: s, storage: files, maxSize: maxSize}
+}
+
+// MaxSize is the largest file Save accepts, in bytes
+func (s *Service) MaxSize() int64 {
+	return s.maxSize
+}
+
+// URL is where clients download an upload
+func URL(id primitive.ObjectID) string {
+	return "/api/v1/uploads/" + id.Hex()
+}
+
+// IDFromURL returns the upload a URL made by URL points at. Links set
+// before uploads existed are not uploads.
+func IDFromURL(url string) (primitive.ObjectID, bool) {
+	hex, ok := strings.CutPrefix(url, "/api/v1/uploads/")
+	if !ok {
+		return primitive.NilObjectID, false
+	}
+	id, err := primitive.ObjectIDFromHex(hex)
+	return id, err == nil
+}
+
+// Save checks and stores a file for upload, which carries the kind, owner,
+// file name, uploader and society. The content type is sniffed from the
+// file rather than trusted from the client. Images get a thumbnail; a
+// failure to make one is logged and the upload goes ahead without it.
+func (s *Service) Save(ctx context.Context, upload *models.Upload, file io.Reader) error {
+	data, err := io.ReadAll(io.LimitReader(file, s.maxSize+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > s.maxSize {
+		return ErrTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	extension, ok := extensions[upload.Kind][contentType]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupported, contentType)
+	}
+
+	upload.ID = primitive.NewObjectID()
+	upload.ContentType = contentType
+	upload.Size = int64(len(data))
+	upload.URL = URL(upload.ID)
+	upload.StorageKey = fmt.Sprintf("uploads/%s/%ss/%s%s", upload.SocietyCode, upload.Kind, upload.ID.Hex(), extension)
+	upload.CreatedAt = time.Now()
+	if err := s.storage.Put(ctx, upload.StorageKey, bytes.NewReader(data)); err != nil {
+		return err
+	}
+
+	if strings.HasPrefix(contentType, "image/") {
+		thumb, width, height, err := thumbnail(data)
+		if err != nil {
+			log.Printf("⚠️ Uploads: no thumbnail for %s: %v", upload.StorageKey, err)
+		} else {
+			key := fmt.Sprintf("uploads/%s/%ss/%s.thumb.jpg", upload.SocietyCode, upload.Kind, upload.ID.Hex())
+			if err := s.storage.Put(ctx, key, bytes.NewReader(thumb)); err != nil {
+				log.Printf("⚠️ Uploads: failed to store thumbnail %s: %v", key, err)
+			} else {
+				upload.ThumbnailKey = key
+				upload.ThumbnailURL = upload.URL + "/thumbnail"
+			}
+			upload.Width, upload.Height = width, height
+		}
+	}
+
+	if err := s.store.Uploads.Create(ctx, upload); err != nil {
+		s.removeFiles(ctx, upload)
+		return err
+	}
+	return nil
+}
+
+// Open returns the stored file, or its thumbnail, with its content type
+func (s *Service) Open(ctx context.Context, upload *models.Upload, thumb bool) (io.ReadCloser, string, error) {
+	if !thumb {
+		file, err := s.storage.Get(ctx, upload.StorageKey)
+		return file, upload.ContentType, err
+	}
+	if upload.ThumbnailKey == "" {
+		return nil, "", ErrNoThumbnail
+	}
+	file, err := s.storage.Get(ctx, upload.ThumbnailKey)
+	return file, "image/jpeg", err
+}
+
+// Remove deletes an upload and its files
+func (s *Service) Remove(ctx context.Context, upload *models.Upload) error {
+	if err := s.store.Uploads.Delete(ctx, upload.SocietyCode, upload.ID); err != nil {
+		return err
+	}
+	s.removeFiles(ctx, upload)
+	return nil
+}
+
+// Replaced removes the upload an old link pointed at once a new file has
+// taken its place. Failures are only logged: the new file is in use.
+func (s *Service) Replaced(ctx context.Context, societyCode, oldURL string) {
+	id, ok := IDFromURL(oldURL)
+	if !ok {
+		return
+	}
+	upload, err := s.store.Uploads.GetByID(ctx, societyCode, id)
+	if err == nil {
+		err = s.Remove(ctx, upload)
+	}
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		log.Printf("⚠️ Uploads: failed to remove replaced upload %s: %v", id.Hex(), err)
+	}
+}
+
+func (s *Service) removeFiles(ctx context.Context, upload *models.Upload) {
+	for _, key := range []string{upload.StorageKey, upload.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := s.storage.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("⚠️ Uploads: failed to remove %s: %v", key, err)
+		}
+	}
+}
@@ -2,11 +2,109 @@ package utils
 
 import (
 	"fmt"
-	"time"
+	"math"
+	"math/rand"
+	"strings"
+	"unicode"
 )
 
-func GenerateQRCode(visitorID, societyCode string) string {
-	// Generate a unique QR code based on visitor ID, society code and current time
-	timestamp := time.Now().Unix()
-	return fmt.Sprintf("BMS-%s-%s-%d", societyCode, visitorID, timestamp)
-}
\ No newline at end of file
+// BuildingCode derives a building's short name from its full name: the
+// first three letters of its last word, so "Tower A" is "A" and "North
+// Tower" is "TOW". Buildings whose names share a code must be given one.
+func BuildingCode(buildingName string) string {
+	words := strings.Fields(strings.ToUpper(buildingName))
+	if len(words) == 0 {
+		return "U"
+	}
+	code := words[len(words)-1]
+	if len(code) > 3 {
+		code = code[:3]
+	}
+	return code
+}
+
+// UnitNumber formats a flat number the way residents write it: the
+// building's code, the floor and a two-digit position, so the first unit
+// on floor 5 of building "A" is "A-501".
+func UnitNumber(buildingCode string, floor, position int) string {
+	return fmt.Sprintf("%s-%d%02d", buildingCode, floor, position)
+}
+
+// GenerateSocietyCode builds an access code in the same shape as the demo
+// codes (GREEN001, BLUE002): up to five letters from the society name
+// followed by three random digits. Callers must retry on collision.
+func GenerateSocietyCode(name string) string {
+	var prefix strings.Builder
+	for _, r := range strings.ToUpper(name) {
+		if prefix.Len() == 5 {
+			break
+		}
+		if r <= unicode.MaxASCII && unicode.IsLetter(r) {
+			prefix.WriteRune(r)
+		} else if prefix.Len() > 0 {
+			// Stop at the end of the first word
+			break
+		}
+	}
+	if prefix.Len() < 3 {
+		prefix.Reset()
+		prefix.WriteString("SOC")
+	}
+	return fmt.Sprintf("%s%03d", prefix.String(), rand.Intn(1000))
+}
+
+var (
+	smallNumberWords = []string{
+		"", "One", "Two", "Three", "Four", "Five", "Six", "Seven", "Eight", "Nine", "Ten",
+		"Eleven", "Twelve", "Thirteen", "Fourteen", "Fifteen", "Sixteen", "Seventeen", "Eighteen", "Nineteen",
+	}
+	tensWords = []string{"", "", "Twenty", "Thirty", "Forty", "Fifty", "Sixty", "Seventy", "Eighty", "Ninety"}
+)
+
+// AmountInWords spells out a rupee amount the way it is written on Indian
+// receipts, using lakh and crore: 125050.5 becomes "Rupees One Lakh Twenty
+// Five Thousand Fifty and Fifty Paise Only".
+func AmountInWords(amount float64) string {
+	paise := int64(math.Round(math.Abs(amount) * 100))
+	rupees, paise := paise/100, paise%100
+
+	words := "Zero"
+	if rupees > 0 {
+		words = indianNumberWords(rupees)
+	}
+	result := "Rupees " + words
+	if paise > 0 {
+		result += " and " + belowHundredWords(paise) + " Paise"
+	}
+	return result + " Only"
+}
+
+func indianNumberWords(n int64) string {
+	var parts []string
+	if crore := n / 10000000; crore > 0 {
+		parts = append(parts, indianNumberWords(crore)+" Crore")
+	}
+	if lakh := n / 100000 % 100; lakh > 0 {
+		parts = append(parts, belowHundredWords(lakh)+" Lakh")
+	}
+	if thousand := n / 1000 % 100; thousand > 0 {
+		parts = append(parts, belowHundredWords(thousand)+" Thousand")
+	}
+	if hundred := n / 100 % 10; hundred > 0 {
+		parts = append(parts, smallNumberWords[hundred]+" Hundred")
+	}
+	if rest := n % 100; rest > 0 {
+		parts = append(parts, belowHundredWords(rest))
+	}
+	return strings.Join(parts, " ")
+}
+
+func belowHundredWords(n int64) string {
+	if n < 20 {
+		return smallNumberWords[n]
+	}
+	if n%10 == 0 {
+		return tensWords[n/10]
+	}
+	return tensWords[n/10] + " " + smallNumberWords[n%10]
+}
@@ -0,0 +1,219 @@
+package visitors
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/store"
+
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Visitor log export formats
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// ExportTimeLayout is how times are written in visitor log exports
+const ExportTimeLayout = "2006-01-02 15:04"
+
+var ErrUnknownFormat = errors.New("visitors: export format must be csv or xlsx")
+
+// exportHeader names the columns of a visitor log export
+var exportHeader = []string{
+	"Visitor ID", "Name", "Phone", "Purpose", "Host", "Unit", "Status", "Walk-in", "Vehicle",
+	"Expected", "Arrived", "Departed", "Duration (min)", "Approved by",
+}
+
+// durationColumn is the index of "Duration (min)" in exportHeader
+const durationColumn = 12
+
+// csvFlushEvery is how many rows are buffered before a CSV export is
+// flushed to the client
+const csvFlushEvery = 100
+
+// Export writes the visitors matching filter to w as CSV or XLSX, in
+// expected time order with times on loc's clock. Visitors are read from the
+// store one at a time; CSV rows go out as they are read, while XLSX rows
+// are buffered by the sheet writer (on disk past a few thousand rows)
+// until the workbook is complete.
+func (s *Service) Export(ctx context.Context, w io.Writer, format string, filter store.VisitorFilter, loc *time.Location) error {
+	approvers := approverNames{store: s.store, names: map[primitive.ObjectID]string{}}
+	switch format {
+	case FormatCSV:
+		return s.exportCSV(ctx, w, filter, loc, &approvers)
+	case FormatXLSX:
+		return s.exportXLSX(ctx, w, filter, loc, &approvers)
+	}
+	return ErrUnknownFormat
+}
+
+func (s *Service) exportCSV(ctx context.Context, w io.Writer, filter store.VisitorFilter, loc *time.Location, approvers *approverNames) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(exportHeader); err != nil {
+		return err
+	}
+
+	rows := 0
+	err := s.store.Visitors.Each(ctx, filter, func(visitor *models.Visitor) error {
+		if err := out.Write(csvSafe(exportRow(ctx, visitor, loc, approvers))); err != nil {
+			return err
+		}
+		rows++
+		if rows%csvFlushEvery == 0 {
+			return flush(out, w)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return flush(out, w)
+}
+
+// csvSafe stops spreadsheets from running fields as formulas. Names,
+// purposes, phones and vehicles are typed in by residents and at the gate,
+// so a field starting with a formula character is prefixed with a quote,
+// which Excel reads as "this is text". XLSX cells are stored as text and
+// need no escaping.
+func csvSafe(fields []string) []string {
+	for i, field := range fields {
+		if field != "" && strings.ContainsRune("=+-@\t\r", rune(field[0])) {
+			fields[i] = "'" + field
+		}
+	}
+	return fields
+}
+
+func (s *Service) exportXLSX(ctx context.Context, w io.Writer, filter store.VisitorFilter, loc *time.Location, approvers *approverNames) error {
+	file := excelize.NewFile()
+	defer file.Close()
+
+	const sheet = "Visitors"
+	if err := file.SetSheetName("Sheet1", sheet); err != nil {
+		return err
+	}
+	sheetWriter, err := file.NewStreamWriter(sheet)
+	if err != nil {
+		return err
+	}
+	bold, err := file.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+
+	header := make([]interface{}, len(exportHeader))
+	for i, title := range exportHeader {
+		header[i] = excelize.Cell{StyleID: bold, Value: title}
+	}
+	if err := sheetWriter.SetRow("A1", header, excelize.RowOpts{}); err != nil {
+		return err
+	}
+
+	row := 1
+	err = s.store.Visitors.Each(ctx, filter, func(visitor *models.Visitor) error {
+		row++
+		fields := exportRow(ctx, visitor, loc, approvers)
+		values := make([]interface{}, len(fields))
+		for i, field := range fields {
+			values[i] = field
+		}
+		// Durations go in as numbers so the sheet can sum them
+		if minutes, err := strconv.Atoi(fields[durationColumn]); err == nil {
+			values[durationColumn] = minutes
+		}
+		cell, err := excelize.CoordinatesToCellName(1, row)
+		if err != nil {
+			return err
+		}
+		return sheetWriter.SetRow(cell, values)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := sheetWriter.Flush(); err != nil {
+		return err
+	}
+	return file.Write(w)
+}
+
+// exportRow is the visitor's line of the export, in exportHeader order
+func exportRow(ctx context.Context, visitor *models.Visitor, loc *time.Location, approvers *approverNames) []string {
+	walkIn := "no"
+	if visitor.WalkIn {
+		walkIn = "yes"
+	}
+	duration := ""
+	if visitor.ActualArrival != nil && visitor.ActualDeparture != nil {
+		duration = strconv.Itoa(int(visitor.ActualDeparture.Sub(*visitor.ActualArrival).Round(time.Minute).Minutes()))
+	}
+
+	return []string{
+		visitor.ID.Hex(),
+		visitor.Name,
+		visitor.Phone,
+		visitor.Purpose,
+		visitor.HostName,
+		visitor.HostUnit,
+		visitor.Status,
+		walkIn,
+		visitor.VehicleNumber,
+		visitor.ExpectedTime.In(loc).Format(ExportTimeLayout),
+		formatTime(visitor.ActualArrival, loc),
+		formatTime(visitor.ActualDeparture, loc),
+		duration,
+		approvers.name(ctx, visitor.SocietyCode, visitor.ApprovedBy),
+	}
+}
+
+func formatTime(t *time.Time, loc *time.Location) string {
+	if t == nil {
+		return ""
+	}
+	return t.In(loc).Format(ExportTimeLayout)
+}
+
+// flush sends the CSV written so far on to the client
+func flush(out *csv.Writer, w io.Writer) error {
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return err
+	}
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// approverNames looks up who approved visitors, once per user per export
+type approverNames struct {
+	store *store.Store
+	names map[primitive.ObjectID]string
+}
+
+// name is the approver's name, or empty if nobody approved the visitor or
+// the user no longer exists
+func (a *approverNames) name(ctx context.Context, societyCode string, id *primitive.ObjectID) string {
+	if id == nil {
+		return ""
+	}
+	if name, ok := a.names[*id]; ok {
+		return name
+	}
+	name := ""
+	if user, err := a.store.Users.GetByID(ctx, societyCode, *id); err == nil {
+		name = user.Name
+	}
+	a.names[*id] = name
+	return name
+}
@@ -0,0 +1,63 @@
+package visitors
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Scheduler escalates walk-ins the host unit has not answered in time, so
+// visitors are not left waiting at the gate. It also expires approved
+// visitors who never arrived and flags checked-in visitors who stay too
+// long.
+type Scheduler struct {
+	service  *Service
+	interval time.Duration
+}
+
+func NewScheduler(service *Service, interval time.Duration) *Scheduler {
+	return &Scheduler{service: service, interval: interval}
+}
+
+// Start runs a pass immediately and then on every tick until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.RunOnce(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce performs one escalation, no-show and overstay pass
+func (s *Scheduler) RunOnce(ctx context.Context, now time.Time) {
+	escalated, err := s.service.EscalateOverdue(ctx, now)
+	if err != nil {
+		log.Printf("⚠️ Walk-in: failed to escalate approvals: %v", err)
+	}
+	if escalated > 0 {
+		log.Printf("⏳ Walk-in: %d approvals escalated to secretaries", escalated)
+	}
+
+	expired, err := s.service.ExpireNoShows(ctx, now)
+	if err != nil {
+		log.Printf("⚠️ Visitors: failed to expire no-shows: %v", err)
+	}
+	if expired > 0 {
+		log.Printf("⌛ Visitors: %d no-shows expired", expired)
+	}
+
+	flagged, err := s.service.FlagOverstays(ctx, now)
+	if err != nil {
+		log.Printf("⚠️ Visitors: failed to flag overstays: %v", err)
+	}
+	if flagged > 0 {
+		log.Printf("🕒 Visitors: %d overstaying visitors reported", flagged)
+	}
+}
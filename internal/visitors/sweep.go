@@ -0,0 +1,146 @@
+package visitors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"bms-backend/internal/amenities"
+	"bms-backend/internal/models"
+	"bms-backend/internal/notifications"
+	"bms-backend/internal/store"
+)
+
+// Notification kinds sent by the no-show and overstay sweeps
+const (
+	KindNoShow   = "visitor_no_show"
+	KindOverstay = "visitor_overstay"
+)
+
+// ExpireNoShows expires approved visitors who have not arrived by
+// noShowAfter past their expected time, so their passes stop working, and
+// tells the host and security. It returns how many were expired.
+func (s *Service) ExpireNoShows(ctx context.Context, now time.Time) (int, error) {
+	due, err := s.store.Visitors.List(ctx, store.VisitorFilter{
+		Statuses:       []string{StatusApproved},
+		ExpectedBefore: now.Add(-s.noShowAfter),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for i := range due {
+		visitor := &due[i]
+		reason := fmt.Sprintf("Did not arrive within %s of the expected time", hours(s.noShowAfter))
+		err := s.Transition(ctx, visitor, StatusExpired, System, reason, store.VisitorUpdate{}, now)
+		var illegal *TransitionError
+		if errors.As(err, &illegal) {
+			// Checked in or cancelled since the list was read
+			continue
+		}
+		if err != nil {
+			log.Printf("⚠️ Visitors: failed to expire visitor %s: %v", visitor.ID.Hex(), err)
+			continue
+		}
+
+		msg := notifications.Message{
+			Kind:        KindNoShow,
+			Title:       visitor.Name + " did not show up",
+			Body:        fmt.Sprintf("%s, expected at %s at %s, did not arrive. Their pass no longer works.", visitor.Name, visitor.HostUnit, local(visitor.ExpectedTime).Format("02 Jan 15:04")),
+			ReferenceID: visitor.ID,
+		}
+		s.notify(ctx, visitor.SocietyCode, visitor.HostID, msg)
+		s.notifySecurity(ctx, visitor.SocietyCode, msg)
+		expired++
+	}
+	return expired, nil
+}
+
+// FlagOverstays flags checked-in visitors who have stayed longer than
+// stayLimit and tells the host and security, once per visit. It returns
+// how many were flagged.
+func (s *Service) FlagOverstays(ctx context.Context, now time.Time) (int, error) {
+	due, err := s.store.Visitors.List(ctx, store.VisitorFilter{
+		Statuses:      []string{StatusCheckedIn},
+		ArrivedBefore: now.Add(-s.stayLimit),
+		UnflaggedOnly: true,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	flagged := 0
+	for i := range due {
+		visitor := &due[i]
+		err := s.store.Visitors.Update(ctx, visitor.SocietyCode, visitor.ID, store.VisitorUpdate{OverstayedAt: &now})
+		if err != nil {
+			log.Printf("⚠️ Visitors: failed to flag visitor %s: %v", visitor.ID.Hex(), err)
+			continue
+		}
+
+		msg := notifications.Message{
+			Kind:  KindOverstay,
+			Title: visitor.Name + " has not checked out",
+			Body: fmt.Sprintf("%s checked in to visit %s at %s and is still inside after %s.",
+				visitor.Name, visitor.HostUnit, local(*visitor.ActualArrival).Format("15:04"), hours(now.Sub(*visitor.ActualArrival))),
+			ReferenceID: visitor.ID,
+		}
+		s.notify(ctx, visitor.SocietyCode, visitor.HostID, msg)
+		s.notifySecurity(ctx, visitor.SocietyCode, msg)
+		flagged++
+	}
+	return flagged, nil
+}
+
+// Overstaying lists the society's checked-in visitors who have stayed
+// longer than the limit, longest stay first
+func (s *Service) Overstaying(ctx context.Context, societyCode string, now time.Time) ([]models.Visitor, error) {
+	visitors, err := s.store.Visitors.List(ctx, store.VisitorFilter{
+		SocietyCode:   societyCode,
+		Statuses:      []string{StatusCheckedIn},
+		ArrivedBefore: now.Add(-s.stayLimit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(visitors, func(i, j int) bool { return visitors[i].ActualArrival.Before(*visitors[j].ActualArrival) })
+	return visitors, nil
+}
+
+// notifySecurity tells every active guard of the society
+func (s *Service) notifySecurity(ctx context.Context, societyCode string, msg notifications.Message) {
+	guards, err := s.store.Users.List(ctx, store.UserFilter{
+		SocietyCode: societyCode,
+		Roles:       []string{"security"},
+		ActiveOnly:  true,
+	})
+	if err != nil {
+		log.Printf("⚠️ Visitors: failed to load security of %s: %v", societyCode, err)
+		return
+	}
+	for _, guard := range guards {
+		s.notify(ctx, societyCode, guard.ID, msg)
+	}
+}
+
+// local is t on the society's clock, for notification text
+func local(t time.Time) time.Time {
+	loc, err := time.LoadLocation(amenities.DefaultTimezone)
+	if err != nil {
+		return t
+	}
+	return t.In(loc)
+}
+
+// hours writes a duration as whole hours and minutes, e.g. "4h 30m"
+func hours(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+}
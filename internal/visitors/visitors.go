@@ -0,0 +1,131 @@
+package visitors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/notifications"
+	"bms-backend/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Visitor statuses
+const (
+	StatusPending   = "pending"
+	StatusApproved  = "approved"
+	StatusRejected  = "rejected"
+	StatusCheckedIn = "checked_in"
+	StatusCompleted = "completed"
+	StatusExpired   = "expired"
+	StatusCancelled = "cancelled"
+)
+
+// transitions lists where each status can move next. Rejected, completed,
+// expired and cancelled visitors are final.
+var transitions = map[string][]string{
+	StatusPending:   {StatusApproved, StatusRejected, StatusCancelled, StatusExpired},
+	StatusApproved:  {StatusCheckedIn, StatusCancelled, StatusExpired},
+	StatusCheckedIn: {StatusCompleted},
+}
+
+// TransitionError is a status change the state machine does not allow
+type TransitionError struct {
+	From string
+	To   string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("visitors: a %s visitor cannot become %s", e.From, e.To)
+}
+
+// CanTransition reports whether a visitor may move from one status to the
+// other
+func CanTransition(from, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Actor is who makes a status change. A zero ID means the system did.
+type Actor struct {
+	ID   primitive.ObjectID
+	Role string
+}
+
+// System is the actor for automatic changes
+var System = Actor{Role: "system"}
+
+// Service moves visitors through their statuses and runs the host
+// approval of walk-ins
+type Service struct {
+	store    *store.Store
+	notifier *notifications.Service
+	// approvalTimeout is how long the host unit has to answer for a walk-in
+	// before it is escalated
+	approvalTimeout time.Duration
+	// noShowAfter is how long after the expected time an approved visitor
+	// is expired; stayLimit how long a checked-in visitor may stay
+	noShowAfter time.Duration
+	stayLimit   time.Duration
+}
+
+func NewService(s *store.Store, notifier *notifications.Service, approvalTimeout, noShowAfter, stayLimit time.Duration) *Service {
+	return &Service{
+		store:           s,
+		notifier:        notifier,
+		approvalTimeout: approvalTimeout,
+		noShowAfter:     noShowAfter,
+		stayLimit:       stayLimit,
+	}
+}
+
+// Created is the first history entry of a new visitor
+func Created(actor Actor, now time.Time) models.VisitorStatusChange {
+	return change(actor, "", StatusPending, "", now)
+}
+
+// Transition moves the visitor to status to, applying update alongside and
+// recording the change in its history. An illegal change, including one
+// that lost a race with another change, is a *TransitionError. On success
+// the visitor is updated in place.
+func (s *Service) Transition(ctx context.Context, visitor *models.Visitor, to string, actor Actor, reason string, update store.VisitorUpdate, now time.Time) error {
+	if !CanTransition(visitor.Status, to) {
+		return &TransitionError{From: visitor.Status, To: to}
+	}
+
+	event := change(actor, visitor.Status, to, reason, now)
+	update.Status = &to
+	update.Event = &event
+	err := s.store.Visitors.Transition(ctx, visitor.SocietyCode, visitor.ID, []string{visitor.Status}, update)
+	if errors.Is(err, store.ErrConflict) {
+		current, getErr := s.store.Visitors.GetByID(ctx, visitor.SocietyCode, visitor.ID)
+		if getErr != nil {
+			return getErr
+		}
+		return &TransitionError{From: current.Status, To: to}
+	}
+	if err != nil {
+		return err
+	}
+
+	visitor.Status = to
+	visitor.History = append(visitor.History, event)
+	visitor.UpdatedAt = now
+	return nil
+}
+
+func change(actor Actor, from, to, reason string, now time.Time) models.VisitorStatusChange {
+	event := models.VisitorStatusChange{From: from, To: to, ByRole: actor.Role, Reason: reason, At: now}
+	if !actor.ID.IsZero() {
+		id := actor.ID
+		event.ByID = &id
+	}
+	return event
+}
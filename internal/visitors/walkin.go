@@ -0,0 +1,224 @@
+package visitors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"bms-backend/internal/models"
+	"bms-backend/internal/notifications"
+	"bms-backend/internal/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Notification kinds sent about walk-ins
+const (
+	KindApprovalRequest = "visitor_approval_request"
+	KindEscalated       = "visitor_approval_escalated"
+	KindDecision        = "visitor_decision"
+)
+
+var (
+	ErrNotHost     = errors.New("visitors: only residents of the host unit can answer")
+	ErrNotAwaiting = errors.New("visitors: visitor is not waiting for host approval")
+)
+
+// RegisterWalkIn stores a visitor registered at the gate for unit and asks
+// the unit's residents to approve. The visitor must have its ID, details
+// and pass set. A unit without residents goes straight to the secretaries.
+func (s *Service) RegisterWalkIn(ctx context.Context, visitor *models.Visitor, unit *models.Unit, guard Actor, now time.Time) error {
+	residents, err := s.HostResidents(ctx, visitor.SocietyCode, unit.ID)
+	if err != nil {
+		return err
+	}
+
+	unitID := unit.ID
+	visitor.WalkIn = true
+	visitor.HostUnitID = &unitID
+	visitor.HostUnit = unit.Number
+	if host := primaryHost(unit, residents); host != nil {
+		visitor.HostID = host.ID
+		visitor.HostName = host.Name
+	}
+	visitor.Status = StatusPending
+	visitor.History = []models.VisitorStatusChange{Created(guard, now)}
+	visitor.HostApproval = &models.HostApproval{
+		RequestedBy: guard.ID,
+		RequestedAt: now,
+		ExpiresAt:   now.Add(s.approvalTimeout),
+		NotifiedIDs: []primitive.ObjectID{},
+	}
+	for _, resident := range residents {
+		visitor.HostApproval.NotifiedIDs = append(visitor.HostApproval.NotifiedIDs, resident.ID)
+	}
+	if len(residents) == 0 {
+		visitor.HostApproval.EscalatedAt = &now
+	}
+	visitor.CreatedAt = now
+	visitor.UpdatedAt = now
+
+	if err := s.store.Visitors.Create(ctx, visitor); err != nil {
+		return err
+	}
+
+	if len(residents) == 0 {
+		s.notifyEscalation(ctx, visitor, "Nobody is registered at "+unit.Number)
+		return nil
+	}
+	for _, resident := range residents {
+		s.notify(ctx, visitor.SocietyCode, resident.ID, notifications.Message{
+			Kind:        KindApprovalRequest,
+			Title:       visitor.Name + " is at the gate",
+			Body:        fmt.Sprintf("%s is at the gate to see %s (%s). Approve or deny within %d minutes.", visitor.Name, unit.Number, visitor.Purpose, int(s.approvalTimeout.Minutes())),
+			ReferenceID: visitor.ID,
+		})
+	}
+	return nil
+}
+
+// Respond records a host resident's answer to a walk-in, approved or
+// rejected, and tells the guard who asked
+func (s *Service) Respond(ctx context.Context, visitor *models.Visitor, resident *models.User, status, reason string, now time.Time) error {
+	if !visitor.WalkIn || visitor.HostApproval == nil {
+		return ErrNotAwaiting
+	}
+	if resident.UnitID == nil || visitor.HostUnitID == nil || *resident.UnitID != *visitor.HostUnitID {
+		return ErrNotHost
+	}
+
+	update := store.VisitorUpdate{}
+	if status == StatusApproved {
+		update.ApprovedBy = &resident.ID
+	}
+	by := Actor{ID: resident.ID, Role: resident.Role}
+	if err := s.Transition(ctx, visitor, status, by, reason, update, now); err != nil {
+		return err
+	}
+	if status == StatusApproved {
+		visitor.ApprovedBy = &resident.ID
+	}
+
+	s.notify(ctx, visitor.SocietyCode, visitor.HostApproval.RequestedBy, notifications.Message{
+		Kind:        KindDecision,
+		Title:       fmt.Sprintf("%s %s %s", resident.Name, status, visitor.Name),
+		Body:        fmt.Sprintf("%s of %s %s the visitor %s.", resident.Name, visitor.HostUnit, status, visitor.Name),
+		ReferenceID: visitor.ID,
+	})
+	return nil
+}
+
+// EscalateOverdue hands walk-ins the host unit did not answer in time to
+// the secretaries and tells the guard to call the host. It returns how
+// many were escalated.
+func (s *Service) EscalateOverdue(ctx context.Context, now time.Time) (int, error) {
+	overdue, err := s.store.Visitors.List(ctx, store.VisitorFilter{
+		Statuses:          []string{StatusPending},
+		HostApprovalDueBy: now,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	escalated := 0
+	for i := range overdue {
+		visitor := &overdue[i]
+		err := s.store.Visitors.Escalate(ctx, visitor.SocietyCode, visitor.ID, now)
+		if errors.Is(err, store.ErrConflict) {
+			// The host answered, or another run escalated it, meanwhile
+			continue
+		}
+		if err != nil {
+			log.Printf("⚠️ Walk-in: failed to escalate visitor %s: %v", visitor.ID.Hex(), err)
+			continue
+		}
+		s.notifyEscalation(ctx, visitor, visitor.HostUnit+" did not answer in time")
+		escalated++
+	}
+	return escalated, nil
+}
+
+// HostResidents are the active residents living in the unit
+func (s *Service) HostResidents(ctx context.Context, societyCode string, unitID primitive.ObjectID) ([]models.User, error) {
+	return s.store.Users.List(ctx, store.UserFilter{
+		SocietyCode: societyCode,
+		Roles:       []string{"resident"},
+		ActiveOnly:  true,
+		UnitID:      unitID,
+	})
+}
+
+// notifyEscalation asks the secretaries to decide and the guard to call
+// the host
+func (s *Service) notifyEscalation(ctx context.Context, visitor *models.Visitor, why string) {
+	contacts := "no phone numbers on file"
+	if visitor.HostUnitID != nil {
+		residents, err := s.HostResidents(ctx, visitor.SocietyCode, *visitor.HostUnitID)
+		if err != nil {
+			log.Printf("⚠️ Walk-in: failed to load host contacts for visitor %s: %v", visitor.ID.Hex(), err)
+		}
+		var phones []string
+		for _, resident := range residents {
+			if resident.Phone != "" {
+				phones = append(phones, resident.Name+" "+resident.Phone)
+			}
+		}
+		if len(phones) > 0 {
+			contacts = strings.Join(phones, ", ")
+		}
+	}
+
+	secretaries, err := s.store.Users.List(ctx, store.UserFilter{
+		SocietyCode: visitor.SocietyCode,
+		Roles:       []string{"secretary"},
+		ActiveOnly:  true,
+	})
+	if err != nil {
+		log.Printf("⚠️ Walk-in: failed to load secretaries for visitor %s: %v", visitor.ID.Hex(), err)
+	}
+	for _, secretary := range secretaries {
+		s.notify(ctx, visitor.SocietyCode, secretary.ID, notifications.Message{
+			Kind:        KindEscalated,
+			Title:       visitor.Name + " is waiting at the gate",
+			Body:        fmt.Sprintf("%s. %s is waiting to see %s (%s); please approve or deny.", why, visitor.Name, visitor.HostUnit, visitor.Purpose),
+			ReferenceID: visitor.ID,
+		})
+	}
+	s.notify(ctx, visitor.SocietyCode, visitor.HostApproval.RequestedBy, notifications.Message{
+		Kind:        KindEscalated,
+		Title:       "Call the host of " + visitor.Name,
+		Body:        fmt.Sprintf("%s. Call the host (%s) or wait for the secretary.", why, contacts),
+		ReferenceID: visitor.ID,
+	})
+}
+
+func (s *Service) notify(ctx context.Context, societyCode string, userID primitive.ObjectID, msg notifications.Message) {
+	if userID.IsZero() {
+		return
+	}
+	if err := s.notifier.Notify(ctx, societyCode, userID, msg); err != nil {
+		log.Printf("⚠️ Visitors: failed to notify user %s: %v", userID.Hex(), err)
+	}
+}
+
+// primaryHost picks who a walk-in is filed under: the tenant if the unit
+// is let, else the owner, else any resident
+func primaryHost(unit *models.Unit, residents []models.User) *models.User {
+	for _, id := range []*primitive.ObjectID{unit.TenantID, unit.OwnerID} {
+		if id == nil {
+			continue
+		}
+		for i := range residents {
+			if residents[i].ID == *id {
+				return &residents[i]
+			}
+		}
+	}
+	if len(residents) > 0 {
+		return &residents[0]
+	}
+	return nil
+}
@@ -8,7 +8,11 @@ import (
 	"bms-backend/internal/config"
 	"bms-backend/internal/database"
 	"bms-backend/internal/models"
+	"bms-backend/internal/passes"
+	"bms-backend/internal/utils"
+	"bms-backend/internal/visitors"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
@@ -26,22 +30,26 @@ func main() {
 
 	// Clear existing data
 	log.Println("🧹 Clearing existing data...")
-	collections := []string{"societies", "users", "visitors", "maintenance", "amenities", "amenity_bookings", "notices"}
+	collections := []string{"societies", "users", "units", "visitors", "maintenance", "billing_plans", "late_fee_rules", "maintenance_penalties", "amenities", "amenity_bookings", "amenity_booking_series", "amenity_reservations", "amenity_waitlist", "calendar_feeds", "staff", "staff_attendance", "parcels", "blacklist", "vehicles", "parking_slots", "vehicle_logs", "uploads", "notices", "notifications", "payments", "ledger_entries", "billing_documents", "counters"}
 	for _, collName := range collections {
 		db.Collection(collName).Drop(context.Background())
 	}
 
+	signer := passes.NewSigner(cfg.VisitorPassSecret, cfg.VisitorPassEarly, cfg.VisitorPassLate)
+
 	// Seed societies first
 	societies := seedSocieties(db)
+	seedPlatformAdmin(db)
 
 	// Seed data for each society
 	for _, society := range societies {
 		log.Printf("🏢 Creating data for society: %s (%s)", society.Name, society.Code)
 		users := seedUsersForSociety(db, society)
+		seedUnitsForSociety(db, society, users)
 		seedAmenitiesForSociety(db, society)
 		seedMaintenanceForSociety(db, society, users)
 		seedNoticesForSociety(db, society, users)
-		seedVisitorsForSociety(db, society, users)
+		seedVisitorsForSociety(db, society, users, signer)
 	}
 
 	log.Println("✅ Multi-society database seeding completed!")
@@ -55,6 +63,29 @@ func main() {
 	log.Println("   Secretary: admin@bluehills.com / demo123")
 	log.Println("   Resident: resident@bluehills.com / demo123")
 	log.Println("   Security: guard@bluehills.com / demo123")
+	log.Println("")
+	log.Println("Platform admin: admin@platform.com / demo123")
+}
+
+func seedPlatformAdmin(db *mongo.Database) {
+	// Platform admins live outside any society, hence the empty society code
+	admin := models.User{
+		ID:        primitive.NewObjectID(),
+		Name:      "Platform Admin",
+		Email:     "admin@platform.com",
+		Password:  hashPassword("demo123"),
+		Role:      "platform_admin",
+		IsActive:  true,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	_, err := db.Collection("users").InsertOne(context.Background(), admin)
+	if err != nil {
+		log.Printf("Error inserting platform admin: %v", err)
+	} else {
+		log.Printf("✓ Created platform admin: %s", admin.Email)
+	}
 }
 
 func seedSocieties(db *mongo.Database) []models.Society {
@@ -75,12 +106,14 @@ func seedSocieties(db *mongo.Database) []models.Society {
 				{
 					ID:            primitive.NewObjectID(),
 					Name:          "Tower A",
+					Code:          "A",
 					Floors:        20,
 					UnitsPerFloor: 4,
 				},
 				{
 					ID:            primitive.NewObjectID(),
 					Name:          "Tower B",
+					Code:          "B",
 					Floors:        15,
 					UnitsPerFloor: 6,
 				},
@@ -103,12 +136,14 @@ func seedSocieties(db *mongo.Database) []models.Society {
 				{
 					ID:            primitive.NewObjectID(),
 					Name:          "Block A",
+					Code:          "A",
 					Floors:        10,
 					UnitsPerFloor: 8,
 				},
 				{
 					ID:            primitive.NewObjectID(),
 					Name:          "Block B",
+					Code:          "B",
 					Floors:        12,
 					UnitsPerFloor: 6,
 				},
@@ -131,6 +166,7 @@ func seedSocieties(db *mongo.Database) []models.Society {
 				{
 					ID:            primitive.NewObjectID(),
 					Name:          "Wing A",
+					Code:          "A",
 					Floors:        8,
 					UnitsPerFloor: 10,
 				},
@@ -290,9 +326,80 @@ func seedUsersForSociety(db *mongo.Database, society models.Society) map[string]
 	return userMap
 }
 
+// seedUnitsForSociety generates every unit of every building and makes the
+// demo users owners of the unit named on their profile.
+func seedUnitsForSociety(db *mongo.Database, society models.Society, users map[string]models.User) {
+	collection := db.Collection("units")
+
+	owners := make(map[string]models.User)
+	for _, user := range users {
+		owners[user.Unit] = user
+	}
+
+	var units []interface{}
+	for _, building := range society.Buildings {
+		for floor := 1; floor <= building.Floors; floor++ {
+			for position := 1; position <= building.UnitsPerFloor; position++ {
+				unit := models.Unit{
+					ID:           primitive.NewObjectID(),
+					Number:       utils.UnitNumber(building.Code, floor, position),
+					BuildingID:   building.ID,
+					BuildingName: building.Name,
+					Floor:        floor,
+					AreaSqFt:     1000,
+					Occupancy:    []models.UnitOccupancy{},
+					SocietyID:    society.ID,
+					SocietyCode:  society.Code,
+					CreatedAt:    time.Now(),
+					UpdatedAt:    time.Now(),
+				}
+
+				if owner, ok := owners[unit.Number]; ok {
+					ownerID := owner.ID
+					unit.OwnerID = &ownerID
+					unit.Occupancy = append(unit.Occupancy, models.UnitOccupancy{
+						UserID:   owner.ID,
+						UserName: owner.Name,
+						Role:     "owner",
+						MovedIn:  time.Now(),
+					})
+
+					unitID := unit.ID
+					owner.UnitID = &unitID
+					users[owner.Role] = owner
+					db.Collection("users").UpdateOne(context.Background(),
+						bson.M{"_id": owner.ID},
+						bson.M{"$set": bson.M{"unit_id": unit.ID}})
+				}
+
+				units = append(units, unit)
+			}
+		}
+	}
+
+	if _, err := collection.InsertMany(context.Background(), units); err != nil {
+		log.Printf("Error inserting units for %s: %v", society.Code, err)
+	} else {
+		log.Printf("✓ Created %d units for %s", len(units), society.Code)
+	}
+}
+
 func seedAmenitiesForSociety(db *mongo.Database, society models.Society) {
 	collection := db.Collection("amenities")
 
+	// Events need notice; fees are refunded in full a week ahead, half
+	// three days ahead
+	hallPolicy := &models.BookingPolicy{
+		MaxPerUnitPerWeek: 1,
+		MinAdvanceDays:    2,
+		MaxAdvanceDays:    60,
+		CancelCutoffHours: 24,
+		RefundTiers: []models.RefundTier{
+			{HoursBefore: 168, Percent: 100},
+			{HoursBefore: 72, Percent: 50},
+		},
+	}
+
 	amenities := []models.Amenity{
 		{
 			ID:             primitive.NewObjectID(),
@@ -302,6 +409,8 @@ func seedAmenitiesForSociety(db *mongo.Database, society models.Society) {
 			Capacity:       100,
 			Facilities:     []string{"AC", "Sound System", "Projector"},
 			AvailableHours: "6:00 AM - 11:00 PM",
+			Schedule:       weeklySchedule("06:00", "23:00", 240, 60),
+			Policy:         hallPolicy,
 			SocietyID:      society.ID,
 			SocietyCode:    society.Code,
 			IsActive:       true,
@@ -314,6 +423,8 @@ func seedAmenitiesForSociety(db *mongo.Database, society models.Society) {
 			Capacity:       20,
 			Facilities:     []string{"Changing Rooms", "Towels"},
 			AvailableHours: "5:00 AM - 10:00 PM",
+			Schedule:       weeklySchedule("05:00", "22:00", 60, 0),
+			Policy:         &models.BookingPolicy{MaxPerUnitPerWeek: 5, MaxAdvanceDays: 7, CancelCutoffHours: 2},
 			SocietyID:      society.ID,
 			SocietyCode:    society.Code,
 			IsActive:       true,
@@ -330,19 +441,32 @@ func seedAmenitiesForSociety(db *mongo.Database, society models.Society) {
 	}
 }
 
+// weeklySchedule opens an amenity at the same hours every day
+func weeklySchedule(open, close string, slotMinutes, bufferMinutes int) *models.AmenitySchedule {
+	schedule := &models.AmenitySchedule{
+		Timezone:      "Asia/Kolkata",
+		SlotMinutes:   slotMinutes,
+		BufferMinutes: bufferMinutes,
+	}
+	for _, day := range []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"} {
+		schedule.Hours = append(schedule.Hours, models.OpeningHours{Weekday: day, Open: open, Close: close})
+	}
+	return schedule
+}
+
 func seedMaintenanceForSociety(db *mongo.Database, society models.Society, users map[string]models.User) {
 	collection := db.Collection("maintenance")
 
 	resident, exists := users["resident"]
-	if !exists {
-		log.Printf("No resident found for maintenance records in %s", society.Code)
+	if !exists || resident.UnitID == nil {
+		log.Printf("No resident unit found for maintenance records in %s", society.Code)
 		return
 	}
 
 	records := []models.MaintenanceRecord{
 		{
 			ID:          primitive.NewObjectID(),
-			UnitID:      resident.ID,
+			UnitID:      *resident.UnitID,
 			UnitNumber:  resident.Unit,
 			Amount:      2500.0,
 			Month:       "October 2025",
@@ -399,7 +523,7 @@ func seedNoticesForSociety(db *mongo.Database, society models.Society, users map
 	}
 }
 
-func seedVisitorsForSociety(db *mongo.Database, society models.Society, users map[string]models.User) {
+func seedVisitorsForSociety(db *mongo.Database, society models.Society, users map[string]models.User, signer *passes.Signer) {
 	collection := db.Collection("visitors")
 
 	resident, exists := users["resident"]
@@ -408,7 +532,7 @@ func seedVisitorsForSociety(db *mongo.Database, society models.Society, users ma
 		return
 	}
 
-	visitors := []models.Visitor{
+	visitorList := []models.Visitor{
 		{
 			ID:           primitive.NewObjectID(),
 			Name:         "John Doe",
@@ -417,9 +541,10 @@ func seedVisitorsForSociety(db *mongo.Database, society models.Society, users ma
 			HostID:       resident.ID,
 			HostName:     resident.Name,
 			HostUnit:     resident.Unit,
+			HostUnitID:   resident.UnitID,
 			ExpectedTime: time.Now().Add(2 * time.Hour),
-			QRCode:       "BMS-" + society.Code + "-visitor-001",
-			Status:       "pending",
+			Status:       visitors.StatusPending,
+			History:      []models.VisitorStatusChange{visitors.Created(visitors.Actor{ID: resident.ID, Role: "resident"}, time.Now())},
 			SocietyID:    society.ID,
 			SocietyCode:  society.Code,
 			CreatedAt:    time.Now(),
@@ -427,12 +552,19 @@ func seedVisitorsForSociety(db *mongo.Database, society models.Society, users ma
 		},
 	}
 
-	for _, visitor := range visitors {
-		_, err := collection.InsertOne(context.Background(), visitor)
+	for _, visitor := range visitorList {
+		token, claims, err := signer.Issue(&visitor)
+		if err != nil {
+			log.Printf("Error issuing visitor pass: %v", err)
+			continue
+		}
+		visitor.QRCode, visitor.PassValidFrom, visitor.PassExpiresAt = token, claims.NotBefore, claims.ExpiresAt
+
+		_, err = collection.InsertOne(context.Background(), visitor)
 		if err != nil {
 			log.Printf("Error inserting visitor: %v", err)
 		} else {
-			log.Printf("✓ Created visitor: %s for %s", visitor.Name, society.Code)
+			log.Printf("✓ Created visitor: %s for %s (pass %s)", visitor.Name, society.Code, visitor.QRCode)
 		}
 	}
 }